@@ -217,6 +217,9 @@ resource "aws_ec2_transit_gateway_vpc_attachment" "inspection" {
   transit_gateway_id = aws_ec2_transit_gateway.this.id
   vpc_id             = aws_vpc.inspection.id
 
+  # Keep both directions of a flow on the same AZ so the GWLB sees symmetric traffic
+  appliance_mode_support = "enable"
+
   tags = merge(var.tags, { Name = "inspection-tgw-attachment" })
 }
 
@@ -340,12 +343,12 @@ resource "aws_cloudwatch_log_group" "flow_logs" {
 }
 
 # TGW Flow Logs
-resource "aws_ec2_transit_gateway_flow_log" "this" {
-  transit_gateway_id             = aws_ec2_transit_gateway.this.id
-  iam_role_arn                   = aws_iam_role.flow_log.arn
-  log_destination_type           = "cloud-watch-logs"
-  log_destination                = aws_cloudwatch_log_group.tgw_flow_logs.arn
-  max_aggregation_interval       = 60
+resource "aws_flow_log" "tgw" {
+  transit_gateway_id       = aws_ec2_transit_gateway.this.id
+  iam_role_arn             = aws_iam_role.flow_log.arn
+  log_destination_type     = "cloud-watch-logs"
+  log_destination          = aws_cloudwatch_log_group.tgw_flow_logs.arn
+  max_aggregation_interval = 60
 
   tags = merge(var.tags, { Name = "tgw-flow-logs" })
 }
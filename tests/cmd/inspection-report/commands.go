@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// formatExtensions maps each report format to the extension of its rendered file
+var formatExtensions = map[string]string{
+	"json":        ".json",
+	"html":        ".html",
+	"markdown":    ".md",
+	"junit":       ".xml",
+	"sarif":       ".sarif",
+	"github":      ".github.txt",
+	"openmetrics": ".prom",
+	"dashboard":   ".dashboard.html",
+}
+
+// runIngest reads test results and writes them to the report directory
+func runIngest(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("ingest", "FILE... (- reads a go test -json stream from stdin)", stderr)
+	env := fs.String("env", os.Getenv("TEST_ENVIRONMENT"), "environment recorded on go test -json suites")
+	region := fs.String("region", os.Getenv("AWS_REGION"), "region recorded on go test -json suites")
+	owners := fs.String("owners", reporting.OwnershipFileName, "ownership file; skipped when empty or missing")
+	appendRun := fs.Bool("append", false, "merge into the existing report instead of replacing it")
+	history := fs.Bool("history", true, "append the run to the run history")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "at least one input file is required")
+	}
+
+	inputs := make([]*reporting.TestAnalytics, 0, fs.NArg())
+	for _, filename := range fs.Args() {
+		analytics, err := readResults(filename, *env, *region, os.Stdin)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, analytics)
+	}
+	run := reporting.MergeAnalytics(inputs...)
+
+	if *owners != "" {
+		ownership, err := reporting.LoadOwnership(*owners)
+		switch {
+		case err == nil:
+			run.AssignOwners(ownership)
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+
+	quarantine, err := reporting.LoadQuarantine(filepath.Join(*dir, reporting.QuarantineFileName))
+	switch {
+	case err == nil:
+		run.SetQuarantine(quarantine)
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	if *history {
+		info := reporting.RunInfoFromEnv()
+		if err := run.SaveHistory(reporting.NewHistoryStore(*dir), info); err != nil {
+			return fmt.Errorf("failed to record history: %w", err)
+		}
+	}
+
+	report := run
+	if *appendRun {
+		existing, err := reporting.LoadReport(reportPath(*dir))
+		switch {
+		case err == nil:
+			report = reporting.MergeAnalytics(existing, run)
+			if quarantine != nil {
+				report.SetQuarantine(quarantine)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+
+	output, err := report.GenerateReport("json")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(reportPath(*dir), output, stdout); err != nil {
+		return err
+	}
+
+	summary := run.Summary()
+	fmt.Fprintf(stdout, "Ingested %d tests from %d suites (%d passed, %d failed, %d skipped) into %s\n",
+		summary.Tests, summary.Suites, summary.Passed, summary.Failed, summary.Skipped, reportPath(*dir))
+	return nil
+}
+
+// readResults reads a go test -json stream, a JSON report or a JUnit XML report.
+// The format is detected from the contents; stdin must be a go test -json stream.
+func readResults(filename, environment, region string, stdin io.Reader) (*reporting.TestAnalytics, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if isGoTestJSON(data) {
+		analytics := reporting.NewTestAnalytics()
+		if err := analytics.IngestGoTestJSON(bytes.NewReader(data), environment, region); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		return analytics, nil
+	}
+	if filename == "-" {
+		return nil, fmt.Errorf("standard input is not a go test -json stream")
+	}
+	return reporting.LoadReport(filename)
+}
+
+// isGoTestJSON reports whether data starts with a go test -json event. JSON
+// reports are indented, so their first line is not a complete object.
+func isGoTestJSON(data []byte) bool {
+	line, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+	var event reporting.GoTestEvent
+	return json.Unmarshal(line, &event) == nil && event.Action != ""
+}
+
+// runRender renders the ingested report in one or more formats
+func runRender(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("render", "", stderr)
+	formats := fs.String("format", "html,markdown", "comma-separated report formats: "+strings.Join(sortedFormats(), ", "))
+	output := fs.String("o", "", "output file, - for stdout; only with a single format (default <dir>/test-analytics.<ext>)")
+	owner := fs.String("owner", "", "render only the tests of this owner")
+	last := fs.Int("last", 30, "runs of history shown in the dashboard trend charts")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	selected := strings.Split(*formats, ",")
+	for _, format := range selected {
+		if _, ok := formatExtensions[format]; !ok {
+			return usageError(fs, "unsupported format: %s", format)
+		}
+	}
+	if *output != "" && len(selected) > 1 {
+		return usageError(fs, "-o requires a single format")
+	}
+
+	analytics, err := loadReport(*dir, "")
+	if err != nil {
+		return err
+	}
+	name := "test-analytics"
+	if *owner != "" {
+		analytics = analytics.ForOwner(*owner)
+		name += "-" + strings.NewReplacer("@", "", "/", "-").Replace(*owner)
+	}
+
+	for _, format := range selected {
+		var rendered string
+		if format == "dashboard" {
+			history, err := loadHistory(*dir, reporting.HistoryQuery{LastRuns: *last}, nil)
+			if err != nil {
+				return err
+			}
+			if len(history.Results) == 0 {
+				history = nil
+			}
+			rendered, err = analytics.GenerateDashboard(history)
+			if err != nil {
+				return err
+			}
+		} else {
+			rendered, err = analytics.GenerateReport(format)
+			if err != nil {
+				return err
+			}
+		}
+
+		filename := *output
+		if filename == "" {
+			filename = filepath.Join(*dir, name+formatExtensions[format])
+		}
+		if err := writeFile(filename, rendered, stdout); err != nil {
+			return err
+		}
+		if filename != "-" {
+			fmt.Fprintf(stdout, "Wrote %s report: %s\n", format, filename)
+		}
+	}
+	return nil
+}
+
+// sortedFormats returns the supported report formats in alphabetical order
+func sortedFormats() []string {
+	formats := make([]string, 0, len(formatExtensions))
+	for format := range formatExtensions {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// loadHistory reads the runs in the history store that match the query. Runs of
+// exclude, typically the ingested report, are left out so a run is not compared
+// against itself.
+func loadHistory(dir string, query reporting.HistoryQuery, exclude *reporting.TestAnalytics) (*reporting.TestAnalytics, error) {
+	suites, err := reporting.NewHistoryStore(dir).Load(query)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool)
+	if exclude != nil {
+		for _, suite := range exclude.Results {
+			if suite.RunID != "" {
+				excluded[suite.RunID] = true
+			}
+		}
+	}
+
+	history := reporting.NewTestAnalytics()
+	for _, suite := range suites {
+		if !excluded[suite.RunID] {
+			history.AddResult(suite)
+		}
+	}
+	return history, nil
+}
+
+// runTrend prints pass rate and duration trends across the recorded runs
+func runTrend(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("trend", "", stderr)
+	env := fs.String("env", "", "only runs for this environment")
+	region := fs.String("region", "", "only runs for this region")
+	last := fs.Int("last", 10, "number of most recent runs")
+	asJSON := fs.Bool("json", false, "print the trend analysis as JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	history, err := loadHistory(*dir, reporting.HistoryQuery{Environment: *env, Region: *region, LastRuns: *last}, nil)
+	if err != nil {
+		return err
+	}
+
+	trend := history.GetTrendAnalysis()
+	if *asJSON {
+		return writeJSON(stdout, trend)
+	}
+	if message, ok := trend["error"]; ok {
+		fmt.Fprintln(stdout, message)
+		return nil
+	}
+
+	runIDs := trend["run_ids"].([]string)
+	passRates := trend["pass_rate_trend"].([]float64)
+	durations := trend["duration_trend"].([]time.Duration)
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tPASS RATE\tDURATION")
+	for i, runID := range runIDs {
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%v\n", orDash(runID), passRates[i], durations[i].Round(time.Second))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	direction := "stable"
+	switch {
+	case trend["regressing"].(bool):
+		direction = "regressing"
+	case trend["improving"].(bool):
+		direction = "improving"
+	}
+	fmt.Fprintf(stdout, "\nPass rate change: %+.1f points\n", trend["pass_rate_change"].(float64))
+	fmt.Fprintf(stdout, "Duration change: %v\n", trend["duration_change"].(time.Duration).Round(time.Second))
+	fmt.Fprintf(stdout, "Trend: %s\n", direction)
+	return nil
+}
+
+// runFailed lists the failed tests of the ingested report
+func runFailed(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("failed", "", stderr)
+	report := fs.String("report", "", "report to read instead of the ingested report")
+	asJSON := fs.Bool("json", false, "print the failed tests as JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	analytics, err := loadReport(*dir, *report)
+	if err != nil {
+		return err
+	}
+
+	failed := analytics.GetFailedTests()
+	if *asJSON {
+		if failed == nil {
+			failed = []reporting.TestResult{}
+		}
+		if err := writeJSON(stdout, failed); err != nil {
+			return err
+		}
+	} else if len(failed) == 0 {
+		fmt.Fprintln(stdout, "No failed tests")
+	} else {
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PACKAGE\tTEST\tOWNER\tERROR")
+		for _, result := range failed {
+			message, _, _ := strings.Cut(strings.TrimSpace(result.Error), "\n")
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Package, result.TestName, orDash(result.Owner), orDash(message))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if commands := replayCommands(analytics); len(commands) > 0 {
+			fmt.Fprintln(stdout, "\nReplay with the recorded fixture seeds:")
+			for _, command := range commands {
+				fmt.Fprintln(stdout, "  "+command)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d tests failed", len(failed))
+	}
+	return nil
+}
+
+// replayCommands returns, for every suite with failures and a recorded fixture
+// seed, the go test command that reruns its failed tests with the same fixtures
+func replayCommands(analytics *reporting.TestAnalytics) []string {
+	var commands []string
+	for _, suite := range analytics.Results {
+		if suite.Seed == nil || suite.FailedTests == 0 {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		var names []string
+		for _, result := range suite.Results {
+			name, _, _ := strings.Cut(result.TestName, "/")
+			if result.Status == "FAIL" && !seen[name] {
+				seen[name] = true
+				names = append(names, regexp.QuoteMeta(name))
+			}
+		}
+		commands = append(commands, fmt.Sprintf("TEST_SEED=%d go test -run '^(%s)$' %s",
+			*suite.Seed, strings.Join(names, "|"), suite.SuiteName))
+	}
+	return commands
+}
+
+// runSlow lists tests slower than a threshold, or checks them against a budget file
+func runSlow(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("slow", "", stderr)
+	report := fs.String("report", "", "report to read instead of the ingested report")
+	threshold := fs.Duration("threshold", 5*time.Minute, "list tests slower than this")
+	budgetsFile := fs.String("budgets", "", "budget file to check tests against instead of -threshold, e.g. "+reporting.BudgetsFileName)
+	last := fs.Int("last", 10, "runs of history used for budget trends")
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	analytics, err := loadReport(*dir, *report)
+	if err != nil {
+		return err
+	}
+
+	if *budgetsFile != "" {
+		budgets, err := reporting.LoadBudgets(*budgetsFile)
+		if err != nil {
+			return err
+		}
+		history, err := loadHistory(*dir, reporting.HistoryQuery{LastRuns: *last}, analytics)
+		if err != nil {
+			return err
+		}
+
+		budgetReport := budgets.Evaluate(analytics, history)
+		if *asJSON {
+			if err := writeJSON(stdout, budgetReport); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprint(stdout, budgetReport.Markdown())
+		}
+		return budgetReport.Err()
+	}
+
+	slow := analytics.GetSlowTests(*threshold)
+	sort.SliceStable(slow, func(i, j int) bool {
+		return slow[i].Duration > slow[j].Duration
+	})
+	if *asJSON {
+		if slow == nil {
+			slow = []reporting.TestResult{}
+		}
+		return writeJSON(stdout, slow)
+	}
+	if len(slow) == 0 {
+		fmt.Fprintf(stdout, "No tests slower than %v\n", *threshold)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DURATION\tPACKAGE\tTEST\tSTATUS")
+	for _, result := range slow {
+		fmt.Fprintf(tw, "%v\t%s\t%s\t%s\n", result.Duration.Round(time.Millisecond), result.Package, result.TestName, result.Status)
+	}
+	return tw.Flush()
+}
+
+// runCompare compares the ingested report against a baseline and fails on regressions
+func runCompare(args []string, stdout, stderr io.Writer) error {
+	fs, dir := newFlagSet("compare", "", stderr)
+	baselineFile := fs.String("baseline", "", "baseline report (JSON or JUnit XML)")
+	candidateFile := fs.String("candidate", "", "candidate report (default the ingested report)")
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	regressionPercent := fs.Float64("regression-percent", reporting.DefaultDurationRegressionPercent, "report tests that slowed down by more than this percentage")
+	minDuration := fs.Duration("min-duration", reporting.DefaultMinRegressionDuration, "ignore slowdowns of tests shorter than this in both runs")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *baselineFile == "" {
+		return usageError(fs, "-baseline is required")
+	}
+	if *format != "markdown" && *format != "json" {
+		return usageError(fs, "unsupported format: %s", *format)
+	}
+
+	baseline, err := reporting.LoadReport(*baselineFile)
+	if err != nil {
+		return err
+	}
+	candidate, err := loadReport(*dir, *candidateFile)
+	if err != nil {
+		return err
+	}
+
+	comparison := reporting.CompareRunsWithOptions(baseline, candidate, reporting.CompareOptions{
+		DurationRegressionPercent: *regressionPercent,
+		MinDuration:               *minDuration,
+	})
+	if *format == "json" {
+		output, err := comparison.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, output)
+	} else {
+		fmt.Fprint(stdout, comparison.Markdown())
+	}
+
+	if comparison.HasRegressions() {
+		return fmt.Errorf("%d newly failing tests, %d duration regressions",
+			len(comparison.NewlyFailing), len(comparison.DurationRegressions))
+	}
+	return nil
+}
+
+// writeJSON writes a value as indented JSON
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
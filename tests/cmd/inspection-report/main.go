@@ -0,0 +1,154 @@
+// Command inspection-report ingests test results into the report directory and
+// renders, queries and compares them with the reporting package.
+//
+// Usage:
+//
+//	inspection-report <command> [flags] [args]
+//
+// Results are ingested into test-analytics.json inside the report directory
+// (test-reports by default, as used by ExportResults) and every ingested run is
+// appended to the run history kept alongside it.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// reportFileName is the report inside the report directory that ingest writes and
+// the other commands read
+const reportFileName = "test-analytics.json"
+
+// errUsage reports invalid command line arguments, which exit with status 2
+var errUsage = errors.New("usage")
+
+// command is a subcommand of inspection-report
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage text
+var commands = []command{
+	{"ingest", "Ingest go test -json streams, JSON reports or JUnit XML into the report directory", runIngest},
+	{"render", "Render the ingested report in one or more formats", runRender},
+	{"trend", "Show pass rate and duration trends from the run history", runTrend},
+	{"failed", "List failed tests", runFailed},
+	{"slow", "List slow tests, or tests over their duration budget", runSlow},
+	{"compare", "Compare the ingested report against a baseline report", runCompare},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit status: 0 on success, 1 when
+// the command fails or finds failures, regressions or budget violations, and 2 on
+// invalid arguments
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdout, stderr)
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			return 2
+		default:
+			fmt.Fprintf(stderr, "inspection-report %s: %v\n", cmd.name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stderr, "inspection-report: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+// usage writes the list of commands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: inspection-report <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'inspection-report <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates the flag set of a command with the shared -dir flag
+func newFlagSet(name, args string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: inspection-report %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", reporting.DefaultReportDir, "report directory")
+	return fs, dir
+}
+
+// parseFlags parses the arguments of a command, mapping parse failures to errUsage
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// usageError prints a usage problem and the command's usage
+func usageError(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+	return errUsage
+}
+
+// reportPath returns the location of the ingested report
+func reportPath(dir string) string {
+	return filepath.Join(dir, reportFileName)
+}
+
+// loadReport reads the ingested report, or the given file when one is set
+func loadReport(dir, filename string) (*reporting.TestAnalytics, error) {
+	if filename == "" {
+		filename = reportPath(dir)
+	}
+	analytics, err := reporting.LoadReport(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no report at %s, run inspection-report ingest first", filename)
+	}
+	return analytics, err
+}
+
+// writeFile writes output to a file, or to stdout when filename is "-"
+func writeFile(filename, output string, stdout io.Writer) error {
+	if filename == "-" {
+		_, err := io.WriteString(stdout, output)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(output), 0644)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const passingEvents = `{"Time":"2024-01-15T10:00:00Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestVPCCreation"}
+{"Time":"2024-01-15T10:00:02Z","Action":"pass","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestVPCCreation","Elapsed":2}
+{"Time":"2024-01-15T10:00:00Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway"}
+{"Time":"2024-01-15T10:00:03Z","Action":"pass","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway","Elapsed":3}
+{"Time":"2024-01-15T10:00:03Z","Action":"pass","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Elapsed":3}
+`
+
+const failingEvents = `{"Time":"2024-01-16T10:00:00Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestVPCCreation"}
+{"Time":"2024-01-16T10:00:02Z","Action":"pass","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestVPCCreation","Elapsed":2}
+{"Time":"2024-01-16T10:00:00Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway"}
+{"Time":"2024-01-16T10:00:00Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway","Output":"    test_data.go:120: fixtures: TEST_SEED=1234 environment=dev region=us-east-1\n"}
+{"Time":"2024-01-16T10:00:01Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway","Output":"    network_test.go:42: route table not propagated\n"}
+{"Time":"2024-01-16T10:00:40Z","Action":"fail","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Test":"TestTransitGateway","Elapsed":40}
+{"Time":"2024-01-16T10:00:40Z","Action":"fail","Package":"github.com/your-org/aws-centralized-inspection/tests/network","Elapsed":40}
+`
+
+// runCLI runs the command line and returns the exit status and output
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// ingest writes events to a file and ingests them as the given run
+func ingest(t *testing.T, dir, runID, events string) {
+	t.Helper()
+	t.Setenv("TEST_RUN_ID", runID)
+
+	input := filepath.Join(t.TempDir(), "unit-tests.json")
+	require.NoError(t, os.WriteFile(input, []byte(events), 0644))
+
+	code, stdout, stderr := runCLI(t, "ingest", "-dir", dir, "-env", "dev", "-region", "us-east-1", "-owners", "", input)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Ingested 2 tests from 1 suites")
+}
+
+func TestIngestAndRender(t *testing.T) {
+	dir := t.TempDir()
+	ingest(t, dir, "run-1", passingEvents)
+
+	assert.FileExists(t, filepath.Join(dir, reportFileName))
+	assert.FileExists(t, filepath.Join(dir, "history.jsonl"))
+
+	code, stdout, stderr := runCLI(t, "render", "-dir", dir, "-format", "markdown,junit,dashboard")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Wrote markdown report")
+	assert.FileExists(t, filepath.Join(dir, "test-analytics.md"))
+	assert.FileExists(t, filepath.Join(dir, "test-analytics.xml"))
+	assert.FileExists(t, filepath.Join(dir, "test-analytics.dashboard.html"))
+
+	code, stdout, _ = runCLI(t, "render", "-dir", dir, "-format", "openmetrics", "-o", "-")
+	require.Equal(t, 0, code)
+	assert.True(t, strings.HasSuffix(stdout, "# EOF\n"))
+}
+
+func TestIngestJUnitAppend(t *testing.T) {
+	dir := t.TempDir()
+	ingest(t, dir, "run-1", passingEvents)
+
+	junit := filepath.Join(t.TempDir(), "chaos.xml")
+	require.NoError(t, os.WriteFile(junit, []byte(`<testsuites>
+  <testsuite name="Chaos Tests" tests="1" failures="0">
+    <testcase name="TestAZFailure" classname="chaos" time="12.5"></testcase>
+  </testsuite>
+</testsuites>`), 0644))
+
+	code, stdout, stderr := runCLI(t, "ingest", "-dir", dir, "-owners", "", "-history=false", "-append", junit)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Ingested 1 tests from 1 suites")
+
+	code, stdout, _ = runCLI(t, "slow", "-dir", dir, "-threshold", "10s")
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout, "TestAZFailure")
+	assert.NotContains(t, stdout, "TestVPCCreation")
+}
+
+func TestFailed(t *testing.T) {
+	dir := t.TempDir()
+	ingest(t, dir, "run-1", failingEvents)
+
+	code, stdout, stderr := runCLI(t, "failed", "-dir", dir)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "TestTransitGateway")
+	assert.Contains(t, stdout, "route table not propagated")
+	assert.Contains(t, stdout, "TEST_SEED=1234 go test -run '^(TestTransitGateway)$' github.com/your-org/aws-centralized-inspection/tests/network")
+	assert.Contains(t, stderr, "1 tests failed")
+
+	passing := t.TempDir()
+	ingest(t, passing, "run-1", passingEvents)
+	code, stdout, _ = runCLI(t, "failed", "-dir", passing)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "No failed tests")
+}
+
+func TestCompare(t *testing.T) {
+	baselineDir := t.TempDir()
+	ingest(t, baselineDir, "run-1", passingEvents)
+
+	dir := t.TempDir()
+	ingest(t, dir, "run-2", failingEvents)
+
+	code, stdout, stderr := runCLI(t, "compare", "-dir", dir, "-baseline", filepath.Join(baselineDir, reportFileName))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "TestTransitGateway")
+	assert.Contains(t, stderr, "1 newly failing tests")
+
+	code, _, _ = runCLI(t, "compare", "-dir", baselineDir, "-baseline", filepath.Join(baselineDir, reportFileName))
+	assert.Equal(t, 0, code)
+}
+
+func TestSlowBudgets(t *testing.T) {
+	dir := t.TempDir()
+	ingest(t, dir, "run-1", passingEvents)
+	ingest(t, dir, "run-2", failingEvents)
+
+	budgets := filepath.Join(t.TempDir(), "test-budgets.json")
+	require.NoError(t, os.WriteFile(budgets, []byte(`{"enforce": true, "default": "30s"}`), 0644))
+
+	code, stdout, stderr := runCLI(t, "slow", "-dir", dir, "-budgets", budgets)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "| TestTransitGateway |")
+	assert.Contains(t, stdout, "| slower |")
+	assert.Contains(t, stderr, "over duration budget")
+}
+
+func TestTrend(t *testing.T) {
+	dir := t.TempDir()
+
+	code, stdout, _ := runCLI(t, "trend", "-dir", dir)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Need at least 2")
+
+	ingest(t, dir, "run-1", passingEvents)
+	ingest(t, dir, "run-2", failingEvents)
+
+	code, stdout, _ = runCLI(t, "trend", "-dir", dir)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "run-1")
+	assert.Contains(t, stdout, "run-2")
+	assert.Contains(t, stdout, "Pass rate change: -50.0 points")
+	assert.Contains(t, stdout, "Trend: regressing")
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runCLI(t, "publish")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "publish"`)
+
+	code, _, _ = runCLI(t, "render", "-format", "pdf")
+	assert.Equal(t, 2, code)
+
+	code, _, stderr = runCLI(t, "failed", "-dir", t.TempDir())
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "run inspection-report ingest first")
+}
@@ -0,0 +1,117 @@
+// Command module-contract checks the terraform.Options in the test sources against
+// the variables.tf and outputs.tf of the modules they deploy, without running
+// Terraform. It reports variables a module does not declare, values of the wrong
+// type, required variables that are not set and outputs a module does not declare.
+//
+// Usage:
+//
+//	module-contract [-json] [-baseline file [-update-baseline]] [dir ...]
+//
+// Every Go package under the given directories (. by default) is checked. Module
+// directories are resolved relative to each package, as go test does. Variables
+// set as TF_VAR_ environment variables count as set. Findings listed in the
+// baseline file are accepted; -update-baseline rewrites it with the current
+// findings.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/your-org/aws-centralized-inspection/tests/contract"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit status: 0 when the sources
+// match the modules, 1 on findings or errors and 2 on invalid arguments
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("module-contract", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the findings as JSON")
+	baselineFile := flags.String("baseline", "", "file of accepted findings")
+	updateBaseline := flags.Bool("update-baseline", false, "rewrite the baseline file with the current findings")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *updateBaseline && *baselineFile == "" {
+		fmt.Fprintln(stderr, "module-contract: -update-baseline requires -baseline")
+		return 2
+	}
+
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	findings, err := contract.NewChecker().CheckTree(roots...)
+	if err != nil {
+		fmt.Fprintf(stderr, "module-contract: %v\n", err)
+		return 1
+	}
+
+	if *updateBaseline {
+		if err := writeBaseline(*baselineFile, findings); err != nil {
+			fmt.Fprintf(stderr, "module-contract: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stderr, "module-contract: %d findings written to %s\n", len(findings), *baselineFile)
+		return 0
+	}
+	if *baselineFile != "" {
+		baseline, err := contract.LoadBaseline(*baselineFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "module-contract: %v\n", err)
+			return 1
+		}
+		var stale []string
+		findings, stale = baseline.Filter(findings)
+		for _, entry := range stale {
+			fmt.Fprintf(stderr, "module-contract: baseline entry no longer found, remove it: %s\n", entry)
+		}
+	}
+
+	if *asJSON {
+		if findings == nil {
+			findings = []contract.Finding{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			fmt.Fprintf(stderr, "module-contract: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, finding := range findings {
+			fmt.Fprintln(stdout, finding)
+		}
+	}
+
+	if len(findings) > 0 {
+		fmt.Fprintf(stderr, "module-contract: %d findings\n", len(findings))
+		return 1
+	}
+	return 0
+}
+
+// writeBaseline writes the findings to a baseline file
+func writeBaseline(filename string, findings []contract.Finding) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := contract.WriteBaseline(f, findings); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "modules", "net")
+	pkg := filepath.Join(root, "tests", "net")
+	require.NoError(t, os.MkdirAll(module, 0755))
+	require.NoError(t, os.MkdirAll(pkg, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(module, "variables.tf"), []byte(`variable "vpc_cidr" {
+  type = string
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkg, "net_test.go"), []byte(`package net_test
+
+func TestNet(t *testing.T) {
+	options := &terraform.Options{
+		TerraformDir: "../../modules/net",
+		Vars:         map[string]interface{}{"vpc_cidr": "10.0.0.0/16"},
+	}
+	terraform.Output(t, options, "vpc_id")
+}
+`), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-json", filepath.Join(root, "tests")}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "1 findings")
+
+	var findings []map[string]string
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &findings))
+	require.Len(t, findings, 1)
+	assert.Equal(t, "unknown-output", findings[0]["kind"])
+	assert.Equal(t, "vpc_id", findings[0]["name"])
+
+	stdout.Reset()
+	code = run([]string{filepath.Join(root, "modules")}, &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stdout.String())
+
+	assert.Equal(t, 2, run([]string{"-format"}, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-update-baseline", root}, &stdout, &stderr))
+
+	// A baseline accepts the known findings, so only new ones fail the check
+	baseline := filepath.Join(root, "contract-baseline.txt")
+	stdout.Reset()
+	stderr.Reset()
+	require.Equal(t, 0, run([]string{"-baseline", baseline, "-update-baseline", filepath.Join(root, "tests")}, &stdout, &stderr))
+	assert.Equal(t, 0, run([]string{"-baseline", baseline, filepath.Join(root, "tests")}, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(pkg, "net_test.go"), []byte(`package net_test
+
+func TestNet(t *testing.T) {
+	options := &terraform.Options{
+		TerraformDir: "../../modules/net",
+		Vars:         map[string]interface{}{"vpc_cidr": "10.0.0.0/16"},
+	}
+	terraform.Output(t, options, "subnet_ids")
+}
+`), 0644))
+	assert.Equal(t, 1, run([]string{"-baseline", baseline, filepath.Join(root, "tests")}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), `output "subnet_ids" is not declared`)
+	assert.Contains(t, stderr.String(), "baseline entry no longer found")
+}
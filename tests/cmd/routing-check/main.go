@@ -0,0 +1,111 @@
+// Command routing-check checks the deployed routes of the hub-and-spoke topology
+// through the EC2 API with the routecheck package: spoke routes to the other
+// spokes, return routes in the private route tables of the inspection VPC and
+// default routes.
+//
+// Usage:
+//
+//	routing-check [-json] [-v] [flags]
+//
+// The VPCs default to INSPECTION_VPC_ID and SPOKE_VPC_IDS, and the spoke CIDRs to
+// SPOKE_VPC_CIDRS or else the CIDR blocks of the spoke VPCs. Lists are separated by
+// commas or spaces. Failed routes are printed, or with -json every route checked is
+// printed as a JSON report that inspection-report ingest reads.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+	"github.com/your-org/aws-centralized-inspection/tests/routecheck"
+)
+
+// newEC2API returns the EC2 client of a region; tests replace it with a fake
+var newEC2API = routecheck.NewEC2API
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit status: 0 when every route
+// is correct, 1 on failed routes or errors and 2 on invalid arguments
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("routing-check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	region := flags.String("region", os.Getenv("AWS_REGION"), "AWS region")
+	inspectionVPC := flags.String("inspection-vpc", os.Getenv("INSPECTION_VPC_ID"), "inspection VPC ID")
+	spokeVPCs := flags.String("spoke-vpcs", os.Getenv("SPOKE_VPC_IDS"), "spoke VPC IDs")
+	spokeCIDRs := flags.String("spoke-cidrs", os.Getenv("SPOKE_VPC_CIDRS"), "spoke CIDRs, by default the CIDR blocks of the spoke VPCs")
+	privateTables := flags.String("private-tables", routecheck.DefaultPrivateRouteTableName, "Name tag pattern of the private route tables of the inspection VPC")
+	transitGateway := flags.String("transit-gateway", os.Getenv("TRANSIT_GATEWAY_ID"), "transit gateway return routes must target, any when empty")
+	env := flags.String("env", os.Getenv("TEST_ENVIRONMENT"), "environment recorded in the JSON report")
+	asJSON := flags.Bool("json", false, "print every route checked as a JSON report")
+	verbose := flags.Bool("v", false, "print the routes that pass too")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "routing-check: unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	api, err := newEC2API(*region)
+	if err != nil {
+		fmt.Fprintf(stderr, "routing-check: %v\n", err)
+		return 1
+	}
+	checker := routecheck.NewChecker(api, routecheck.Config{
+		InspectionVPCID:       *inspectionVPC,
+		SpokeVPCIDs:           splitList(*spokeVPCs),
+		SpokeCIDRs:            splitList(*spokeCIDRs),
+		PrivateRouteTableName: *privateTables,
+		TransitGatewayID:      *transitGateway,
+	})
+
+	start := time.Now()
+	results, err := checker.Run(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "routing-check: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		analytics := reporting.NewTestAnalytics()
+		analytics.AddResult(routecheck.Suite(results, *env, *region, start, time.Now()))
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(analytics); err != nil {
+			fmt.Fprintf(stderr, "routing-check: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, result := range results {
+			if *verbose || !result.Passed {
+				fmt.Fprintln(stdout, result)
+			}
+		}
+	}
+
+	failed := routecheck.Failed(results)
+	fmt.Fprintf(stderr, "routing-check: %d routes checked, %d failed\n", len(results), len(failed))
+	if len(failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// splitList splits a list separated by commas or spaces
+func splitList(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' })
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+	"github.com/your-org/aws-centralized-inspection/tests/routecheck"
+)
+
+func TestRun(t *testing.T) {
+	fake := routecheck.NewFakeEC2()
+	fake.AddVPC("vpc-inspection", "10.0.0.0/16")
+	fake.AddVPC("vpc-spoke-0", "10.1.0.0/16")
+	fake.AddVPC("vpc-spoke-1", "10.2.0.0/16")
+	fake.AddRouteTable("rtb-private", "vpc-inspection", "inspection-private-rt-0", []string{"subnet-private-0"},
+		routecheck.Route("0.0.0.0/0", "nat-0"),
+		routecheck.Route("10.0.0.0/8", "tgw-inspection"))
+	fake.AddRouteTable("rtb-spoke-0", "vpc-spoke-0", "spoke-rt-0", []string{"subnet-spoke-0"},
+		routecheck.Route("10.2.0.0/16", "vpce-spoke-0"))
+	fake.AddGWLBEndpoint("vpce-spoke-0", "vpc-spoke-0", "subnet-spoke-0")
+	fake.AddRouteTable("rtb-spoke-1", "vpc-spoke-1", "spoke-rt-1", []string{"subnet-spoke-1"},
+		routecheck.Route("10.1.0.0/16", "tgw-inspection"))
+	fake.AddGWLBEndpoint("vpce-spoke-1", "vpc-spoke-1", "subnet-spoke-1")
+
+	var region string
+	newEC2API = func(r string) (routecheck.EC2API, error) {
+		region = r
+		return fake, nil
+	}
+	t.Cleanup(func() { newEC2API = routecheck.NewEC2API })
+
+	args := []string{"-region", "us-east-1", "-inspection-vpc", "vpc-inspection", "-spoke-vpcs", "vpc-spoke-0 vpc-spoke-1"}
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "us-east-1", region)
+	assert.Equal(t, "FAIL spoke-route/rtb-spoke-1/10.1.0.0/16: 10.1.0.0/16 routes to tgw-inspection, expected a GWLB endpoint of vpc-spoke-1\n", stdout.String())
+	assert.Equal(t, "routing-check: 5 routes checked, 1 failed\n", stderr.String())
+
+	// The JSON report is read like the other reports
+	stdout.Reset()
+	code = run(append([]string{"-json", "-env", "prod"}, args...), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	report := filepath.Join(t.TempDir(), "routing-check.json")
+	require.NoError(t, os.WriteFile(report, stdout.Bytes(), 0644))
+	analytics, err := reporting.LoadReport(report)
+	require.NoError(t, err)
+	require.Len(t, analytics.Results, 1)
+	assert.Equal(t, "prod", analytics.Results[0].Environment)
+	assert.Equal(t, 5, analytics.Results[0].TotalTests)
+	require.Len(t, analytics.GetFailedTests(), 1)
+	assert.Equal(t, "spoke-route/rtb-spoke-1/10.1.0.0/16", analytics.GetFailedTests()[0].TestName)
+
+	// Correct routes pass
+	fake.RouteTables[2].Routes[1] = routecheck.Route("10.1.0.0/16", "vpce-spoke-1")
+	stdout.Reset()
+	stderr.Reset()
+	assert.Equal(t, 0, run(append([]string{"-v"}, args...), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "PASS return-route/rtb-private/10.2.0.0/16: 10.2.0.0/16 routes via 10.0.0.0/8 to tgw-inspection\n")
+
+	assert.Equal(t, 1, run([]string{"-inspection-vpc", "", "-spoke-vpcs", ""}, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"-format"}, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"vpc-spoke-0"}, &stdout, &stderr))
+}
@@ -0,0 +1,62 @@
+package compliance_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/netsec"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// internetHost is an address on the internet, from the documentation range
+var internetHost = netip.MustParseAddr("203.0.113.10")
+
+// host returns the first host address of a CIDR block
+func host(t *testing.T, cidr string) netip.Addr {
+	t.Helper()
+	prefix, err := netip.ParsePrefix(cidr)
+	require.NoError(t, err)
+	return prefix.Masked().Addr().Next()
+}
+
+// assertNetworkSecurityControls checks the network ACLs of the inspection VPC:
+// they open no management or database port to the internet and pass traffic
+// between the spokes through the firewalls
+func assertNetworkSecurityControls(t *testing.T, rules *netsec.RuleSet, vpcCIDR string, spokeCIDRs []string) {
+	t.Helper()
+	require.NotEmpty(t, rules.NetworkACLs, "Network ACLs should be created")
+	for _, finding := range rules.Permissive() {
+		assert.Fail(t, "Overly permissive rule", finding.String())
+	}
+
+	internet := netsec.Endpoint{}
+	inside := host(t, vpcCIDR)
+	for _, acl := range rules.NetworkACLs {
+		inspection := netsec.Endpoint{NetworkACL: acl}
+		for _, port := range netsec.ManagementPorts {
+			verdict := netsec.Evaluate(netsec.NewFlow(port.Protocol, internetHost, inside, port.Number), internet, inspection)
+			assert.False(t, verdict.Allowed, "%s should be closed to the internet: %s", port.Service, verdict)
+		}
+		for i, cidr := range spokeCIDRs {
+			destination := host(t, spokeCIDRs[(i+1)%len(spokeCIDRs)])
+			verdict := netsec.Evaluate(netsec.NewFlow("tcp", host(t, cidr), destination, 443), inspection, inspection)
+			assert.True(t, verdict.Allowed, "Spoke traffic should reach the firewalls: %s", verdict)
+		}
+	}
+}
+
+// TestNetworkSecurityControlsPlan checks the network security controls of the
+// recorded network plan, without AWS
+func TestNetworkSecurityControlsPlan(t *testing.T) {
+	t.Parallel()
+
+	networkPlan, err := plan.Load("../network/testdata/network_plan.json")
+	require.NoError(t, err)
+	rules, err := netsec.FromPlan(networkPlan)
+	require.NoError(t, err)
+	assertNetworkSecurityControls(t, rules, "10.10.0.0/16", []string{"10.11.0.0/16", "10.12.0.0/16"})
+	assert.NotEmpty(t, networkPlan.Resources("aws_flow_log"), "VPC Flow Logs should be enabled")
+}
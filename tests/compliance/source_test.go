@@ -0,0 +1,121 @@
+package compliance_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/inventory"
+)
+
+// loadInventory loads the module sources
+func loadInventory(t *testing.T) *inventory.Inventory {
+	t.Helper()
+	inv, err := inventory.Load(fixtures.ModulesDir)
+	require.NoError(t, err)
+	return inv
+}
+
+// assertLiteral checks that an attribute of a resource is set to a constant value
+func assertLiteral(t *testing.T, resource *inventory.Resource, path string, expected cty.Value) {
+	t.Helper()
+	value, ok := resource.Literal(path)
+	if !assert.True(t, ok, "%s: %s is not set to a constant", resource, path) {
+		return
+	}
+	assert.True(t, value.Equals(expected).True(), "%s: %s is %#v, expected %#v", resource, path, value, expected)
+}
+
+// TestS3BucketsBlockPublicAccess checks every S3 bucket has a public access block
+// that turns on all four settings
+func TestS3BucketsBlockPublicAccess(t *testing.T) {
+	t.Parallel()
+	inv := loadInventory(t)
+
+	buckets := inv.ResourcesOfType("aws_s3_bucket")
+	require.NotEmpty(t, buckets)
+	for _, bucket := range buckets {
+		blocks := inv.Referencing(bucket, "aws_s3_bucket_public_access_block")
+		if !assert.Len(t, blocks, 1, "%s needs a public access block", bucket) {
+			continue
+		}
+		for _, setting := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+			assertLiteral(t, blocks[0], setting, cty.True)
+		}
+	}
+}
+
+// TestS3BucketsEncryptedAndVersioned checks every S3 bucket has default encryption
+// and versioning
+func TestS3BucketsEncryptedAndVersioned(t *testing.T) {
+	t.Parallel()
+	inv := loadInventory(t)
+
+	for _, bucket := range inv.ResourcesOfType("aws_s3_bucket") {
+		encryption := inv.Referencing(bucket, "aws_s3_bucket_server_side_encryption_configuration")
+		if assert.Len(t, encryption, 1, "%s needs default encryption", bucket) {
+			_, ok := encryption[0].AttributeExpr("rule.apply_server_side_encryption_by_default.sse_algorithm")
+			assert.True(t, ok, "%s sets no encryption algorithm", encryption[0])
+		}
+		versioning := inv.Referencing(bucket, "aws_s3_bucket_versioning")
+		if assert.Len(t, versioning, 1, "%s needs versioning", bucket) {
+			assertLiteral(t, versioning[0], "versioning_configuration.status", cty.StringVal("Enabled"))
+		}
+	}
+}
+
+// TestKMSKeysRotate checks every customer managed key rotates
+func TestKMSKeysRotate(t *testing.T) {
+	t.Parallel()
+	inv := loadInventory(t)
+
+	for _, key := range inv.ResourcesOfType("aws_kms_key") {
+		assertLiteral(t, key, "enable_key_rotation", cty.True)
+	}
+}
+
+// TestLogGroupsRetainForAudit checks every log group keeps logs at least as long as
+// the prod profile's flow log retention
+func TestLogGroupsRetainForAudit(t *testing.T) {
+	t.Parallel()
+	inv := loadInventory(t)
+	profiles, err := fixtures.DefaultProfiles()
+	require.NoError(t, err)
+	profile, err := profiles.Profile("prod", "us-east-1")
+	require.NoError(t, err)
+
+	groups := inv.ResourcesOfType("aws_cloudwatch_log_group")
+	require.NotEmpty(t, groups)
+	for _, group := range groups {
+		value, ok := group.Literal("retention_in_days")
+		if !assert.True(t, ok, "%s: retention_in_days is not set to a constant", group) {
+			continue
+		}
+		days, _ := value.AsBigFloat().Int64()
+		assert.GreaterOrEqual(t, days, int64(profile.Monitoring.FlowLogsRetentionDays), "%s keeps logs for %d days", group, days)
+	}
+}
+
+// TestLaunchTemplatesHarden checks launch templates require IMDSv2 and encrypt every
+// EBS volume
+func TestLaunchTemplatesHarden(t *testing.T) {
+	t.Parallel()
+	inv := loadInventory(t)
+
+	for _, template := range inv.ResourcesOfType("aws_launch_template") {
+		assertLiteral(t, template, "metadata_options.http_tokens", cty.StringVal("required"))
+		for _, mapping := range inventory.Blocks(template.Body, "block_device_mappings") {
+			for _, ebs := range inventory.Blocks(mapping, "ebs") {
+				attribute, ok := ebs.Attributes["encrypted"]
+				if !assert.True(t, ok, "%s has an EBS volume without encrypted", template) {
+					continue
+				}
+				value, diags := attribute.Expr.Value(nil)
+				assert.True(t, !diags.HasErrors() && value.Type() == cty.Bool && value.True(), "%s has an unencrypted EBS volume", template)
+			}
+		}
+	}
+}
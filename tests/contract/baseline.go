@@ -0,0 +1,101 @@
+package contract
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Baseline is a set of accepted findings, such as the known mismatches of tests
+// that predate the checker. Entries are keyed by file and message, without the
+// line, so edits elsewhere in a file don't invalidate them.
+type Baseline map[string]bool
+
+// BaselineKey returns the baseline entry of a finding: its position without the
+// line number, then its message
+func BaselineKey(f Finding) string {
+	file := f.Pos
+	if i := strings.LastIndex(file, ":"); i >= 0 {
+		file = file[:i]
+	}
+	return file + ": " + f.Message
+}
+
+// ParseBaseline parses a baseline file. Each non-empty line that does not start
+// with # is an entry as returned by BaselineKey.
+func ParseBaseline(r io.Reader) (Baseline, error) {
+	baseline := make(Baseline)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		baseline[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// LoadBaseline reads a baseline file
+func LoadBaseline(filename string) (Baseline, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	baseline, err := ParseBaseline(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline file %s: %w", filename, err)
+	}
+	return baseline, nil
+}
+
+// Filter splits findings into those the baseline does not accept and returns, in
+// order, the baseline entries that no longer match any finding
+func (b Baseline) Filter(findings []Finding) (remaining []Finding, stale []string) {
+	matched := make(map[string]bool)
+	for _, finding := range findings {
+		key := BaselineKey(finding)
+		if b[key] {
+			matched[key] = true
+			continue
+		}
+		remaining = append(remaining, finding)
+	}
+
+	for key := range b {
+		if !matched[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	return remaining, stale
+}
+
+// WriteBaseline writes a baseline accepting the findings, one sorted entry per line
+func WriteBaseline(w io.Writer, findings []Finding) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, finding := range findings {
+		if key := BaselineKey(finding); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Known module contract findings, accepted by make check-contracts.")
+	fmt.Fprintln(bw, "# Fix a finding and remove its line; regenerate with -update-baseline.")
+	for _, key := range keys {
+		fmt.Fprintln(bw, key)
+	}
+	return bw.Flush()
+}
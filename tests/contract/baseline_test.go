@@ -0,0 +1,34 @@
+package contract_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/contract"
+)
+
+func TestBaseline(t *testing.T) {
+	findings := []contract.Finding{
+		{Pos: "network/network_test.go:37", Message: `output "public_subnet_ids" is not declared by ../modules/network`},
+		{Pos: "network/network_test.go:94", Message: `output "public_subnet_ids" is not declared by ../modules/network`},
+		{Pos: "inspection/inspection_test.go:56", Message: `required variable "inspection_vpc_cidr" of ../modules/inspection is not set`},
+	}
+
+	var out strings.Builder
+	require.NoError(t, contract.WriteBaseline(&out, findings[:2]))
+	assert.Contains(t, out.String(), "\nnetwork/network_test.go: output \"public_subnet_ids\" is not declared by ../modules/network\n")
+	assert.Equal(t, 1, strings.Count(out.String(), "public_subnet_ids"), "entries are unique")
+
+	baseline, err := contract.ParseBaseline(strings.NewReader(out.String() + "\nchaos/chaos_test.go: fixed since\n"))
+	require.NoError(t, err)
+	assert.Len(t, baseline, 2, "comments and blank lines are skipped")
+
+	// A baseline entry accepts the finding wherever it moves in its file
+	moved := append([]contract.Finding{{Pos: "network/network_test.go:120", Message: findings[0].Message}}, findings[2])
+	remaining, stale := baseline.Filter(moved)
+	assert.Equal(t, []contract.Finding{findings[2]}, remaining)
+	assert.Equal(t, []string{"chaos/chaos_test.go: fixed since"}, stale)
+}
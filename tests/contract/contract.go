@@ -0,0 +1,416 @@
+package contract
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Finding kinds
+const (
+	UnknownVariable = "unknown-variable"
+	TypeMismatch    = "type-mismatch"
+	MissingVariable = "missing-variable"
+	UnknownOutput   = "unknown-output"
+	MissingModule   = "missing-module"
+)
+
+// Finding is a mismatch between what a test passes to or reads from a module and
+// what the module declares
+type Finding struct {
+	Pos     string `json:"pos"`
+	Module  string `json:"module"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the finding like a compiler error
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Pos, f.Message)
+}
+
+// Variable is an input variable declared by a module
+type Variable struct {
+	Name     string
+	Type     cty.Type // cty.DynamicPseudoType when the variable has no type constraint
+	Required bool     // no default, so every caller has to set it
+}
+
+// Module is the variable and output contract of a Terraform module
+type Module struct {
+	Dir       string
+	Variables map[string]Variable
+	Outputs   map[string]bool
+}
+
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "type"}, {Name: "default"}},
+}
+
+// LoadModule parses the variable and output blocks of the .tf files in dir
+func LoadModule(dir string) (*Module, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Terraform configuration in %s", dir)
+	}
+
+	module := &Module{Dir: dir, Variables: make(map[string]Variable), Outputs: make(map[string]bool)}
+	parser := hclparse.NewParser()
+	for _, filename := range files {
+		file, diags := parser.ParseHCLFile(filename)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		content, _, diags := file.Body.PartialContent(moduleSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, block := range content.Blocks {
+			name := block.Labels[0]
+			if block.Type == "output" {
+				module.Outputs[name] = true
+				continue
+			}
+
+			attributes, _, diags := block.Body.PartialContent(variableSchema)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			variable := Variable{Name: name, Type: cty.DynamicPseudoType}
+			if attribute, ok := attributes.Attributes["type"]; ok {
+				if variable.Type, err = typeConstraint(attribute.Expr); err != nil {
+					return nil, fmt.Errorf("%s: variable %s: %w", attribute.Range, name, err)
+				}
+			}
+			_, variable.Required = attributes.Attributes["default"]
+			variable.Required = !variable.Required
+			module.Variables[name] = variable
+		}
+	}
+	return module, nil
+}
+
+// typeConstraint decodes a variable type expression, including optional object
+// attributes
+func typeConstraint(expr hcl.Expression) (cty.Type, error) {
+	switch keyword := hcl.ExprAsKeyword(expr); keyword {
+	case "string":
+		return cty.String, nil
+	case "number":
+		return cty.Number, nil
+	case "bool":
+		return cty.Bool, nil
+	case "any":
+		return cty.DynamicPseudoType, nil
+	case "list", "set", "map":
+		return cty.DynamicPseudoType, nil
+	case "":
+	default:
+		return cty.NilType, fmt.Errorf("unknown type keyword %q", keyword)
+	}
+
+	call, diags := hcl.ExprCall(expr)
+	if diags.HasErrors() {
+		return cty.NilType, fmt.Errorf("invalid type expression")
+	}
+	if len(call.Arguments) == 0 {
+		return cty.NilType, fmt.Errorf("%s() needs an argument", call.Name)
+	}
+
+	switch call.Name {
+	case "list", "set", "map", "optional":
+		element, err := typeConstraint(call.Arguments[0])
+		if err != nil {
+			return cty.NilType, err
+		}
+		switch call.Name {
+		case "list":
+			return cty.List(element), nil
+		case "set":
+			return cty.Set(element), nil
+		case "map":
+			return cty.Map(element), nil
+		}
+		return element, nil
+	case "tuple":
+		items, diags := hcl.ExprList(call.Arguments[0])
+		if diags.HasErrors() {
+			return cty.NilType, fmt.Errorf("tuple() needs a list of types")
+		}
+		elements := make([]cty.Type, len(items))
+		for i, item := range items {
+			element, err := typeConstraint(item)
+			if err != nil {
+				return cty.NilType, err
+			}
+			elements[i] = element
+		}
+		return cty.Tuple(elements), nil
+	case "object":
+		pairs, diags := hcl.ExprMap(call.Arguments[0])
+		if diags.HasErrors() {
+			return cty.NilType, fmt.Errorf("object() needs a map of attribute types")
+		}
+		attributes := make(map[string]cty.Type, len(pairs))
+		var optional []string
+		for _, pair := range pairs {
+			name := hcl.ExprAsKeyword(pair.Key)
+			if name == "" {
+				return cty.NilType, fmt.Errorf("object attribute names must be identifiers")
+			}
+			attribute, err := typeConstraint(pair.Value)
+			if err != nil {
+				return cty.NilType, err
+			}
+			if inner, _ := hcl.ExprCall(pair.Value); inner != nil && inner.Name == "optional" {
+				optional = append(optional, name)
+			}
+			attributes[name] = attribute
+		}
+		return cty.ObjectWithOptionalAttrs(attributes, optional), nil
+	default:
+		return cty.NilType, fmt.Errorf("unknown type constructor %s()", call.Name)
+	}
+}
+
+// unknown stands for a value the source scanner could not evaluate; it conforms
+// to every type
+type unknown struct{}
+
+// CheckVars checks variables set from Go against the module: every name must be
+// declared and every value convertible to the declared type. Required variables
+// missing from vars are only reported when required is true.
+func (m *Module) CheckVars(vars map[string]interface{}, required bool) []Finding {
+	var findings []Finding
+	for _, name := range sortedNames(vars) {
+		variable, ok := m.Variables[name]
+		if !ok {
+			findings = append(findings, Finding{
+				Kind:    UnknownVariable,
+				Name:    name,
+				Message: fmt.Sprintf("variable %q is not declared by %s", name, m.Dir),
+			})
+			continue
+		}
+		if problem := conforms(vars[name], variable.Type); problem != "" {
+			findings = append(findings, Finding{
+				Kind:    TypeMismatch,
+				Name:    name,
+				Message: fmt.Sprintf("variable %q of %s is %s: %s", name, m.Dir, variable.Type.FriendlyNameForConstraint(), problem),
+			})
+		}
+	}
+
+	if required {
+		for _, name := range sortedNames(m.Variables) {
+			if _, ok := vars[name]; !ok && m.Variables[name].Required {
+				findings = append(findings, Finding{
+					Kind:    MissingVariable,
+					Name:    name,
+					Message: fmt.Sprintf("required variable %q of %s is not set", name, m.Dir),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// CheckOutput checks that the module declares an output
+func (m *Module) CheckOutput(name string) []Finding {
+	if m.Outputs[name] {
+		return nil
+	}
+	return []Finding{{
+		Kind:    UnknownOutput,
+		Name:    name,
+		Message: fmt.Sprintf("output %q is not declared by %s", name, m.Dir),
+	}}
+}
+
+// conforms describes why a Go value cannot be converted to a Terraform type, or
+// returns "" if it can. Conversions follow Terraform: primitives convert to
+// strings, and strings holding numbers or booleans convert back.
+func conforms(value interface{}, ty cty.Type) string {
+	if value == nil || ty == cty.DynamicPseudoType {
+		return ""
+	}
+	if _, ok := value.(unknown); ok {
+		return ""
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if _, ok := v.Interface().(unknown); ok {
+		return ""
+	}
+
+	got := describe(v)
+	switch {
+	case ty == cty.String:
+		switch v.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return ""
+		}
+	case ty == cty.Number:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return ""
+		case reflect.String:
+			if _, err := strconv.ParseFloat(v.String(), 64); err == nil {
+				return ""
+			}
+			got = fmt.Sprintf("string %q", v.String())
+		}
+	case ty == cty.Bool:
+		switch v.Kind() {
+		case reflect.Bool:
+			return ""
+		case reflect.String:
+			if v.String() == "true" || v.String() == "false" {
+				return ""
+			}
+			got = fmt.Sprintf("string %q", v.String())
+		}
+	case ty.IsListType() || ty.IsSetType():
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				if problem := conforms(v.Index(i).Interface(), ty.ElementType()); problem != "" {
+					return fmt.Sprintf("element %d: %s", i, problem)
+				}
+			}
+			return ""
+		}
+	case ty.IsTupleType():
+		elements := ty.TupleElementTypes()
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Len() == len(elements) {
+			for i, element := range elements {
+				if problem := conforms(v.Index(i).Interface(), element); problem != "" {
+					return fmt.Sprintf("element %d: %s", i, problem)
+				}
+			}
+			return ""
+		}
+	case ty.IsMapType():
+		if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+			for _, key := range sortedMapKeys(v) {
+				if problem := conforms(v.MapIndex(key).Interface(), ty.ElementType()); problem != "" {
+					return fmt.Sprintf("key %q: %s", key.String(), problem)
+				}
+			}
+			return ""
+		}
+	case ty.IsObjectType():
+		if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+			attributes := ty.AttributeTypes()
+			for _, key := range sortedMapKeys(v) {
+				attribute, ok := attributes[key.String()]
+				if !ok {
+					return fmt.Sprintf("unexpected attribute %q", key.String())
+				}
+				if problem := conforms(v.MapIndex(key).Interface(), attribute); problem != "" {
+					return fmt.Sprintf("attribute %q: %s", key.String(), problem)
+				}
+			}
+			for _, name := range sortedNames(attributes) {
+				if !ty.AttributeOptional(name) && !v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid() {
+					return fmt.Sprintf("attribute %q is required", name)
+				}
+			}
+			return ""
+		}
+	}
+	return "got " + got
+}
+
+// describe names the kind of a Go value in Terraform terms
+func describe(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	case reflect.Struct:
+		return "struct " + v.Type().String()
+	}
+	if v.CanInt() || v.CanUint() || v.CanFloat() {
+		return "number"
+	}
+	return v.Type().String()
+}
+
+// sortedNames returns the keys of a map in order
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedMapKeys returns the keys of a reflected string-keyed map in order
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
+
+// moduleCache loads every module directory once
+type moduleCache struct {
+	modules map[string]*Module
+	errors  map[string]error
+}
+
+// load returns the module in dir, keyed by its cleaned path
+func (c *moduleCache) load(dir string) (*Module, error) {
+	dir = filepath.Clean(dir)
+	if module, ok := c.modules[dir]; ok {
+		return module, nil
+	}
+	if err, ok := c.errors[dir]; ok {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		err := fmt.Errorf("module directory %s does not exist", dir)
+		c.errors[dir] = err
+		return nil, err
+	}
+
+	module, err := LoadModule(dir)
+	if err != nil {
+		c.errors[dir] = err
+		return nil, err
+	}
+	c.modules[dir] = module
+	return module, nil
+}
@@ -0,0 +1,154 @@
+package contract_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/contract"
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+const testVariables = `
+variable "vpc_cidr" {
+  type = string
+}
+
+variable "azs" {
+  type    = list(string)
+  default = []
+}
+
+variable "min_size" {
+  type    = number
+  default = 1
+}
+
+variable "enable_logs" {
+  type    = bool
+  default = true
+}
+
+variable "rules" {
+  type = list(object({
+    name   = string
+    ports  = list(number)
+    action = optional(string, "allow")
+  }))
+  default = []
+
+  validation {
+    condition     = length(var.rules) < 10
+    error_message = "Too many rules."
+  }
+}
+
+variable "anything" {}
+`
+
+const testOutputs = `
+output "vpc_id" {
+  value = "vpc-1"
+}
+`
+
+// writeModule writes a module with the test variables and outputs
+func writeModule(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testVariables), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outputs.tf"), []byte(testOutputs), 0644))
+}
+
+func TestLoadModule(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir)
+
+	module, err := contract.LoadModule(dir)
+	require.NoError(t, err)
+	assert.Len(t, module.Variables, 6)
+	assert.True(t, module.Variables["vpc_cidr"].Required)
+	assert.False(t, module.Variables["azs"].Required)
+	assert.True(t, module.Variables["anything"].Required)
+	assert.Equal(t, "list of object", module.Variables["rules"].Type.FriendlyNameForConstraint())
+	assert.True(t, module.Outputs["vpc_id"])
+
+	_, err = contract.LoadModule(t.TempDir())
+	assert.ErrorContains(t, err, "no Terraform configuration")
+}
+
+func TestCheckVars(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir)
+	module, err := contract.LoadModule(dir)
+	require.NoError(t, err)
+
+	findings := module.CheckVars(map[string]interface{}{
+		"vpc_cidr":    "10.0.0.0/16",
+		"azs":         []string{"us-east-1a"},
+		"min_size":    "2",
+		"enable_logs": "true",
+		"rules":       []map[string]interface{}{{"name": "web", "ports": []int{80, 443}}},
+		"anything":    [][]string{{"a"}},
+	}, true)
+	assert.Empty(t, findings, "Terraform converts these values")
+
+	findings = module.CheckVars(map[string]interface{}{
+		"azs":              [][]string{{"us-east-1a"}},
+		"min_size":         "two",
+		"rules":            []map[string]interface{}{{"name": "web", "protocol": "tcp"}},
+		"performance_mode": true,
+	}, true)
+	var messages []string
+	for _, finding := range findings {
+		messages = append(messages, finding.Kind+": "+finding.Message)
+	}
+	assert.Equal(t, []string{
+		"type-mismatch: variable \"azs\" of " + dir + " is list of string: element 0: got list",
+		"type-mismatch: variable \"min_size\" of " + dir + " is number: got string \"two\"",
+		"unknown-variable: variable \"performance_mode\" is not declared by " + dir,
+		"type-mismatch: variable \"rules\" of " + dir + " is list of object: element 0: unexpected attribute \"protocol\"",
+		"missing-variable: required variable \"anything\" of " + dir + " is not set",
+		"missing-variable: required variable \"vpc_cidr\" of " + dir + " is not set",
+	}, messages)
+
+	assert.Empty(t, module.CheckOutput("vpc_id"))
+	assert.Equal(t, contract.UnknownOutput, module.CheckOutput("kms_key_arn")[0].Kind)
+}
+
+// TestBuildersMatchModules checks the fixtures option builders against the modules
+// they target
+func TestBuildersMatchModules(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-east-1", 1)
+	require.NoError(t, err)
+	network := tdm.PlaceholderNetworkOutputs()
+
+	for dir, options := range map[string]*terraform.Options{
+		"network":           fixtures.NetworkOptions(tdm),
+		"inspection":        fixtures.InspectionOptions(tdm, network),
+		"firewall-vmseries": fixtures.VMSeriesOptions(tdm, network, "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/1"),
+	} {
+		module, err := contract.LoadModule(filepath.Join(fixtures.ModulesDir, dir))
+		require.NoError(t, err)
+		assert.Empty(t, module.CheckVars(optionVars(options), true), dir)
+	}
+}
+
+// optionVars returns the variables options set, including TF_VAR_ environment variables
+func optionVars(options *terraform.Options) map[string]interface{} {
+	vars := make(map[string]interface{})
+	for name, value := range options.EnvVars {
+		if variable := strings.TrimPrefix(name, "TF_VAR_"); variable != name {
+			vars[variable] = value
+		}
+	}
+	for name, value := range options.Vars {
+		vars[name] = value
+	}
+	return vars
+}
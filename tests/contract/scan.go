@@ -0,0 +1,546 @@
+package contract
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+// DefaultBuilders maps the fixtures option builders to the module they target,
+// relative to a test package
+var DefaultBuilders = map[string]string{
+	"NetworkOptions":    fixtures.ModulesDir + "/network",
+	"InspectionOptions": fixtures.ModulesDir + "/inspection",
+	"VMSeriesOptions":   fixtures.ModulesDir + "/firewall-vmseries",
+}
+
+// Checker checks the terraform.Options in Go test sources against the variables
+// and outputs of the modules they deploy, without running Terraform
+type Checker struct {
+	// Provided are variables set outside the test sources, such as TF_VAR_
+	// environment variables, that count as set for the missing-variable check
+	Provided map[string]bool
+
+	// Builders maps functions returning *terraform.Options, by name, to the module
+	// directory they target relative to the calling package
+	Builders map[string]string
+
+	cache moduleCache
+}
+
+// NewChecker creates a checker that treats the TF_VAR_ variables of the current
+// environment as provided
+func NewChecker() *Checker {
+	provided := make(map[string]bool)
+	for _, env := range os.Environ() {
+		if name, _, ok := strings.Cut(env, "="); ok && strings.HasPrefix(name, "TF_VAR_") {
+			provided[strings.TrimPrefix(name, "TF_VAR_")] = true
+		}
+	}
+	return &Checker{
+		Provided: provided,
+		Builders: DefaultBuilders,
+		cache:    moduleCache{modules: make(map[string]*Module), errors: make(map[string]error)},
+	}
+}
+
+// CheckTree checks every Go package under the root directories, skipping testdata
+// and hidden directories
+func (c *Checker) CheckTree(roots ...string) ([]Finding, error) {
+	var findings []Finding
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+			name := entry.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			packageFindings, err := c.CheckPackage(path)
+			findings = append(findings, packageFindings...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return findings, nil
+}
+
+// CheckPackage checks the Go files, including tests, of one package directory
+func (c *Checker) CheckPackage(dir string) ([]Finding, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil || len(filenames) == 0 {
+		return nil, err
+	}
+
+	p := &packageScan{checker: c, dir: dir, fset: token.NewFileSet(), funcs: make(map[string]*funcScan)}
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(p.fset, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil && fn.Recv == nil {
+				p.addFunc(fn)
+			}
+		}
+	}
+
+	for _, fn := range p.order {
+		p.scanSources(fn)
+	}
+	for _, fn := range p.order {
+		p.checkFunc(fn)
+	}
+
+	sort.SliceStable(p.findings, func(i, j int) bool { return lessPos(p.findings[i].Pos, p.findings[j].Pos) })
+	return p.findings, nil
+}
+
+// optionsSource is a terraform.Options value found in the source
+type optionsSource struct {
+	pos      token.Pos
+	dir      string                 // TerraformDir as written in the source
+	module   string                 // module directory, "" when it cannot be determined
+	vars     map[string]interface{} // statically known variables
+	complete bool                   // vars holds every variable, so missing ones can be reported
+	checked  bool
+}
+
+// outputRead is a terraform.Output call
+type outputRead struct {
+	pos     token.Pos
+	options ast.Expr
+	name    string
+}
+
+// funcScan holds what one function does with terraform.Options
+type funcScan struct {
+	decl    *ast.FuncDecl
+	params  []string
+	sources map[string]*optionsSource // by variable name
+	all     []*optionsSource
+	outputs []outputRead
+}
+
+// packageScan checks the functions of one package
+type packageScan struct {
+	checker  *Checker
+	dir      string
+	fset     *token.FileSet
+	funcs    map[string]*funcScan
+	order    []*funcScan
+	findings []Finding
+}
+
+func (p *packageScan) addFunc(decl *ast.FuncDecl) {
+	fn := &funcScan{decl: decl, sources: make(map[string]*optionsSource)}
+	for _, field := range decl.Type.Params.List {
+		if len(field.Names) == 0 {
+			fn.params = append(fn.params, "_")
+		}
+		for _, name := range field.Names {
+			fn.params = append(fn.params, name.Name)
+		}
+	}
+	// Functions of the package and its _test package can share a name; the first wins
+	if _, ok := p.funcs[decl.Name.Name]; !ok {
+		p.funcs[decl.Name.Name] = fn
+	}
+	p.order = append(p.order, fn)
+}
+
+// scanSources records the options a function creates, the variables it sets on
+// them and the outputs it reads
+func (p *packageScan) scanSources(fn *funcScan) {
+	ast.Inspect(fn.decl.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				switch target := lhs.(type) {
+				case *ast.Ident:
+					if source := p.sourceOf(fn, n.Rhs[i]); source != nil {
+						fn.sources[target.Name] = source
+					}
+				case *ast.IndexExpr:
+					p.recordVarAssignment(fn, target, n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					if source := p.sourceOf(fn, n.Values[i]); source != nil {
+						fn.sources[name.Name] = source
+					}
+				}
+			}
+		case *ast.CallExpr:
+			if name, ok := selectorCall(n, "terraform"); ok && strings.HasPrefix(name, "Output") && len(n.Args) >= 3 {
+				if output, ok := stringLit(n.Args[2]); ok {
+					fn.outputs = append(fn.outputs, outputRead{pos: n.Pos(), options: n.Args[1], name: output})
+				}
+			}
+			// Options passed straight to a call are sources too
+			for _, arg := range n.Args {
+				p.sourceOf(fn, arg)
+			}
+		}
+		return true
+	})
+}
+
+// recordVarAssignment handles options.Vars["name"] = value
+func (p *packageScan) recordVarAssignment(fn *funcScan, index *ast.IndexExpr, value ast.Expr) {
+	selector, ok := index.X.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "Vars" {
+		return
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	name, ok := stringLit(index.Index)
+	if source := fn.sources[ident.Name]; ok && source != nil {
+		source.vars[name] = evalExpr(value)
+	}
+}
+
+// sourceOf returns the options an expression creates, or the options held by a
+// variable the function assigned earlier
+func (p *packageScan) sourceOf(fn *funcScan, expr ast.Expr) *optionsSource {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return fn.sources[e.Name]
+	case *ast.ParenExpr:
+		return p.sourceOf(fn, e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return p.sourceOf(fn, e.X)
+		}
+	case *ast.CompositeLit:
+		if typeName(e.Type) == "terraform.Options" {
+			return p.literalSource(fn, e)
+		}
+	case *ast.CallExpr:
+		if name, ok := selectorCall(e, "terraform"); ok && name == "WithDefaultRetryableErrors" && len(e.Args) == 2 {
+			return p.sourceOf(fn, e.Args[1])
+		}
+		if module, ok := p.checker.Builders[calleeName(e)]; ok {
+			return p.builderSource(fn, e, module)
+		}
+	}
+	return nil
+}
+
+// literalSource reads the module and variables of a terraform.Options literal
+func (p *packageScan) literalSource(fn *funcScan, lit *ast.CompositeLit) *optionsSource {
+	for _, source := range fn.all {
+		if source.pos == lit.Pos() {
+			return source
+		}
+	}
+
+	source := &optionsSource{pos: lit.Pos(), vars: make(map[string]interface{}), complete: true}
+	for _, elt := range lit.Elts {
+		field, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, _ := field.Key.(*ast.Ident)
+		switch {
+		case key == nil:
+		case key.Name == "TerraformDir":
+			if dir, ok := stringLit(field.Value); ok {
+				source.dir = dir
+				source.module = filepath.Join(p.dir, dir)
+			}
+		case key.Name == "Vars":
+			vars, ok := evalExpr(field.Value).(map[string]interface{})
+			if !ok {
+				source.complete = false
+				continue
+			}
+			for name, value := range vars {
+				source.vars[name] = value
+			}
+		}
+	}
+	fn.all = append(fn.all, source)
+	return source
+}
+
+// builderSource reads a fixtures builder call; only variables set with WithVar are known
+func (p *packageScan) builderSource(fn *funcScan, call *ast.CallExpr, module string) *optionsSource {
+	for _, source := range fn.all {
+		if source.pos == call.Pos() {
+			return source
+		}
+	}
+
+	source := &optionsSource{pos: call.Pos(), dir: module, module: filepath.Join(p.dir, module), vars: make(map[string]interface{})}
+	for _, arg := range call.Args {
+		option, ok := arg.(*ast.CallExpr)
+		if !ok || len(option.Args) == 0 {
+			continue
+		}
+		switch calleeName(option) {
+		case "WithVar":
+			if name, ok := stringLit(option.Args[0]); ok && len(option.Args) == 2 {
+				source.vars[name] = evalExpr(option.Args[1])
+			}
+		case "WithTerraformDir":
+			if dir, ok := stringLit(option.Args[0]); ok {
+				source.dir = dir
+				source.module = filepath.Join(p.dir, dir)
+			} else {
+				source.module = ""
+			}
+		}
+	}
+	fn.all = append(fn.all, source)
+	return source
+}
+
+// checkFunc reports the findings of the options a function creates and the outputs it reads
+func (p *packageScan) checkFunc(fn *funcScan) {
+	for _, source := range fn.all {
+		if source.checked || source.module == "" {
+			continue
+		}
+		source.checked = true
+
+		module, err := p.checker.cache.load(source.module)
+		if err != nil {
+			p.add(source.pos, source.module, Finding{Kind: MissingModule, Message: fmt.Sprintf("TerraformDir %q: %v", source.dir, err)})
+			continue
+		}
+		vars := source.vars
+		if source.complete {
+			vars = make(map[string]interface{}, len(source.vars)+len(p.checker.Provided))
+			for name := range p.checker.Provided {
+				if _, declared := module.Variables[name]; declared {
+					vars[name] = unknown{}
+				}
+			}
+			for name, value := range source.vars {
+				vars[name] = value
+			}
+		}
+		for _, finding := range module.CheckVars(vars, source.complete) {
+			p.add(source.pos, source.module, finding)
+		}
+	}
+
+	for _, read := range fn.outputs {
+		seen := make(map[string]bool)
+		for _, source := range p.resolve(fn, read.options, make(map[string]bool)) {
+			if source.module == "" || seen[source.module] {
+				continue
+			}
+			seen[source.module] = true
+			module, err := p.checker.cache.load(source.module)
+			if err != nil {
+				continue // reported where the options are created
+			}
+			for _, finding := range module.CheckOutput(read.name) {
+				p.add(read.pos, source.module, finding)
+			}
+		}
+	}
+}
+
+// resolve finds the options an expression can hold, following function parameters
+// to the arguments of every call of the function in the package
+func (p *packageScan) resolve(fn *funcScan, expr ast.Expr, visiting map[string]bool) []*optionsSource {
+	if source := p.sourceOf(fn, expr); source != nil {
+		return []*optionsSource{source}
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	param := -1
+	for i, name := range fn.params {
+		if name == ident.Name {
+			param = i
+		}
+	}
+	key := fn.decl.Name.Name + "/" + ident.Name
+	if param < 0 || visiting[key] {
+		return nil
+	}
+	visiting[key] = true
+
+	var sources []*optionsSource
+	for _, caller := range p.order {
+		ast.Inspect(caller.decl.Body, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if ok && calleeIdent(call) == fn.decl.Name.Name && p.funcs[fn.decl.Name.Name] == fn && param < len(call.Args) {
+				sources = append(sources, p.resolve(caller, call.Args[param], visiting)...)
+			}
+			return true
+		})
+	}
+	return sources
+}
+
+// add records a finding at a source position
+func (p *packageScan) add(pos token.Pos, module string, finding Finding) {
+	position := p.fset.Position(pos)
+	finding.Pos = position.Filename + ":" + strconv.Itoa(position.Line)
+	finding.Module = module
+	p.findings = append(p.findings, finding)
+}
+
+// evalExpr evaluates literal Go expressions into values; anything else is unknown
+func evalExpr(expr ast.Expr) interface{} {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return evalExpr(e.X)
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			if value, err := strconv.Unquote(e.Value); err == nil {
+				return value
+			}
+		case token.INT:
+			if value, err := strconv.ParseInt(e.Value, 0, 64); err == nil {
+				return value
+			}
+		case token.FLOAT:
+			if value, err := strconv.ParseFloat(e.Value, 64); err == nil {
+				return value
+			}
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			switch value := evalExpr(e.X).(type) {
+			case int64:
+				return -value
+			case float64:
+				return -value
+			}
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "nil":
+			return nil
+		}
+	case *ast.CompositeLit:
+		switch t := e.Type.(type) {
+		case *ast.ArrayType:
+			list := make([]interface{}, 0, len(e.Elts))
+			for _, elt := range e.Elts {
+				if inner, ok := elt.(*ast.CompositeLit); ok && inner.Type == nil {
+					inner.Type = t.Elt
+				}
+				list = append(list, evalExpr(elt))
+			}
+			return list
+		case *ast.MapType:
+			values := make(map[string]interface{}, len(e.Elts))
+			for _, elt := range e.Elts {
+				pair, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					return unknown{}
+				}
+				key, ok := stringLit(pair.Key)
+				if !ok {
+					return unknown{}
+				}
+				if inner, ok := pair.Value.(*ast.CompositeLit); ok && inner.Type == nil {
+					inner.Type = t.Value
+				}
+				values[key] = evalExpr(pair.Value)
+			}
+			return values
+		}
+	}
+	return unknown{}
+}
+
+// selectorCall returns the function name of a pkg.Func(...) call
+func selectorCall(call *ast.CallExpr, pkg string) (string, bool) {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok || ident.Name != pkg {
+		return "", false
+	}
+	return selector.Sel.Name, true
+}
+
+// calleeName returns the name of the called function, with or without a package
+func calleeName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+// calleeIdent returns the name of a call to a function of the same package
+func calleeIdent(call *ast.CallExpr) string {
+	if ident, ok := call.Fun.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// typeName formats a type expression such as terraform.Options
+func typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return typeName(e.X) + "." + e.Sel.Name
+	}
+	return ""
+}
+
+// stringLit returns the value of a string literal
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
+
+// lessPos orders file:line positions by file, then numerically by line
+func lessPos(a, b string) bool {
+	fileA, lineA, _ := strings.Cut(a, ":")
+	fileB, lineB, _ := strings.Cut(b, ":")
+	if fileA != fileB {
+		return fileA < fileB
+	}
+	numberA, _ := strconv.Atoi(lineA)
+	numberB, _ := strconv.Atoi(lineB)
+	return numberA < numberB
+}
@@ -0,0 +1,85 @@
+package contract_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/contract"
+)
+
+const testSource = `package pkg_test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func TestLiteral(t *testing.T) {
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../modules/net",
+		Vars: map[string]interface{}{
+			"azs":              []string{"a", "b"},
+			"min_size":         "two",
+			"performance_mode": true,
+		},
+	}
+	terraformOptions.Vars["scale_up_threshold"] = 80
+
+	terraform.InitAndApply(t, terraformOptions)
+	assert(t, terraformOptions)
+}
+
+func TestBuilder(t *testing.T) {
+	options := fixtures.NetOptions(tdm, fixtures.WithVar("enable_logs", []string{"yes"}))
+	assert(t, terraform.WithDefaultRetryableErrors(t, options))
+}
+
+func TestMissingModule(t *testing.T) {
+	terraform.InitAndApply(t, &terraform.Options{TerraformDir: "../"})
+}
+
+func assert(t *testing.T, opts *terraform.Options) {
+	terraform.Output(t, opts, "vpc_id")
+	t.Run("nested", func(t *testing.T) {
+		verify(t, opts)
+	})
+}
+
+func verify(t *testing.T, terraformOptions *terraform.Options) {
+	terraform.OutputList(t, terraformOptions, "kms_key_arn")
+}
+`
+
+func TestCheckTree(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "modules", "net"))
+	pkg := filepath.Join(root, "tests", "pkg")
+	require.NoError(t, os.MkdirAll(pkg, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pkg, "pkg_test.go"), []byte(testSource), 0644))
+
+	checker := contract.NewChecker()
+	checker.Provided = map[string]bool{"anything": true}
+	checker.Builders = map[string]string{"NetOptions": "../../modules/net"}
+
+	findings, err := checker.CheckTree(filepath.Join(root, "tests"))
+	require.NoError(t, err)
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.Kind+" "+finding.Name+" "+filepath.Base(finding.Pos))
+	}
+	assert.Equal(t, []string{
+		"type-mismatch min_size pkg_test.go:10",
+		"unknown-variable performance_mode pkg_test.go:10",
+		"unknown-variable scale_up_threshold pkg_test.go:10",
+		"missing-variable vpc_cidr pkg_test.go:10",
+		"type-mismatch enable_logs pkg_test.go:25",
+		"missing-module  pkg_test.go:30",
+		"unknown-output kms_key_arn pkg_test.go:41",
+	}, got)
+}
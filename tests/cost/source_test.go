@@ -0,0 +1,65 @@
+package cost_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/inventory"
+)
+
+// literalTags are the resources that set literal tags and drop var.tags (README.md,
+// Known Issues). A fix removes them here.
+var literalTags = map[string]bool{
+	"iam/aws_iam_policy.mfa_required":       true,
+	"iam/aws_iam_role.cross_account_access": true,
+	"iam/aws_cloudtrail.security_audit":     true,
+	"iam/aws_s3_bucket.audit_logs":          true,
+}
+
+// TestTagsInheritCostAllocation checks every tagged AWS resource merges in var.tags,
+// so the cost allocation tags passed to a module reach everything it creates
+func TestTagsInheritCostAllocation(t *testing.T) {
+	t.Parallel()
+	inv, err := inventory.Load(fixtures.ModulesDir)
+	require.NoError(t, err)
+
+	checked := 0
+	for _, name := range inv.ModuleNames() {
+		module := inv.Modules[name]
+		if _, ok := module.Variables["tags"]; !ok {
+			continue
+		}
+		for _, resource := range module.Resources {
+			if !strings.HasPrefix(resource.Type, "aws_") {
+				continue
+			}
+			if _, ok := resource.AttributeExpr("tags"); !ok {
+				continue
+			}
+			checked++
+			if literalTags[resource.String()] {
+				assert.False(t, inv.TagsOf(resource).Inherits("var.tags"), "%s merges var.tags now; remove it from literalTags", resource)
+				continue
+			}
+			assert.True(t, inv.TagsOf(resource).Inherits("var.tags"), "%s does not merge var.tags into its tags", resource)
+		}
+	}
+	assert.NotZero(t, checked)
+}
+
+// TestLogGroupsExpire checks every log group sets a retention, since log groups
+// without one keep and bill for logs forever
+func TestLogGroupsExpire(t *testing.T) {
+	t.Parallel()
+	inv, err := inventory.Load(fixtures.ModulesDir)
+	require.NoError(t, err)
+
+	for _, group := range inv.ResourcesOfType("aws_cloudwatch_log_group") {
+		_, ok := group.AttributeExpr("retention_in_days")
+		assert.True(t, ok, "%s sets no retention_in_days", group)
+	}
+}
@@ -0,0 +1,83 @@
+package firewall_vmseries_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/netsec"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// TestVMseriesPlan checks the planned firewalls against the recorded plan in
+// testdata, without AWS. Set UPDATE_PLAN_FIXTURES=1 to record the plan again.
+func TestVMseriesPlan(t *testing.T) {
+	t.Parallel()
+
+	// A fixed seed keeps the placeholder network IDs in the recorded plan stable.
+	// The security group uses panorama_ip as a CIDR block, so it needs one here.
+	tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-east-1", 1)
+	require.NoError(t, err)
+	network := tdm.PlaceholderNetworkOutputs()
+	targetGroupARN := "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/inspection-tg/6d0ecf831eec9f09"
+	terraformOptions := fixtures.VMSeriesOptions(tdm, network, targetGroupARN, fixtures.WithVar("panorama_ip", "10.10.20.10/32"))
+	vmseriesPlan := plan.LoadOrRecord(t, terraformOptions, "testdata/vmseries_plan.json")
+
+	// Autoscaling group sized from the profile and registered with the GWLB
+	firewall := tdm.GetFirewallTestData()
+	plan.AssertValue(t, vmseriesPlan, "aws_autoscaling_group.vmseries", "min_size", firewall.MinSize)
+	plan.AssertValue(t, vmseriesPlan, "aws_autoscaling_group.vmseries", "max_size", firewall.MaxSize)
+	plan.AssertValue(t, vmseriesPlan, "aws_autoscaling_attachment.vmseries", "lb_target_group_arn", targetGroupARN)
+
+	// Hardened launch template: IMDSv2, no public IPs, encrypted volumes
+	plan.AssertValue(t, vmseriesPlan, "aws_launch_template.vmseries", "instance_type", firewall.InstanceType)
+	plan.AssertValue(t, vmseriesPlan, "aws_launch_template.vmseries", "metadata_options.0.http_tokens", "required")
+	plan.AssertValue(t, vmseriesPlan, "aws_launch_template.vmseries", "network_interfaces.0.associate_public_ip_address", "false")
+	plan.AssertValue(t, vmseriesPlan, "aws_launch_template.vmseries", "block_device_mappings.0.ebs.0.encrypted", "true")
+	plan.AssertValue(t, vmseriesPlan, "aws_launch_template.vmseries", "block_device_mappings.1.ebs.0.encrypted", "true")
+	plan.AssertUnknown(t, vmseriesPlan, "aws_launch_template.vmseries", "block_device_mappings.0.ebs.0.kms_key_id")
+	plan.AssertValue(t, vmseriesPlan, "aws_kms_key.ebs", "enable_key_rotation", true)
+
+	// Private, encrypted and versioned bootstrap bucket
+	for _, setting := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+		plan.AssertValue(t, vmseriesPlan, "aws_s3_bucket_public_access_block.bootstrap", setting, true)
+	}
+	plan.AssertValue(t, vmseriesPlan, "aws_s3_bucket_versioning.bootstrap", "versioning_configuration.0.status", "Enabled")
+	plan.AssertAllValues(t, vmseriesPlan, "aws_s3_object", "server_side_encryption", "AES256")
+
+	// The firewalls are managed from the inspection VPC, reach Panorama and the
+	// update servers, and open nothing to the internet
+	rules, err := netsec.FromPlan(vmseriesPlan)
+	require.NoError(t, err)
+	assert.Empty(t, rules.Permissive())
+	group, ok := rules.SecurityGroup("aws_security_group.vmseries")
+	require.True(t, ok)
+	vmseries := netsec.Endpoint{SecurityGroups: []*netsec.SecurityGroup{group}}
+	peer := netsec.Endpoint{}
+	for _, tc := range []struct {
+		protocol    string
+		source      string
+		destination string
+		port        int
+		allowed     bool
+	}{
+		{"tcp", "10.10.1.10", "10.10.10.10", 22, true},
+		{"udp", "10.10.1.10", "10.10.10.10", 6081, true},
+		{"tcp", "203.0.113.10", "10.10.10.10", 22, false},
+		{"tcp", "203.0.113.10", "10.10.10.10", 443, false},
+		{"tcp", "10.10.10.10", "10.10.20.10", 3978, true},
+		{"tcp", "10.10.10.10", "203.0.113.10", 443, true},
+		{"udp", "10.10.10.10", "203.0.113.10", 123, true},
+		{"tcp", "10.10.10.10", "203.0.113.10", 80, false},
+	} {
+		source, destination := peer, vmseries
+		if tc.source == "10.10.10.10" {
+			source, destination = vmseries, peer
+		}
+		verdict := netsec.Evaluate(netsec.NewFlow(tc.protocol, netip.MustParseAddr(tc.source), netip.MustParseAddr(tc.destination), tc.port), source, destination)
+		assert.Equal(t, tc.allowed, verdict.Allowed, verdict.String())
+	}
+}
@@ -5,32 +5,19 @@ import (
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
 )
 
 // TestVMseriesProvisioning tests VM-Series firewall provisioning
 func TestVMseriesProvisioning(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/firewall-vmseries",
-		Vars: map[string]interface{}{
-			"vpc_id":            "vpc-12345",
-			"subnet_ids":        []string{"subnet-priv-1", "subnet-priv-2"},
-			"instance_type":     "m5.xlarge",
-			"min_size":          2,
-			"max_size":          4,
-			"key_name":          "test-key",
-			"target_group_arn":  "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test-tg/1234567890abcdef",
-			"panorama_ip":       "10.0.0.100",
-			"panorama_username": "admin",
-			"panorama_password": "test-password",
-			"management_cidrs":  []string{"10.0.0.0/8"},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
+	tdm := fixtures.NewTestDataManagerForTest(t, "staging", "us-east-1")
+	terraformOptions := fixtures.VMSeriesOptions(tdm, tdm.PlaceholderNetworkOutputs(),
+		"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test-tg/1234567890abcdef",
+		fixtures.WithVar("management_cidrs", []string{"10.0.0.0/8"}),
+	)
 
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
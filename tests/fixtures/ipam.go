@@ -0,0 +1,359 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const (
+	// IPAMDirEnvVar overrides the directory holding the lease file, e.g. to share
+	// leases between CI jobs that deploy into the same account
+	IPAMDirEnvVar = "TEST_IPAM_DIR"
+
+	// LeaseFileName is the lease file inside the IPAM directory
+	LeaseFileName = "ipam-leases.json"
+
+	// DefaultLeaseTTL bounds how long a lease survives a test process that exited
+	// without releasing it
+	DefaultLeaseTTL = 4 * time.Hour
+
+	// DefaultLockTimeout is how long Allocate and Release wait for the lease file lock
+	DefaultLockTimeout = 30 * time.Second
+
+	// VPCPrefixLength is the size of the VPC blocks handed out by AllocateNetwork
+	VPCPrefixLength = 20
+
+	// SubnetPrefixLength is the size of the per-AZ subnets carved from each VPC
+	SubnetPrefixLength = 24
+)
+
+// RFC1918Pools are the private address ranges test CIDRs are allocated from
+var RFC1918Pools = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+}
+
+// ProfileCIDRs are the fixed VPC and spoke blocks of the built-in profiles. Tests
+// that don't lease their CIDRs deploy into them, so they are never handed out.
+var ProfileCIDRs = []netip.Prefix{
+	// dev
+	netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("10.1.0.0/16"),
+	// staging
+	netip.MustParsePrefix("10.10.0.0/16"), netip.MustParsePrefix("10.11.0.0/16"), netip.MustParsePrefix("10.12.0.0/16"),
+	// qa
+	netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("10.21.0.0/16"), netip.MustParsePrefix("10.22.0.0/16"),
+	// prod
+	netip.MustParsePrefix("10.100.0.0/16"), netip.MustParsePrefix("10.101.0.0/16"),
+	netip.MustParsePrefix("10.102.0.0/16"), netip.MustParsePrefix("10.103.0.0/16"),
+}
+
+// Lease is a CIDR block held by a test
+type Lease struct {
+	CIDR        string    `json:"cidr"`
+	Owner       string    `json:"owner"` // usually the test name
+	PID         int       `json:"pid"`
+	AllocatedAt time.Time `json:"allocated_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// leaseFile is the JSON document stored in the lease file
+type leaseFile struct {
+	Leases []Lease `json:"leases"`
+}
+
+// CIDRAllocator hands out unique, aligned RFC1918 blocks. Leases are stored in a
+// flock-protected file, so parallel `go test` processes on the same host never get
+// overlapping blocks.
+type CIDRAllocator struct {
+	LeaseFile   string
+	Pools       []netip.Prefix
+	Reserved    []netip.Prefix // blocks never handed out, e.g. ranges used by long-lived environments
+	TTL         time.Duration
+	LockTimeout time.Duration
+}
+
+// VPCAllocation is the address plan of a single VPC
+type VPCAllocation struct {
+	CIDR           string   `json:"cidr"`
+	PublicSubnets  []string `json:"public_subnets"`  // one per AZ
+	PrivateSubnets []string `json:"private_subnets"` // one per AZ
+}
+
+// NetworkAllocation is the address plan of an inspection VPC and its spokes
+type NetworkAllocation struct {
+	Inspection VPCAllocation   `json:"inspection"`
+	Spokes     []VPCAllocation `json:"spokes"`
+}
+
+// NewCIDRAllocator creates an allocator whose lease file lives in dir and which
+// never hands out the ProfileCIDRs
+func NewCIDRAllocator(dir string) *CIDRAllocator {
+	return &CIDRAllocator{
+		LeaseFile:   filepath.Join(dir, LeaseFileName),
+		Pools:       RFC1918Pools,
+		Reserved:    ProfileCIDRs,
+		TTL:         DefaultLeaseTTL,
+		LockTimeout: DefaultLockTimeout,
+	}
+}
+
+// DefaultCIDRAllocator creates an allocator using TEST_IPAM_DIR, or a directory
+// in the system temp dir shared by every test process of the user
+func DefaultCIDRAllocator() *CIDRAllocator {
+	dir := os.Getenv(IPAMDirEnvVar)
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "aws-centralized-inspection-ipam")
+	}
+	return NewCIDRAllocator(dir)
+}
+
+// Allocate leases the first free block of the given prefix length
+func (a *CIDRAllocator) Allocate(owner string, prefixLength int) (netip.Prefix, error) {
+	prefixes, err := a.allocate(owner, []int{prefixLength})
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefixes[0], nil
+}
+
+// AllocateNetwork leases a VPCPrefixLength block for the inspection VPC and for
+// each spoke, and carves each into SubnetPrefixLength public and private subnets
+// per AZ. Public subnets start at the beginning of the VPC block and private
+// subnets at its midpoint.
+func (a *CIDRAllocator) AllocateNetwork(owner string, azs []string, spokes int) (*NetworkAllocation, error) {
+	subnetsPerHalf := 1 << (SubnetPrefixLength - VPCPrefixLength - 1)
+	if len(azs) == 0 || len(azs) > subnetsPerHalf {
+		return nil, fmt.Errorf("between 1 and %d AZs are required, got %d", subnetsPerHalf, len(azs))
+	}
+	if spokes < 0 {
+		return nil, fmt.Errorf("spoke count must not be negative")
+	}
+
+	lengths := make([]int, spokes+1)
+	for i := range lengths {
+		lengths[i] = VPCPrefixLength
+	}
+	vpcs, err := a.allocate(owner, lengths)
+	if err != nil {
+		return nil, err
+	}
+
+	allocation := &NetworkAllocation{Inspection: carveVPC(vpcs[0], len(azs), subnetsPerHalf)}
+	for _, vpc := range vpcs[1:] {
+		allocation.Spokes = append(allocation.Spokes, carveVPC(vpc, len(azs), subnetsPerHalf))
+	}
+	return allocation, nil
+}
+
+// carveVPC splits a VPC block into public and private subnets
+func carveVPC(vpc netip.Prefix, azs, subnetsPerHalf int) VPCAllocation {
+	allocation := VPCAllocation{CIDR: vpc.String()}
+	for i := 0; i < azs; i++ {
+		allocation.PublicSubnets = append(allocation.PublicSubnets, nthSubnet(vpc, SubnetPrefixLength, i).String())
+		allocation.PrivateSubnets = append(allocation.PrivateSubnets, nthSubnet(vpc, SubnetPrefixLength, subnetsPerHalf+i).String())
+	}
+	return allocation
+}
+
+// Release removes the lease of a block
+func (a *CIDRAllocator) Release(cidr netip.Prefix) error {
+	return a.update(func(leases []Lease) ([]Lease, error) {
+		kept := leases[:0]
+		for _, lease := range leases {
+			if lease.CIDR != cidr.String() {
+				kept = append(kept, lease)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// ReleaseOwner removes every lease the owner holds in this process
+func (a *CIDRAllocator) ReleaseOwner(owner string) error {
+	pid := os.Getpid()
+	return a.update(func(leases []Lease) ([]Lease, error) {
+		kept := leases[:0]
+		for _, lease := range leases {
+			if lease.Owner != owner || lease.PID != pid {
+				kept = append(kept, lease)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// Leases returns the active leases ordered by CIDR
+func (a *CIDRAllocator) Leases() ([]Lease, error) {
+	var active []Lease
+	err := a.update(func(leases []Lease) ([]Lease, error) {
+		active = append(active, leases...)
+		return leases, nil
+	})
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].CIDR < active[j].CIDR
+	})
+	return active, err
+}
+
+// allocate leases one block per prefix length in a single transaction
+func (a *CIDRAllocator) allocate(owner string, prefixLengths []int) ([]netip.Prefix, error) {
+	var allocated []netip.Prefix
+	err := a.update(func(leases []Lease) ([]Lease, error) {
+		taken := append([]netip.Prefix{}, a.Reserved...)
+		for _, lease := range leases {
+			prefix, err := netip.ParsePrefix(lease.CIDR)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lease %q: %w", lease.CIDR, err)
+			}
+			taken = append(taken, prefix)
+		}
+
+		now := time.Now().UTC()
+		for _, length := range prefixLengths {
+			prefix, err := a.firstFree(length, taken)
+			if err != nil {
+				return nil, err
+			}
+			taken = append(taken, prefix)
+			allocated = append(allocated, prefix)
+			leases = append(leases, Lease{
+				CIDR:        prefix.String(),
+				Owner:       owner,
+				PID:         os.Getpid(),
+				AllocatedAt: now,
+				ExpiresAt:   now.Add(a.TTL),
+			})
+		}
+		return leases, nil
+	})
+	return allocated, err
+}
+
+// firstFree returns the lowest aligned block of the given length that overlaps no taken block
+func (a *CIDRAllocator) firstFree(length int, taken []netip.Prefix) (netip.Prefix, error) {
+	for _, pool := range a.Pools {
+		if length < pool.Bits() || length > 32 {
+			continue
+		}
+		for i := 0; i < 1<<(length-pool.Bits()); i++ {
+			candidate := nthSubnet(pool, length, i)
+			free := true
+			for _, prefix := range taken {
+				if candidate.Overlaps(prefix) {
+					free = false
+					break
+				}
+			}
+			if free {
+				return candidate, nil
+			}
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("no free /%d block left in the IPAM pools", length)
+}
+
+// nthSubnet returns the n-th block of the given length inside an IPv4 prefix
+func nthSubnet(parent netip.Prefix, length, n int) netip.Prefix {
+	base := parent.Masked().Addr().As4()
+	value := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	value += uint32(n) << (32 - length)
+	addr := netip.AddrFrom4([4]byte{byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
+	return netip.PrefixFrom(addr, length)
+}
+
+// update runs fn on the unexpired leases while holding the lease file lock and
+// writes back the leases it returns
+func (a *CIDRAllocator) update(fn func(leases []Lease) ([]Lease, error)) error {
+	unlock, err := a.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var file leaseFile
+	data, err := os.ReadFile(a.LeaseFile)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("invalid lease file %s: %w", a.LeaseFile, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	now := time.Now()
+	active := make([]Lease, 0, len(file.Leases))
+	for _, lease := range file.Leases {
+		if lease.ExpiresAt.After(now) {
+			active = append(active, lease)
+		}
+	}
+
+	leases, err := fn(active)
+	if err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(leaseFile{Leases: leases}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := a.LeaseFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.LeaseFile)
+}
+
+// lock takes the lease file lock, waiting up to LockTimeout for another holder
+func (a *CIDRAllocator) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(a.LeaseFile), 0755); err != nil {
+		return nil, err
+	}
+	return lockFile(a.LeaseFile+".lock", a.LockTimeout)
+}
+
+// AllocateNetworkTestData returns the environment's network test data with CIDRs
+// leased from the allocator instead of the fixed defaults, so parallel runs in one
+// account don't collide. The leases are held until DestroyAndRelease releases them
+// or, when the infrastructure could not be destroyed, until they expire.
+func (tdm *TestDataManager) AllocateNetworkTestData(t testing.TB, allocator *CIDRAllocator) *NetworkTestData {
+	t.Helper()
+
+	data := tdm.GetNetworkTestData()
+	allocation, err := allocator.AllocateNetwork(t.Name(), data.Azs, len(data.SpokeVpcCidrs))
+	if err != nil {
+		t.Fatalf("failed to allocate test CIDRs: %v", err)
+	}
+
+	data.VpcCidr = allocation.Inspection.CIDR
+	data.PublicSubnets = allocation.Inspection.PublicSubnets
+	data.PrivateSubnets = allocation.Inspection.PrivateSubnets
+	data.SpokeVpcCidrs = nil
+	for _, spoke := range allocation.Spokes {
+		data.SpokeVpcCidrs = append(data.SpokeVpcCidrs, spoke.CIDR)
+	}
+	return data
+}
+
+// DestroyAndRelease destroys the infrastructure and then releases the CIDRs the test
+// leased from the allocator. A failed destroy stops the test before the release, so
+// blocks that may still be in use stay leased until their TTL expires.
+func DestroyAndRelease(t testing.TB, options *terraform.Options, allocator *CIDRAllocator) {
+	t.Helper()
+
+	terraform.Destroy(t, options)
+	if err := allocator.ReleaseOwner(t.Name()); err != nil {
+		t.Errorf("failed to release test CIDRs: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !unix
+
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file is considered left behind by a
+// crashed holder. Lease updates only hold the lock for milliseconds.
+const staleLockAge = time.Minute
+
+// lockFile takes the lock by creating path exclusively and releases it by removing
+// the file. Without flock a crashed holder leaves the file behind, so a lock file
+// older than staleLockAge is taken over.
+func lockFile(path string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for IPAM lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !unix
+
+package fixtures_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+func TestStaleLockFileIsTakenOver(t *testing.T) {
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, fixtures.LeaseFileName+".lock")
+	require.NoError(t, os.WriteFile(lockFile, []byte("1"), 0644))
+
+	allocator := fixtures.NewCIDRAllocator(dir)
+	allocator.LockTimeout = 50 * time.Millisecond
+	_, err := allocator.Allocate("TestA", 16)
+	assert.EqualError(t, err, "timed out waiting for IPAM lock "+lockFile, "a fresh lock file is held")
+
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(lockFile, old, old))
+	_, err = allocator.Allocate("TestA", 16)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, lockFile, "the lock file is removed on release")
+}
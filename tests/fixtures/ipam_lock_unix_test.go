@@ -0,0 +1,48 @@
+//go:build unix
+
+package fixtures_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+func TestLeftoverLockFileDoesNotBlock(t *testing.T) {
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, fixtures.LeaseFileName+".lock")
+	require.NoError(t, os.WriteFile(lockFile, []byte("1"), 0644))
+
+	allocator := fixtures.NewCIDRAllocator(dir)
+	allocator.LockTimeout = time.Second
+	_, err := allocator.Allocate("TestA", 16)
+	assert.NoError(t, err)
+	assert.FileExists(t, lockFile, "the lock file is never removed")
+}
+
+func TestHeldLockIsNotTakenOver(t *testing.T) {
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, fixtures.LeaseFileName+".lock")
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(lockFile, old, old))
+
+	allocator := fixtures.NewCIDRAllocator(dir)
+	allocator.LockTimeout = 50 * time.Millisecond
+	_, err = allocator.Allocate("TestA", 16)
+	assert.EqualError(t, err, "timed out waiting for IPAM lock "+lockFile, "an old lock that is still held is not stale")
+
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+	_, err = allocator.Allocate("TestA", 16)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+package fixtures_test
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+func TestAllocateReturnsAlignedPrivateBlocks(t *testing.T) {
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+
+	first, err := allocator.Allocate("TestA", 16)
+	require.NoError(t, err)
+	second, err := allocator.Allocate("TestB", 16)
+	require.NoError(t, err)
+	small, err := allocator.Allocate("TestC", 24)
+	require.NoError(t, err)
+
+	// 10.0.0.0/16 and 10.1.0.0/16 are the dev profile's fixed blocks
+	assert.Equal(t, "10.2.0.0/16", first.String())
+	assert.Equal(t, "10.3.0.0/16", second.String())
+	assert.Equal(t, "10.4.0.0/24", small.String())
+	for _, prefix := range []netip.Prefix{first, second, small} {
+		assert.Equal(t, prefix.Masked(), prefix, "host bits must not be set")
+		assert.True(t, prefix.Addr().IsPrivate())
+	}
+}
+
+func TestAllocateSkipsReservedAndReusesReleasedBlocks(t *testing.T) {
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+	allocator.Reserved = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/15")}
+
+	first, err := allocator.Allocate("TestA", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "10.2.0.0/16", first.String())
+
+	require.NoError(t, allocator.Release(first))
+	again, err := allocator.Allocate("TestB", 16)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+}
+
+func TestLeasesPersistAcrossAllocators(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := fixtures.NewCIDRAllocator(dir).Allocate("TestA", 20)
+	require.NoError(t, err)
+
+	// A second allocator on the same lease file stands in for another go test process
+	other := fixtures.NewCIDRAllocator(dir)
+	second, err := other.Allocate("TestB", 20)
+	require.NoError(t, err)
+	assert.False(t, first.Overlaps(second))
+
+	leases, err := other.Leases()
+	require.NoError(t, err)
+	require.Len(t, leases, 2)
+	assert.Equal(t, "TestA", leases[0].Owner)
+	assert.Equal(t, os.Getpid(), leases[0].PID)
+
+	require.NoError(t, other.ReleaseOwner("TestA"))
+	leases, err = other.Leases()
+	require.NoError(t, err)
+	require.Len(t, leases, 1)
+	assert.Equal(t, "TestB", leases[0].Owner)
+}
+
+func TestConcurrentAllocationsDoNotOverlap(t *testing.T) {
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var prefixes []netip.Prefix
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefix, err := fixtures.NewCIDRAllocator(dir).Allocate("TestParallel", 20)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			prefixes = append(prefixes, prefix)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, prefixes, 20)
+	for i := range prefixes {
+		for j := i + 1; j < len(prefixes); j++ {
+			assert.False(t, prefixes[i].Overlaps(prefixes[j]), "%s overlaps %s", prefixes[i], prefixes[j])
+		}
+	}
+}
+
+func TestExpiredLeasesAreReclaimed(t *testing.T) {
+	dir := t.TempDir()
+	data, err := json.Marshal(map[string]interface{}{
+		"leases": []fixtures.Lease{{
+			CIDR:      "10.2.0.0/16",
+			Owner:     "TestCrashed",
+			PID:       1,
+			ExpiresAt: time.Now().Add(-time.Minute),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fixtures.LeaseFileName), data, 0644))
+
+	prefix, err := fixtures.NewCIDRAllocator(dir).Allocate("TestA", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "10.2.0.0/16", prefix.String())
+}
+
+func TestAllocateExhaustedPool(t *testing.T) {
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+	allocator.Pools = []netip.Prefix{netip.MustParsePrefix("192.168.0.0/23")}
+
+	for i := 0; i < 2; i++ {
+		_, err := allocator.Allocate("TestA", 24)
+		require.NoError(t, err)
+	}
+	_, err := allocator.Allocate("TestA", 24)
+	assert.EqualError(t, err, "no free /24 block left in the IPAM pools")
+}
+
+func TestAllocateNetwork(t *testing.T) {
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+
+	allocation, err := allocator.AllocateNetwork("TestNetwork", []string{"us-east-1a", "us-east-1b"}, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.2.0.0/20", allocation.Inspection.CIDR)
+	assert.Equal(t, []string{"10.2.0.0/24", "10.2.1.0/24"}, allocation.Inspection.PublicSubnets)
+	assert.Equal(t, []string{"10.2.8.0/24", "10.2.9.0/24"}, allocation.Inspection.PrivateSubnets)
+	require.Len(t, allocation.Spokes, 2)
+	assert.Equal(t, "10.2.16.0/20", allocation.Spokes[0].CIDR)
+	assert.Equal(t, []string{"10.2.40.0/24", "10.2.41.0/24"}, allocation.Spokes[1].PrivateSubnets)
+
+	_, err = allocator.AllocateNetwork("TestNetwork", make([]string, 9), 0)
+	assert.Error(t, err)
+}
+
+func TestAllocateNetworkTestData(t *testing.T) {
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+
+	t.Run("staging", func(t *testing.T) {
+		tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-east-1", 1)
+		require.NoError(t, err)
+		data := tdm.AllocateNetworkTestData(t, allocator)
+
+		assert.Equal(t, "10.2.0.0/20", data.VpcCidr)
+		assert.Len(t, data.SpokeVpcCidrs, 2)
+		assert.Len(t, data.PublicSubnets, len(data.Azs))
+		assert.Len(t, data.PrivateSubnets, len(data.Azs))
+		assert.Equal(t, 64513, data.TgwAsn)
+	})
+
+	// The leases are held until the infrastructure is destroyed
+	leases, err := allocator.Leases()
+	require.NoError(t, err)
+	require.Len(t, leases, 3)
+	assert.Equal(t, "TestAllocateNetworkTestData/staging", leases[0].Owner)
+
+	require.NoError(t, allocator.ReleaseOwner("TestAllocateNetworkTestData/staging"))
+	leases, err = allocator.Leases()
+	require.NoError(t, err)
+	assert.Empty(t, leases)
+}
+
+func TestGenerateRandomCIDRIsAlignedAndPrivate(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 3)
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		prefix := netip.MustParsePrefix(tdm.GenerateRandomCIDR(16))
+		assert.Equal(t, prefix.Masked(), prefix)
+		assert.True(t, netip.MustParsePrefix("10.0.0.0/8").Contains(prefix.Addr()))
+	}
+	assert.Equal(t, "10.0.0.0/8", tdm.GenerateRandomCIDR(4))
+}
@@ -0,0 +1,304 @@
+package fixtures
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProfileVersion is the profile file format version this package reads
+	ProfileVersion = 1
+
+	// ProfilesDirEnvVar points at a directory of profiles to use instead of the
+	// profiles built into the package
+	ProfilesDirEnvVar = "TEST_PROFILES_DIR"
+)
+
+// builtinProfiles holds the default environment profiles
+//
+//go:embed profiles
+var builtinProfiles embed.FS
+
+// Profile is the fixture data of one environment, loaded from a YAML or JSON file
+// named after the environment
+type Profile struct {
+	Version     int                 `yaml:"version"`
+	Extends     string              `yaml:"extends,omitempty"`
+	Network     NetworkTestData     `yaml:"network"`
+	Firewall    FirewallTestData    `yaml:"firewall"`
+	Monitoring  MonitoringTestData  `yaml:"monitoring"`
+	Compliance  ComplianceTestData  `yaml:"compliance"`
+	Performance PerformanceTestData `yaml:"performance"`
+	Chaos       ChaosTestData       `yaml:"chaos"`
+	Cost        CostTestData        `yaml:"cost"`
+}
+
+// Profiles are the environment profiles of a profile directory with inheritance
+// resolved. A profile that sets extends starts from the named profile; maps are
+// merged key by key and any other value, including lists, replaces the parent's.
+type Profiles struct {
+	resolved map[string]map[string]interface{}
+}
+
+// DefaultProfiles loads the profiles in TEST_PROFILES_DIR, or the built-in
+// profiles when it is not set
+func DefaultProfiles() (*Profiles, error) {
+	if dir := os.Getenv(ProfilesDirEnvVar); dir != "" {
+		return LoadProfiles(os.DirFS(dir))
+	}
+	sub, err := fs.Sub(builtinProfiles, "profiles")
+	if err != nil {
+		return nil, err
+	}
+	return LoadProfiles(sub)
+}
+
+// LoadProfiles loads every .yaml, .yml and .json profile in the root of fsys,
+// resolves inheritance and validates each profile
+func LoadProfiles(fsys fs.FS) (*Profiles, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]map[string]interface{})
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		environment := strings.TrimSuffix(entry.Name(), ext)
+		if _, ok := raw[environment]; ok {
+			return nil, fmt.Errorf("duplicate profile for environment %s", environment)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		document := make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", entry.Name(), err)
+		}
+		if version, _ := document["version"].(int); version != ProfileVersion {
+			return nil, fmt.Errorf("profile %s: unsupported version %v, expected %d", entry.Name(), document["version"], ProfileVersion)
+		}
+		raw[environment] = document
+	}
+
+	profiles := &Profiles{resolved: make(map[string]map[string]interface{})}
+	for environment := range raw {
+		resolved, err := resolveProfile(environment, raw, nil)
+		if err != nil {
+			return nil, err
+		}
+		profiles.resolved[environment] = resolved
+	}
+
+	for _, environment := range profiles.Environments() {
+		if _, err := profiles.Profile(environment, ""); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+// resolveProfile merges a profile onto the profiles it extends
+func resolveProfile(environment string, raw map[string]map[string]interface{}, chain []string) (map[string]interface{}, error) {
+	for _, seen := range chain {
+		if seen == environment {
+			return nil, fmt.Errorf("profile inheritance cycle: %s -> %s", strings.Join(chain, " -> "), environment)
+		}
+	}
+	document, ok := raw[environment]
+	if !ok {
+		return nil, fmt.Errorf("profile %s extends unknown profile %s", chain[len(chain)-1], environment)
+	}
+
+	parent, ok := document["extends"].(string)
+	if !ok || parent == "" {
+		return document, nil
+	}
+	base, err := resolveProfile(parent, raw, append(chain, environment))
+	if err != nil {
+		return nil, err
+	}
+	return mergeProfileValues(base, document).(map[string]interface{}), nil
+}
+
+// mergeProfileValues overlays child onto base, merging maps recursively
+func mergeProfileValues(base, child interface{}) interface{} {
+	baseMap, baseIsMap := base.(map[string]interface{})
+	childMap, childIsMap := child.(map[string]interface{})
+	if !baseIsMap || !childIsMap {
+		return child
+	}
+
+	merged := make(map[string]interface{}, len(baseMap)+len(childMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range childMap {
+		merged[key] = mergeProfileValues(baseMap[key], value)
+	}
+	return merged
+}
+
+// Environments returns the names of the loaded profiles in alphabetical order
+func (p *Profiles) Environments() []string {
+	environments := make([]string, 0, len(p.resolved))
+	for environment := range p.resolved {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	return environments
+}
+
+// Profile returns the validated profile of an environment with ${environment} and
+// ${region} replaced. Unknown environments are an error.
+func (p *Profiles) Profile(environment, region string) (*Profile, error) {
+	resolved, ok := p.resolved[environment]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment %q, known environments: %s", environment, strings.Join(p.Environments(), ", "))
+	}
+
+	replacer := strings.NewReplacer("${environment}", environment, "${region}", region)
+	data, err := yaml.Marshal(expandProfileValue(resolved, replacer))
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(profile); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", environment, err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", environment, err)
+	}
+	return profile, nil
+}
+
+// expandProfileValue replaces placeholders in every string of a decoded document
+func expandProfileValue(value interface{}, replacer *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replacer.Replace(v)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expanded[key] = expandProfileValue(item, replacer)
+		}
+		return expanded
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded[i] = expandProfileValue(item, replacer)
+		}
+		return expanded
+	default:
+		return value
+	}
+}
+
+// Validate checks the profile for values the tests cannot work with
+func (p *Profile) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	network := p.Network
+	vpc, err := netip.ParsePrefix(network.VpcCidr)
+	if err != nil {
+		fail("network.vpc_cidr: %v", err)
+	}
+	if len(network.Azs) == 0 {
+		fail("network.azs: at least one AZ is required")
+	}
+	for field, subnets := range map[string][]string{
+		"public_subnets":  network.PublicSubnets,
+		"private_subnets": network.PrivateSubnets,
+	} {
+		if len(subnets) != len(network.Azs) {
+			fail("network.%s: expected one subnet per AZ (%d), got %d", field, len(network.Azs), len(subnets))
+		}
+		for _, subnet := range subnets {
+			prefix, err := netip.ParsePrefix(subnet)
+			switch {
+			case err != nil:
+				fail("network.%s: %v", field, err)
+			case vpc.IsValid() && (prefix.Bits() < vpc.Bits() || !vpc.Contains(prefix.Addr())):
+				fail("network.%s: %s is outside %s", field, subnet, network.VpcCidr)
+			}
+		}
+	}
+	for _, spoke := range network.SpokeVpcCidrs {
+		prefix, err := netip.ParsePrefix(spoke)
+		switch {
+		case err != nil:
+			fail("network.spoke_vpc_cidrs: %v", err)
+		case vpc.IsValid() && prefix.Overlaps(vpc):
+			fail("network.spoke_vpc_cidrs: %s overlaps %s", spoke, network.VpcCidr)
+		}
+	}
+	if network.TgwAsn < 64512 || network.TgwAsn > 65534 {
+		fail("network.tgw_asn: %d is not a private 16-bit ASN (64512-65534)", network.TgwAsn)
+	}
+
+	firewall := p.Firewall
+	if firewall.Version == "" || firewall.InstanceType == "" {
+		fail("firewall: version and instance_type are required")
+	}
+	if firewall.MinSize < 1 || firewall.MaxSize < firewall.MinSize {
+		fail("firewall: expected 1 <= min_size <= max_size, got %d and %d", firewall.MinSize, firewall.MaxSize)
+	}
+	for _, rule := range firewall.SecurityRules {
+		if rule.Action != "allow" && rule.Action != "deny" && rule.Action != "drop" {
+			fail("firewall.security_rules %s: unsupported action %q", rule.Name, rule.Action)
+		}
+	}
+
+	if p.Monitoring.FlowLogsRetentionDays <= 0 {
+		fail("monitoring.flow_logs_retention_days must be positive")
+	}
+	for _, alarm := range p.Monitoring.Alarms {
+		if alarm.Name == "" || alarm.MetricName == "" || alarm.EvaluationPeriods < 1 {
+			fail("monitoring.alarms: name, metric_name and evaluation_periods are required")
+		}
+	}
+
+	performance := p.Performance
+	if performance.LoadTestDuration <= 0 || performance.LatencyThreshold <= 0 {
+		fail("performance: load_test_duration and latency_threshold must be positive")
+	}
+	if performance.LatencyPercentile < 0 || performance.LatencyPercentile > 100 {
+		fail("performance.latency_percentile must be between 0 and 100")
+	}
+	if performance.ErrorRateThreshold < 0 || performance.ErrorRateThreshold > 1 {
+		fail("performance.error_rate_threshold must be between 0 and 1")
+	}
+
+	if p.Cost.BudgetAmount <= 0 {
+		fail("cost.budget_amount must be positive")
+	}
+	if p.Cost.AlertThreshold <= 0 || p.Cost.AlertThreshold > 100 {
+		fail("cost.alert_threshold must be a percentage between 0 and 100")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid profile: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package fixtures_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+// minimalProfile is a valid base profile for profile loading tests
+const minimalProfile = `version: 1
+network:
+  vpc_cidr: 10.0.0.0/16
+  spoke_vpc_cidrs: [10.1.0.0/16]
+  public_subnets: [10.0.10.0/24]
+  private_subnets: [10.0.20.0/24]
+  azs: ["${region}a"]
+  tgw_asn: 64512
+firewall:
+  version: 10.2.0
+  instance_type: m5.xlarge
+  min_size: 1
+  max_size: 2
+monitoring:
+  flow_logs_retention_days: 7
+performance:
+  load_test_duration: 1m
+  latency_threshold: 50ms
+cost:
+  budget_amount: 100
+  alert_threshold: 80
+`
+
+func TestBuiltinProfiles(t *testing.T) {
+	profiles, err := fixtures.DefaultProfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod", "qa", "staging"}, profiles.Environments())
+
+	dev, err := profiles.Profile("dev", "eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu-west-1a"}, dev.Network.Azs)
+	assert.Equal(t, 10*time.Minute, dev.Performance.LoadTestDuration)
+	assert.Equal(t, 50*time.Millisecond, dev.Performance.LatencyThreshold)
+	assert.Equal(t, "dev", dev.Compliance.Tags["Environment"])
+
+	staging, err := profiles.Profile("staging", "us-east-1")
+	require.NoError(t, err)
+	qa, err := profiles.Profile("qa", "us-east-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.20.0.0/16", qa.Network.VpcCidr)
+	assert.Equal(t, 64515, qa.Network.TgwAsn)
+	assert.Equal(t, staging.Network.Azs, qa.Network.Azs, "qa should inherit the staging AZs")
+	assert.Equal(t, staging.Firewall.SecurityRules, qa.Firewall.SecurityRules, "qa should inherit the staging firewall")
+	assert.Equal(t, "vmseries-qa", qa.Firewall.BootstrapConfig["hostname"], "placeholders should expand to the inheriting environment")
+	assert.Equal(t, "qa", qa.Compliance.Tags["Environment"])
+}
+
+func TestUnknownEnvironment(t *testing.T) {
+	_, err := fixtures.NewTestDataManager("sandbox", "us-east-1")
+	assert.EqualError(t, err, `unknown environment "sandbox", known environments: dev, prod, qa, staging`)
+}
+
+func TestProfilesDirEnvVar(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(fixtures.ProfilesDirEnvVar, dir)
+
+	_, err := fixtures.NewTestDataManager("dev", "us-east-1")
+	assert.EqualError(t, err, `unknown environment "dev", known environments: `)
+}
+
+func TestLoadProfilesInheritance(t *testing.T) {
+	profiles, err := fixtures.LoadProfiles(fstest.MapFS{
+		"base.yaml": {Data: []byte(minimalProfile)},
+		"child.json": {Data: []byte(`{
+  "version": 1,
+  "extends": "base",
+  "network": {"tgw_asn": 65000},
+  "firewall": {"bootstrap_config": {"hostname": "fw-${environment}"}}
+}`)},
+		"README.md": {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "child"}, profiles.Environments())
+
+	child, err := profiles.Profile("child", "ap-south-1")
+	require.NoError(t, err)
+	assert.Equal(t, 65000, child.Network.TgwAsn)
+	assert.Equal(t, "10.0.0.0/16", child.Network.VpcCidr, "maps should be merged with the parent")
+	assert.Equal(t, []string{"ap-south-1a"}, child.Network.Azs)
+	assert.Equal(t, "fw-child", child.Firewall.BootstrapConfig["hostname"])
+	assert.Equal(t, 2, child.Firewall.MaxSize)
+}
+
+func TestLoadProfilesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name:  "unsupported version",
+			files: fstest.MapFS{"dev.yaml": {Data: []byte("version: 2\n")}},
+			err:   "profile dev.yaml: unsupported version 2, expected 1",
+		},
+		{
+			name: "inheritance cycle",
+			files: fstest.MapFS{
+				"a.yaml": {Data: []byte("version: 1\nextends: b\n")},
+				"b.yaml": {Data: []byte("version: 1\nextends: a\n")},
+			},
+			err: "profile inheritance cycle",
+		},
+		{
+			name:  "unknown parent",
+			files: fstest.MapFS{"qa.yaml": {Data: []byte("version: 1\nextends: staging\n")}},
+			err:   "profile qa extends unknown profile staging",
+		},
+		{
+			name:  "unknown field",
+			files: fstest.MapFS{"dev.yaml": {Data: []byte(minimalProfile + "database:\n  engine: postgres\n")}},
+			err:   "field database not found",
+		},
+		{
+			name:  "wrong type",
+			files: fstest.MapFS{"dev.yaml": {Data: []byte(minimalProfile + "chaos:\n  recovery_time_objectives:\n    az: soon\n")}},
+			err:   "profile dev:",
+		},
+		{
+			name: "invalid values",
+			files: fstest.MapFS{
+				"base.yaml": {Data: []byte(minimalProfile)},
+				"dev.yaml": {Data: []byte(`version: 1
+extends: base
+network:
+  public_subnets: [10.5.0.0/24]
+  spoke_vpc_cidrs: [10.0.128.0/17]
+  tgw_asn: 100
+firewall:
+  security_rules:
+    - name: any
+      action: permit
+`)},
+			},
+			err: "invalid profile: firewall.security_rules any: unsupported action \"permit\"; " +
+				"network.public_subnets: 10.5.0.0/24 is outside 10.0.0.0/16; " +
+				"network.spoke_vpc_cidrs: 10.0.128.0/17 overlaps 10.0.0.0/16; " +
+				"network.tgw_asn: 100 is not a private 16-bit ASN (64512-65534)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fixtures.LoadProfiles(tt.files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestGettersReturnCopies(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("prod", "us-west-2", 1)
+	require.NoError(t, err)
+
+	network := tdm.GetNetworkTestData()
+	assert.Len(t, network.Azs, 3)
+	assert.Equal(t, "us-west-2c", network.Azs[2])
+	network.VpcCidr = "192.168.0.0/16"
+	assert.Equal(t, "10.100.0.0/16", tdm.GetNetworkTestData().VpcCidr)
+}
@@ -0,0 +1,320 @@
+package fixtures
+
+import (
+	"crypto/rand"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// ModulesDir is the Terraform modules directory relative to a test package
+const ModulesDir = "../../modules"
+
+// TerraformOption overrides part of the options a builder produces
+type TerraformOption func(*terraform.Options)
+
+// WithVar sets a Terraform variable
+func WithVar(name string, value interface{}) TerraformOption {
+	return func(options *terraform.Options) {
+		options.Vars[name] = value
+	}
+}
+
+// WithVars sets several Terraform variables
+func WithVars(vars map[string]interface{}) TerraformOption {
+	return func(options *terraform.Options) {
+		for name, value := range vars {
+			options.Vars[name] = value
+		}
+	}
+}
+
+// WithoutVar removes a Terraform variable so the module default applies
+func WithoutVar(name string) TerraformOption {
+	return func(options *terraform.Options) {
+		delete(options.Vars, name)
+	}
+}
+
+// WithTags adds tags to the standard tags, replacing tags with the same key
+func WithTags(tags map[string]string) TerraformOption {
+	return func(options *terraform.Options) {
+		merged := make(map[string]string)
+		if existing, ok := options.Vars["tags"].(map[string]string); ok {
+			for key, value := range existing {
+				merged[key] = value
+			}
+		}
+		for key, value := range tags {
+			merged[key] = value
+		}
+		options.Vars["tags"] = merged
+	}
+}
+
+// WithTerraformDir points the options at another module directory
+func WithTerraformDir(dir string) TerraformOption {
+	return func(options *terraform.Options) {
+		options.TerraformDir = dir
+	}
+}
+
+// WithNetwork sets the network module CIDR, subnet and ASN variables from network
+// data, for example data leased with AllocateNetworkTestData
+func WithNetwork(network *NetworkTestData) TerraformOption {
+	return WithVars(map[string]interface{}{
+		"vpc_cidr":              network.VpcCidr,
+		"azs":                   network.Azs,
+		"public_subnets":        network.PublicSubnets,
+		"private_subnets":       network.PrivateSubnets,
+		"tgw_asn":               network.TgwAsn,
+		"spoke_vpc_cidrs":       network.SpokeVpcCidrs,
+		"spoke_azs":             network.Azs,
+		"spoke_private_subnets": spokePrivateSubnets(network),
+	})
+}
+
+// NetworkOutputs are the network module outputs the other modules take as input
+type NetworkOutputs struct {
+	InspectionVpcID       string
+	InspectionVpcCidr     string
+	PublicSubnetIDs       []string
+	PrivateSubnetIDs      []string
+	PrivateRouteTableIDs  []string
+	SpokeVpcIDs           []string
+	SpokeVpcCidrs         []string
+	SpokePrivateSubnetIDs []string
+	SpokeRouteTableIDs    []string
+	TransitGatewayID      string
+	InternetGatewayID     string
+}
+
+// NetworkOutputsFromTerraform reads the outputs of an applied network module
+func NetworkOutputsFromTerraform(t testing.TB, options *terraform.Options) NetworkOutputs {
+	outputs := NetworkOutputs{
+		InspectionVpcID:       terraform.Output(t, options, "inspection_vpc_id"),
+		PublicSubnetIDs:       terraform.OutputList(t, options, "inspection_public_subnet_ids"),
+		PrivateSubnetIDs:      terraform.OutputList(t, options, "inspection_private_subnet_ids"),
+		PrivateRouteTableIDs:  terraform.OutputList(t, options, "inspection_private_route_table_ids"),
+		SpokeVpcIDs:           terraform.OutputList(t, options, "spoke_vpc_ids"),
+		SpokePrivateSubnetIDs: terraform.OutputList(t, options, "spoke_private_subnet_ids"),
+		SpokeRouteTableIDs:    terraform.OutputList(t, options, "spoke_route_table_ids"),
+		TransitGatewayID:      terraform.Output(t, options, "transit_gateway_id"),
+		InternetGatewayID:     terraform.Output(t, options, "internet_gateway_id"),
+	}
+	outputs.InspectionVpcCidr, _ = options.Vars["vpc_cidr"].(string)
+	outputs.SpokeVpcCidrs, _ = options.Vars["spoke_vpc_cidrs"].([]string)
+	return outputs
+}
+
+// PlaceholderNetworkOutputs returns network outputs with made-up resource IDs shaped
+// like the environment's network, for tests of a module without a deployed network
+func (tdm *TestDataManager) PlaceholderNetworkOutputs() NetworkOutputs {
+	network := tdm.GetNetworkTestData()
+	ids := func(prefix string, count int) []string {
+		result := make([]string, count)
+		for i := range result {
+			result[i] = tdm.placeholderID(prefix)
+		}
+		return result
+	}
+
+	azs := len(network.Azs)
+	spokes := len(network.SpokeVpcCidrs)
+	return NetworkOutputs{
+		InspectionVpcID:       tdm.placeholderID("vpc"),
+		InspectionVpcCidr:     network.VpcCidr,
+		PublicSubnetIDs:       ids("subnet", azs),
+		PrivateSubnetIDs:      ids("subnet", azs),
+		PrivateRouteTableIDs:  ids("rtb", azs),
+		SpokeVpcIDs:           ids("vpc", spokes),
+		SpokeVpcCidrs:         network.SpokeVpcCidrs,
+		SpokePrivateSubnetIDs: ids("subnet", spokes*azs),
+		SpokeRouteTableIDs:    ids("rtb", spokes),
+		TransitGatewayID:      tdm.placeholderID("tgw"),
+		InternetGatewayID:     tdm.placeholderID("igw"),
+	}
+}
+
+// placeholderID returns an AWS style resource ID with 17 random hex digits
+func (tdm *TestDataManager) placeholderID(prefix string) string {
+	const hex = "0123456789abcdef"
+	id := make([]byte, 17)
+	for i := range id {
+		id[i] = hex[tdm.Random.Intn(len(hex))]
+	}
+	return prefix + "-" + string(id)
+}
+
+// NamePrefix returns a short name unique to the test data manager, such as
+// dev-k3x9q2ab, for naming and tagging the resources a test creates. It is drawn from
+// the seeded random source once, so replaying a seed gives the same prefix.
+func (tdm *TestDataManager) NamePrefix() string {
+	if tdm.namePrefix == "" {
+		tdm.namePrefix = tdm.Environment + "-" + strings.ToLower(tdm.GenerateRandomString(8))
+	}
+	return tdm.namePrefix
+}
+
+// StandardTags returns the compliance and cost tags of the profile plus tags that
+// identify the test run, so leaked resources can be traced and cleaned up
+func (tdm *TestDataManager) StandardTags() map[string]string {
+	tags := make(map[string]string)
+	for key, value := range tdm.Profile.Cost.ResourceTags {
+		tags[key] = value
+	}
+	for key, value := range tdm.Profile.Compliance.Tags {
+		tags[key] = value
+	}
+	tags["ManagedBy"] = "terratest"
+	tags["TestPrefix"] = tdm.NamePrefix()
+	tags["TestSeed"] = strconv.FormatInt(tdm.Seed, 10)
+	return tags
+}
+
+// moduleOptions returns options for a module with the standard tags and region,
+// with the variables and overrides applied in order
+func (tdm *TestDataManager) moduleOptions(module string, vars map[string]interface{}, overrides []TerraformOption) *terraform.Options {
+	options := &terraform.Options{
+		TerraformDir: ModulesDir + "/" + module,
+		Vars:         map[string]interface{}{"tags": tdm.StandardTags()},
+		EnvVars:      map[string]string{"AWS_DEFAULT_REGION": tdm.Region},
+	}
+	WithVars(vars)(options)
+	for _, override := range overrides {
+		override(options)
+	}
+	return options
+}
+
+// NetworkOptions returns options for modules/network from the environment's network
+// data. Its CIDRs are the profile's fixed ones, which recorded plans rely on; tests
+// that apply the module should override them with
+// WithNetwork(tdm.AllocateNetworkTestData(t, allocator)).
+func NetworkOptions(tdm *TestDataManager, overrides ...TerraformOption) *terraform.Options {
+	return tdm.moduleOptions("network", nil, append([]TerraformOption{WithNetwork(tdm.GetNetworkTestData())}, overrides...))
+}
+
+// InspectionOptions returns options for modules/inspection wired to the outputs of
+// the network module
+func InspectionOptions(tdm *TestDataManager, network NetworkOutputs, overrides ...TerraformOption) *terraform.Options {
+	return tdm.moduleOptions("inspection", map[string]interface{}{
+		"inspection_vpc_id":                  network.InspectionVpcID,
+		"inspection_vpc_cidr":                network.InspectionVpcCidr,
+		"public_subnet_ids":                  network.PublicSubnetIDs,
+		"inspection_private_route_table_ids": network.PrivateRouteTableIDs,
+		"spoke_vpc_ids":                      network.SpokeVpcIDs,
+		"spoke_vpc_cidrs":                    network.SpokeVpcCidrs,
+		"spoke_private_subnet_ids":           network.SpokePrivateSubnetIDs,
+		"spoke_route_table_ids":              network.SpokeRouteTableIDs,
+		"transit_gateway_id":                 network.TransitGatewayID,
+		"internet_gateway_id":                network.InternetGatewayID,
+	}, overrides)
+}
+
+// VMSeriesOptions returns options for modules/firewall-vmseries from the
+// environment's firewall data, placing the firewalls in the private subnets of the
+// network and registering them with the GWLB target group. Panorama credentials are
+// read from PANORAMA_USERNAME and PANORAMA_PASSWORD. The password is passed as
+// TF_VAR_panorama_password, so terratest doesn't log it with the command line.
+func VMSeriesOptions(tdm *TestDataManager, network NetworkOutputs, targetGroupARN string, overrides ...TerraformOption) *terraform.Options {
+	firewall := tdm.GetFirewallTestData()
+
+	username := os.Getenv("PANORAMA_USERNAME")
+	if username == "" {
+		username = "admin"
+	}
+
+	options := tdm.moduleOptions("firewall-vmseries", map[string]interface{}{
+		"vpc_id":              network.InspectionVpcID,
+		"subnet_ids":          network.PrivateSubnetIDs,
+		"target_group_arn":    targetGroupARN,
+		"vmseries_version":    firewall.Version,
+		"instance_type":       firewall.InstanceType,
+		"min_size":            firewall.MinSize,
+		"max_size":            firewall.MaxSize,
+		"key_name":            firewall.KeyName,
+		"panorama_ip":         firewall.BootstrapConfig["panorama-server"],
+		"panorama_username":   username,
+		"aws_region":          tdm.Region,
+		"management_cidrs":    []string{network.InspectionVpcCidr},
+		"inspection_vpc_cidr": network.InspectionVpcCidr,
+	}, overrides)
+	options.EnvVars["TF_VAR_panorama_password"] = panoramaPassword()
+	return options
+}
+
+// panoramaPassword returns PANORAMA_PASSWORD, or a password drawn from crypto/rand
+// when it is unset. It never comes from the seeded source, so a logged seed doesn't
+// reveal it.
+func panoramaPassword() string {
+	if password := os.Getenv("PANORAMA_PASSWORD"); password != "" {
+		return password
+	}
+
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	password := make([]byte, 0, 20)
+	buf := make([]byte, 32)
+	for len(password) < cap(password) {
+		if _, err := rand.Read(buf); err != nil {
+			panic("fixtures: failed to generate a Panorama password: " + err.Error())
+		}
+		for _, b := range buf {
+			// Reject the bytes past the last full run of the charset so every
+			// character is equally likely
+			if int(b) < 256-256%len(charset) && len(password) < cap(password) {
+				password = append(password, charset[int(b)%len(charset)])
+			}
+		}
+	}
+	return string(password)
+}
+
+// spokePrivateSubnets lays out the private subnets of each spoke VPC like the
+// private subnets of the inspection VPC, one per AZ at the same offset
+func spokePrivateSubnets(network *NetworkTestData) [][]string {
+	vpc, err := netip.ParsePrefix(network.VpcCidr)
+	if err != nil {
+		return nil
+	}
+
+	subnets := make([][]string, len(network.SpokeVpcCidrs))
+	for i, cidr := range network.SpokeVpcCidrs {
+		spoke, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		for j, private := range network.PrivateSubnets {
+			prefix, err := netip.ParsePrefix(private)
+			if err != nil {
+				continue
+			}
+			subnet := netip.PrefixFrom(addAddr(spoke.Masked().Addr(), addrValue(prefix.Addr())-addrValue(vpc.Masked().Addr())), prefix.Bits())
+			if subnet.Bits() < spoke.Bits() || !spoke.Contains(subnet.Addr()) {
+				bits := prefix.Bits()
+				if bits < spoke.Bits() {
+					bits = spoke.Bits()
+				}
+				subnet = nthSubnet(spoke, bits, j)
+			}
+			subnets[i] = append(subnets[i], subnet.Masked().String())
+		}
+	}
+	return subnets
+}
+
+// addrValue returns an IPv4 address as a number
+func addrValue(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// addAddr adds an offset to an IPv4 address
+func addAddr(addr netip.Addr, offset uint32) netip.Addr {
+	value := addrValue(addr) + offset
+	return netip.AddrFrom4([4]byte{byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
+}
@@ -0,0 +1,99 @@
+package fixtures_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+)
+
+func TestNetworkOptions(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-west-2", 5)
+	require.NoError(t, err)
+
+	options := fixtures.NetworkOptions(tdm)
+	assert.Equal(t, "../../modules/network", options.TerraformDir)
+	assert.Equal(t, "us-west-2", options.EnvVars["AWS_DEFAULT_REGION"])
+	assert.Equal(t, "10.10.0.0/16", options.Vars["vpc_cidr"])
+	assert.Equal(t, 64513, options.Vars["tgw_asn"])
+	assert.Equal(t, []string{"us-west-2a", "us-west-2b"}, options.Vars["spoke_azs"])
+	assert.Equal(t, [][]string{
+		{"10.11.20.0/24", "10.11.21.0/24"},
+		{"10.12.20.0/24", "10.12.21.0/24"},
+	}, options.Vars["spoke_private_subnets"])
+
+	tags := options.Vars["tags"].(map[string]string)
+	assert.Equal(t, "staging", tags["Environment"])
+	assert.Equal(t, "security-operations", tags["CostCenter"])
+	assert.Equal(t, "5", tags["TestSeed"])
+	assert.True(t, strings.HasPrefix(tags["TestPrefix"], "staging-"))
+	assert.Equal(t, tdm.NamePrefix(), tags["TestPrefix"], "the prefix should be stable for a manager")
+}
+
+func TestNetworkOptionsOverrides(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 5)
+	require.NoError(t, err)
+	allocator := fixtures.NewCIDRAllocator(t.TempDir())
+	moduleCopy := t.TempDir()
+
+	options := fixtures.NetworkOptions(tdm,
+		fixtures.WithNetwork(tdm.AllocateNetworkTestData(t, allocator)),
+		fixtures.WithVar("tgw_asn", 65000),
+		fixtures.WithTags(map[string]string{"Owner": "network-team"}),
+		fixtures.WithoutVar("spoke_azs"),
+		fixtures.WithTerraformDir(moduleCopy),
+	)
+	assert.Equal(t, moduleCopy, options.TerraformDir)
+	assert.Equal(t, "10.2.0.0/20", options.Vars["vpc_cidr"])
+	assert.Equal(t, [][]string{{"10.2.24.0/24"}}, options.Vars["spoke_private_subnets"])
+	assert.Equal(t, 65000, options.Vars["tgw_asn"])
+	assert.NotContains(t, options.Vars, "spoke_azs")
+
+	tags := options.Vars["tags"].(map[string]string)
+	assert.Equal(t, "network-team", tags["Owner"])
+	assert.Equal(t, "dev", tags["Environment"], "overrides should keep the standard tags")
+}
+
+func TestInspectionAndVMSeriesOptions(t *testing.T) {
+	t.Setenv("PANORAMA_PASSWORD", "from-env")
+	tdm, err := fixtures.NewTestDataManagerWithSeed("prod", "eu-west-1", 9)
+	require.NoError(t, err)
+
+	network := tdm.PlaceholderNetworkOutputs()
+	assert.Regexp(t, `^vpc-[0-9a-f]{17}$`, network.InspectionVpcID)
+	assert.Len(t, network.SpokeVpcIDs, 3)
+	assert.Len(t, network.SpokePrivateSubnetIDs, 9)
+
+	inspection := fixtures.InspectionOptions(tdm, network)
+	assert.Equal(t, "../../modules/inspection", inspection.TerraformDir)
+	assert.Equal(t, network.InspectionVpcID, inspection.Vars["inspection_vpc_id"])
+	assert.Equal(t, network.SpokePrivateSubnetIDs, inspection.Vars["spoke_private_subnet_ids"])
+	assert.Equal(t, "10.100.0.0/16", inspection.Vars["inspection_vpc_cidr"])
+
+	vmseries := fixtures.VMSeriesOptions(tdm, network, "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/test/1",
+		fixtures.WithVar("min_size", 1))
+	assert.Equal(t, "../../modules/firewall-vmseries", vmseries.TerraformDir)
+	assert.Equal(t, network.PrivateSubnetIDs, vmseries.Vars["subnet_ids"])
+	assert.Equal(t, "10.2.0", vmseries.Vars["vmseries_version"])
+	assert.Equal(t, 1, vmseries.Vars["min_size"])
+	assert.Equal(t, "from-env", vmseries.EnvVars["TF_VAR_panorama_password"])
+	assert.NotContains(t, vmseries.Vars, "panorama_password", "-var arguments are logged")
+	assert.Equal(t, "eu-west-1", vmseries.Vars["aws_region"])
+	assert.Equal(t, inspection.Vars["tags"], vmseries.Vars["tags"], "modules of one test should share tags")
+}
+
+func TestVMSeriesOptionsPasswordIsNotSeeded(t *testing.T) {
+	t.Setenv("PANORAMA_PASSWORD", "")
+	passwords := make([]string, 2)
+	for i := range passwords {
+		tdm, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 1)
+		require.NoError(t, err)
+		options := fixtures.VMSeriesOptions(tdm, tdm.PlaceholderNetworkOutputs(), "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/1")
+		passwords[i] = options.EnvVars["TF_VAR_panorama_password"]
+		assert.Regexp(t, `^[a-zA-Z0-9]{20}$`, passwords[i])
+	}
+	assert.NotEqual(t, passwords[0], passwords[1], "the same seed should not give the same password")
+}
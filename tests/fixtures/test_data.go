@@ -2,369 +2,346 @@ package fixtures
 
 import (
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
 	"time"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// SeedEnvVar is the environment variable that fixes the fixture seed, for example
+// to replay the fixtures of a failed run
+const SeedEnvVar = "TEST_SEED"
+
+var (
+	processSeed     int64
+	processSeedOnce sync.Once
 )
 
 // TestDataManager manages test data and fixtures
 type TestDataManager struct {
 	Environment string
 	Region      string
+	Seed        int64 // seed of Random, logged and recorded so fixtures can be replayed
 	Random      *rand.Rand
+	Profile     *Profile
+
+	namePrefix string
+}
+
+// NewTestDataManager creates a new test data manager for an environment profile
+// from DefaultProfiles, seeded by SeedFromEnv
+func NewTestDataManager(environment, region string) (*TestDataManager, error) {
+	return NewTestDataManagerWithSeed(environment, region, SeedFromEnv())
 }
 
-// NewTestDataManager creates a new test data manager
-func NewTestDataManager(environment, region string) *TestDataManager {
+// NewTestDataManagerWithSeed creates a test data manager whose random fixtures are
+// generated from the given seed
+func NewTestDataManagerWithSeed(environment, region string, seed int64) (*TestDataManager, error) {
+	profiles, err := DefaultProfiles()
+	if err != nil {
+		return nil, err
+	}
+	profile, err := profiles.Profile(environment, region)
+	if err != nil {
+		return nil, err
+	}
+	return NewTestDataManagerFromProfile(environment, region, seed, profile), nil
+}
+
+// NewTestDataManagerFromProfile creates a test data manager for an already loaded profile
+func NewTestDataManagerFromProfile(environment, region string, seed int64, profile *Profile) *TestDataManager {
 	return &TestDataManager{
 		Environment: environment,
 		Region:      region,
-		Random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		Seed:        seed,
+		Random:      rand.New(rand.NewSource(seed)),
+		Profile:     profile,
+	}
+}
+
+// NewTestDataManagerForTest creates a test data manager for a single test, see
+// ForTest. The test fails if the environment has no profile.
+func NewTestDataManagerForTest(t testing.TB, environment, region string) *TestDataManager {
+	t.Helper()
+	tdm, err := NewTestDataManager(environment, region)
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	return tdm.ForTest(t)
+}
+
+// ReplayTestDataManager recreates the test data manager of a recorded suite with
+// its environment, region and seed, so the fixtures of a failed run are generated
+// again exactly. Use ForTest on the result to replay a single test.
+func ReplayTestDataManager(suite reporting.TestSuiteResult) (*TestDataManager, error) {
+	if suite.Seed == nil {
+		return nil, fmt.Errorf("suite %s has no recorded seed", suite.SuiteName)
 	}
+	return NewTestDataManagerWithSeed(suite.Environment, suite.Region, *suite.Seed)
+}
+
+// SeedFromEnv returns the seed set in TEST_SEED. Values that are not integers, such
+// as a CI build ID, are hashed into a seed. Without TEST_SEED a seed is drawn from
+// the clock once per test binary, so every test in a package shares it.
+func SeedFromEnv() int64 {
+	value := strings.TrimSpace(os.Getenv(SeedEnvVar))
+	if value == "" {
+		processSeedOnce.Do(func() {
+			processSeed = time.Now().UnixNano()
+		})
+		return processSeed
+	}
+
+	if seed, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seed
+	}
+	return hashSeed(value)
+}
+
+// hashSeed derives a seed from a string
+func hashSeed(value string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	return int64(hash.Sum64())
+}
+
+// ForTest returns a copy of the manager for a single test. Its random values are
+// derived from the seed and the test name, so they don't depend on test order and
+// parallel tests get distinct values. The seed is logged as TEST_SEED=<seed>, which
+// go test -json ingestion records on the suite, and a failing test logs the command
+// that replays it.
+func (tdm *TestDataManager) ForTest(t testing.TB) *TestDataManager {
+	t.Helper()
+
+	forTest := *tdm
+	forTest.Random = rand.New(rand.NewSource(tdm.Seed ^ hashSeed(t.Name())))
+	forTest.namePrefix = ""
+
+	t.Logf("fixtures: %s=%d environment=%s region=%s", SeedEnvVar, tdm.Seed, tdm.Environment, tdm.Region)
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("replay fixtures with: %s=%d go test -run '%s'", SeedEnvVar, tdm.Seed, runPattern(t.Name()))
+		}
+	})
+	return &forTest
+}
+
+// runPattern returns the go test -run pattern that selects exactly the named test
+func runPattern(testName string) string {
+	parts := strings.Split(testName, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
 }
 
 // NetworkTestData contains network-related test data
 type NetworkTestData struct {
-	VpcCidr        string
-	SpokeVpcCidrs  []string
-	PublicSubnets  []string
-	PrivateSubnets []string
-	Azs            []string
-	TgwAsn         int
+	VpcCidr        string   `yaml:"vpc_cidr"`
+	SpokeVpcCidrs  []string `yaml:"spoke_vpc_cidrs"`
+	PublicSubnets  []string `yaml:"public_subnets"`
+	PrivateSubnets []string `yaml:"private_subnets"`
+	Azs            []string `yaml:"azs"`
+	TgwAsn         int      `yaml:"tgw_asn"`
 }
 
-// GetNetworkTestData returns network test data for the specified environment
+// GetNetworkTestData returns a copy of the environment's network test data
 func (tdm *TestDataManager) GetNetworkTestData() *NetworkTestData {
-	switch tdm.Environment {
-	case "dev":
-		return &NetworkTestData{
-			VpcCidr:        "10.0.0.0/16",
-			SpokeVpcCidrs:  []string{"10.1.0.0/16"},
-			PublicSubnets:  []string{"10.0.10.0/24"},
-			PrivateSubnets: []string{"10.0.20.0/24"},
-			Azs:            []string{"us-east-1a"},
-			TgwAsn:         64512,
-		}
-	case "staging":
-		return &NetworkTestData{
-			VpcCidr:        "10.10.0.0/16",
-			SpokeVpcCidrs:  []string{"10.11.0.0/16", "10.12.0.0/16"},
-			PublicSubnets:  []string{"10.10.10.0/24", "10.10.11.0/24"},
-			PrivateSubnets: []string{"10.10.20.0/24", "10.10.21.0/24"},
-			Azs:            []string{"us-east-1a", "us-east-1b"},
-			TgwAsn:         64513,
-		}
-	case "prod":
-		return &NetworkTestData{
-			VpcCidr:        "10.100.0.0/16",
-			SpokeVpcCidrs:  []string{"10.101.0.0/16", "10.102.0.0/16", "10.103.0.0/16"},
-			PublicSubnets:  []string{"10.100.10.0/24", "10.100.11.0/24", "10.100.12.0/24"},
-			PrivateSubnets: []string{"10.100.20.0/24", "10.100.21.0/24", "10.100.22.0/24"},
-			Azs:            []string{"us-east-1a", "us-east-1b", "us-east-1c"},
-			TgwAsn:         64514,
-		}
-	default:
-		return &NetworkTestData{
-			VpcCidr:        "10.0.0.0/16",
-			SpokeVpcCidrs:  []string{"10.1.0.0/16"},
-			PublicSubnets:  []string{"10.0.10.0/24"},
-			PrivateSubnets: []string{"10.0.20.0/24"},
-			Azs:            []string{"us-east-1a"},
-			TgwAsn:         64512,
-		}
-	}
+	data := tdm.Profile.Network
+	data.SpokeVpcCidrs = cloneSlice(data.SpokeVpcCidrs)
+	data.PublicSubnets = cloneSlice(data.PublicSubnets)
+	data.PrivateSubnets = cloneSlice(data.PrivateSubnets)
+	data.Azs = cloneSlice(data.Azs)
+	return &data
 }
 
 // FirewallTestData contains firewall-related test data
 type FirewallTestData struct {
-	Version         string
-	InstanceType    string
-	MinSize         int
-	MaxSize         int
-	KeyName         string
-	SecurityRules   []SecurityRule
-	BootstrapConfig map[string]string
+	Version         string            `yaml:"version"`
+	InstanceType    string            `yaml:"instance_type"`
+	MinSize         int               `yaml:"min_size"`
+	MaxSize         int               `yaml:"max_size"`
+	KeyName         string            `yaml:"key_name"`
+	SecurityRules   []SecurityRule    `yaml:"security_rules"`
+	BootstrapConfig map[string]string `yaml:"bootstrap_config"`
 }
 
 // SecurityRule represents a firewall security rule
 type SecurityRule struct {
-	Name                 string
-	Action               string
-	SourceZones          []string
-	DestinationZones     []string
-	SourceAddresses      []string
-	DestinationAddresses []string
-	Applications         []string
-	Services             []string
+	Name                 string   `yaml:"name"`
+	Action               string   `yaml:"action"`
+	SourceZones          []string `yaml:"source_zones"`
+	DestinationZones     []string `yaml:"destination_zones"`
+	SourceAddresses      []string `yaml:"source_addresses"`
+	DestinationAddresses []string `yaml:"destination_addresses"`
+	Applications         []string `yaml:"applications"`
+	Services             []string `yaml:"services"`
 }
 
-// GetFirewallTestData returns firewall test data
+// GetFirewallTestData returns a copy of the firewall test data
 func (tdm *TestDataManager) GetFirewallTestData() *FirewallTestData {
-	return &FirewallTestData{
-		Version:      "10.2.0",
-		InstanceType: "m5.xlarge",
-		MinSize:      2,
-		MaxSize:      4,
-		KeyName:      fmt.Sprintf("vmseries-key-%s", tdm.Environment),
-		SecurityRules: []SecurityRule{
-			{
-				Name:                 "allow-web-traffic",
-				Action:               "allow",
-				SourceZones:          []string{"trust"},
-				DestinationZones:     []string{"untrust"},
-				SourceAddresses:      []string{"10.1.0.0/16"},
-				DestinationAddresses: []string{"0.0.0.0/0"},
-				Applications:         []string{"web-browsing", "ssl"},
-				Services:             []string{"service-http", "service-https"},
-			},
-			{
-				Name:                 "allow-ssh",
-				Action:               "allow",
-				SourceZones:          []string{"trust"},
-				DestinationZones:     []string{"untrust"},
-				SourceAddresses:      []string{"10.0.0.0/8"},
-				DestinationAddresses: []string{"0.0.0.0/0"},
-				Applications:         []string{"ssh"},
-				Services:             []string{"application-default"},
-			},
-		},
-		BootstrapConfig: map[string]string{
-			"type":            "dhcp-client",
-			"hostname":        fmt.Sprintf("vmseries-%s", tdm.Environment),
-			"panorama-server": "panorama.example.com",
-			"auth-key":        "your-auth-key",
-			"dgname":          fmt.Sprintf("%s-dg", tdm.Environment),
-			"tplname":         fmt.Sprintf("%s-template", tdm.Environment),
-		},
+	data := tdm.Profile.Firewall
+	data.SecurityRules = cloneSlice(data.SecurityRules)
+	for i := range data.SecurityRules {
+		rule := &data.SecurityRules[i]
+		rule.SourceZones = cloneSlice(rule.SourceZones)
+		rule.DestinationZones = cloneSlice(rule.DestinationZones)
+		rule.SourceAddresses = cloneSlice(rule.SourceAddresses)
+		rule.DestinationAddresses = cloneSlice(rule.DestinationAddresses)
+		rule.Applications = cloneSlice(rule.Applications)
+		rule.Services = cloneSlice(rule.Services)
 	}
+	data.BootstrapConfig = cloneMap(data.BootstrapConfig)
+	return &data
 }
 
 // MonitoringTestData contains monitoring-related test data
 type MonitoringTestData struct {
-	FlowLogsRetentionDays int
-	LogGroups             []string
-	Metrics               []string
-	Alarms                []AlarmConfig
+	FlowLogsRetentionDays int           `yaml:"flow_logs_retention_days"`
+	LogGroups             []string      `yaml:"log_groups"`
+	Metrics               []string      `yaml:"metrics"`
+	Alarms                []AlarmConfig `yaml:"alarms"`
 }
 
 // AlarmConfig represents a CloudWatch alarm configuration
 type AlarmConfig struct {
-	Name              string
-	MetricName        string
-	Namespace         string
-	Statistic         string
-	ComparisonOp      string
-	Threshold         float64
-	EvaluationPeriods int
+	Name              string  `yaml:"name"`
+	MetricName        string  `yaml:"metric_name"`
+	Namespace         string  `yaml:"namespace"`
+	Statistic         string  `yaml:"statistic"`
+	ComparisonOp      string  `yaml:"comparison_op"`
+	Threshold         float64 `yaml:"threshold"`
+	EvaluationPeriods int     `yaml:"evaluation_periods"`
 }
 
-// GetMonitoringTestData returns monitoring test data
+// GetMonitoringTestData returns a copy of the monitoring test data
 func (tdm *TestDataManager) GetMonitoringTestData() *MonitoringTestData {
-	return &MonitoringTestData{
-		FlowLogsRetentionDays: 30,
-		LogGroups: []string{
-			fmt.Sprintf("/aws/vpc/flow-logs/inspection-%s", tdm.Environment),
-			fmt.Sprintf("/aws/vmseries/%s", tdm.Environment),
-		},
-		Metrics: []string{
-			"ActiveFlowCount",
-			"ProcessedBytes",
-			"CPUUtilization",
-			"MemoryUtilization",
-		},
-		Alarms: []AlarmConfig{
-			{
-				Name:              fmt.Sprintf("inspection-high-cpu-%s", tdm.Environment),
-				MetricName:        "CPUUtilization",
-				Namespace:         "AWS/EC2",
-				Statistic:         "Average",
-				ComparisonOp:      "GreaterThanThreshold",
-				Threshold:         80.0,
-				EvaluationPeriods: 2,
-			},
-			{
-				Name:              fmt.Sprintf("inspection-unhealthy-targets-%s", tdm.Environment),
-				MetricName:        "UnHealthyHostCount",
-				Namespace:         "AWS/GatewayELB",
-				Statistic:         "Maximum",
-				ComparisonOp:      "GreaterThanThreshold",
-				Threshold:         0.0,
-				EvaluationPeriods: 1,
-			},
-		},
-	}
+	data := tdm.Profile.Monitoring
+	data.LogGroups = cloneSlice(data.LogGroups)
+	data.Metrics = cloneSlice(data.Metrics)
+	data.Alarms = cloneSlice(data.Alarms)
+	return &data
 }
 
 // ComplianceTestData contains compliance-related test data
 type ComplianceTestData struct {
-	DataClassification   string
-	EncryptionRequired   bool
-	BackupRequired       bool
-	Tags                 map[string]string
-	ComplianceFrameworks []string
+	DataClassification   string            `yaml:"data_classification"`
+	EncryptionRequired   bool              `yaml:"encryption_required"`
+	BackupRequired       bool              `yaml:"backup_required"`
+	Tags                 map[string]string `yaml:"tags"`
+	ComplianceFrameworks []string          `yaml:"compliance_frameworks"`
 }
 
-// GetComplianceTestData returns compliance test data
+// GetComplianceTestData returns a copy of the compliance test data
 func (tdm *TestDataManager) GetComplianceTestData() *ComplianceTestData {
-	return &ComplianceTestData{
-		DataClassification: "sensitive",
-		EncryptionRequired: true,
-		BackupRequired:     true,
-		Tags: map[string]string{
-			"Environment":        tdm.Environment,
-			"Project":            "centralized-inspection",
-			"DataClassification": "sensitive",
-			"EncryptionAtRest":   "required",
-			"Backup":             "required",
-			"Compliance":         "pci-dss,hipaa,soc2,gdpr,nist-800-53",
-		},
-		ComplianceFrameworks: []string{
-			"PCI DSS",
-			"HIPAA",
-			"SOC 2",
-			"GDPR",
-			"NIST 800-53",
-		},
-	}
+	data := tdm.Profile.Compliance
+	data.Tags = cloneMap(data.Tags)
+	data.ComplianceFrameworks = cloneSlice(data.ComplianceFrameworks)
+	return &data
 }
 
 // PerformanceTestData contains performance-related test data
 type PerformanceTestData struct {
-	LoadTestDuration   time.Duration
-	ConcurrentUsers    int
-	TargetThroughput   float64
-	LatencyThreshold   time.Duration
-	ErrorRateThreshold float64
-	TestScenarios      []TestScenario
+	LoadTestDuration   time.Duration  `yaml:"load_test_duration"`
+	ConcurrentUsers    int            `yaml:"concurrent_users"`
+	TargetThroughput   float64        `yaml:"target_throughput"`
+	LatencyThreshold   time.Duration  `yaml:"latency_threshold"`
+	LatencyPercentile  float64        `yaml:"latency_percentile"` // percentile LatencyThreshold applies to, e.g. 99; 0 checks the mean
+	ErrorRateThreshold float64        `yaml:"error_rate_threshold"`
+	TestScenarios      []TestScenario `yaml:"test_scenarios"`
+}
+
+// CheckLatency returns an error if the recorded latency at LatencyPercentile exceeds LatencyThreshold
+func (ptd *PerformanceTestData) CheckLatency(latency *reporting.LatencyHistogram) error {
+	return latency.CheckThreshold(ptd.LatencyPercentile, ptd.LatencyThreshold)
 }
 
 // TestScenario represents a performance test scenario
 type TestScenario struct {
-	Name        string
-	Description string
-	TrafficType string
-	Volume      int
-	Duration    time.Duration
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	TrafficType string        `yaml:"traffic_type"`
+	Volume      int           `yaml:"volume"`
+	Duration    time.Duration `yaml:"duration"`
 }
 
-// GetPerformanceTestData returns performance test data
+// GetPerformanceTestData returns a copy of the performance test data
 func (tdm *TestDataManager) GetPerformanceTestData() *PerformanceTestData {
-	return &PerformanceTestData{
-		LoadTestDuration:   10 * time.Minute,
-		ConcurrentUsers:    100,
-		TargetThroughput:   1000000000, // 1 Gbps
-		LatencyThreshold:   50 * time.Millisecond,
-		ErrorRateThreshold: 0.01, // 1%
-		TestScenarios: []TestScenario{
-			{
-				Name:        "HTTP Traffic",
-				Description: "Test HTTP traffic inspection performance",
-				TrafficType: "http",
-				Volume:      1000,
-				Duration:    5 * time.Minute,
-			},
-			{
-				Name:        "HTTPS Traffic",
-				Description: "Test HTTPS traffic inspection performance",
-				TrafficType: "https",
-				Volume:      800,
-				Duration:    5 * time.Minute,
-			},
-			{
-				Name:        "East-West Traffic",
-				Description: "Test inter-VPC traffic inspection performance",
-				TrafficType: "internal",
-				Volume:      500,
-				Duration:    3 * time.Minute,
-			},
-		},
-	}
+	data := tdm.Profile.Performance
+	data.TestScenarios = cloneSlice(data.TestScenarios)
+	return &data
 }
 
 // ChaosTestData contains chaos engineering test data
 type ChaosTestData struct {
-	FailureScenarios       []FailureScenario
-	RecoveryTimeObjectives map[string]time.Duration
-	BlastRadiusLimits      map[string]int
+	FailureScenarios       []FailureScenario        `yaml:"failure_scenarios"`
+	RecoveryTimeObjectives map[string]time.Duration `yaml:"recovery_time_objectives"`
+	BlastRadiusLimits      map[string]int           `yaml:"blast_radius_limits"`
 }
 
 // FailureScenario represents a chaos engineering failure scenario
 type FailureScenario struct {
-	Name        string
-	Description string
-	TargetType  string
-	FailureType string
-	Duration    time.Duration
-	Impact      string
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	TargetType  string        `yaml:"target_type"`
+	FailureType string        `yaml:"failure_type"`
+	Duration    time.Duration `yaml:"duration"`
+	Impact      string        `yaml:"impact"`
 }
 
-// GetChaosTestData returns chaos engineering test data
+// GetChaosTestData returns a copy of the chaos engineering test data
 func (tdm *TestDataManager) GetChaosTestData() *ChaosTestData {
-	return &ChaosTestData{
-		FailureScenarios: []FailureScenario{
-			{
-				Name:        "AZ Failure",
-				Description: "Simulate complete Availability Zone failure",
-				TargetType:  "infrastructure",
-				FailureType: "az-outage",
-				Duration:    10 * time.Minute,
-				Impact:      "high",
-			},
-			{
-				Name:        "Firewall Instance Failure",
-				Description: "Terminate firewall instances to test auto-scaling",
-				TargetType:  "application",
-				FailureType: "instance-termination",
-				Duration:    5 * time.Minute,
-				Impact:      "medium",
-			},
-			{
-				Name:        "Network Connectivity Loss",
-				Description: "Simulate network connectivity issues",
-				TargetType:  "network",
-				FailureType: "connectivity-loss",
-				Duration:    3 * time.Minute,
-				Impact:      "high",
-			},
-		},
-		RecoveryTimeObjectives: map[string]time.Duration{
-			"critical": 15 * time.Minute,
-			"high":     30 * time.Minute,
-			"medium":   60 * time.Minute,
-		},
-		BlastRadiusLimits: map[string]int{
-			"critical": 10,
-			"high":     25,
-			"medium":   50,
-		},
-	}
+	data := tdm.Profile.Chaos
+	data.FailureScenarios = cloneSlice(data.FailureScenarios)
+	data.RecoveryTimeObjectives = cloneMap(data.RecoveryTimeObjectives)
+	data.BlastRadiusLimits = cloneMap(data.BlastRadiusLimits)
+	return &data
 }
 
 // CostTestData contains cost optimization test data
 type CostTestData struct {
-	BudgetAmount                float64
-	AlertThreshold              float64
-	ReservedInstanceUtilization float64
-	SpotInstanceSavings         float64
-	ResourceTags                map[string]string
+	BudgetAmount                float64           `yaml:"budget_amount"`
+	AlertThreshold              float64           `yaml:"alert_threshold"`
+	ReservedInstanceUtilization float64           `yaml:"reserved_instance_utilization"`
+	SpotInstanceSavings         float64           `yaml:"spot_instance_savings"`
+	ResourceTags                map[string]string `yaml:"resource_tags"`
 }
 
-// GetCostTestData returns cost optimization test data
+// GetCostTestData returns a copy of the cost optimization test data
 func (tdm *TestDataManager) GetCostTestData() *CostTestData {
-	return &CostTestData{
-		BudgetAmount:                1000.0,
-		AlertThreshold:              80.0,
-		ReservedInstanceUtilization: 85.0,
-		SpotInstanceSavings:         70.0,
-		ResourceTags: map[string]string{
-			"Environment":      tdm.Environment,
-			"Project":          "centralized-inspection",
-			"CostCenter":       "security-operations",
-			"Owner":            "cost-optimization-team",
-			"AutoShutdown":     "enabled",
-			"ReservedInstance": "eligible",
-			"SpotInstance":     "eligible",
-		},
+	data := tdm.Profile.Cost
+	data.ResourceTags = cloneMap(data.ResourceTags)
+	return &data
+}
+
+// cloneSlice copies a slice of profile data so callers can't change the profile
+// through it. nil stays nil.
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
 	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+// cloneMap copies a map of profile data so callers can't change the profile
+// through it. nil stays nil.
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[K]V, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
 }
 
 // GenerateRandomString generates a random string of specified length
@@ -386,9 +363,18 @@ func (tdm *TestDataManager) GenerateRandomIP() string {
 		tdm.Random.Intn(256))
 }
 
-// GenerateRandomCIDR generates a random CIDR block
+// GenerateRandomCIDR generates a random, correctly aligned CIDR block inside
+// 10.0.0.0/8. Prefixes are clamped to /8 through /32. Blocks may overlap those of
+// other tests; use CIDRAllocator for infrastructure that is actually deployed.
 func (tdm *TestDataManager) GenerateRandomCIDR(prefix int) string {
-	return fmt.Sprintf("%s/%d", tdm.GenerateRandomIP(), prefix)
+	if prefix < 8 {
+		prefix = 8
+	}
+	if prefix > 32 {
+		prefix = 32
+	}
+	addr := netip.AddrFrom4([4]byte{10, byte(tdm.Random.Intn(256)), byte(tdm.Random.Intn(256)), byte(tdm.Random.Intn(256))})
+	return netip.PrefixFrom(addr, prefix).Masked().String()
 }
 
 // GetTestDataSummary returns a summary of all test data
@@ -396,6 +382,7 @@ func (tdm *TestDataManager) GetTestDataSummary() map[string]interface{} {
 	return map[string]interface{}{
 		"environment": tdm.Environment,
 		"region":      tdm.Region,
+		"seed":        tdm.Seed,
 		"network":     tdm.GetNetworkTestData(),
 		"firewall":    tdm.GetFirewallTestData(),
 		"monitoring":  tdm.GetMonitoringTestData(),
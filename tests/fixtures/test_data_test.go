@@ -0,0 +1,106 @@
+package fixtures_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestSeedFromEnv(t *testing.T) {
+	t.Setenv(fixtures.SeedEnvVar, "1234")
+	assert.Equal(t, int64(1234), fixtures.SeedFromEnv())
+
+	t.Setenv(fixtures.SeedEnvVar, "nightly-2024-05-01")
+	hashed := fixtures.SeedFromEnv()
+	assert.NotZero(t, hashed)
+	assert.Equal(t, hashed, fixtures.SeedFromEnv(), "non-numeric seeds should hash consistently")
+
+	t.Setenv(fixtures.SeedEnvVar, "")
+	assert.Equal(t, fixtures.SeedFromEnv(), fixtures.SeedFromEnv(), "the generated seed should be shared by the test binary")
+}
+
+func TestSameSeedGeneratesSameFixtures(t *testing.T) {
+	first, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 42)
+	require.NoError(t, err)
+	second, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 42)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.GenerateRandomString(16), second.GenerateRandomString(16))
+	assert.Equal(t, first.GenerateRandomIP(), second.GenerateRandomIP())
+	assert.Equal(t, first.GenerateRandomCIDR(24), second.GenerateRandomCIDR(24))
+	assert.Equal(t, int64(42), first.GetTestDataSummary()["seed"])
+}
+
+func TestForTestDerivesPerTestValues(t *testing.T) {
+	t.Setenv(fixtures.SeedEnvVar, "99")
+
+	var names []string
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			tdm := fixtures.NewTestDataManagerForTest(t, "dev", "us-east-1")
+			replayed := fixtures.NewTestDataManagerForTest(t, "dev", "us-east-1")
+			assert.Equal(t, int64(99), tdm.Seed)
+
+			value := tdm.GenerateRandomString(12)
+			assert.Equal(t, value, replayed.GenerateRandomString(12), "the same test should get the same values")
+			names = append(names, value)
+		})
+	}
+
+	require.Len(t, names, 2)
+	assert.NotEqual(t, names[0], names[1], "tests should get distinct values")
+}
+
+func TestReplayTestDataManager(t *testing.T) {
+	_, err := fixtures.ReplayTestDataManager(reporting.TestSuiteResult{SuiteName: "network"})
+	assert.EqualError(t, err, "suite network has no recorded seed")
+
+	// TEST_SEED=0 is a valid seed and replays like any other
+	for _, seed := range []int64{7, 0} {
+		original, err := fixtures.NewTestDataManagerWithSeed("staging", "eu-west-1", seed)
+		require.NoError(t, err)
+		replayed, err := fixtures.ReplayTestDataManager(reporting.TestSuiteResult{
+			SuiteName:   "network",
+			Environment: "staging",
+			Region:      "eu-west-1",
+			Seed:        &seed,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, original.GetNetworkTestData(), replayed.GetNetworkTestData())
+		assert.Equal(t, original.GenerateRandomCIDR(16), replayed.GenerateRandomCIDR(16))
+	}
+}
+
+func TestTestDataIsCopiedFromTheProfile(t *testing.T) {
+	tdm, err := fixtures.NewTestDataManagerWithSeed("dev", "us-east-1", 1)
+	require.NoError(t, err)
+
+	network := tdm.GetNetworkTestData()
+	require.NotEmpty(t, network.Azs)
+	want := network.Azs[0]
+	network.Azs[0] = "changed"
+	network.PublicSubnets = append(network.PublicSubnets[:0], "changed")
+	assert.Equal(t, want, tdm.GetNetworkTestData().Azs[0])
+	assert.NotContains(t, tdm.GetNetworkTestData().PublicSubnets, "changed")
+
+	firewall := tdm.GetFirewallTestData()
+	require.NotEmpty(t, firewall.SecurityRules)
+	require.NotEmpty(t, firewall.SecurityRules[0].Applications)
+	firewall.SecurityRules[0].Applications[0] = "changed"
+	firewall.BootstrapConfig["panorama-server"] = "changed"
+	assert.NotEqual(t, "changed", tdm.GetFirewallTestData().SecurityRules[0].Applications[0])
+	assert.NotEqual(t, "changed", tdm.GetFirewallTestData().BootstrapConfig["panorama-server"])
+
+	compliance := tdm.GetComplianceTestData()
+	compliance.Tags["Environment"] = "changed"
+	assert.NotEqual(t, "changed", tdm.GetComplianceTestData().Tags["Environment"])
+	assert.NotEqual(t, "changed", tdm.StandardTags()["Environment"], "the standard tags come from the profile")
+
+	cost := tdm.GetCostTestData()
+	cost.ResourceTags["CostCenter"] = "changed"
+	assert.NotEqual(t, "changed", tdm.GetCostTestData().ResourceTags["CostCenter"])
+}
@@ -0,0 +1,79 @@
+package inspection_test
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/netsec"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// TestInspectionPlan checks the planned GWLB and endpoints against the recorded plan
+// in testdata, without AWS. Set UPDATE_PLAN_FIXTURES=1 to record the plan again.
+func TestInspectionPlan(t *testing.T) {
+	t.Parallel()
+
+	// A fixed seed keeps the placeholder network IDs in the recorded plan stable
+	tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-east-1", 1)
+	require.NoError(t, err)
+	network := tdm.PlaceholderNetworkOutputs()
+	terraformOptions := fixtures.InspectionOptions(tdm, network)
+	inspectionPlan := plan.LoadOrRecord(t, terraformOptions, "testdata/inspection_plan.json")
+
+	// Internal Gateway Load Balancer
+	plan.AssertValue(t, inspectionPlan, "aws_lb.gwlb", "load_balancer_type", "gateway")
+	plan.AssertValue(t, inspectionPlan, "aws_lb.gwlb", "internal", true)
+
+	// GENEVE target group with TCP health checks on port 22
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "protocol", "GENEVE")
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "port", 6081)
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "target_type", "ip")
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "vpc_id", network.InspectionVpcID)
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "health_check.0.protocol", "TCP")
+	plan.AssertValue(t, inspectionPlan, "aws_lb_target_group.gwlb", "health_check.0.port", "22")
+
+	// One GWLB endpoint per spoke VPC, in that spoke's private subnets
+	plan.AssertResourceCount(t, inspectionPlan, "aws_vpc_endpoint", len(network.SpokeVpcIDs))
+	plan.AssertAllValues(t, inspectionPlan, "aws_vpc_endpoint", "vpc_endpoint_type", "GatewayLoadBalancer")
+	plan.AssertValue(t, inspectionPlan, "aws_vpc_endpoint.gwlb[1]", "vpc_id", network.SpokeVpcIDs[1])
+	plan.AssertValue(t, inspectionPlan, "aws_vpc_endpoint_service.gwlb", "acceptance_required", false)
+
+	// Return traffic from the inspection VPC to the spokes goes through the Transit Gateway
+	plan.AssertResourceCount(t, inspectionPlan, "aws_route", 8)
+	for i := 0; i < len(network.PrivateRouteTableIDs)*len(network.SpokeVpcCidrs); i++ {
+		address := fmt.Sprintf("aws_route.inspection_to_spoke[%d]", i)
+		plan.AssertValue(t, inspectionPlan, address, "destination_cidr_block", network.SpokeVpcCidrs[i%len(network.SpokeVpcCidrs)])
+		plan.AssertValue(t, inspectionPlan, address, "transit_gateway_id", network.TransitGatewayID)
+	}
+	plan.AssertResourceExists(t, inspectionPlan, "aws_shield_protection.gwlb")
+
+	// The GWLB security group takes GENEVE from the spokes and management from
+	// the inspection VPC only
+	rules, err := netsec.FromPlan(inspectionPlan)
+	require.NoError(t, err)
+	assert.Empty(t, rules.Permissive())
+	group, ok := rules.SecurityGroup("aws_security_group.gwlb")
+	require.True(t, ok)
+	gwlb := netsec.Endpoint{SecurityGroups: []*netsec.SecurityGroup{group}}
+	for _, tc := range []struct {
+		protocol string
+		source   string
+		port     int
+		allowed  bool
+	}{
+		{"udp", "10.11.20.10", 6081, true},
+		{"udp", "10.12.21.10", 6081, true},
+		{"tcp", "10.10.1.10", 22, true},
+		{"tcp", "10.10.1.10", 443, true},
+		{"tcp", "203.0.113.10", 22, false},
+		{"tcp", "10.11.20.10", 443, false},
+	} {
+		verdict := netsec.Evaluate(netsec.NewFlow(tc.protocol, netip.MustParseAddr(tc.source), netip.MustParseAddr("10.10.10.10"), tc.port), netsec.Endpoint{}, gwlb)
+		assert.Equal(t, tc.allowed, verdict.Allowed, verdict.String())
+	}
+}
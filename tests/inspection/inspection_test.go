@@ -5,33 +5,17 @@ import (
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
 )
 
 // TestInspectionProvisioning tests GWLB and endpoint provisioning
 func TestInspectionProvisioning(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/inspection",
-		Vars: map[string]interface{}{
-			"inspection_vpc_id": "vpc-12345", // Would be from network module
-			"public_subnet_ids": []string{"subnet-pub-1", "subnet-pub-2"},
-			"spoke_vpc_ids":     []string{"vpc-spoke-1", "vpc-spoke-2"},
-			"spoke_private_subnet_ids": [][]string{
-				{"subnet-spoke-1-priv-1", "subnet-spoke-1-priv-2"},
-				{"subnet-spoke-2-priv-1", "subnet-spoke-2-priv-2"},
-			},
-			"spoke_vpc_cidrs":                    []string{"10.1.0.0/16", "10.2.0.0/16"},
-			"transit_gateway_id":                 "tgw-12345",
-			"internet_gateway_id":                "igw-12345",
-			"inspection_private_route_table_ids": []string{"rt-priv-1", "rt-priv-2"},
-			"spoke_route_table_ids":              []string{"rt-spoke-1", "rt-spoke-2"},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
+	// Network IDs would be from the network module, see fixtures.NetworkOutputsFromTerraform
+	tdm := fixtures.NewTestDataManagerForTest(t, "staging", "us-east-1")
+	terraformOptions := fixtures.InspectionOptions(tdm, tdm.PlaceholderNetworkOutputs())
 
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
@@ -150,47 +134,6 @@ func TestInspectionTrafficSteering(t *testing.T) {
 	assert.Len(t, endpointIds, 2, "Should have endpoints for 2 spoke VPCs")
 }
 
-// TestInspectionSymmetricRouting tests symmetric routing through appliance mode
-func TestInspectionSymmetricRouting(t *testing.T) {
-	t.Parallel()
-
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/inspection",
-		Vars: map[string]interface{}{
-			"inspection_vpc_id": "vpc-12345",
-			"public_subnet_ids": []string{"subnet-pub-1"},
-			"spoke_vpc_ids":     []string{"vpc-spoke-1"},
-			"spoke_private_subnet_ids": [][]string{
-				{"subnet-spoke-1-priv-1"},
-			},
-			"spoke_vpc_cidrs":                    []string{"10.1.0.0/16"},
-			"transit_gateway_id":                 "tgw-12345",
-			"internet_gateway_id":                "igw-12345",
-			"inspection_private_route_table_ids": []string{"rt-priv-1"},
-			"spoke_route_table_ids":              []string{"rt-spoke-1"},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
-
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Validate that routes ensure symmetric flow
-	spokeRtId := terraform.Output(t, terraformOptions, "spoke_route_table_id")
-	inspectionRtId := terraform.Output(t, terraformOptions, "inspection_private_route_table_id")
-	endpointId := terraform.Output(t, terraformOptions, "endpoint_id")
-
-	assert.NotEmpty(t, spokeRtId, "Spoke route table should exist")
-	assert.NotEmpty(t, inspectionRtId, "Inspection route table should exist")
-	assert.NotEmpty(t, endpointId, "Endpoint should exist")
-
-	// This ensures symmetric routing: outbound via GWLB, return via TGW
-	t.Log("Symmetric routing validation completed")
-}
-
 // TestInspectionHealthChecks tests GWLB health check configuration
 func TestInspectionHealthChecks(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,256 @@
+// Package inventory loads the Terraform modules under modules/ from source into an
+// inventory of resources, variables, outputs, locals and module calls, so tests can
+// check how the modules are written without running Terraform.
+package inventory
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Inventory is the Terraform source of a tree of modules
+type Inventory struct {
+	// Modules are keyed by their directory relative to the root, such as network,
+	// or the base name of the root when it holds .tf files itself
+	Modules map[string]*Module
+	files   map[string]*hcl.File
+}
+
+// Module is the Terraform source of one directory
+type Module struct {
+	Name        string
+	Dir         string
+	Resources   []*Resource // managed resources, sorted by address
+	DataSources []*Resource // data resources, sorted by address
+	Variables   map[string]*Variable
+	Outputs     map[string]*Output
+	Locals      map[string]*Local
+	ModuleCalls map[string]*ModuleCall
+}
+
+// Resource is a resource or data block
+type Resource struct {
+	Module string
+	Mode   string // managed or data
+	Type   string
+	Name   string
+	Body   *hclsyntax.Body
+	Range  hcl.Range
+}
+
+// Variable is a variable block
+type Variable struct {
+	Name        string
+	Description string
+	Type        hcl.Expression // nil without a type constraint
+	Default     hcl.Expression // nil for required variables
+	Sensitive   bool
+	Range       hcl.Range
+}
+
+// Output is an output block
+type Output struct {
+	Name      string
+	Value     hcl.Expression
+	Sensitive bool
+	Range     hcl.Range
+}
+
+// Local is a named value of a locals block
+type Local struct {
+	Name  string
+	Expr  hcl.Expression
+	Range hcl.Range
+}
+
+// ModuleCall is a module block
+type ModuleCall struct {
+	Name   string
+	Source string
+	Body   *hclsyntax.Body
+	Range  hcl.Range
+}
+
+// Load loads root and every directory below it that contains .tf files, skipping
+// hidden directories such as .terraform
+func Load(root string) (*Inventory, error) {
+	inventory := &Inventory{Modules: make(map[string]*Module), files: make(map[string]*hcl.File)}
+	parser := hclparse.NewParser()
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		module, err := loadModule(parser, path)
+		if err != nil || module == nil {
+			return err
+		}
+		module.Name = filepath.Base(path)
+		if path != root {
+			if module.Name, err = filepath.Rel(root, path); err != nil {
+				return err
+			}
+			module.Name = filepath.ToSlash(module.Name)
+		}
+		for _, resource := range append(module.Resources, module.DataSources...) {
+			resource.Module = module.Name
+		}
+		inventory.Modules[module.Name] = module
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(inventory.Modules) == 0 {
+		return nil, fmt.Errorf("no Terraform configuration under %s", root)
+	}
+	for filename, file := range parser.Files() {
+		inventory.files[filename] = file
+	}
+	return inventory, nil
+}
+
+// loadModule parses the .tf files of dir, returning nil when there are none
+func loadModule(parser *hclparse.Parser, dir string) (*Module, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	module := &Module{
+		Dir:         dir,
+		Variables:   make(map[string]*Variable),
+		Outputs:     make(map[string]*Output),
+		Locals:      make(map[string]*Local),
+		ModuleCalls: make(map[string]*ModuleCall),
+	}
+	for _, filename := range files {
+		file, diags := parser.ParseHCLFile(filename)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return nil, fmt.Errorf("%s: not native HCL syntax", filename)
+		}
+		if err := module.add(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, resources := range [][]*Resource{module.Resources, module.DataSources} {
+		sort.Slice(resources, func(i, j int) bool {
+			return resources[i].Address() < resources[j].Address()
+		})
+	}
+	return module, nil
+}
+
+// add adds the top-level blocks of a file to the module
+func (m *Module) add(body *hclsyntax.Body) error {
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "resource", "data":
+			if len(block.Labels) != 2 {
+				return fmt.Errorf("%s: %s block needs a type and a name", block.DefRange(), block.Type)
+			}
+			resource := &Resource{Mode: "managed", Type: block.Labels[0], Name: block.Labels[1], Body: block.Body, Range: block.DefRange()}
+			if block.Type == "data" {
+				resource.Mode = "data"
+				m.DataSources = append(m.DataSources, resource)
+			} else {
+				m.Resources = append(m.Resources, resource)
+			}
+		case "variable":
+			variable := &Variable{Name: block.Labels[0], Range: block.DefRange()}
+			if attribute, ok := block.Body.Attributes["type"]; ok {
+				variable.Type = attribute.Expr
+			}
+			if attribute, ok := block.Body.Attributes["default"]; ok {
+				variable.Default = attribute.Expr
+			}
+			variable.Description, _ = literalString(block.Body.Attributes["description"])
+			variable.Sensitive = literalTrue(block.Body.Attributes["sensitive"])
+			m.Variables[variable.Name] = variable
+		case "output":
+			output := &Output{Name: block.Labels[0], Range: block.DefRange()}
+			if attribute, ok := block.Body.Attributes["value"]; ok {
+				output.Value = attribute.Expr
+			}
+			output.Sensitive = literalTrue(block.Body.Attributes["sensitive"])
+			m.Outputs[output.Name] = output
+		case "locals":
+			for name, attribute := range block.Body.Attributes {
+				m.Locals[name] = &Local{Name: name, Expr: attribute.Expr, Range: attribute.SrcRange}
+			}
+		case "module":
+			call := &ModuleCall{Name: block.Labels[0], Body: block.Body, Range: block.DefRange()}
+			call.Source, _ = literalString(block.Body.Attributes["source"])
+			m.ModuleCalls[call.Name] = call
+		}
+	}
+	return nil
+}
+
+// LoadModule loads the .tf files of one directory
+func LoadModule(dir string) (*Module, error) {
+	module, err := loadModule(hclparse.NewParser(), dir)
+	if err != nil {
+		return nil, err
+	}
+	if module == nil {
+		return nil, fmt.Errorf("no Terraform configuration in %s", dir)
+	}
+	module.Name = filepath.Base(dir)
+	for _, resource := range append(module.Resources, module.DataSources...) {
+		resource.Module = module.Name
+	}
+	return module, nil
+}
+
+// Address returns the address of the resource within its module, such as
+// aws_s3_bucket.bootstrap or data.aws_ami.vmseries
+func (r *Resource) Address() string {
+	if r.Mode == "data" {
+		return "data." + r.Type + "." + r.Name
+	}
+	return r.Type + "." + r.Name
+}
+
+// String returns the module and address, such as network/aws_vpc.inspection
+func (r *Resource) String() string {
+	return r.Module + "/" + r.Address()
+}
+
+// Source returns the source text of a range, or "" when the file is not part of
+// the inventory
+func (inv *Inventory) Source(rng hcl.Range) string {
+	file, ok := inv.files[rng.Filename]
+	if !ok || rng.End.Byte > len(file.Bytes) {
+		return ""
+	}
+	return string(rng.SliceBytes(file.Bytes))
+}
+
+// ModuleNames returns the module names sorted
+func (inv *Inventory) ModuleNames() []string {
+	names := make([]string, 0, len(inv.Modules))
+	for name := range inv.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,221 @@
+package inventory_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/inventory"
+)
+
+const storageModule = `
+variable "tags" {
+  description = "Tags for all resources"
+  type        = map(string)
+  default     = {}
+}
+
+variable "admin_password" {
+  type      = string
+  sensitive = true
+}
+
+locals {
+  common_tags = merge(var.tags, { Module = "storage" })
+}
+
+data "aws_caller_identity" "current" {}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs-${data.aws_caller_identity.current.account_id}"
+  tags   = merge(local.common_tags, { Name = "logs", Environment = var.environment })
+}
+
+resource "aws_s3_bucket_public_access_block" "logs" {
+  bucket                  = aws_s3_bucket.logs.id
+  block_public_acls       = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket" "scratch" {
+  bucket = "scratch"
+  tags   = { Name = "scratch" }
+}
+
+resource "aws_security_group" "web" {
+  name = "web"
+
+  dynamic "ingress" {
+    for_each = [443, 8443]
+    content {
+      from_port = ingress.value
+      to_port   = ingress.value
+    }
+  }
+
+  egress {
+    from_port   = 0
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_launch_template" "web" {
+  metadata_options {
+    http_tokens = "required"
+  }
+  tags = var.tags
+}
+
+output "bucket" {
+  value     = aws_s3_bucket.logs.id
+  sensitive = true
+}
+`
+
+const rootModule = `
+module "storage" {
+  source = "./modules/storage"
+  tags   = { Project = "inspection" }
+}
+`
+
+// writeTree writes a root configuration and a storage module to a temporary directory
+func writeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	storage := filepath.Join(root, "modules", "storage")
+	require.NoError(t, os.MkdirAll(storage, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".terraform", "modules", "cached"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), []byte(rootModule), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(storage, "main.tf"), []byte(storageModule), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".terraform", "modules", "cached", "main.tf"), []byte(rootModule), 0644))
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	root := writeTree(t)
+	inv, err := inventory.Load(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Base(root), "modules/storage"}, inv.ModuleNames(), "hidden directories are skipped")
+
+	storage := inv.Modules["modules/storage"]
+	var addresses []string
+	for _, resource := range storage.Resources {
+		addresses = append(addresses, resource.Address())
+	}
+	assert.Equal(t, []string{
+		"aws_launch_template.web",
+		"aws_s3_bucket.logs",
+		"aws_s3_bucket.scratch",
+		"aws_s3_bucket_public_access_block.logs",
+		"aws_security_group.web",
+	}, addresses)
+	require.Len(t, storage.DataSources, 1)
+	assert.Equal(t, "modules/storage/data.aws_caller_identity.current", storage.DataSources[0].String())
+
+	assert.Equal(t, "Tags for all resources", storage.Variables["tags"].Description)
+	assert.NotNil(t, storage.Variables["tags"].Default)
+	assert.True(t, storage.Variables["admin_password"].Sensitive)
+	assert.Nil(t, storage.Variables["admin_password"].Default, "required variables have no default")
+	assert.True(t, storage.Outputs["bucket"].Sensitive)
+	assert.Equal(t, "merge(var.tags, { Module = \"storage\" })", inv.Source(storage.Locals["common_tags"].Expr.Range()))
+	assert.Equal(t, "./modules/storage", inv.Modules[filepath.Base(root)].ModuleCalls["storage"].Source)
+
+	_, err = inventory.Load(t.TempDir())
+	assert.ErrorContains(t, err, "no Terraform configuration under")
+
+	module, err := inventory.LoadModule(filepath.Join(root, "modules", "storage"))
+	require.NoError(t, err)
+	assert.Equal(t, "storage", module.Name)
+	assert.Equal(t, "storage", module.Resources[0].Module)
+	_, err = inventory.LoadModule(filepath.Join(root, "modules"))
+	assert.ErrorContains(t, err, "no Terraform configuration in")
+}
+
+func TestQueries(t *testing.T) {
+	inv, err := inventory.Load(writeTree(t))
+	require.NoError(t, err)
+
+	buckets := inv.ResourcesOfType("aws_s3_bucket")
+	require.Len(t, buckets, 2)
+	logs, scratch := buckets[0], buckets[1]
+	assert.Equal(t, "aws_s3_bucket.logs", logs.Address())
+
+	blocks := inv.Referencing(logs, "aws_s3_bucket_public_access_block")
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "aws_s3_bucket_public_access_block.logs", blocks[0].Address())
+	assert.Empty(t, inv.Referencing(scratch, "aws_s3_bucket_public_access_block"))
+	assert.Equal(t, []string{"aws_s3_bucket.logs"}, blocks[0].References())
+	assert.Equal(t, []string{"data.aws_caller_identity.current", "local.common_tags", "var.environment"}, logs.References())
+
+	value, ok := blocks[0].Literal("block_public_acls")
+	require.True(t, ok)
+	assert.True(t, value.True())
+	_, ok = blocks[0].Literal("ignore_public_acls")
+	assert.False(t, ok, "missing attributes have no value")
+	_, ok = blocks[0].Literal("bucket")
+	assert.False(t, ok, "references have no literal value")
+
+	template, ok := inv.Resource("modules/storage", "aws_launch_template.web")
+	require.True(t, ok)
+	value, ok = template.Literal("metadata_options.http_tokens")
+	require.True(t, ok)
+	assert.Equal(t, cty.StringVal("required"), value)
+
+	group, ok := inv.Resource("modules/storage", "aws_security_group.web")
+	require.True(t, ok)
+	expr, ok := group.AttributeExpr("ingress.from_port")
+	require.True(t, ok, "dynamic block content is searched")
+	assert.Equal(t, "ingress.value", inv.Source(expr.Range()))
+	value, ok = group.Literal("egress.cidr_blocks")
+	require.True(t, ok)
+	assert.Equal(t, cty.TupleVal([]cty.Value{cty.StringVal("0.0.0.0/0")}), value)
+	assert.Empty(t, group.References(), "dynamic iterators are not references")
+	_, ok = group.AttributeExpr("timeouts.create")
+	assert.False(t, ok)
+
+	_, ok = inv.Resource("modules/storage", "aws_s3_bucket.missing")
+	assert.False(t, ok)
+	_, ok = inv.Resource("missing", "aws_s3_bucket.logs")
+	assert.False(t, ok)
+	_, ok = inv.Resource("modules/storage", "data.aws_caller_identity.current")
+	assert.True(t, ok)
+}
+
+func TestTagsOf(t *testing.T) {
+	inv, err := inventory.Load(writeTree(t))
+	require.NoError(t, err)
+
+	logs, _ := inv.Resource("modules/storage", "aws_s3_bucket.logs")
+	tags := inv.TagsOf(logs)
+	assert.Equal(t, map[string]string{"Module": "storage", "Name": "logs", "Environment": "var.environment"}, tags.Values)
+	assert.Equal(t, []string{"var.tags"}, tags.Merged, "locals are followed")
+	assert.True(t, tags.Inherits("var.tags"))
+
+	scratch, _ := inv.Resource("modules/storage", "aws_s3_bucket.scratch")
+	tags = inv.TagsOf(scratch)
+	assert.Equal(t, map[string]string{"Name": "scratch"}, tags.Values)
+	assert.False(t, tags.Inherits("var.tags"))
+
+	template, _ := inv.Resource("modules/storage", "aws_launch_template.web")
+	assert.True(t, inv.TagsOf(template).Inherits("var.tags"))
+
+	group, _ := inv.Resource("modules/storage", "aws_security_group.web")
+	tags = inv.TagsOf(group)
+	assert.Empty(t, tags.Values)
+	assert.Empty(t, tags.Merged)
+}
+
+func TestLoadModules(t *testing.T) {
+	inv, err := inventory.Load(fixtures.ModulesDir)
+	require.NoError(t, err)
+	for _, name := range []string{"network", "inspection", "firewall-vmseries", "iam"} {
+		assert.Contains(t, inv.Modules, name)
+	}
+	assert.NotEmpty(t, inv.ResourcesOfType("aws_vpc"))
+}
@@ -0,0 +1,273 @@
+package inventory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Tags are the tags a resource sets in its tags attribute
+type Tags struct {
+	// Values are the tags with literal keys. Values that are not literal strings
+	// hold their source text, such as var.environment.
+	Values map[string]string
+	// Merged are the source text of maps merged into the tags, such as var.tags
+	Merged []string
+}
+
+// Inherits reports whether the tags merge in the map expression, such as var.tags
+func (t Tags) Inherits(expr string) bool {
+	for _, merged := range t.Merged {
+		if merged == expr {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourcesOfType returns the managed resources of a type in every module, sorted
+// by module and address
+func (inv *Inventory) ResourcesOfType(resourceType string) []*Resource {
+	var resources []*Resource
+	for _, name := range inv.ModuleNames() {
+		resources = append(resources, inv.Modules[name].ResourcesOfType(resourceType)...)
+	}
+	return resources
+}
+
+// ResourcesOfType returns the managed resources of a type in the module
+func (m *Module) ResourcesOfType(resourceType string) []*Resource {
+	var resources []*Resource
+	for _, resource := range m.Resources {
+		if resource.Type == resourceType {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// Resource returns the resource of a module by address, such as
+// aws_s3_bucket.bootstrap
+func (inv *Inventory) Resource(module, address string) (*Resource, bool) {
+	m, ok := inv.Modules[module]
+	if !ok {
+		return nil, false
+	}
+	for _, resource := range append(m.Resources, m.DataSources...) {
+		if resource.Address() == address {
+			return resource, true
+		}
+	}
+	return nil, false
+}
+
+// AttributeExpr returns the expression of an attribute. A dotted path selects an
+// attribute of a nested block, such as metadata_options.http_tokens; the first block
+// of each type is used, including the content of dynamic blocks.
+func (r *Resource) AttributeExpr(path string) (hcl.Expression, bool) {
+	names := strings.Split(path, ".")
+	body := r.Body
+	for _, blockType := range names[:len(names)-1] {
+		blocks := Blocks(body, blockType)
+		if len(blocks) == 0 {
+			return nil, false
+		}
+		body = blocks[0]
+	}
+	attribute, ok := body.Attributes[names[len(names)-1]]
+	if !ok {
+		return nil, false
+	}
+	return attribute.Expr, true
+}
+
+// Literal returns the value of an attribute that is a constant, such as true or
+// "AES256". It returns false for missing attributes and for expressions that
+// reference variables, resources or functions.
+func (r *Resource) Literal(path string) (cty.Value, bool) {
+	expr, ok := r.AttributeExpr(path)
+	if !ok {
+		return cty.NilVal, false
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
+	return value, true
+}
+
+// Blocks returns the bodies of the nested blocks of a type, followed by the content
+// bodies of dynamic blocks of that type
+func Blocks(body *hclsyntax.Body, blockType string) []*hclsyntax.Body {
+	var bodies []*hclsyntax.Body
+	var dynamic []*hclsyntax.Body
+	for _, block := range body.Blocks {
+		switch {
+		case block.Type == blockType:
+			bodies = append(bodies, block.Body)
+		case block.Type == "dynamic" && len(block.Labels) == 1 && block.Labels[0] == blockType:
+			for _, content := range block.Body.Blocks {
+				if content.Type == "content" {
+					dynamic = append(dynamic, content.Body)
+				}
+			}
+		}
+	}
+	return append(bodies, dynamic...)
+}
+
+// References returns the objects the resource refers to, sorted, such as
+// aws_s3_bucket.bootstrap, data.aws_ami.vmseries, var.tags and local.name
+func (r *Resource) References() []string {
+	seen := make(map[string]bool)
+	var walk func(body *hclsyntax.Body)
+	walk = func(body *hclsyntax.Body) {
+		for _, attribute := range body.Attributes {
+			for _, traversal := range attribute.Expr.Variables() {
+				if reference := referenceName(traversal); reference != "" {
+					seen[reference] = true
+				}
+			}
+		}
+		for _, block := range body.Blocks {
+			walk(block.Body)
+		}
+	}
+	walk(r.Body)
+
+	references := make([]string, 0, len(seen))
+	for reference := range seen {
+		references = append(references, reference)
+	}
+	sort.Strings(references)
+	return references
+}
+
+// referenceName returns the object a traversal starts from, without attributes and
+// indexes
+func referenceName(traversal hcl.Traversal) string {
+	var names []string
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, step.Name)
+		case hcl.TraverseAttr:
+			names = append(names, step.Name)
+		default:
+			return prefix(names)
+		}
+	}
+	return prefix(names)
+}
+
+// prefix trims names to the object they name: two names for resources, variables,
+// locals and modules and three for data sources. Dynamic block iterators, count,
+// each and the like name no object and are dropped.
+func prefix(names []string) string {
+	switch {
+	case len(names) == 0:
+		return ""
+	case names[0] == "data" && len(names) >= 3:
+		return strings.Join(names[:3], ".")
+	case len(names) < 2:
+		return ""
+	case names[0] == "var" || names[0] == "local" || names[0] == "module" || strings.Contains(names[0], "_"):
+		return strings.Join(names[:2], ".")
+	}
+	return ""
+}
+
+// Referencing returns the resources of a type in the same module as target that
+// refer to it, such as the aws_s3_bucket_public_access_block of a bucket
+func (inv *Inventory) Referencing(target *Resource, resourceType string) []*Resource {
+	module, ok := inv.Modules[target.Module]
+	if !ok {
+		return nil
+	}
+	var resources []*Resource
+	for _, resource := range module.ResourcesOfType(resourceType) {
+		for _, reference := range resource.References() {
+			if reference == target.Address() {
+				resources = append(resources, resource)
+				break
+			}
+		}
+	}
+	return resources
+}
+
+// TagsOf returns the tags attribute of a resource. Tags set with merge() are combined
+// in order, and locals of the module are followed.
+func (inv *Inventory) TagsOf(r *Resource) Tags {
+	tags := Tags{Values: make(map[string]string)}
+	if expr, ok := r.AttributeExpr("tags"); ok {
+		inv.addTags(&tags, inv.Modules[r.Module], expr, 0)
+	}
+	return tags
+}
+
+// addTags adds the tags of one expression
+func (inv *Inventory) addTags(tags *Tags, module *Module, expr hcl.Expression, depth int) {
+	switch expr := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		for _, item := range expr.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || !key.IsKnown() || key.Type() != cty.String {
+				tags.Merged = append(tags.Merged, inv.Source(item.KeyExpr.Range()))
+				continue
+			}
+			tags.Values[key.AsString()] = inv.text(item.ValueExpr)
+		}
+		return
+	case *hclsyntax.FunctionCallExpr:
+		if expr.Name == "merge" {
+			for _, arg := range expr.Args {
+				inv.addTags(tags, module, arg, depth)
+			}
+			return
+		}
+	case *hclsyntax.ScopeTraversalExpr:
+		if module != nil && depth < 8 && len(expr.Traversal) == 2 && expr.Traversal.RootName() == "local" {
+			if attr, ok := expr.Traversal[1].(hcl.TraverseAttr); ok {
+				if local, ok := module.Locals[attr.Name]; ok {
+					inv.addTags(tags, module, local.Expr, depth+1)
+					return
+				}
+			}
+		}
+	}
+	tags.Merged = append(tags.Merged, inv.Source(expr.Range()))
+}
+
+// text returns the value of a literal string expression, or its source text
+func (inv *Inventory) text(expr hcl.Expression) string {
+	value, diags := expr.Value(nil)
+	if !diags.HasErrors() && value.IsKnown() && !value.IsNull() && value.Type() == cty.String {
+		return value.AsString()
+	}
+	return inv.Source(expr.Range())
+}
+
+// literalString returns the value of an attribute that is a literal string
+func literalString(attribute *hclsyntax.Attribute) (string, bool) {
+	if attribute == nil {
+		return "", false
+	}
+	value, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// literalTrue reports whether an attribute is the literal true
+func literalTrue(attribute *hclsyntax.Attribute) bool {
+	if attribute == nil {
+		return false
+	}
+	value, diags := attribute.Expr.Value(nil)
+	return !diags.HasErrors() && value.IsKnown() && !value.IsNull() && value.Type() == cty.Bool && value.True()
+}
@@ -0,0 +1,146 @@
+package netsec
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/your-org/aws-centralized-inspection/tests/routing"
+)
+
+// NewFlow returns a flow from source, on port routing.EphemeralPort, to port of
+// destination
+func NewFlow(protocol string, source, destination netip.Addr, port int) routing.Flow {
+	return routing.Flow{Protocol: protocol, Source: source, SourcePort: routing.EphemeralPort, Destination: destination, DestinationPort: port}
+}
+
+// Endpoint is one end of a flow: the network ACL of its subnet and the security
+// groups of its network interface. An endpoint without an ACL or security groups,
+// such as a host on the internet, does not filter. Leave out the ACL of both
+// endpoints for flows within one subnet, which network ACLs do not see.
+type Endpoint struct {
+	NetworkACL     *NetworkACL
+	SecurityGroups []*SecurityGroup
+}
+
+// Decision is what one security group or network ACL does with the packets of a
+// flow or its replies
+type Decision struct {
+	// Control is the security group or network ACL
+	Control   string
+	Direction Direction
+	// Reply is true for the packets of the replies
+	Reply   bool
+	Allowed bool
+	// Rule is the rule that decided, nil when no rule matched
+	Rule *Rule
+}
+
+// String returns the control, direction, outcome and rule of the decision
+func (d Decision) String() string {
+	packets := "flow"
+	if d.Reply {
+		packets = "reply"
+	}
+	outcome := "allows"
+	if !d.Allowed {
+		outcome = "denies"
+	}
+	rule := "no rule matches"
+	if d.Rule != nil {
+		rule = d.Rule.String()
+	}
+	return fmt.Sprintf("%s %s %s %s: %s", d.Control, d.Direction, outcome, packets, rule)
+}
+
+// Verdict is whether a flow and its replies get through
+type Verdict struct {
+	Flow      routing.Flow
+	Allowed   bool
+	Decisions []Decision
+}
+
+// Denied returns the decisions that drop the flow or its replies
+func (v Verdict) Denied() []Decision {
+	var denied []Decision
+	for _, decision := range v.Decisions {
+		if !decision.Allowed {
+			denied = append(denied, decision)
+		}
+	}
+	return denied
+}
+
+// String returns the flow and the decisions that drop it
+func (v Verdict) String() string {
+	if v.Allowed {
+		return fmt.Sprintf("%s: allowed", v.Flow)
+	}
+	reasons := make([]string, 0, len(v.Decisions))
+	for _, decision := range v.Denied() {
+		reasons = append(reasons, decision.String())
+	}
+	return fmt.Sprintf("%s: denied: %s", v.Flow, strings.Join(reasons, "; "))
+}
+
+// Evaluate decides whether a flow from source to destination is allowed, together
+// with its replies. The flow leaves the security groups and network ACL of the
+// source and enters those of the destination. Security groups track connections
+// and let the replies through; the network ACLs see the replies as packets of
+// their own, back to the source port of the flow.
+func Evaluate(flow routing.Flow, source, destination Endpoint) Verdict {
+	verdict := Verdict{Flow: flow, Allowed: true}
+	add := func(decision Decision) {
+		verdict.Decisions = append(verdict.Decisions, decision)
+		verdict.Allowed = verdict.Allowed && decision.Allowed
+	}
+
+	if len(source.SecurityGroups) > 0 {
+		add(decideGroups(source.SecurityGroups, Egress, flow))
+	}
+	if source.NetworkACL != nil {
+		add(decideACL(source.NetworkACL, Egress, flow, false))
+	}
+	if destination.NetworkACL != nil {
+		add(decideACL(destination.NetworkACL, Ingress, flow, false))
+	}
+	if len(destination.SecurityGroups) > 0 {
+		add(decideGroups(destination.SecurityGroups, Ingress, flow))
+	}
+
+	reply := flow.Reverse()
+	if destination.NetworkACL != nil {
+		add(decideACL(destination.NetworkACL, Egress, reply, true))
+	}
+	if source.NetworkACL != nil {
+		add(decideACL(source.NetworkACL, Ingress, reply, true))
+	}
+	return verdict
+}
+
+// decideACL returns the decision of a network ACL on the packets of flow
+func decideACL(acl *NetworkACL, direction Direction, flow routing.Flow, reply bool) Decision {
+	decision := Decision{Control: acl.String(), Direction: direction, Reply: reply}
+	if rule, ok := acl.Match(direction, flow); ok {
+		decision.Rule = &rule
+		decision.Allowed = rule.Action == Allow
+	}
+	return decision
+}
+
+// decideGroups returns the decision of the security groups of an interface, which
+// allow a packet when one of their rules does
+func decideGroups(groups []*SecurityGroup, direction Direction, flow routing.Flow) Decision {
+	names := make([]string, len(groups))
+	for i, group := range groups {
+		names[i] = group.ID
+		if rule, ok := group.Match(direction, flow); ok {
+			return Decision{Control: group.String(), Direction: direction, Allowed: true, Rule: &rule}
+		}
+	}
+	control := "security group " + names[0]
+	if len(groups) > 1 {
+		control = "security groups " + strings.Join(names, ", ")
+	}
+	return Decision{Control: control, Direction: direction}
+}
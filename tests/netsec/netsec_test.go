@@ -0,0 +1,221 @@
+package netsec_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/netsec"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+	"github.com/your-org/aws-centralized-inspection/tests/routing"
+)
+
+// rule returns a rule for a CIDR block
+func rule(number int, action netsec.Action, protocol string, from, to int, cidr string) netsec.Rule {
+	return netsec.Rule{Number: number, Action: action, Protocol: protocol, FromPort: from, ToPort: to, CIDR: netip.MustParsePrefix(cidr)}
+}
+
+// flow returns a flow from an ephemeral port of source to port of destination
+func flow(protocol, source, destination string, port int) routing.Flow {
+	return netsec.NewFlow(protocol, netip.MustParseAddr(source), netip.MustParseAddr(destination), port)
+}
+
+// testACL allows the VPC, SSH from one network and TCP replies from the internet
+// to the dynamic ports, denies RDP and denies the rest
+var testACL = &netsec.NetworkACL{
+	ID: "acl-1",
+	Ingress: []netsec.Rule{
+		rule(1000, netsec.Deny, "-1", 0, 0, "0.0.0.0/0"),
+		rule(100, netsec.Allow, "-1", 0, 0, "10.0.0.0/16"),
+		rule(110, netsec.Allow, "tcp", 22, 22, "198.51.100.0/24"),
+		rule(900, netsec.Deny, "tcp", 3389, 3389, "0.0.0.0/0"),
+		rule(910, netsec.Allow, "6", 49152, 65535, "0.0.0.0/0"),
+	},
+	Egress: []netsec.Rule{
+		rule(100, netsec.Allow, "-1", 0, 0, "0.0.0.0/0"),
+	},
+}
+
+func TestNetworkACLMatch(t *testing.T) {
+	// The lowest-numbered matching rule decides, whatever the order of the rules
+	matched, ok := testACL.Match(netsec.Ingress, flow("tcp", "203.0.113.10", "10.0.1.4", 3389))
+	require.True(t, ok)
+	assert.Equal(t, 900, matched.Number)
+	assert.False(t, testACL.Allows(netsec.Ingress, flow("tcp", "203.0.113.10", "10.0.1.4", 3389)))
+	assert.True(t, testACL.Allows(netsec.Ingress, flow("tcp", "203.0.113.10", "10.0.1.4", 50000)))
+	assert.False(t, testACL.Allows(netsec.Ingress, flow("tcp", "203.0.113.10", "10.0.1.4", 8080)))
+	assert.True(t, testACL.Allows(netsec.Ingress, flow("tcp", "198.51.100.7", "10.0.1.4", 22)))
+	assert.False(t, testACL.Allows(netsec.Ingress, flow("tcp", "203.0.113.10", "10.0.1.4", 22)))
+	assert.True(t, testACL.Allows(netsec.Ingress, flow("icmp", "10.0.2.9", "10.0.1.4", 0)))
+	assert.False(t, testACL.Allows(netsec.Ingress, flow("udp", "203.0.113.10", "10.0.1.4", 53000)))
+
+	// Without a matching rule the implicit final rule denies
+	_, ok = testACL.Match(netsec.Ingress, flow("tcp", "2001:db8::1", "10.0.1.4", 443))
+	assert.False(t, ok)
+}
+
+func TestEvaluate(t *testing.T) {
+	inside := netsec.Endpoint{NetworkACL: testACL}
+	internet := netsec.Endpoint{}
+
+	// Replies to the ephemeral port of the flow come back through rule 910
+	verdict := netsec.Evaluate(flow("tcp", "10.0.1.4", "203.0.113.10", 443), inside, internet)
+	assert.True(t, verdict.Allowed, verdict.String())
+	require.Len(t, verdict.Decisions, 2)
+	assert.Equal(t, "network ACL acl-1 ingress allows reply: rule 910: allow 6 49152-65535 0.0.0.0/0", verdict.Decisions[1].String())
+
+	// The ACL is stateless: UDP replies have no rule
+	verdict = netsec.Evaluate(flow("udp", "10.0.1.4", "203.0.113.10", 123), inside, internet)
+	assert.False(t, verdict.Allowed)
+	denied := verdict.Denied()
+	require.Len(t, denied, 1)
+	assert.True(t, denied[0].Reply)
+	assert.Equal(t, "udp 10.0.1.4:49152 -> 203.0.113.10:123: denied: network ACL acl-1 ingress denies reply: rule 1000: deny -1 0.0.0.0/0", verdict.String())
+
+	// Security groups are stateful: the reply needs no rule, and any group of the
+	// interface can allow the flow
+	web := &netsec.SecurityGroup{ID: "sg-web", Ingress: []netsec.Rule{rule(0, netsec.Allow, "tcp", 443, 443, "0.0.0.0/0")}}
+	admin := &netsec.SecurityGroup{ID: "sg-admin", Ingress: []netsec.Rule{rule(0, netsec.Allow, "tcp", 22, 22, "198.51.100.0/24")}}
+	server := netsec.Endpoint{SecurityGroups: []*netsec.SecurityGroup{web, admin}}
+	client := netsec.Endpoint{SecurityGroups: []*netsec.SecurityGroup{{ID: "sg-client", Egress: []netsec.Rule{rule(0, netsec.Allow, "-1", 0, 0, "0.0.0.0/0")}}}}
+
+	verdict = netsec.Evaluate(flow("tcp", "198.51.100.7", "10.0.1.4", 22), client, server)
+	assert.True(t, verdict.Allowed, verdict.String())
+	assert.Equal(t, "security group sg-admin ingress allows flow: allow tcp 22 198.51.100.0/24", verdict.Decisions[1].String())
+
+	verdict = netsec.Evaluate(flow("tcp", "203.0.113.10", "10.0.1.4", 22), client, server)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, "security groups sg-web, sg-admin ingress denies flow: no rule matches", verdict.Denied()[0].String())
+
+	// A server without egress rules still answers, but cannot connect out
+	assert.True(t, netsec.Evaluate(flow("tcp", "203.0.113.10", "10.0.1.4", 443), internet, server).Allowed)
+	assert.False(t, netsec.Evaluate(flow("tcp", "10.0.1.4", "203.0.113.10", 443), server, internet).Allowed)
+
+	// Both the ACL and the security groups apply
+	assert.False(t, netsec.Evaluate(flow("tcp", "203.0.113.10", "10.0.1.4", 443), internet,
+		netsec.Endpoint{NetworkACL: testACL, SecurityGroups: server.SecurityGroups}).Allowed)
+}
+
+func TestPermissive(t *testing.T) {
+	group := &netsec.SecurityGroup{
+		ID: "sg-1",
+		Ingress: []netsec.Rule{
+			rule(0, netsec.Allow, "tcp", 20, 25, "0.0.0.0/0"),
+			rule(0, netsec.Allow, "tcp", 3389, 3389, "10.0.0.0/8"),
+			rule(0, netsec.Allow, "tcp", 443, 443, "0.0.0.0/0"),
+			rule(0, netsec.Allow, "-1", 0, 0, "::/0"),
+		},
+		Egress: []netsec.Rule{rule(0, netsec.Allow, "-1", 0, 0, "0.0.0.0/0")},
+	}
+	var messages []string
+	for _, finding := range group.Permissive() {
+		messages = append(messages, finding.String())
+	}
+	assert.Equal(t, []string{
+		"open-management-port: security group sg-1: allow tcp 20-25 0.0.0.0/0 opens SSH (tcp 22) to the internet",
+		"open-management-port: security group sg-1: allow tcp 20-25 0.0.0.0/0 opens Telnet (tcp 23) to the internet",
+		"open-all-traffic: security group sg-1: allow -1 ::/0 opens all traffic to the internet",
+	}, messages)
+
+	// Rule 920 opens MySQL and RDP, and only MySQL once rule 900 denies RDP first
+	assert.Empty(t, testACL.Permissive())
+	open := &netsec.NetworkACL{ID: "acl-2", Ingress: []netsec.Rule{testACL.Ingress[0], rule(920, netsec.Allow, "tcp", 3000, 3999, "0.0.0.0/0")}}
+	require.Len(t, open.Permissive(), 2)
+	open.Ingress = append(open.Ingress, testACL.Ingress[3])
+	findings := open.Permissive()
+	require.Len(t, findings, 1)
+	assert.Equal(t, netsec.FindingManagementPort, findings[0].Kind)
+	assert.Equal(t, "rule 920: allow tcp 3000-3999 0.0.0.0/0 opens MySQL (tcp 3306) to the internet", findings[0].Message)
+
+	open.Ingress = append(open.Ingress, rule(50, netsec.Allow, "-1", 0, 0, "0.0.0.0/0"))
+	assert.Len(t, open.Permissive(), 2)
+	open.Ingress = append(open.Ingress, rule(10, netsec.Deny, "-1", 0, 0, "0.0.0.0/0"))
+	assert.Empty(t, open.Permissive())
+
+	// Ephemeral port rules for replies open the remote administration and database
+	// ports above 1024, even when SSH and RDP are denied first
+	replies := &netsec.NetworkACL{ID: "acl-3", Ingress: []netsec.Rule{
+		rule(900, netsec.Deny, "tcp", 3389, 3389, "0.0.0.0/0"),
+		rule(910, netsec.Allow, "tcp", 1024, 65535, "0.0.0.0/0"),
+		rule(920, netsec.Allow, "udp", 1024, 65535, "0.0.0.0/0"),
+		rule(1000, netsec.Deny, "-1", 0, 0, "0.0.0.0/0"),
+	}}
+	var services []string
+	for _, finding := range replies.Permissive() {
+		assert.Equal(t, 910, finding.Rule.Number)
+		services = append(services, finding.Message)
+	}
+	assert.Equal(t, []string{
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens SQL Server (tcp 1433) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens Oracle (tcp 1521) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens MySQL (tcp 3306) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens PostgreSQL (tcp 5432) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens VNC (tcp 5900) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens WinRM (tcp 5985) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens WinRM over HTTPS (tcp 5986) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens Redis (tcp 6379) to the internet",
+		"rule 910: allow tcp 1024-65535 0.0.0.0/0 opens MongoDB (tcp 27017) to the internet",
+	}, services)
+}
+
+// securityPlan has a network ACL with an inline rule and a standalone rule, a
+// security group with an inline rule and a standalone rule, and an ACL association
+const securityPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_network_acl.main", "mode": "managed", "type": "aws_network_acl", "name": "main",
+     "values": {"id": "acl-1", "subnet_ids": ["subnet-1"],
+       "ingress": [{"action": "allow", "cidr_block": "10.0.0.0/16", "ipv6_cidr_block": "", "from_port": 0, "to_port": 0, "protocol": "-1", "rule_no": 100}],
+       "egress": [{"action": "allow", "cidr_block": "", "ipv6_cidr_block": "::/0", "from_port": 443, "to_port": 443, "protocol": "6", "rule_no": 100}]}},
+    {"address": "aws_network_acl_rule.ssh", "mode": "managed", "type": "aws_network_acl_rule", "name": "ssh",
+     "values": {"network_acl_id": "acl-1", "rule_number": 200, "egress": false, "protocol": "tcp", "rule_action": "allow", "cidr_block": "0.0.0.0/0", "from_port": 22, "to_port": 22}},
+    {"address": "aws_network_acl_association.b", "mode": "managed", "type": "aws_network_acl_association", "name": "b",
+     "values": {"network_acl_id": "acl-1", "subnet_id": "subnet-2"}},
+    {"address": "aws_security_group.app", "mode": "managed", "type": "aws_security_group", "name": "app",
+     "values": {"name": "app",
+       "ingress": [{"cidr_blocks": ["10.0.0.0/16", "10.1.0.0/16"], "ipv6_cidr_blocks": [], "description": "HTTPS", "from_port": 443, "to_port": 443, "protocol": "tcp", "security_groups": [], "self": false, "prefix_list_ids": []}],
+       "egress": []}},
+    {"address": "aws_security_group_rule.rdp", "mode": "managed", "type": "aws_security_group_rule", "name": "rdp",
+     "values": {"type": "ingress", "cidr_blocks": ["0.0.0.0/0"], "from_port": 3389, "to_port": 3389, "protocol": "tcp"}}
+  ]}},
+  "resource_changes": [
+    {"address": "aws_security_group.app", "mode": "managed", "type": "aws_security_group", "name": "app",
+     "change": {"actions": ["create"], "after": {}, "after_unknown": {"id": true}}},
+    {"address": "aws_security_group_rule.rdp", "mode": "managed", "type": "aws_security_group_rule", "name": "rdp",
+     "change": {"actions": ["create"], "after": {}, "after_unknown": {"security_group_id": true}}}
+  ]
+}`
+
+func TestFromPlan(t *testing.T) {
+	p, err := plan.Parse([]byte(securityPlan))
+	require.NoError(t, err)
+	rules, err := netsec.FromPlan(p)
+	require.NoError(t, err)
+
+	acl, ok := rules.NetworkACL("acl-1")
+	require.True(t, ok)
+	assert.Equal(t, []string{"subnet-1", "subnet-2"}, acl.Subnets)
+	associated, ok := rules.SubnetACL("subnet-2")
+	require.True(t, ok)
+	assert.Same(t, acl, associated)
+	assert.Equal(t, []netsec.Rule{
+		rule(100, netsec.Allow, "-1", 0, 0, "10.0.0.0/16"),
+		rule(200, netsec.Allow, "tcp", 22, 22, "0.0.0.0/0"),
+	}, acl.Ingress)
+	assert.Equal(t, []netsec.Rule{rule(100, netsec.Allow, "6", 443, 443, "::/0")}, acl.Egress)
+
+	// The group's ID is only known after apply, so the standalone rule that
+	// references it is left out
+	group, ok := rules.SecurityGroup("aws_security_group.app")
+	require.True(t, ok)
+	assert.Equal(t, "app", group.Name)
+	require.Len(t, group.Ingress, 2)
+	assert.Equal(t, "allow tcp 443 10.1.0.0/16 (HTTPS)", group.Ingress[1].String())
+	assert.Empty(t, group.Egress)
+
+	findings := rules.Permissive()
+	require.Len(t, findings, 1)
+	assert.Equal(t, "open-management-port: network ACL acl-1: rule 200: allow tcp 22 0.0.0.0/0 opens SSH (tcp 22) to the internet", findings[0].String())
+}
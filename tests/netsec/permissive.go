@@ -0,0 +1,125 @@
+package netsec
+
+import "fmt"
+
+// Port is a TCP or UDP port of a service
+type Port struct {
+	Protocol string
+	Number   int
+	Service  string
+}
+
+// ManagementPorts are the remote administration and database ports that no rule
+// may open to the internet
+var ManagementPorts = []Port{
+	{Protocol: "tcp", Number: 22, Service: "SSH"},
+	{Protocol: "tcp", Number: 23, Service: "Telnet"},
+	{Protocol: "tcp", Number: 1433, Service: "SQL Server"},
+	{Protocol: "tcp", Number: 1521, Service: "Oracle"},
+	{Protocol: "tcp", Number: 3306, Service: "MySQL"},
+	{Protocol: "tcp", Number: 3389, Service: "RDP"},
+	{Protocol: "tcp", Number: 5432, Service: "PostgreSQL"},
+	{Protocol: "tcp", Number: 5900, Service: "VNC"},
+	{Protocol: "tcp", Number: 5985, Service: "WinRM"},
+	{Protocol: "tcp", Number: 5986, Service: "WinRM over HTTPS"},
+	{Protocol: "tcp", Number: 6379, Service: "Redis"},
+	{Protocol: "tcp", Number: 27017, Service: "MongoDB"},
+}
+
+// FindingKind is the kind of overly permissive rule a finding reports
+type FindingKind string
+
+// Finding kinds
+const (
+	// FindingManagementPort is an ingress rule that opens a management or
+	// database port to the internet
+	FindingManagementPort FindingKind = "open-management-port"
+	// FindingAllTraffic is an ingress rule that opens every protocol or every
+	// port to the internet
+	FindingAllTraffic FindingKind = "open-all-traffic"
+)
+
+// Finding is an overly permissive rule
+type Finding struct {
+	Kind FindingKind
+	// Control is the security group or network ACL of the rule
+	Control string
+	Rule    Rule
+	Message string
+}
+
+// String returns the finding as kind, control and message
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Kind, f.Control, f.Message)
+}
+
+// Permissive returns the ingress rules of the security group that are open to the
+// internet on every port or on a management port
+func (g *SecurityGroup) Permissive() []Finding {
+	var findings []Finding
+	for _, rule := range g.Ingress {
+		findings = append(findings, permissive(g.String(), rule, func(int, int) bool { return true })...)
+	}
+	return findings
+}
+
+// Permissive returns the ingress allow rules of the network ACL that are open to
+// the internet on every port or on a management port. A rule does not open a port
+// that a lower-numbered rule denies to the internet.
+func (a *NetworkACL) Permissive() []Finding {
+	var findings []Finding
+	for _, rule := range a.Ingress {
+		if rule.Action != Allow {
+			continue
+		}
+		reached := func(protocol, port int) bool {
+			for _, earlier := range a.Ingress {
+				if earlier.Number < rule.Number && earlier.Action == Deny && earlier.anywhere() &&
+					earlier.CIDR.Addr().Is4() == rule.CIDR.Addr().Is4() && earlier.covers(protocol, port) {
+					return false
+				}
+			}
+			return true
+		}
+		findings = append(findings, permissive(a.String(), rule, reached)...)
+	}
+	return findings
+}
+
+// permissive returns the findings of an ingress rule that allows traffic.
+// reached reports whether packets of a protocol to a port get to the rule, and
+// for allProtocols whether any packets do.
+func permissive(control string, rule Rule, reached func(protocol, port int) bool) []Finding {
+	if !rule.anywhere() {
+		return nil
+	}
+	protocol, ok := rule.protocol()
+	if !ok {
+		return nil
+	}
+	if protocol == allProtocols || (rule.hasPorts() && rule.FromPort <= 0 && rule.ToPort >= 65535) {
+		if !reached(allProtocols, 0) {
+			return nil
+		}
+		return []Finding{{
+			Kind:    FindingAllTraffic,
+			Control: control,
+			Rule:    rule,
+			Message: fmt.Sprintf("%s opens all traffic to the internet", rule),
+		}}
+	}
+
+	var findings []Finding
+	for _, port := range ManagementPorts {
+		number, _ := protocolNumber(port.Protocol)
+		if rule.covers(number, port.Number) && reached(number, port.Number) {
+			findings = append(findings, Finding{
+				Kind:    FindingManagementPort,
+				Control: control,
+				Rule:    rule,
+				Message: fmt.Sprintf("%s opens %s (%s %d) to the internet", rule, port.Service, port.Protocol, port.Number),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,282 @@
+package netsec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strconv"
+
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// RuleSet is the security groups and network ACLs of a plan
+type RuleSet struct {
+	NetworkACLs    []*NetworkACL
+	SecurityGroups []*SecurityGroup
+}
+
+// NetworkACL returns the network ACL with an ID or, in a plan, a resource address
+func (s *RuleSet) NetworkACL(id string) (*NetworkACL, bool) {
+	for _, acl := range s.NetworkACLs {
+		if acl.ID == id {
+			return acl, true
+		}
+	}
+	return nil, false
+}
+
+// SubnetACL returns the network ACL associated with a subnet
+func (s *RuleSet) SubnetACL(subnet string) (*NetworkACL, bool) {
+	for _, acl := range s.NetworkACLs {
+		for _, id := range acl.Subnets {
+			if id == subnet {
+				return acl, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// SecurityGroup returns the security group with an ID or, in a plan, a resource
+// address
+func (s *RuleSet) SecurityGroup(id string) (*SecurityGroup, bool) {
+	for _, group := range s.SecurityGroups {
+		if group.ID == id {
+			return group, true
+		}
+	}
+	return nil, false
+}
+
+// Permissive returns the overly permissive rules of every security group and
+// network ACL
+func (s *RuleSet) Permissive() []Finding {
+	var findings []Finding
+	for _, acl := range s.NetworkACLs {
+		findings = append(findings, acl.Permissive()...)
+	}
+	for _, group := range s.SecurityGroups {
+		findings = append(findings, group.Permissive()...)
+	}
+	return findings
+}
+
+// FromPlan returns the security groups and network ACLs of a plan, with their
+// inline rules and the rules of aws_security_group_rule and aws_network_acl_rule
+// resources. Resources whose ID is only known after apply are named by their
+// address, and standalone rules and associations that reference them by an
+// unknown ID are left out. Rules with a security group or prefix list as their
+// peer are left out as well.
+func FromPlan(p *plan.Plan) (*RuleSet, error) {
+	s := &RuleSet{}
+
+	for _, r := range p.Resources("aws_network_acl") {
+		acl := &NetworkACL{ID: resourceID(r), Subnets: stringList(r, "subnet_ids")}
+		var err error
+		if acl.Ingress, err = aclRules(r, "ingress"); err != nil {
+			return nil, err
+		}
+		if acl.Egress, err = aclRules(r, "egress"); err != nil {
+			return nil, err
+		}
+		s.NetworkACLs = append(s.NetworkACLs, acl)
+	}
+	for _, r := range p.Resources("aws_network_acl_rule") {
+		acl, ok := s.NetworkACL(stringValue(r, "network_acl_id"))
+		if !ok {
+			continue
+		}
+		rules, err := aclRule(r.Address, r.Values, "rule_number", "rule_action")
+		if err != nil {
+			return nil, err
+		}
+		if egress, _ := r.Value("egress"); egress == true {
+			acl.Egress = append(acl.Egress, rules...)
+		} else {
+			acl.Ingress = append(acl.Ingress, rules...)
+		}
+	}
+	for _, r := range p.Resources("aws_network_acl_association") {
+		if acl, ok := s.NetworkACL(stringValue(r, "network_acl_id")); ok {
+			acl.Subnets = append(acl.Subnets, stringValue(r, "subnet_id"))
+		}
+	}
+
+	for _, r := range p.Resources("aws_security_group") {
+		group := &SecurityGroup{ID: resourceID(r), Name: stringValue(r, "name")}
+		var err error
+		if group.Ingress, err = groupRules(r, "ingress"); err != nil {
+			return nil, err
+		}
+		if group.Egress, err = groupRules(r, "egress"); err != nil {
+			return nil, err
+		}
+		s.SecurityGroups = append(s.SecurityGroups, group)
+	}
+	for _, r := range p.Resources("aws_security_group_rule") {
+		group, ok := s.SecurityGroup(stringValue(r, "security_group_id"))
+		if !ok {
+			continue
+		}
+		rules, err := groupRule(r.Address, r.Values)
+		if err != nil {
+			return nil, err
+		}
+		if stringValue(r, "type") == "egress" {
+			group.Egress = append(group.Egress, rules...)
+		} else {
+			group.Ingress = append(group.Ingress, rules...)
+		}
+	}
+	return s, nil
+}
+
+// aclRules returns the inline rules of a network ACL in direction
+func aclRules(r *plan.Resource, direction string) ([]Rule, error) {
+	values, _ := r.Value(direction)
+	var rules []Rule
+	for _, value := range listValue(values) {
+		block, _ := value.(map[string]interface{})
+		rule, err := aclRule(r.Address, block, "rule_no", "action")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule...)
+	}
+	return rules, nil
+}
+
+// aclRule returns the rule of a network ACL rule block, which has an IPv4 or an
+// IPv6 CIDR block
+func aclRule(address string, block map[string]interface{}, numberKey, actionKey string) ([]Rule, error) {
+	rule := Rule{
+		Number:   intValue(block[numberKey]),
+		Action:   Action(mapString(block, actionKey)),
+		Protocol: protocolValue(block["protocol"]),
+		FromPort: intValue(block["from_port"]),
+		ToPort:   intValue(block["to_port"]),
+	}
+	return withCIDRs(address, rule, mapString(block, "cidr_block"), mapString(block, "ipv6_cidr_block"))
+}
+
+// groupRules returns the inline rules of a security group in direction
+func groupRules(r *plan.Resource, direction string) ([]Rule, error) {
+	values, _ := r.Value(direction)
+	var rules []Rule
+	for _, value := range listValue(values) {
+		block, _ := value.(map[string]interface{})
+		rule, err := groupRule(r.Address, block)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule...)
+	}
+	return rules, nil
+}
+
+// groupRule returns a rule for each CIDR block of a security group rule block
+func groupRule(address string, block map[string]interface{}) ([]Rule, error) {
+	rule := Rule{
+		Action:      Allow,
+		Protocol:    protocolValue(block["protocol"]),
+		FromPort:    intValue(block["from_port"]),
+		ToPort:      intValue(block["to_port"]),
+		Description: mapString(block, "description"),
+	}
+	var cidrs []string
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+		for _, cidr := range listValue(block[key]) {
+			if cidr, ok := cidr.(string); ok {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return withCIDRs(address, rule, cidrs...)
+}
+
+// withCIDRs returns a copy of rule for each non-empty CIDR block
+func withCIDRs(address string, rule Rule, cidrs ...string) ([]Rule, error) {
+	var rules []Rule
+	for _, cidr := range cidrs {
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", address, err)
+		}
+		rule.CIDR = prefix
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// resourceID returns the id of a planned resource, or its address when the id is
+// only known after apply
+func resourceID(r *plan.Resource) string {
+	if id := stringValue(r, "id"); id != "" {
+		return id
+	}
+	return r.Address
+}
+
+// stringValue returns a planned string value, or "" when it is unknown, null or
+// not a string
+func stringValue(r *plan.Resource, path string) string {
+	value, err := r.Value(path)
+	if err != nil {
+		return ""
+	}
+	s, _ := value.(string)
+	return s
+}
+
+// stringList returns the strings of a planned list value
+func stringList(r *plan.Resource, path string) []string {
+	value, _ := r.Value(path)
+	var list []string
+	for _, item := range listValue(value) {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// listValue returns a list value, or nil when the value is not a list
+func listValue(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// mapString returns a string attribute of a decoded object
+func mapString(object map[string]interface{}, key string) string {
+	s, _ := object[key].(string)
+	return s
+}
+
+// intValue returns a decoded number, 0 when it is not one
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// protocolValue returns a decoded protocol, which Terraform keeps as a name or a
+// number
+func protocolValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return strconv.Itoa(intValue(value))
+}
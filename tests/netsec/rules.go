@@ -0,0 +1,219 @@
+// Package netsec evaluates security group and network ACL rules the way a VPC
+// does. Network ACLs are stateless and apply their lowest-numbered matching rule,
+// so replies need rules of their own; security groups are stateful and allow a
+// packet when any rule does. Tests use it to ask whether a flow is allowed in both
+// directions and to list rules that open management ports or all traffic to the
+// internet, from planned or deployed rules alike.
+package netsec
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/aws-centralized-inspection/tests/routing"
+)
+
+// Action is what a network ACL rule does with the packets it matches
+type Action string
+
+// Actions
+const (
+	Allow Action = "allow"
+	Deny  Action = "deny"
+)
+
+// Direction is the direction a packet crosses a security group or network ACL
+type Direction string
+
+// Directions
+const (
+	Ingress Direction = "ingress"
+	Egress  Direction = "egress"
+)
+
+// allProtocols is the protocol of rules that match every protocol and port
+const allProtocols = -1
+
+// protocolNumbers maps the protocol names AWS and Terraform accept to numbers
+var protocolNumbers = map[string]int{
+	"-1":     allProtocols,
+	"all":    allProtocols,
+	"icmp":   1,
+	"tcp":    6,
+	"udp":    17,
+	"icmpv6": 58,
+}
+
+// Rule is an ingress or egress rule of a security group or network ACL, for one
+// CIDR block
+type Rule struct {
+	// Number orders the rules of a network ACL; security group rules have none
+	Number int
+	// Action is Allow for every security group rule
+	Action Action
+	// Protocol is a protocol name or number, "-1" for all protocols
+	Protocol string
+	// FromPort and ToPort are the TCP or UDP port range; other protocols match on
+	// any port
+	FromPort    int
+	ToPort      int
+	CIDR        netip.Prefix
+	Description string
+}
+
+// String returns the rule number, action, protocol, ports and CIDR block
+func (r Rule) String() string {
+	var b strings.Builder
+	if r.Number > 0 {
+		fmt.Fprintf(&b, "rule %d: ", r.Number)
+	}
+	fmt.Fprintf(&b, "%s %s", r.Action, r.Protocol)
+	if r.hasPorts() {
+		if r.FromPort == r.ToPort {
+			fmt.Fprintf(&b, " %d", r.FromPort)
+		} else {
+			fmt.Fprintf(&b, " %d-%d", r.FromPort, r.ToPort)
+		}
+	}
+	fmt.Fprintf(&b, " %s", r.CIDR)
+	if r.Description != "" {
+		fmt.Fprintf(&b, " (%s)", r.Description)
+	}
+	return b.String()
+}
+
+// protocol returns the protocol number of the rule, allProtocols included
+func (r Rule) protocol() (int, bool) {
+	return protocolNumber(r.Protocol)
+}
+
+// hasPorts reports whether the rule matches on a port range
+func (r Rule) hasPorts() bool {
+	p, ok := r.protocol()
+	return ok && (p == protocolNumbers["tcp"] || p == protocolNumbers["udp"])
+}
+
+// anywhere reports whether the CIDR block of the rule is every address
+func (r Rule) anywhere() bool {
+	return r.CIDR.IsValid() && r.CIDR.Bits() == 0
+}
+
+// covers reports whether the rule matches every packet of protocol to port, from
+// any address of its CIDR block
+func (r Rule) covers(protocol, port int) bool {
+	p, ok := r.protocol()
+	switch {
+	case !ok:
+		return false
+	case p == allProtocols:
+		return true
+	case p != protocol:
+		return false
+	case !r.hasPorts():
+		return true
+	default:
+		return r.FromPort <= port && port <= r.ToPort
+	}
+}
+
+// matches reports whether the rule matches a packet of flow crossing in
+// direction: on protocol, destination port and the address on the other side
+func (r Rule) matches(direction Direction, flow routing.Flow) bool {
+	protocol, ok := protocolNumber(flow.Protocol)
+	if !ok || !r.covers(protocol, flow.DestinationPort) {
+		return false
+	}
+	peer := flow.Destination
+	if direction == Ingress {
+		peer = flow.Source
+	}
+	return r.CIDR.Contains(peer)
+}
+
+// protocolNumber returns the number of a protocol name or number
+func protocolNumber(protocol string) (int, bool) {
+	if n, ok := protocolNumbers[strings.ToLower(protocol)]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(protocol)
+	if err != nil || n < 0 || n > 255 {
+		return 0, false
+	}
+	return n, true
+}
+
+// NetworkACL is a network ACL and the subnets associated with it
+type NetworkACL struct {
+	ID      string
+	Subnets []string
+	Ingress []Rule
+	Egress  []Rule
+}
+
+// String names the network ACL
+func (a *NetworkACL) String() string {
+	return "network ACL " + a.ID
+}
+
+// Rules returns the rules of a direction
+func (a *NetworkACL) Rules(direction Direction) []Rule {
+	if direction == Ingress {
+		return a.Ingress
+	}
+	return a.Egress
+}
+
+// Match returns the rule that decides a packet of flow crossing the ACL in
+// direction: the lowest-numbered rule that matches it. It returns false when no
+// rule does and the implicit final rule denies the packet.
+func (a *NetworkACL) Match(direction Direction, flow routing.Flow) (Rule, bool) {
+	var decision Rule
+	found := false
+	for _, rule := range a.Rules(direction) {
+		if rule.matches(direction, flow) && (!found || rule.Number < decision.Number) {
+			decision, found = rule, true
+		}
+	}
+	return decision, found
+}
+
+// Allows reports whether the ACL lets a packet of flow cross in direction
+func (a *NetworkACL) Allows(direction Direction, flow routing.Flow) bool {
+	rule, ok := a.Match(direction, flow)
+	return ok && rule.Action == Allow
+}
+
+// SecurityGroup is a security group. Rules that reference other security groups
+// or prefix lists are not modelled.
+type SecurityGroup struct {
+	ID      string
+	Name    string
+	Ingress []Rule
+	Egress  []Rule
+}
+
+// String names the security group
+func (g *SecurityGroup) String() string {
+	return "security group " + g.ID
+}
+
+// Rules returns the rules of a direction
+func (g *SecurityGroup) Rules(direction Direction) []Rule {
+	if direction == Ingress {
+		return g.Ingress
+	}
+	return g.Egress
+}
+
+// Match returns the first rule that allows a packet of flow in direction, and
+// false when none does and the packet is dropped
+func (g *SecurityGroup) Match(direction Direction, flow routing.Flow) (Rule, bool) {
+	for _, rule := range g.Rules(direction) {
+		if rule.matches(direction, flow) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
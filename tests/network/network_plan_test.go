@@ -0,0 +1,87 @@
+package network_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
+	"github.com/your-org/aws-centralized-inspection/tests/netsec"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+	"github.com/your-org/aws-centralized-inspection/tests/routing"
+)
+
+// TestNetworkPlan checks the planned network against the recorded plan in testdata,
+// without AWS. Set UPDATE_PLAN_FIXTURES=1 to record the plan again.
+func TestNetworkPlan(t *testing.T) {
+	t.Parallel()
+
+	// A fixed seed keeps the tags in the recorded plan stable
+	tdm, err := fixtures.NewTestDataManagerWithSeed("staging", "us-east-1", 1)
+	require.NoError(t, err)
+	terraformOptions := fixtures.NetworkOptions(tdm)
+	networkPlan := plan.LoadOrRecord(t, terraformOptions, "testdata/network_plan.json")
+
+	// Inspection VPC and two spokes, each with subnets in both AZs
+	plan.AssertResourceCount(t, networkPlan, "aws_vpc", 3)
+	plan.AssertResourceCount(t, networkPlan, "aws_subnet", 8)
+	plan.AssertValue(t, networkPlan, "aws_vpc.inspection", "cidr_block", terraformOptions.Vars["vpc_cidr"])
+	plan.AssertValue(t, networkPlan, "aws_subnet.spoke_private[3]", "cidr_block", "10.12.21.0/24")
+	plan.AssertValue(t, networkPlan, "aws_subnet.spoke_private[3]", "availability_zone", "us-east-1b")
+	plan.AssertAllValues(t, networkPlan, "aws_subnet", "map_public_ip_on_launch", false)
+	plan.AssertAllValues(t, networkPlan, "aws_vpc", "tags.TestPrefix", tdm.NamePrefix())
+
+	// Transit Gateway with one attachment per VPC
+	plan.AssertValue(t, networkPlan, "aws_ec2_transit_gateway.this", "amazon_side_asn", 64513)
+	plan.AssertResourceCount(t, networkPlan, "aws_ec2_transit_gateway_vpc_attachment", 3)
+	plan.AssertUnknown(t, networkPlan, "aws_ec2_transit_gateway_vpc_attachment.inspection", "id")
+
+	// Appliance mode keeps both directions of a flow on the same firewall
+	plan.AssertValue(t, networkPlan, "aws_ec2_transit_gateway_vpc_attachment.inspection", "appliance_mode_support", "enable")
+
+	// Spokes are associated with the spoke route table and propagate into the inspection route table
+	plan.AssertResourceCount(t, networkPlan, "aws_ec2_transit_gateway_route_table_association", 3)
+	plan.AssertResourceCount(t, networkPlan, "aws_ec2_transit_gateway_route_table_propagation", 3)
+
+	// Flow logs for the inspection VPC and the Transit Gateway
+	plan.AssertValue(t, networkPlan, "aws_flow_log.inspection_vpc", "traffic_type", "ALL")
+	plan.AssertValue(t, networkPlan, "aws_flow_log.tgw", "max_aggregation_interval", 60)
+	plan.AssertAllValues(t, networkPlan, "aws_cloudwatch_log_group", "retention_in_days", 30)
+
+	// The network ACL passes spoke-to-spoke traffic through the firewalls and keeps
+	// management ports closed
+	rules, err := netsec.FromPlan(networkPlan)
+	require.NoError(t, err)
+	assert.Empty(t, rules.Permissive())
+	acl, ok := rules.NetworkACL("aws_network_acl.inspection")
+	require.True(t, ok)
+	inspection := netsec.Endpoint{NetworkACL: acl}
+	internet := netsec.Endpoint{}
+	for _, f := range []routing.Flow{flow("tcp", "10.11.20.10", "10.12.20.10", 443), flow("udp", "10.12.21.10", "10.11.21.10", 53)} {
+		verdict := netsec.Evaluate(f, inspection, inspection)
+		assert.True(t, verdict.Allowed, verdict.String())
+	}
+	for _, port := range netsec.ManagementPorts {
+		verdict := netsec.Evaluate(flow(port.Protocol, "203.0.113.10", "10.10.1.10", port.Number), internet, inspection)
+		require.False(t, verdict.Allowed, verdict.String())
+		assert.Equal(t, 1000, verdict.Denied()[0].Rule.Number)
+	}
+
+	// The ACL is stateless and only lets the VPC and the spokes in, so rule 1000 also
+	// denies the replies to internet-bound traffic (README.md, Known Issues)
+	for _, f := range []routing.Flow{flow("tcp", "10.10.1.10", "203.0.113.10", 443), flow("udp", "10.10.1.10", "203.0.113.10", 123)} {
+		verdict := netsec.Evaluate(f, inspection, internet)
+		require.False(t, verdict.Allowed, verdict.String())
+		denied := verdict.Denied()
+		require.Len(t, denied, 1)
+		assert.True(t, denied[0].Reply, verdict.String())
+		assert.Equal(t, 1000, denied[0].Rule.Number)
+	}
+}
+
+// flow returns a flow from an ephemeral port of source to port of destination
+func flow(protocol, source, destination string, port int) routing.Flow {
+	return netsec.NewFlow(protocol, netip.MustParseAddr(source), netip.MustParseAddr(destination), port)
+}
@@ -5,38 +5,26 @@ import (
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/your-org/aws-centralized-inspection/tests/fixtures"
 )
 
 // TestNetworkProvisioning tests the core network infrastructure provisioning
 func TestNetworkProvisioning(t *testing.T) {
 	t.Parallel()
 
-	// Test configuration
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/network",
-		Vars: map[string]interface{}{
-			"vpc_cidr":        "10.0.0.0/16",
-			"tgw_asn":         64512,
-			"spoke_vpc_cidrs": []string{"10.1.0.0/16", "10.2.0.0/16"},
-			"public_subnets":  []string{"10.0.10.0/24", "10.0.11.0/24"},
-			"private_subnets": []string{"10.0.20.0/24", "10.0.21.0/24"},
-			"azs":             []string{"us-east-1a", "us-east-1b"},
-			"spoke_azs":       []string{"us-east-1a", "us-east-1b"},
-			"spoke_private_subnets": [][]string{
-				{"10.1.20.0/24", "10.1.21.0/24"},
-				{"10.2.20.0/24", "10.2.21.0/24"},
-			},
-			"tags": map[string]string{
-				"Environment":        "test",
-				"Project":            "centralized-inspection",
-				"Owner":              "test-team",
-				"DataClassification": "sensitive",
-			},
-		},
-	}
-
-	// Ensure cleanup on failure
-	defer terraform.Destroy(t, terraformOptions)
+	// Test configuration: two AZs and two spokes, with CIDRs leased so parallel runs
+	// in the account don't overlap
+	tdm := fixtures.NewTestDataManagerForTest(t, "staging", "us-east-1")
+	allocator := fixtures.DefaultCIDRAllocator()
+	network := tdm.AllocateNetworkTestData(t, allocator)
+	terraformOptions := fixtures.NetworkOptions(tdm,
+		fixtures.WithNetwork(network),
+		fixtures.WithTags(map[string]string{"Owner": "test-team"}),
+	)
+
+	// Ensure cleanup on failure; the CIDRs are released once the VPCs are gone
+	defer fixtures.DestroyAndRelease(t, terraformOptions, allocator)
 
 	// Deploy infrastructure
 	terraform.InitAndApply(t, terraformOptions)
@@ -94,27 +82,12 @@ func TestNetworkProvisioning(t *testing.T) {
 func TestNetworkConfigurationValidation(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/network",
-		Vars: map[string]interface{}{
-			"vpc_cidr":        "10.0.0.0/16",
-			"tgw_asn":         64512,
-			"spoke_vpc_cidrs": []string{"10.1.0.0/16"},
-			"public_subnets":  []string{"10.0.10.0/24"},
-			"private_subnets": []string{"10.0.20.0/24"},
-			"azs":             []string{"us-east-1a"},
-			"spoke_azs":       []string{"us-east-1a"},
-			"spoke_private_subnets": [][]string{
-				{"10.1.20.0/24"},
-			},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
-
-	defer terraform.Destroy(t, terraformOptions)
+	// One AZ and one spoke, with leased CIDRs
+	tdm := fixtures.NewTestDataManagerForTest(t, "dev", "us-east-1")
+	allocator := fixtures.DefaultCIDRAllocator()
+	terraformOptions := fixtures.NetworkOptions(tdm, fixtures.WithNetwork(tdm.AllocateNetworkTestData(t, allocator)))
+
+	defer fixtures.DestroyAndRelease(t, terraformOptions, allocator)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Validate subnet configurations
@@ -137,27 +110,12 @@ func TestNetworkConfigurationValidation(t *testing.T) {
 func TestNetworkIdempotency(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/network",
-		Vars: map[string]interface{}{
-			"vpc_cidr":        "10.0.0.0/16",
-			"tgw_asn":         64512,
-			"spoke_vpc_cidrs": []string{"10.1.0.0/16"},
-			"public_subnets":  []string{"10.0.10.0/24"},
-			"private_subnets": []string{"10.0.20.0/24"},
-			"azs":             []string{"us-east-1a"},
-			"spoke_azs":       []string{"us-east-1a"},
-			"spoke_private_subnets": [][]string{
-				{"10.1.20.0/24"},
-			},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
-
-	defer terraform.Destroy(t, terraformOptions)
+	// One AZ and one spoke, with leased CIDRs
+	tdm := fixtures.NewTestDataManagerForTest(t, "dev", "us-east-1")
+	allocator := fixtures.DefaultCIDRAllocator()
+	terraformOptions := fixtures.NetworkOptions(tdm, fixtures.WithNetwork(tdm.AllocateNetworkTestData(t, allocator)))
+
+	defer fixtures.DestroyAndRelease(t, terraformOptions, allocator)
 
 	// First apply
 	terraform.InitAndApply(t, terraformOptions)
@@ -181,27 +139,12 @@ func TestNetworkIdempotency(t *testing.T) {
 func TestNetworkDriftDetection(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/network",
-		Vars: map[string]interface{}{
-			"vpc_cidr":        "10.0.0.0/16",
-			"tgw_asn":         64512,
-			"spoke_vpc_cidrs": []string{"10.1.0.0/16"},
-			"public_subnets":  []string{"10.0.10.0/24"},
-			"private_subnets": []string{"10.0.20.0/24"},
-			"azs":             []string{"us-east-1a"},
-			"spoke_azs":       []string{"us-east-1a"},
-			"spoke_private_subnets": [][]string{
-				{"10.1.20.0/24"},
-			},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
-
-	defer terraform.Destroy(t, terraformOptions)
+	// One AZ and one spoke, with leased CIDRs
+	tdm := fixtures.NewTestDataManagerForTest(t, "dev", "us-east-1")
+	allocator := fixtures.DefaultCIDRAllocator()
+	terraformOptions := fixtures.NetworkOptions(tdm, fixtures.WithNetwork(tdm.AllocateNetworkTestData(t, allocator)))
+
+	defer fixtures.DestroyAndRelease(t, terraformOptions, allocator)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get VPC ID for drift simulation
@@ -220,28 +163,15 @@ func TestNetworkDriftDetection(t *testing.T) {
 func TestNetworkResiliency(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/network",
-		Vars: map[string]interface{}{
-			"vpc_cidr":        "10.0.0.0/16",
-			"tgw_asn":         64512,
-			"spoke_vpc_cidrs": []string{"10.1.0.0/16", "10.2.0.0/16"},
-			"public_subnets":  []string{"10.0.10.0/24", "10.0.11.0/24", "10.0.12.0/24"},
-			"private_subnets": []string{"10.0.20.0/24", "10.0.21.0/24", "10.0.22.0/24"},
-			"azs":             []string{"us-east-1a", "us-east-1b", "us-east-1c"},
-			"spoke_azs":       []string{"us-east-1a", "us-east-1b", "us-east-1c"},
-			"spoke_private_subnets": [][]string{
-				{"10.1.20.0/24", "10.1.21.0/24", "10.1.22.0/24"},
-				{"10.2.20.0/24", "10.2.21.0/24", "10.2.22.0/24"},
-			},
-			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "centralized-inspection",
-			},
-		},
-	}
-
-	defer terraform.Destroy(t, terraformOptions)
+	// Three AZs and two spokes, with leased CIDRs. The prod profile has the three AZs;
+	// its third spoke is left out.
+	tdm := fixtures.NewTestDataManagerForTest(t, "prod", "us-east-1")
+	allocator := fixtures.DefaultCIDRAllocator()
+	network := tdm.AllocateNetworkTestData(t, allocator)
+	network.SpokeVpcCidrs = network.SpokeVpcCidrs[:2]
+	terraformOptions := fixtures.NetworkOptions(tdm, fixtures.WithNetwork(network))
+
+	defer fixtures.DestroyAndRelease(t, terraformOptions, allocator)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Validate multi-AZ deployment
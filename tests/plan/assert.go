@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertResourceExists checks that the plan has a managed resource at address
+func AssertResourceExists(t testing.TB, p *Plan, address string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	if _, ok := p.Resource(address); !ok {
+		return assert.Fail(t, fmt.Sprintf("plan has no resource %s", address), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertResourceCount checks the number of managed resources of a type
+func AssertResourceCount(t testing.TB, p *Plan, resourceType string, count int, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	resources := p.Resources(resourceType)
+	if len(resources) != count {
+		addresses := make([]string, len(resources))
+		for i, resource := range resources {
+			addresses[i] = resource.Address
+		}
+		return assert.Fail(t, fmt.Sprintf("plan has %d %s resources, expected %d: %v", len(resources), resourceType, count, addresses), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertValue checks the planned value at path of the resource at address.
+// Expected values are compared in their JSON form, so 6081 matches the number 6081
+// and []string{"a"} matches a list of strings.
+func AssertValue(t testing.TB, p *Plan, address, path string, expected interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	resource, ok := p.Resource(address)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("plan has no resource %s", address), msgAndArgs...)
+	}
+	return assertValue(t, resource, path, expected, msgAndArgs...)
+}
+
+// AssertAllValues checks the planned value at path of every resource of a type,
+// failing when the plan has none
+func AssertAllValues(t testing.TB, p *Plan, resourceType, path string, expected interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	resources := p.Resources(resourceType)
+	if len(resources) == 0 {
+		return assert.Fail(t, fmt.Sprintf("plan has no %s resources", resourceType), msgAndArgs...)
+	}
+	ok := true
+	for _, resource := range resources {
+		ok = assertValue(t, resource, path, expected, msgAndArgs...) && ok
+	}
+	return ok
+}
+
+// AssertUnknown checks that the value at path is only known after apply
+func AssertUnknown(t testing.TB, p *Plan, address, path string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	resource, ok := p.Resource(address)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("plan has no resource %s", address), msgAndArgs...)
+	}
+	value, err := resource.Value(path)
+	if errors.Is(err, ErrUnknown) {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%s: %s should be known after apply, got %v (%v)", address, path, value, err), msgAndArgs...)
+}
+
+// assertValue compares the value at path with the JSON form of expected
+func assertValue(t testing.TB, resource *Resource, path string, expected interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	actual, err := resource.Value(path)
+	if err != nil {
+		return assert.Fail(t, err.Error(), msgAndArgs...)
+	}
+	normalized, err := normalize(expected)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("%s: %s: expected value: %v", resource.Address, path, err), msgAndArgs...)
+	}
+	if !assert.ObjectsAreEqual(normalized, actual) {
+		return assert.Fail(t, fmt.Sprintf("%s: %s\nexpected: %#v\nactual  : %#v", resource.Address, path, normalized, actual), msgAndArgs...)
+	}
+	return true
+}
+
+// normalize converts a Go value to the types encoding/json decodes plan values to
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
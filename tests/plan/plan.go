@@ -0,0 +1,437 @@
+// Package plan reads the JSON form of Terraform plans, as written by
+// `terraform show -json`, so tests can check what a module would create without
+// applying it. Plans are recorded once into fixture files and checked in, and the
+// tests assert against the fixtures without AWS credentials.
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// UpdateEnvVar names the environment variable that makes LoadOrRecord run
+// terraform plan and rewrite the fixture instead of reading it
+const UpdateEnvVar = "UPDATE_PLAN_FIXTURES"
+
+// ErrUnknown is returned for values Terraform only knows after apply, such as IDs
+var ErrUnknown = errors.New("known after apply")
+
+// fixtureKeys are the parts of a plan kept in fixtures. The configuration and prior
+// state change with every module edit and are not needed by the assertions. The
+// variables are left out too: they hold sensitive inputs in plain text.
+var fixtureKeys = []string{"format_version", "terraform_version", "planned_values", "resource_changes"}
+
+// Redacted replaces the values a plan marks as sensitive in fixtures
+const Redacted = "(sensitive value)"
+
+// Plan is a parsed Terraform plan
+type Plan struct {
+	// Struct is the terratest representation of the plan
+	Struct    *terraform.PlanStruct
+	resources []*Resource
+}
+
+// Resource is a resource the plan creates, updates or keeps
+type Resource struct {
+	Address string
+	Module  string
+	Type    string
+	Name    string
+	// Index is the count index as an int, the for_each key as a string or nil
+	Index   interface{}
+	Actions []string
+	// Values are the planned attribute values, without those known after apply
+	Values  map[string]interface{}
+	unknown interface{}
+}
+
+// Parse parses the output of terraform show -json
+func Parse(data []byte) (*Plan, error) {
+	planStruct, err := terraform.ParsePlanJSON(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+
+	plan := &Plan{Struct: planStruct}
+	for address, planned := range planStruct.ResourcePlannedValuesMap {
+		if planned.Mode != "managed" {
+			continue
+		}
+		resource := &Resource{
+			Address: address,
+			Type:    planned.Type,
+			Name:    planned.Name,
+			Index:   index(planned.Index),
+			Values:  planned.AttributeValues,
+		}
+		if change, ok := planStruct.ResourceChangesMap[address]; ok {
+			resource.Module = change.ModuleAddress
+			if change.Change != nil {
+				for _, action := range change.Change.Actions {
+					resource.Actions = append(resource.Actions, string(action))
+				}
+				resource.unknown = change.Change.AfterUnknown
+			}
+		}
+		plan.resources = append(plan.resources, resource)
+	}
+	sort.Slice(plan.resources, func(i, j int) bool {
+		return plan.resources[i].Address < plan.resources[j].Address
+	})
+	return plan, nil
+}
+
+// Load parses a plan JSON file
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// Record runs terraform init and plan with the options, writes the plan JSON to
+// path and returns the parsed plan. The options are not modified.
+func Record(t testing.TB, options *terraform.Options, path string) *Plan {
+	t.Helper()
+	planOptions, err := options.Clone()
+	if err != nil {
+		t.Fatalf("clone terraform options: %v", err)
+	}
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "plan.tfplan")
+
+	data, err := FixtureJSON([]byte(terraform.InitAndPlanAndShow(t, planOptions)))
+	if err != nil {
+		t.Fatalf("record plan: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("record plan: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("record plan: %v", err)
+	}
+
+	plan, err := Parse(data)
+	if err != nil {
+		t.Fatalf("record plan: %v", err)
+	}
+	return plan
+}
+
+// LoadOrRecord loads the plan fixture at path, or records it with Record when
+// UPDATE_PLAN_FIXTURES is set. Fixtures are recorded with the options of the test,
+// and sensitive values are redacted before they are written.
+func LoadOrRecord(t testing.TB, options *terraform.Options, path string) *Plan {
+	t.Helper()
+	if os.Getenv(UpdateEnvVar) != "" {
+		return Record(t, options, path)
+	}
+	plan, err := Load(path)
+	if err != nil {
+		t.Fatalf("%v (set %s=1 to record the plan)", err, UpdateEnvVar)
+	}
+	return plan
+}
+
+// FixtureJSON keeps the fixtureKeys of a plan, replaces the values marked sensitive
+// with Redacted and indents it for review. Objects keep Terraform's key order.
+func FixtureJSON(data []byte) ([]byte, error) {
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+	kept := make(map[string]json.RawMessage)
+	for _, key := range fixtureKeys {
+		if value, ok := full[key]; ok {
+			kept[key] = value
+		}
+	}
+
+	if planned, ok := kept["planned_values"]; ok {
+		redacted, err := editObject(planned, func(values *object) error {
+			return values.edit("root_module", redactModule)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("redact planned values: %w", err)
+		}
+		kept["planned_values"] = redacted
+	}
+	if changes, ok := kept["resource_changes"]; ok {
+		redacted, err := editList(changes, func(change json.RawMessage) (json.RawMessage, error) {
+			return editObject(change, func(change *object) error {
+				return change.edit("change", func(values json.RawMessage) (json.RawMessage, error) {
+					return editObject(values, func(values *object) error {
+						if err := values.redact("before", "before_sensitive"); err != nil {
+							return err
+						}
+						return values.redact("after", "after_sensitive")
+					})
+				})
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("redact resource changes: %w", err)
+		}
+		kept["resource_changes"] = redacted
+	}
+
+	out, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// redactModule redacts the sensitive values of the resources of a planned module
+// and its child modules
+func redactModule(module json.RawMessage) (json.RawMessage, error) {
+	return editObject(module, func(module *object) error {
+		err := module.edit("resources", func(resources json.RawMessage) (json.RawMessage, error) {
+			return editList(resources, func(resource json.RawMessage) (json.RawMessage, error) {
+				return editObject(resource, func(resource *object) error {
+					return resource.redact("values", "sensitive_values")
+				})
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return module.edit("child_modules", func(children json.RawMessage) (json.RawMessage, error) {
+			return editList(children, redactModule)
+		})
+	})
+}
+
+// redact replaces the parts of a JSON value that a sensitive mask marks with true.
+// Masks mirror the value: objects and lists mask their elements.
+func redact(value json.RawMessage, mask interface{}) (json.RawMessage, error) {
+	switch mask := mask.(type) {
+	case bool:
+		if mask && string(value) != "null" {
+			return json.Marshal(Redacted)
+		}
+	case map[string]interface{}:
+		if len(value) > 0 && value[0] == '{' {
+			return editObject(value, func(values *object) error {
+				for key, elementMask := range mask {
+					err := values.edit(key, func(element json.RawMessage) (json.RawMessage, error) {
+						return redact(element, elementMask)
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	case []interface{}:
+		if len(value) > 0 && value[0] == '[' {
+			i := 0
+			return editList(value, func(element json.RawMessage) (json.RawMessage, error) {
+				defer func() { i++ }()
+				if i >= len(mask) {
+					return element, nil
+				}
+				return redact(element, mask[i])
+			})
+		}
+	}
+	return value, nil
+}
+
+// object is a JSON object that keeps the order of its keys
+type object struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// editObject decodes a JSON object, runs fn on it and encodes it in the same key order
+func editObject(data json.RawMessage, fn func(*object) error) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	o := &object{values: make(map[string]json.RawMessage)}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		o.keys = append(o.keys, key)
+		o.values[key] = value
+	}
+	if err := fn(o); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(o.values[key])
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// edit replaces the value of key, if present, with what fn returns
+func (o *object) edit(key string, fn func(json.RawMessage) (json.RawMessage, error)) error {
+	value, ok := o.values[key]
+	if !ok || string(value) == "null" {
+		return nil
+	}
+	edited, err := fn(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	o.values[key] = edited
+	return nil
+}
+
+// redact redacts the value of key with the sensitive mask stored under maskKey
+func (o *object) redact(key, maskKey string) error {
+	var mask interface{}
+	if raw, ok := o.values[maskKey]; ok {
+		if err := json.Unmarshal(raw, &mask); err != nil {
+			return fmt.Errorf("%s: %w", maskKey, err)
+		}
+	}
+	return o.edit(key, func(value json.RawMessage) (json.RawMessage, error) {
+		return redact(value, mask)
+	})
+}
+
+// editList runs fn on every element of a JSON list
+func editList(data json.RawMessage, fn func(json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, err
+	}
+	for i, element := range elements {
+		edited, err := fn(element)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		elements[i] = edited
+	}
+	return json.Marshal(elements)
+}
+
+// index converts a JSON count index to an int
+func index(value interface{}) interface{} {
+	if number, ok := value.(float64); ok {
+		return int(number)
+	}
+	return value
+}
+
+// Resources returns the managed resources of a type, such as aws_subnet, sorted by
+// address
+func (p *Plan) Resources(resourceType string) []*Resource {
+	var resources []*Resource
+	for _, resource := range p.resources {
+		if resource.Type == resourceType {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// Resource returns the managed resource with the full address, such as
+// aws_subnet.private[0]
+func (p *Plan) Resource(address string) (*Resource, bool) {
+	for _, resource := range p.resources {
+		if resource.Address == address {
+			return resource, true
+		}
+	}
+	return nil, false
+}
+
+// Value returns the planned value at a dotted path of attribute names, map keys and
+// list indexes, such as health_check.0.port or tags.Name. It returns ErrUnknown for
+// values known after apply.
+func (r *Resource) Value(path string) (interface{}, error) {
+	var value interface{} = r.Values
+	unknown := r.unknown
+	for _, step := range strings.Split(path, ".") {
+		unknown = child(unknown, step)
+		if unknown == true {
+			return nil, fmt.Errorf("%s: %s is %w", r.Address, path, ErrUnknown)
+		}
+
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[step]
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: no attribute %q", r.Address, path, step)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(step)
+			if err != nil || i < 0 || i >= len(current) {
+				return nil, fmt.Errorf("%s: %s: no element %q in a list of %d", r.Address, path, step, len(current))
+			}
+			value = current[i]
+		case nil:
+			return nil, fmt.Errorf("%s: %s: %q of a null value", r.Address, path, step)
+		default:
+			return nil, fmt.Errorf("%s: %s: %q of a %T value", r.Address, path, step, current)
+		}
+	}
+	return value, nil
+}
+
+// UnknownAttributes returns the names of the top-level attributes known after
+// apply, sorted, such as id and arn
+func (r *Resource) UnknownAttributes() []string {
+	unknown, _ := r.unknown.(map[string]interface{})
+	var names []string
+	for name, value := range unknown {
+		if value == true {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// child returns the after_unknown entry for one path step
+func child(unknown interface{}, step string) interface{} {
+	switch current := unknown.(type) {
+	case map[string]interface{}:
+		return current[step]
+	case []interface{}:
+		if i, err := strconv.Atoi(step); err == nil && i >= 0 && i < len(current) {
+			return current[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package plan_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+const testPlan = `{
+  "format_version": "1.2",
+  "terraform_version": "1.9.8",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_lb_target_group.gwlb",
+          "mode": "managed",
+          "type": "aws_lb_target_group",
+          "name": "gwlb",
+          "values": {
+            "port": 6081,
+            "protocol": "GENEVE",
+            "health_check": [{"enabled": true, "port": "22", "protocol": "TCP"}],
+            "tags": {"Name": "inspection-tg"}
+          }
+        },
+        {
+          "address": "aws_subnet.private[1]",
+          "mode": "managed",
+          "type": "aws_subnet",
+          "name": "private",
+          "index": 1,
+          "values": {"cidr_block": "10.0.21.0/24", "map_public_ip_on_launch": false}
+        },
+        {
+          "address": "aws_subnet.private[0]",
+          "mode": "managed",
+          "type": "aws_subnet",
+          "name": "private",
+          "index": 0,
+          "values": {"cidr_block": "10.0.20.0/24", "map_public_ip_on_launch": false}
+        },
+        {
+          "address": "data.aws_caller_identity.current",
+          "mode": "data",
+          "type": "aws_caller_identity",
+          "name": "current",
+          "values": {}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.spoke",
+          "resources": [
+            {
+              "address": "module.spoke.aws_subnet.this[\"a\"]",
+              "mode": "managed",
+              "type": "aws_subnet",
+              "name": "this",
+              "index": "a",
+              "values": {"cidr_block": "10.1.0.0/24", "map_public_ip_on_launch": true}
+            }
+          ]
+        }
+      ]
+    }
+  },
+  "resource_changes": [
+    {
+      "address": "aws_lb_target_group.gwlb",
+      "mode": "managed",
+      "type": "aws_lb_target_group",
+      "name": "gwlb",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after_unknown": {"arn": true, "health_check": [{"timeout": true}], "tags": {}}
+      }
+    },
+    {
+      "address": "module.spoke.aws_subnet.this[\"a\"]",
+      "module_address": "module.spoke",
+      "mode": "managed",
+      "type": "aws_subnet",
+      "name": "this",
+      "index": "a",
+      "change": {"actions": ["create"], "before": null, "after_unknown": {"id": true}}
+    }
+  ]
+}`
+
+// writePlan writes the test plan to a temporary file
+func writePlan(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPlan), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	p, err := plan.Load(writePlan(t))
+	require.NoError(t, err)
+
+	var addresses []string
+	for _, resource := range p.Resources("aws_subnet") {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.Equal(t, []string{"aws_subnet.private[0]", "aws_subnet.private[1]", "module.spoke.aws_subnet.this[\"a\"]"}, addresses)
+	assert.Empty(t, p.Resources("aws_caller_identity"), "data sources are not planned resources")
+
+	subnet, ok := p.Resource("aws_subnet.private[1]")
+	require.True(t, ok)
+	assert.Equal(t, 1, subnet.Index)
+	spoke, ok := p.Resource("module.spoke.aws_subnet.this[\"a\"]")
+	require.True(t, ok)
+	assert.Equal(t, "a", spoke.Index)
+	assert.Equal(t, "module.spoke", spoke.Module)
+	assert.Equal(t, []string{"create"}, spoke.Actions)
+
+	_, err = plan.Parse([]byte(`{"planned_values": {}}`))
+	assert.ErrorContains(t, err, "format version is missing")
+	_, err = plan.Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFixtureJSON(t *testing.T) {
+	data, err := plan.FixtureJSON([]byte(`{
+  "format_version": "1.2",
+  "terraform_version": "1.9.8",
+  "variables": {"password": {"value": "hunter2"}},
+  "configuration": {"root_module": {}},
+  "planned_values": {"root_module": {
+    "resources": [{"address": "aws_s3_object.cfg", "mode": "managed", "type": "aws_s3_object", "name": "cfg",
+      "values": {"key": "init-cfg.txt", "content": "auth-key=hunter2", "tags": {"Owner": "a", "Token": "hunter2"}, "rules": ["open", "hunter2"], "etag": null},
+      "sensitive_values": {"content": true, "tags": {"Token": true}, "rules": [false, true], "etag": true}}],
+    "child_modules": [{"address": "module.spoke", "resources": [{"address": "module.spoke.aws_ssm_parameter.key", "mode": "managed", "type": "aws_ssm_parameter", "name": "key",
+      "values": {"name": "key", "value": "hunter2"}, "sensitive_values": {"value": true}}]}]
+  }},
+  "resource_changes": [{"address": "aws_s3_object.cfg", "change": {"actions": ["update"],
+    "before": {"content": "auth-key=hunter1"}, "after": {"content": "auth-key=hunter2"},
+    "before_sensitive": {"content": true}, "after_sensitive": {"content": true}}}]
+}`))
+	require.NoError(t, err)
+	fixture := string(data)
+
+	assert.NotContains(t, fixture, "hunter")
+	assert.NotContains(t, fixture, `"variables"`)
+	assert.NotContains(t, fixture, `"configuration"`)
+	assert.Contains(t, fixture, `"key": "init-cfg.txt",`+"\n"+`            "content": "(sensitive value)"`, "key order is kept")
+	assert.Contains(t, fixture, `"Owner": "a"`)
+	assert.Contains(t, fixture, `"open"`)
+	assert.Contains(t, fixture, `"etag": null`, "null values are not redacted")
+
+	p, err := plan.Parse(data)
+	require.NoError(t, err)
+	plan.AssertValue(t, p, "aws_s3_object.cfg", "content", plan.Redacted)
+	plan.AssertValue(t, p, "module.spoke.aws_ssm_parameter.key", "value", plan.Redacted)
+}
+
+func TestResourceValue(t *testing.T) {
+	p, err := plan.Load(writePlan(t))
+	require.NoError(t, err)
+	targetGroup, ok := p.Resource("aws_lb_target_group.gwlb")
+	require.True(t, ok)
+
+	value, err := targetGroup.Value("health_check.0.port")
+	require.NoError(t, err)
+	assert.Equal(t, "22", value)
+	value, err = targetGroup.Value("tags.Name")
+	require.NoError(t, err)
+	assert.Equal(t, "inspection-tg", value)
+
+	_, err = targetGroup.Value("arn")
+	assert.True(t, errors.Is(err, plan.ErrUnknown))
+	_, err = targetGroup.Value("health_check.0.timeout")
+	assert.True(t, errors.Is(err, plan.ErrUnknown))
+
+	assert.Equal(t, []string{"arn"}, targetGroup.UnknownAttributes(), "partly unknown blocks are not listed")
+
+	_, err = targetGroup.Value("health_check.1.port")
+	assert.EqualError(t, err, `aws_lb_target_group.gwlb: health_check.1.port: no element "1" in a list of 1`)
+	_, err = targetGroup.Value("stickiness")
+	assert.EqualError(t, err, `aws_lb_target_group.gwlb: stickiness: no attribute "stickiness"`)
+	_, err = targetGroup.Value("port.0")
+	assert.EqualError(t, err, `aws_lb_target_group.gwlb: port.0: "0" of a float64 value`)
+}
+
+func TestAssertions(t *testing.T) {
+	p, err := plan.Load(writePlan(t))
+	require.NoError(t, err)
+
+	assert.True(t, plan.AssertResourceExists(t, p, "aws_lb_target_group.gwlb"))
+	assert.True(t, plan.AssertResourceCount(t, p, "aws_subnet", 3))
+	assert.True(t, plan.AssertValue(t, p, "aws_lb_target_group.gwlb", "port", 6081))
+	assert.True(t, plan.AssertValue(t, p, "aws_lb_target_group.gwlb", "health_check", []map[string]interface{}{
+		{"enabled": true, "port": "22", "protocol": "TCP"},
+	}))
+	assert.True(t, plan.AssertUnknown(t, p, "aws_lb_target_group.gwlb", "arn"))
+
+	failures := &recorder{T: t}
+	assert.False(t, plan.AssertResourceExists(failures, p, "aws_lb.gwlb"))
+	assert.False(t, plan.AssertValue(failures, p, "aws_lb_target_group.gwlb", "port", "6081"))
+	assert.False(t, plan.AssertValue(failures, p, "aws_lb_target_group.gwlb", "arn", ""))
+	assert.False(t, plan.AssertAllValues(failures, p, "aws_subnet", "map_public_ip_on_launch", false),
+		"the module subnet maps public IPs")
+	assert.False(t, plan.AssertAllValues(failures, p, "aws_vpc", "cidr_block", "10.0.0.0/16"), "no resources is a failure")
+	assert.False(t, plan.AssertUnknown(failures, p, "aws_lb_target_group.gwlb", "port"))
+	assert.Len(t, failures.errors, 6)
+	assert.Contains(t, failures.errors[0], "plan has no resource aws_lb.gwlb")
+}
+
+// recorder collects assertion failures instead of failing the test
+type recorder struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
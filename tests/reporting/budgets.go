@@ -0,0 +1,267 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BudgetsFileName is the default duration budget file in the tests directory
+const BudgetsFileName = "test-budgets.json"
+
+// budgetTrendTolerance is the change against the historical mean below which a
+// test's duration is considered stable
+const budgetTrendTolerance = 0.10
+
+// BudgetDuration is a duration written as a Go duration string, e.g. "90s" or "45m"
+type BudgetDuration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *BudgetDuration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("budget must be a duration string such as \"10m\": %w", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = BudgetDuration(duration)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d BudgetDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// TestBudget is a budget for tests matching a pattern, using the pattern syntax of OwnershipRule
+type TestBudget struct {
+	Pattern string         `json:"pattern"`
+	Budget  BudgetDuration `json:"budget"`
+}
+
+// Budgets declares how long tests may take. The most specific budget applies:
+// the last matching test pattern, then the package, then the category, then the
+// default. Tests without any applicable budget are not checked.
+type Budgets struct {
+	Enforce    bool                      `json:"enforce"` // make Err fail when tests are over budget
+	Default    BudgetDuration            `json:"default,omitempty"`
+	Categories map[string]BudgetDuration `json:"categories,omitempty"`
+	Packages   map[string]BudgetDuration `json:"packages,omitempty"`
+	Tests      []TestBudget              `json:"tests,omitempty"`
+}
+
+// BudgetViolation represents a test that ran over its duration budget
+type BudgetViolation struct {
+	TestName       string          `json:"test_name"`
+	Package        string          `json:"package"`
+	Category       string          `json:"category"`
+	Duration       time.Duration   `json:"duration"`
+	Budget         time.Duration   `json:"budget"`
+	BudgetSource   string          `json:"budget_source"` // e.g. "test:TestEndToEnd*", "package:network", "category:unit", "default"
+	Over           time.Duration   `json:"over"`
+	OverPercent    float64         `json:"over_percent"`
+	History        []time.Duration `json:"history,omitempty"` // durations in previous runs, oldest first
+	OverBudgetRuns int             `json:"over_budget_runs"`  // previous runs that were also over budget
+	Trend          string          `json:"trend"`             // "new", "slower", "faster" or "stable" against the historical mean
+}
+
+// BudgetReport is the result of evaluating a run against budgets
+type BudgetReport struct {
+	Enforce    bool              `json:"enforce"`
+	Checked    int               `json:"checked"`
+	Violations []BudgetViolation `json:"violations"`
+}
+
+// ParseBudgets parses and validates a budget file
+func ParseBudgets(data []byte) (*Budgets, error) {
+	budgets := &Budgets{}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(budgets); err != nil {
+		return nil, err
+	}
+
+	if budgets.Default < 0 {
+		return nil, fmt.Errorf("default budget must not be negative")
+	}
+	for name, budget := range budgets.Categories {
+		if budget <= 0 {
+			return nil, fmt.Errorf("category %s: budget must be positive", name)
+		}
+	}
+	for name, budget := range budgets.Packages {
+		if budget <= 0 {
+			return nil, fmt.Errorf("package %s: budget must be positive", name)
+		}
+	}
+	for i, test := range budgets.Tests {
+		if test.Pattern == "" {
+			return nil, fmt.Errorf("tests[%d]: pattern is required", i)
+		}
+		if err := validateTestPattern(test.Pattern); err != nil {
+			return nil, fmt.Errorf("tests[%d]: %w", i, err)
+		}
+		if test.Budget <= 0 {
+			return nil, fmt.Errorf("tests[%d] %s: budget must be positive", i, test.Pattern)
+		}
+	}
+
+	return budgets, nil
+}
+
+// LoadBudgets reads a budget file
+func LoadBudgets(filename string) (*Budgets, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := ParseBudgets(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget file %s: %w", filename, err)
+	}
+	return budgets, nil
+}
+
+// BudgetFor returns the budget that applies to a test and where it came from.
+// ok is false when no budget applies.
+func (b *Budgets) BudgetFor(result TestResult) (budget time.Duration, source string, ok bool) {
+	for i := len(b.Tests) - 1; i >= 0; i-- {
+		if matchTestPattern(b.Tests[i].Pattern, result.Package, result.TestName) {
+			return time.Duration(b.Tests[i].Budget), "test:" + b.Tests[i].Pattern, true
+		}
+	}
+	if budget, ok := b.Packages[result.Package]; ok {
+		return time.Duration(budget), "package:" + result.Package, true
+	}
+	if budget, ok := b.Categories[result.Category]; ok {
+		return time.Duration(budget), "category:" + result.Category, true
+	}
+	if b.Default > 0 {
+		return time.Duration(b.Default), "default", true
+	}
+	return 0, "", false
+}
+
+// Evaluate checks the latest result of every test in the run against its budget.
+// Durations of the same test in history, typically previous runs loaded with
+// LoadHistory, show whether an overrun is new or persistent. history may be nil.
+// Skipped tests are not checked.
+func (b *Budgets) Evaluate(run, history *TestAnalytics) *BudgetReport {
+	report := &BudgetReport{Enforce: b.Enforce, Violations: []BudgetViolation{}}
+
+	previous := make(map[[2]string][]TestResult)
+	if history != nil {
+		for _, suite := range history.Results {
+			for _, result := range suite.Results {
+				key := [2]string{result.Package, result.TestName}
+				previous[key] = append(previous[key], result)
+			}
+		}
+	}
+
+	for key, result := range latestResults(run) {
+		if result.Status == "SKIP" {
+			continue
+		}
+		budget, source, ok := b.BudgetFor(result)
+		if !ok {
+			continue
+		}
+		report.Checked++
+		if result.Duration <= budget {
+			continue
+		}
+
+		violation := BudgetViolation{
+			TestName:     result.TestName,
+			Package:      result.Package,
+			Category:     result.Category,
+			Duration:     result.Duration,
+			Budget:       budget,
+			BudgetSource: source,
+			Over:         result.Duration - budget,
+			OverPercent:  float64(result.Duration-budget) / float64(budget) * 100,
+			Trend:        "new",
+		}
+
+		runs := previous[key]
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].Timestamp.Before(runs[j].Timestamp)
+		})
+		var total time.Duration
+		for _, earlier := range runs {
+			if earlier.Status == "SKIP" {
+				continue
+			}
+			violation.History = append(violation.History, earlier.Duration)
+			total += earlier.Duration
+			if earlier.Duration > budget {
+				violation.OverBudgetRuns++
+			}
+		}
+		if len(violation.History) > 0 {
+			mean := float64(total) / float64(len(violation.History))
+			change := (float64(result.Duration) - mean) / mean
+			switch {
+			case mean == 0 || change > budgetTrendTolerance:
+				violation.Trend = "slower"
+			case change < -budgetTrendTolerance:
+				violation.Trend = "faster"
+			default:
+				violation.Trend = "stable"
+			}
+		}
+
+		report.Violations = append(report.Violations, violation)
+	}
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		return report.Violations[i].OverPercent > report.Violations[j].OverPercent
+	})
+	return report
+}
+
+// Err returns an error listing over-budget tests when the budgets are enforced,
+// so CI can exit non-zero
+func (r *BudgetReport) Err() error {
+	if !r.Enforce || len(r.Violations) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(r.Violations))
+	for _, violation := range r.Violations {
+		names = append(names, violation.Package+"/"+violation.TestName)
+	}
+	return fmt.Errorf("%d of %d tests over duration budget: %s", len(r.Violations), r.Checked, strings.Join(names, ", "))
+}
+
+// Markdown renders the over-budget tests as a Markdown table
+func (r *BudgetReport) Markdown() string {
+	var md strings.Builder
+
+	md.WriteString("## Duration Budgets\n\n")
+	if len(r.Violations) == 0 {
+		md.WriteString(fmt.Sprintf("All %d checked tests are within budget.\n", r.Checked))
+		return md.String()
+	}
+
+	md.WriteString(fmt.Sprintf("%d of %d checked tests are over budget.\n\n", len(r.Violations), r.Checked))
+	md.WriteString("| Test Name | Package | Duration | Budget | Over | Budget Source | Trend | Previously Over |\n")
+	md.WriteString("|-----------|---------|----------|--------|------|---------------|-------|-----------------|\n")
+	for _, violation := range r.Violations {
+		md.WriteString(fmt.Sprintf("| %s | %s | %v | %v | +%.0f%% | %s | %s | %d/%d |\n",
+			violation.TestName, violation.Package,
+			violation.Duration.Round(time.Millisecond), violation.Budget,
+			violation.OverPercent, violation.BudgetSource, violation.Trend,
+			violation.OverBudgetRuns, len(violation.History)))
+	}
+	md.WriteString("\n")
+
+	return md.String()
+}
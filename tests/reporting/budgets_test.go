@@ -0,0 +1,146 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+const testBudgets = `{
+  "enforce": true,
+  "default": "10m",
+  "categories": {"performance": "30m"},
+  "packages": {"network": "5m"},
+  "tests": [
+    {"pattern": "TestEndToEnd*", "budget": "60m"},
+    {"pattern": "network/TestNetworkConfigurationValidation", "budget": "2m"}
+  ]
+}`
+
+func TestBudgetFor(t *testing.T) {
+	budgets, err := reporting.ParseBudgets([]byte(testBudgets))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		result reporting.TestResult
+		budget time.Duration
+		source string
+	}{
+		{reporting.TestResult{TestName: "TestNetworkConfigurationValidation", Package: "network", Category: "unit"}, 2 * time.Minute, "test:network/TestNetworkConfigurationValidation"},
+		{reporting.TestResult{TestName: "TestNetworkProvisioning/subnets", Package: "network", Category: "unit"}, 5 * time.Minute, "package:network"},
+		{reporting.TestResult{TestName: "TestEndToEndPerformance", Package: "performance", Category: "performance"}, time.Hour, "test:TestEndToEnd*"},
+		{reporting.TestResult{TestName: "TestGWLBPerformance", Package: "performance", Category: "performance"}, 30 * time.Minute, "category:performance"},
+		{reporting.TestResult{TestName: "TestPCIDSSCompliance", Package: "compliance", Category: "compliance"}, 10 * time.Minute, "default"},
+	} {
+		budget, source, ok := budgets.BudgetFor(tc.result)
+		assert.True(t, ok, tc.result.TestName)
+		assert.Equal(t, tc.budget, budget, tc.result.TestName)
+		assert.Equal(t, tc.source, source, tc.result.TestName)
+	}
+
+	noDefault, err := reporting.ParseBudgets([]byte(`{"packages": {"network": "5m"}}`))
+	require.NoError(t, err)
+	_, _, ok := noDefault.BudgetFor(reporting.TestResult{TestName: "TestPCIDSSCompliance", Package: "compliance"})
+	assert.False(t, ok)
+}
+
+func TestParseBudgetsErrors(t *testing.T) {
+	for input, message := range map[string]string{
+		`{"default": "ten minutes"}`:                            "invalid duration",
+		`{"default": 600}`:                                      "duration string",
+		`{"packages": {"network": "0s"}}`:                       "package network: budget must be positive",
+		`{"tests": [{"pattern": "network/[", "budget": "1m"}]}`: "tests[0]: invalid pattern",
+		`{"tests": [{"budget": "1m"}]}`:                         "tests[0]: pattern is required",
+		`{"package": {"network": "5m"}}`:                        "unknown field",
+	} {
+		_, err := reporting.ParseBudgets([]byte(input))
+		assert.ErrorContains(t, err, message, input)
+	}
+}
+
+func TestEvaluateBudgets(t *testing.T) {
+	budgets, err := reporting.ParseBudgets([]byte(testBudgets))
+	require.NoError(t, err)
+
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	var nightly []reporting.TestSuiteResult
+	for i, duration := range []time.Duration{3 * time.Minute, 90 * time.Second, 100 * time.Second} {
+		nightly = append(nightly, reporting.TestSuiteResult{SuiteName: "nightly", RunID: "nightly", StartTime: start.Add(time.Duration(i) * 24 * time.Hour), Results: []reporting.TestResult{
+			{TestName: "TestNetworkConfigurationValidation", Package: "network", Category: "unit", Status: "PASS", Duration: duration},
+			{TestName: "TestGWLBPerformance", Package: "performance", Category: "performance", Status: "PASS", Duration: 20 * time.Minute},
+		}})
+	}
+	history := newAnalytics(nightly...)
+
+	run := newAnalytics(reporting.TestSuiteResult{SuiteName: "nightly", RunID: "candidate", StartTime: start.Add(72 * time.Hour), Results: []reporting.TestResult{
+		{TestName: "TestNetworkConfigurationValidation", Package: "network", Category: "unit", Status: "PASS", Duration: 3 * time.Minute},
+		{TestName: "TestNetworkProvisioning", Package: "network", Category: "unit", Status: "PASS", Duration: 4 * time.Minute},
+		{TestName: "TestEndToEndPerformance", Package: "performance", Category: "performance", Status: "PASS", Duration: 50 * time.Minute},
+		{TestName: "TestGWLBPerformance", Package: "performance", Category: "performance", Status: "PASS", Duration: 40 * time.Minute},
+		{TestName: "TestPCIDSSCompliance", Package: "compliance", Category: "compliance", Status: "PASS", Duration: 12 * time.Minute},
+	}})
+
+	report := budgets.Evaluate(run, history)
+	assert.Equal(t, 5, report.Checked)
+	require.Len(t, report.Violations, 3)
+
+	// Ordered by how far over budget they are
+	validation := report.Violations[0]
+	assert.Equal(t, "TestNetworkConfigurationValidation", validation.TestName)
+	assert.Equal(t, time.Minute, validation.Over)
+	assert.InDelta(t, 50.0, validation.OverPercent, 0.001)
+	assert.Equal(t, []time.Duration{3 * time.Minute, 90 * time.Second, 100 * time.Second}, validation.History)
+	assert.Equal(t, 1, validation.OverBudgetRuns)
+	assert.Equal(t, "slower", validation.Trend)
+
+	gwlb := report.Violations[1]
+	assert.Equal(t, "TestGWLBPerformance", gwlb.TestName)
+	assert.Equal(t, "category:performance", gwlb.BudgetSource)
+	assert.Equal(t, 0, gwlb.OverBudgetRuns)
+
+	compliance := report.Violations[2]
+	assert.Equal(t, "new", compliance.Trend)
+	assert.Empty(t, compliance.History)
+
+	assert.EqualError(t, report.Err(), "3 of 5 tests over duration budget: network/TestNetworkConfigurationValidation, performance/TestGWLBPerformance, compliance/TestPCIDSSCompliance")
+	assert.Contains(t, report.Markdown(), "| TestNetworkConfigurationValidation | network | 3m0s | 2m0s | +50% | test:network/TestNetworkConfigurationValidation | slower | 1/3 |")
+
+	budgets.Enforce = false
+	assert.NoError(t, budgets.Evaluate(run, nil).Err(), "warn-only budgets never fail CI")
+}
+
+func TestEvaluateBudgetsWithinBudget(t *testing.T) {
+	budgets, err := reporting.ParseBudgets([]byte(testBudgets))
+	require.NoError(t, err)
+
+	run := newAnalytics(reporting.TestSuiteResult{SuiteName: "nightly", RunID: "candidate", StartTime: time.Now(), Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Package: "network", Category: "unit", Status: "PASS", Duration: time.Minute},
+	}})
+	report := budgets.Evaluate(run, nil)
+	assert.NoError(t, report.Err())
+	assert.Contains(t, report.Markdown(), "All 1 checked tests are within budget.")
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"enforce": true, "checked": 1, "violations": []}`, string(data))
+}
+
+func TestLoadBudgetsFile(t *testing.T) {
+	budgets, err := reporting.LoadBudgets(filepath.Join("..", reporting.BudgetsFileName))
+	require.NoError(t, err)
+	budget, _, ok := budgets.BudgetFor(reporting.TestResult{TestName: "TestEndToEndPerformance", Package: "performance", Category: "performance"})
+	require.True(t, ok)
+	assert.Equal(t, 90*time.Minute, budget)
+
+	filename := filepath.Join(t.TempDir(), reporting.BudgetsFileName)
+	require.NoError(t, os.WriteFile(filename, []byte(`{"default": "-1m"}`), 0644))
+	_, err = reporting.LoadBudgets(filename)
+	assert.ErrorContains(t, err, "invalid budget file")
+}
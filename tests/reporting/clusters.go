@@ -0,0 +1,111 @@
+package reporting
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FailureCluster represents a group of failures sharing a normalized error signature
+type FailureCluster struct {
+	ID        string   `json:"id"`
+	Signature string   `json:"signature"`
+	Count     int      `json:"count"`
+	Tests     []string `json:"tests"`
+	Packages  []string `json:"packages"`
+	Message   string   `json:"message"` // representative, un-normalized message
+}
+
+// signatureReplacements normalizes the volatile parts of an error message, in order.
+// ARNs go first since they embed account IDs, regions and resource IDs.
+var signatureReplacements = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`arn:aws[\w-]*:[^\s"'\],;)]+`), "<arn>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<timestamp>"},
+	{regexp.MustCompile(`(?i)(request[ _-]?id:?\s*)[\w-]+`), "${1}<request-id>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b(vpc|subnet|tgw-attach|tgw-rtb|tgw|rtb|igw|nat|eni|sg|acl|vpce|eipalloc|ami|vol|snap|lt|pl|i)-[0-9a-f]{5,17}\b`), "$1-<id>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(/\d{1,2})?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<addr>"},
+	// time.Duration.String() values such as 1h2m3.5s, 5m0s and 1.5µs
+	{regexp.MustCompile(`-?\b(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+\b`), "<duration>"},
+	{regexp.MustCompile(`\b\d+\b`), "<n>"},
+}
+
+// NormalizeErrorSignature strips ARNs, resource IDs, timestamps, request IDs and
+// other volatile values from an error message so that failures with the same root
+// cause produce the same signature
+func NormalizeErrorSignature(message string) string {
+	signature := message
+	for _, r := range signatureReplacements {
+		signature = r.pattern.ReplaceAllString(signature, r.replacement)
+	}
+	return strings.Join(strings.Fields(signature), " ")
+}
+
+// failureMessage returns the message used to cluster a failed test
+func failureMessage(result TestResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	return errorFromOutput(result.Output)
+}
+
+// GetFailureClusters groups failed tests by normalized error signature, largest cluster first
+func (ta *TestAnalytics) GetFailureClusters() []FailureCluster {
+	clusters := make(map[string]*FailureCluster)
+	tests := make(map[string]map[string]bool)
+	packages := make(map[string]map[string]bool)
+
+	for _, result := range ta.GetFailedTests() {
+		message := failureMessage(result)
+		signature := NormalizeErrorSignature(message)
+
+		cluster, ok := clusters[signature]
+		if !ok {
+			sum := sha1.Sum([]byte(signature))
+			cluster = &FailureCluster{
+				ID:        hex.EncodeToString(sum[:])[:10],
+				Signature: signature,
+				Message:   message,
+			}
+			clusters[signature] = cluster
+			tests[signature] = make(map[string]bool)
+			packages[signature] = make(map[string]bool)
+		}
+
+		cluster.Count++
+		tests[signature][result.TestName] = true
+		packages[signature][result.Package] = true
+	}
+
+	result := make([]FailureCluster, 0, len(clusters))
+	for signature, cluster := range clusters {
+		cluster.Tests = sortedKeys(tests[signature])
+		cluster.Packages = sortedKeys(packages[signature])
+		result = append(result, *cluster)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Signature < result[j].Signature
+	})
+
+	return result
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
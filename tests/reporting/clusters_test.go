@@ -0,0 +1,72 @@
+package reporting_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestNormalizeErrorSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "arn and request id",
+			message:  "AccessDenied: User arn:aws:iam::123456789012:user/ci is not authorized\n\tstatus code: 403, request id: 5f1c2a3b-1111-2222-3333-444455556666",
+			expected: "AccessDenied: User <arn> is not authorized status code: <n>, request id: <request-id>",
+		},
+		{
+			name:     "resource ids and timestamps",
+			message:  "2024-05-01T10:00:00Z timeout waiting for tgw-attach-0a1b2c3d4e5f in vpc-12345 after 5m0s",
+			expected: "<timestamp> timeout waiting for tgw-attach-<id> in vpc-<id> after <duration>",
+		},
+		{
+			name:     "durations",
+			message:  "timed out after 1h2m3.5s (limit 30m0s), retried in 250ms, 1.5µs and 2us, took -3s",
+			expected: "timed out after <duration> (limit <duration>), retried in <duration>, <duration> and <duration>, took <duration>",
+		},
+		{
+			name:     "ip addresses",
+			message:  "dial tcp 10.0.20.15:443: i/o timeout",
+			expected: "dial tcp <ip>:<n>: i/o timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, reporting.NormalizeErrorSignature(tt.message))
+		})
+	}
+}
+
+func TestGetFailureClusters(t *testing.T) {
+	analytics := newAnalytics(reporting.TestSuiteResult{SuiteName: "regional-outage", Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Package: "network", Status: "FAIL",
+			Error: "RequestLimitExceeded: Request limit exceeded. request id: aaaa-1111"},
+		{TestName: "TestGWLBFailure", Package: "chaos", Status: "FAIL",
+			Error: "RequestLimitExceeded: Request limit exceeded. request id: bbbb-2222"},
+		{TestName: "TestInspectionProvisioning", Package: "inspection", Status: "FAIL",
+			Output: "    inspection_test.go:42: RequestLimitExceeded: Request limit exceeded. request id: cccc-3333\n"},
+		{TestName: "TestVMSeriesBootstrap", Package: "firewall-vmseries", Status: "FAIL",
+			Error: "bootstrap bucket vmseries-bootstrap-dev not found"},
+		{TestName: "TestNetworkIdempotency", Package: "network", Status: "PASS"},
+	}})
+
+	clusters := analytics.GetFailureClusters()
+	require.Len(t, clusters, 2)
+	assert.Equal(t, 3, clusters[0].Count)
+	assert.Equal(t, []string{"chaos", "inspection", "network"}, clusters[0].Packages)
+	assert.Equal(t, []string{"TestGWLBFailure", "TestInspectionProvisioning", "TestNetworkProvisioning"}, clusters[0].Tests)
+	assert.Contains(t, clusters[0].Message, "RequestLimitExceeded")
+	assert.Equal(t, 1, clusters[1].Count)
+
+	report, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, report, "## Failure Clusters")
+	assert.Contains(t, report, "| 3 | `RequestLimitExceeded: Request limit exceeded. request id: <request-id>` |")
+}
@@ -0,0 +1,257 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDurationRegressionPercent is the slowdown above which a test is reported as a duration regression
+	DefaultDurationRegressionPercent = 20.0
+
+	// DefaultMinRegressionDuration ignores slowdowns of tests faster than this in both runs, which are mostly noise
+	DefaultMinRegressionDuration = time.Second
+)
+
+// CompareOptions configures CompareRunsWithOptions
+type CompareOptions struct {
+	DurationRegressionPercent float64       `json:"duration_regression_percent"` // report tests that slowed down by more than this percentage
+	MinDuration               time.Duration `json:"min_duration"`                // ignore tests shorter than this in both runs
+}
+
+// TestChange represents a test whose outcome differs between two runs
+type TestChange struct {
+	TestName        string `json:"test_name"`
+	Package         string `json:"package"`
+	BaselineStatus  string `json:"baseline_status,omitempty"`
+	CandidateStatus string `json:"candidate_status,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// DurationRegression represents a test that got slower between two runs
+type DurationRegression struct {
+	TestName          string        `json:"test_name"`
+	Package           string        `json:"package"`
+	BaselineDuration  time.Duration `json:"baseline_duration"`
+	CandidateDuration time.Duration `json:"candidate_duration"`
+	IncreasePercent   float64       `json:"increase_percent"`
+}
+
+// RunComparison represents the differences between a baseline and a candidate run
+type RunComparison struct {
+	Baseline            ReportSummary        `json:"baseline"`
+	Candidate           ReportSummary        `json:"candidate"`
+	Options             CompareOptions       `json:"options"`
+	NewlyFailing        []TestChange         `json:"newly_failing"`
+	NewlyPassing        []TestChange         `json:"newly_passing"`
+	NewlySkipped        []TestChange         `json:"newly_skipped"`
+	Added               []TestChange         `json:"added"`
+	Removed             []TestChange         `json:"removed"`
+	DurationRegressions []DurationRegression `json:"duration_regressions"`
+}
+
+// CompareRuns compares a candidate run against a baseline using the default options
+func CompareRuns(baseline, candidate *TestAnalytics) *RunComparison {
+	return CompareRunsWithOptions(baseline, candidate, CompareOptions{
+		DurationRegressionPercent: DefaultDurationRegressionPercent,
+		MinDuration:               DefaultMinRegressionDuration,
+	})
+}
+
+// CompareRunsWithOptions compares a candidate run against a baseline. When a run
+// contains the same test more than once, its latest result is used.
+func CompareRunsWithOptions(baseline, candidate *TestAnalytics, opts CompareOptions) *RunComparison {
+	comparison := &RunComparison{
+		Baseline:            baseline.Summary(),
+		Candidate:           candidate.Summary(),
+		Options:             opts,
+		NewlyFailing:        []TestChange{},
+		NewlyPassing:        []TestChange{},
+		NewlySkipped:        []TestChange{},
+		Added:               []TestChange{},
+		Removed:             []TestChange{},
+		DurationRegressions: []DurationRegression{},
+	}
+
+	before := latestResults(baseline)
+	after := latestResults(candidate)
+
+	for key, current := range after {
+		previous, ok := before[key]
+		change := TestChange{
+			TestName:        current.TestName,
+			Package:         current.Package,
+			BaselineStatus:  previous.Status,
+			CandidateStatus: current.Status,
+		}
+		if current.Status == "FAIL" {
+			change.Error = failureMessage(current)
+		}
+
+		if !ok {
+			comparison.Added = append(comparison.Added, change)
+			continue
+		}
+
+		if previous.Status != current.Status {
+			switch current.Status {
+			case "FAIL":
+				comparison.NewlyFailing = append(comparison.NewlyFailing, change)
+			case "PASS":
+				comparison.NewlyPassing = append(comparison.NewlyPassing, change)
+			case "SKIP":
+				comparison.NewlySkipped = append(comparison.NewlySkipped, change)
+			}
+		}
+
+		if previous.Duration > 0 && (previous.Duration >= opts.MinDuration || current.Duration >= opts.MinDuration) {
+			increase := float64(current.Duration-previous.Duration) / float64(previous.Duration) * 100
+			if increase > opts.DurationRegressionPercent {
+				comparison.DurationRegressions = append(comparison.DurationRegressions, DurationRegression{
+					TestName:          current.TestName,
+					Package:           current.Package,
+					BaselineDuration:  previous.Duration,
+					CandidateDuration: current.Duration,
+					IncreasePercent:   increase,
+				})
+			}
+		}
+	}
+
+	for key, previous := range before {
+		if _, ok := after[key]; !ok {
+			comparison.Removed = append(comparison.Removed, TestChange{
+				TestName:       previous.TestName,
+				Package:        previous.Package,
+				BaselineStatus: previous.Status,
+			})
+		}
+	}
+
+	for _, changes := range [][]TestChange{
+		comparison.NewlyFailing, comparison.NewlyPassing, comparison.NewlySkipped, comparison.Added, comparison.Removed,
+	} {
+		sortTestChanges(changes)
+	}
+	sort.Slice(comparison.DurationRegressions, func(i, j int) bool {
+		return comparison.DurationRegressions[i].IncreasePercent > comparison.DurationRegressions[j].IncreasePercent
+	})
+
+	return comparison
+}
+
+// latestResults indexes a run's results by package and test name, keeping the latest attempt
+func latestResults(ta *TestAnalytics) map[[2]string]TestResult {
+	latest := make(map[[2]string]TestResult)
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			key := [2]string{result.Package, result.TestName}
+			if previous, ok := latest[key]; ok && result.Timestamp.Before(previous.Timestamp) {
+				continue
+			}
+			latest[key] = result
+		}
+	}
+	return latest
+}
+
+// sortTestChanges orders changes by package, then test name
+func sortTestChanges(changes []TestChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].TestName < changes[j].TestName
+	})
+}
+
+// HasRegressions reports whether the candidate has newly failing tests or duration regressions
+func (rc *RunComparison) HasRegressions() bool {
+	return len(rc.NewlyFailing) > 0 || len(rc.DurationRegressions) > 0
+}
+
+// JSON renders the comparison as indented JSON
+func (rc *RunComparison) JSON() (string, error) {
+	data, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Markdown renders the comparison as Markdown suitable for a pull request comment
+func (rc *RunComparison) Markdown() string {
+	var md strings.Builder
+
+	md.WriteString("## Test Run Comparison\n\n")
+	md.WriteString("| | Baseline | Candidate |\n")
+	md.WriteString("|---|---|---|\n")
+	md.WriteString(fmt.Sprintf("| Tests | %d | %d |\n", rc.Baseline.Tests, rc.Candidate.Tests))
+	md.WriteString(fmt.Sprintf("| Passed | %d | %d |\n", rc.Baseline.Passed, rc.Candidate.Passed))
+	md.WriteString(fmt.Sprintf("| Failed | %d | %d |\n", rc.Baseline.Failed, rc.Candidate.Failed))
+	md.WriteString(fmt.Sprintf("| Skipped | %d | %d |\n", rc.Baseline.Skipped, rc.Candidate.Skipped))
+	md.WriteString(fmt.Sprintf("| Pass Rate | %.1f%% | %.1f%% |\n\n", rc.Baseline.PassRate, rc.Candidate.PassRate))
+
+	if !rc.HasRegressions() && len(rc.NewlyPassing) == 0 && len(rc.NewlySkipped) == 0 &&
+		len(rc.Added) == 0 && len(rc.Removed) == 0 {
+		md.WriteString("No test changes between baseline and candidate.\n")
+		return md.String()
+	}
+
+	writeChanges := func(title string, changes []TestChange, withError bool) {
+		if len(changes) == 0 {
+			return
+		}
+		md.WriteString(fmt.Sprintf("### %s (%d)\n\n", title, len(changes)))
+		if withError {
+			md.WriteString("| Test Name | Package | Was | Error |\n")
+			md.WriteString("|-----------|---------|-----|-------|\n")
+		} else {
+			md.WriteString("| Test Name | Package | Was | Now |\n")
+			md.WriteString("|-----------|---------|-----|-----|\n")
+		}
+		for _, change := range changes {
+			last := change.CandidateStatus
+			if withError {
+				last = markdownCell(change.Error)
+			}
+			md.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+				change.TestName, change.Package, orDash(change.BaselineStatus), orDash(last)))
+		}
+		md.WriteString("\n")
+	}
+
+	writeChanges("Newly Failing", rc.NewlyFailing, true)
+	writeChanges("Newly Passing", rc.NewlyPassing, false)
+	writeChanges("Newly Skipped", rc.NewlySkipped, false)
+	writeChanges("Added Tests", rc.Added, false)
+	writeChanges("Removed Tests", rc.Removed, false)
+
+	if len(rc.DurationRegressions) > 0 {
+		md.WriteString(fmt.Sprintf("### Duration Regressions over %.0f%% (%d)\n\n",
+			rc.Options.DurationRegressionPercent, len(rc.DurationRegressions)))
+		md.WriteString("| Test Name | Package | Baseline | Candidate | Change |\n")
+		md.WriteString("|-----------|---------|----------|-----------|--------|\n")
+		for _, regression := range rc.DurationRegressions {
+			md.WriteString(fmt.Sprintf("| %s | %s | %v | %v | +%.1f%% |\n",
+				regression.TestName, regression.Package,
+				regression.BaselineDuration.Round(time.Millisecond),
+				regression.CandidateDuration.Round(time.Millisecond),
+				regression.IncreasePercent))
+		}
+		md.WriteString("\n")
+	}
+
+	return md.String()
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
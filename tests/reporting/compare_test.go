@@ -0,0 +1,73 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestCompareRuns(t *testing.T) {
+	baseline := newAnalytics(reporting.TestSuiteResult{SuiteName: "inspection", Results: []reporting.TestResult{
+		{TestName: "TestInspectionProvisioning", Package: "inspection", Status: "PASS", Duration: 10 * time.Second},
+		{TestName: "TestGWLBConfiguration", Package: "inspection", Status: "FAIL", Duration: 5 * time.Second},
+		{TestName: "TestInspectionIdempotency", Package: "inspection", Status: "PASS", Duration: 20 * time.Second},
+		{TestName: "TestInspectionSymmetricRouting", Package: "inspection", Status: "PASS", Duration: 30 * time.Second},
+		{TestName: "TestLegacy", Package: "inspection", Status: "PASS", Duration: 100 * time.Millisecond},
+		{TestName: "TestFast", Package: "inspection", Status: "PASS", Duration: 100 * time.Millisecond},
+	}})
+	candidate := newAnalytics(reporting.TestSuiteResult{SuiteName: "inspection", Results: []reporting.TestResult{
+		{TestName: "TestInspectionProvisioning", Package: "inspection", Status: "FAIL", Duration: 10 * time.Second, Error: "GWLB endpoint missing"},
+		{TestName: "TestGWLBConfiguration", Package: "inspection", Status: "PASS", Duration: 5 * time.Second},
+		{TestName: "TestInspectionIdempotency", Package: "inspection", Status: "SKIP"},
+		{TestName: "TestInspectionSymmetricRouting", Package: "inspection", Status: "PASS", Duration: 45 * time.Second},
+		{TestName: "TestFast", Package: "inspection", Status: "PASS", Duration: 300 * time.Millisecond},
+		{TestName: "TestInspectionHA", Package: "inspection", Status: "PASS", Duration: time.Second},
+	}})
+
+	comparison := reporting.CompareRuns(baseline, candidate)
+
+	require.Len(t, comparison.NewlyFailing, 1)
+	assert.Equal(t, "TestInspectionProvisioning", comparison.NewlyFailing[0].TestName)
+	assert.Equal(t, "GWLB endpoint missing", comparison.NewlyFailing[0].Error)
+	require.Len(t, comparison.NewlyPassing, 1)
+	assert.Equal(t, "TestGWLBConfiguration", comparison.NewlyPassing[0].TestName)
+	require.Len(t, comparison.NewlySkipped, 1)
+	require.Len(t, comparison.Added, 1)
+	assert.Equal(t, "TestInspectionHA", comparison.Added[0].TestName)
+	require.Len(t, comparison.Removed, 1)
+	assert.Equal(t, "TestLegacy", comparison.Removed[0].TestName)
+
+	require.Len(t, comparison.DurationRegressions, 1, "sub-second slowdowns are ignored")
+	assert.Equal(t, "TestInspectionSymmetricRouting", comparison.DurationRegressions[0].TestName)
+	assert.InDelta(t, 50.0, comparison.DurationRegressions[0].IncreasePercent, 0.001)
+	assert.True(t, comparison.HasRegressions())
+
+	strict := reporting.CompareRunsWithOptions(baseline, candidate, reporting.CompareOptions{DurationRegressionPercent: 60})
+	assert.Len(t, strict.DurationRegressions, 1, "only TestFast slowed down by more than 60%")
+
+	markdown := comparison.Markdown()
+	assert.Contains(t, markdown, "### Newly Failing (1)")
+	assert.Contains(t, markdown, "| TestInspectionProvisioning | inspection | PASS | GWLB endpoint missing |")
+	assert.Contains(t, markdown, "| TestInspectionSymmetricRouting | inspection | 30s | 45s | +50.0% |")
+
+	data, err := comparison.JSON()
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &decoded))
+	assert.Len(t, decoded["removed"], 1)
+}
+
+func TestCompareRunsNoChanges(t *testing.T) {
+	run := newAnalytics(reporting.TestSuiteResult{SuiteName: "network", Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Status: "PASS"},
+	}})
+
+	comparison := reporting.CompareRuns(run, run)
+	assert.False(t, comparison.HasRegressions())
+	assert.Contains(t, comparison.Markdown(), "No test changes between baseline and candidate.")
+}
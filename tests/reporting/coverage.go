@@ -0,0 +1,221 @@
+package reporting
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// coverBlockPattern matches a coverprofile block line: file:startLine.startCol,endLine.endCol statements count
+var coverBlockPattern = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// coverBlock is a single block of a coverprofile
+type coverBlock struct {
+	file       string
+	statements int
+	count      int64
+}
+
+// coverProfile accumulates blocks from one or more coverprofiles
+type coverProfile struct {
+	mode   string
+	blocks map[string]*coverBlock
+}
+
+// ParseCoverProfile parses a profile written by `go test -coverprofile`
+func ParseCoverProfile(r io.Reader) (*CoverageInfo, error) {
+	profile := &coverProfile{blocks: make(map[string]*coverBlock)}
+	if err := profile.read(r); err != nil {
+		return nil, err
+	}
+	return profile.coverage(), nil
+}
+
+// ParseCoverProfiles parses and merges several coverprofiles, such as the ones
+// written per test directory by `make test-unit`. All profiles must use the same
+// mode. Blocks present in more than one profile have their counts summed in
+// count and atomic mode, and are covered if any profile covered them in set mode.
+func ParseCoverProfiles(filenames ...string) (*CoverageInfo, error) {
+	profile := &coverProfile{blocks: make(map[string]*coverBlock)}
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		err = profile.read(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverprofile %s: %w", filename, err)
+		}
+	}
+	return profile.coverage(), nil
+}
+
+// read merges a single profile into p
+func (p *coverProfile) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if lineNumber == 1 {
+			mode, ok := strings.CutPrefix(line, "mode: ")
+			if !ok {
+				return fmt.Errorf("missing mode line")
+			}
+			if p.mode != "" && p.mode != mode {
+				return fmt.Errorf("cannot merge %s profile into %s profile", mode, p.mode)
+			}
+			p.mode = mode
+			continue
+		}
+
+		match := coverBlockPattern.FindStringSubmatch(line)
+		if match == nil {
+			return fmt.Errorf("line %d: malformed block %q", lineNumber, line)
+		}
+		statements, _ := strconv.Atoi(match[6])
+		count, err := strconv.ParseInt(match[7], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid count: %w", lineNumber, err)
+		}
+
+		key := strings.Join(match[1:6], ":")
+		block, ok := p.blocks[key]
+		if !ok {
+			p.blocks[key] = &coverBlock{file: match[1], statements: statements, count: count}
+			continue
+		}
+		if p.mode == "set" {
+			if count > 0 {
+				block.count = 1
+			}
+		} else {
+			block.count += count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if lineNumber == 0 {
+		return fmt.Errorf("empty profile")
+	}
+	return nil
+}
+
+// coverage computes statement coverage overall, per file and per package
+func (p *coverProfile) coverage() *CoverageInfo {
+	covered := make(map[string]int)
+	statements := make(map[string]int)
+	for _, block := range p.blocks {
+		statements[block.file] += block.statements
+		if block.count > 0 {
+			covered[block.file] += block.statements
+		}
+	}
+
+	percentages := make(map[string]float64, len(statements))
+	for file, total := range statements {
+		percentages[file] = percentage(float64(covered[file]), total)
+	}
+	return coverageFromFiles(percentages, statements)
+}
+
+// coverageFromFiles computes the overall and per-package coverage from the
+// coverage and statement count of each file
+func coverageFromFiles(files map[string]float64, statements map[string]int) *CoverageInfo {
+	type tally struct {
+		covered float64
+		total   int
+	}
+	packages := make(map[string]*tally)
+	overall := tally{}
+
+	for file, filePercentage := range files {
+		pkg := path.Dir(file)
+		if packages[pkg] == nil {
+			packages[pkg] = &tally{}
+		}
+		for _, t := range []*tally{packages[pkg], &overall} {
+			t.total += statements[file]
+			t.covered += filePercentage / 100 * float64(statements[file])
+		}
+	}
+
+	info := &CoverageInfo{
+		Percentage:      percentage(overall.covered, overall.total),
+		Statements:      overall.total,
+		FileCoverage:    make(map[string]float64, len(files)),
+		FileStatements:  make(map[string]int, len(files)),
+		PackageCoverage: make(map[string]float64, len(packages)),
+	}
+	for file, filePercentage := range files {
+		info.FileCoverage[file] = filePercentage
+		info.FileStatements[file] = statements[file]
+	}
+	for pkg, t := range packages {
+		info.PackageCoverage[pkg] = percentage(t.covered, t.total)
+	}
+	return info
+}
+
+// percentage returns covered statements as a percentage of total, 0 for no statements
+func percentage(covered float64, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return covered / float64(total) * 100
+}
+
+// SetCoverage attaches coverage to the named suite
+func (ta *TestAnalytics) SetCoverage(suiteName string, coverage *CoverageInfo) error {
+	for i := range ta.Results {
+		if ta.Results[i].SuiteName == suiteName {
+			ta.Results[i].Coverage = coverage
+			return nil
+		}
+	}
+	return fmt.Errorf("suite not found: %s", suiteName)
+}
+
+// Coverage returns the coverage of all suites combined, or nil if no suite has coverage
+func (ta *TestAnalytics) Coverage() *CoverageInfo {
+	var combined *CoverageInfo
+	for _, suite := range ta.Results {
+		combined = mergeCoverage(combined, suite.Coverage)
+	}
+	return combined
+}
+
+// PackageCoverageEntry is a row of the per-package coverage table
+type PackageCoverageEntry struct {
+	Package    string  `json:"package"`
+	Percentage float64 `json:"percentage"`
+}
+
+// SortedPackages returns per-package coverage ordered by package path
+func (c *CoverageInfo) SortedPackages() []PackageCoverageEntry {
+	if c == nil {
+		return nil
+	}
+	entries := make([]PackageCoverageEntry, 0, len(c.PackageCoverage))
+	for pkg, percentage := range c.PackageCoverage {
+		entries = append(entries, PackageCoverageEntry{Package: pkg, Percentage: percentage})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Package < entries[j].Package
+	})
+	return entries
+}
@@ -0,0 +1,120 @@
+package reporting_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+const (
+	reportingProfile = `mode: atomic
+github.com/your-org/aws-centralized-inspection/tests/reporting/flaky.go:10.2,12.3 4 3
+github.com/your-org/aws-centralized-inspection/tests/reporting/flaky.go:14.2,15.3 2 0
+github.com/your-org/aws-centralized-inspection/tests/reporting/merge.go:20.2,25.3 4 1
+`
+	fixturesProfile = `mode: atomic
+github.com/your-org/aws-centralized-inspection/tests/fixtures/test_data.go:30.2,40.3 6 0
+github.com/your-org/aws-centralized-inspection/tests/fixtures/test_data.go:42.2,44.3 4 2
+github.com/your-org/aws-centralized-inspection/tests/reporting/flaky.go:14.2,15.3 2 5
+`
+)
+
+func writeProfile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+	return filename
+}
+
+func TestParseCoverProfile(t *testing.T) {
+	coverage, err := reporting.ParseCoverProfile(strings.NewReader(reportingProfile))
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, coverage.Statements)
+	assert.InDelta(t, 80.0, coverage.Percentage, 0.001)
+	assert.InDelta(t, float64(4)/float64(6)*100, coverage.FileCoverage["github.com/your-org/aws-centralized-inspection/tests/reporting/flaky.go"], 0.001)
+	assert.InDelta(t, 100.0, coverage.FileCoverage["github.com/your-org/aws-centralized-inspection/tests/reporting/merge.go"], 0.001)
+	assert.InDelta(t, 80.0, coverage.PackageCoverage["github.com/your-org/aws-centralized-inspection/tests/reporting"], 0.001)
+}
+
+func TestParseCoverProfilesMergesAtomicProfiles(t *testing.T) {
+	dir := t.TempDir()
+	coverage, err := reporting.ParseCoverProfiles(
+		writeProfile(t, dir, "reporting.out", reportingProfile),
+		writeProfile(t, dir, "fixtures.out", fixturesProfile),
+	)
+	require.NoError(t, err)
+
+	// The flaky.go block uncovered in the first profile is covered by the second
+	assert.Equal(t, 20, coverage.Statements)
+	assert.InDelta(t, 70.0, coverage.Percentage, 0.001)
+	assert.InDelta(t, 100.0, coverage.PackageCoverage["github.com/your-org/aws-centralized-inspection/tests/reporting"], 0.001)
+	assert.InDelta(t, 40.0, coverage.PackageCoverage["github.com/your-org/aws-centralized-inspection/tests/fixtures"], 0.001)
+
+	packages := coverage.SortedPackages()
+	require.Len(t, packages, 2)
+	assert.Equal(t, "github.com/your-org/aws-centralized-inspection/tests/fixtures", packages[0].Package)
+}
+
+func TestParseCoverProfilesRejectsInvalidProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := reporting.ParseCoverProfiles(
+		writeProfile(t, dir, "atomic.out", reportingProfile),
+		writeProfile(t, dir, "set.out", "mode: set\nexample.com/pkg/a.go:1.1,2.2 1 1\n"),
+	)
+	assert.ErrorContains(t, err, "cannot merge set profile into atomic profile")
+
+	_, err = reporting.ParseCoverProfile(strings.NewReader("mode: atomic\nnot a block\n"))
+	assert.ErrorContains(t, err, "malformed block")
+
+	_, err = reporting.ParseCoverProfile(strings.NewReader("example.com/pkg/a.go:1.1,2.2 1 1\n"))
+	assert.ErrorContains(t, err, "missing mode line")
+}
+
+func TestCoverageInReports(t *testing.T) {
+	coverage, err := reporting.ParseCoverProfile(strings.NewReader(reportingProfile))
+	require.NoError(t, err)
+
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(reporting.TestSuiteResult{SuiteName: "reporting"})
+	require.NoError(t, analytics.SetCoverage("reporting", coverage))
+	assert.Error(t, analytics.SetCoverage("missing", coverage))
+
+	markdown, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "**Total**: 80.0% of 10 statements")
+	assert.Contains(t, markdown, "| github.com/your-org/aws-centralized-inspection/tests/reporting | 80.0% |")
+
+	html, err := analytics.GenerateReport("html")
+	require.NoError(t, err)
+	assert.Contains(t, html, "<tr><td>github.com/your-org/aws-centralized-inspection/tests/reporting</td><td>80.0%</td></tr>")
+}
+
+func TestCoverageOfOverlappingShards(t *testing.T) {
+	shard := func(name string, files map[string]float64, statements map[string]int) reporting.TestSuiteResult {
+		return reporting.TestSuiteResult{SuiteName: name, Coverage: &reporting.CoverageInfo{FileCoverage: files, FileStatements: statements}}
+	}
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(shard("shard-1",
+		map[string]float64{"example.com/pkg/a.go": 50, "example.com/pkg/b.go": 20},
+		map[string]int{"example.com/pkg/a.go": 10, "example.com/pkg/b.go": 10}))
+	analytics.AddResult(shard("shard-2",
+		map[string]float64{"example.com/pkg/a.go": 70},
+		map[string]int{"example.com/pkg/a.go": 10}))
+
+	// Both shards ran over a.go: its statements count once, at the best coverage,
+	// and the totals agree with the files
+	coverage := analytics.Coverage()
+	require.NotNil(t, coverage)
+	assert.Equal(t, 20, coverage.Statements)
+	assert.InDelta(t, 70.0, coverage.FileCoverage["example.com/pkg/a.go"], 0.001)
+	assert.InDelta(t, 45.0, coverage.Percentage, 0.001)
+	assert.InDelta(t, 45.0, coverage.PackageCoverage["example.com/pkg"], 0.001)
+}
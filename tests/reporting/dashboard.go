@@ -0,0 +1,242 @@
+package reporting
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// dashboardFS holds the dashboard template and its stylesheet and script, which are
+// inlined into every dashboard so it renders without network access
+//
+//go:embed dashboard
+var dashboardFS embed.FS
+
+// Dashboard chart geometry in SVG user units
+const (
+	dashboardChartWidth  = 600
+	dashboardChartHeight = 200
+	dashboardChartAxis   = 40 // left space for axis labels
+	dashboardChartMargin = 20
+	dashboardChartLines  = 4
+)
+
+// dashboardChart is a line chart of one value per run
+type dashboardChart struct {
+	Title  string
+	Width  int
+	Height int
+	Left   int
+	Right  int
+	Line   string
+	Points []dashboardPoint
+	Grid   []dashboardPoint
+}
+
+// dashboardPoint is a labelled position on a chart
+type dashboardPoint struct {
+	X     float64
+	Y     float64
+	Label string
+}
+
+// dashboardCategory is a row of the per-category breakdown
+type dashboardCategory struct {
+	Name           string
+	Total          int
+	Passed         int
+	Failed         int
+	Skipped        int
+	PassedPercent  float64
+	FailedPercent  float64
+	SkippedPercent float64
+}
+
+// dashboardTest is a test result together with the suite it ran in
+type dashboardTest struct {
+	Suite string
+	TestResult
+}
+
+// dashboardData is the data rendered by the dashboard template
+type dashboardData struct {
+	GeneratedAt   time.Time
+	Summary       ReportSummary
+	Charts        []dashboardChart
+	Categories    []dashboardCategory
+	Clusters      []FailureCluster
+	Packages      []string
+	Statuses      []string
+	CategoryNames []string
+	Owners        []string
+	Tests         []dashboardTest
+	CSS           template.CSS
+	JS            template.JS
+}
+
+// GenerateDashboard renders a self-contained interactive HTML dashboard. Trend
+// charts are drawn from history, for example runs loaded with LoadHistory; when
+// history is nil the analytics' own runs are used.
+func (ta *TestAnalytics) GenerateDashboard(history *TestAnalytics) (string, error) {
+	tmpl, err := template.New("dashboard.html").Funcs(template.FuncMap{
+		"lower": strings.ToLower,
+		"join":  strings.Join,
+	}).ParseFS(dashboardFS, "dashboard/dashboard.html")
+	if err != nil {
+		return "", err
+	}
+	css, err := dashboardFS.ReadFile("dashboard/dashboard.css")
+	if err != nil {
+		return "", err
+	}
+	js, err := dashboardFS.ReadFile("dashboard/dashboard.js")
+	if err != nil {
+		return "", err
+	}
+
+	if history == nil {
+		history = ta
+	}
+
+	data := dashboardData{
+		GeneratedAt: time.Now(),
+		Summary:     ta.Summary(),
+		Charts:      dashboardTrendCharts(history.runSummaries()),
+		Categories:  ta.dashboardCategories(),
+		Clusters:    ta.GetFailureClusters(),
+		CSS:         template.CSS(css),
+		JS:          template.JS(js),
+	}
+
+	packages := make(map[string]bool)
+	statuses := make(map[string]bool)
+	categories := make(map[string]bool)
+	owners := make(map[string]bool)
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			data.Tests = append(data.Tests, dashboardTest{Suite: suite.SuiteName, TestResult: result})
+			packages[result.Package] = true
+			statuses[result.Status] = true
+			categories[result.Category] = true
+			if result.Owner != "" {
+				owners[result.Owner] = true
+			}
+		}
+	}
+	data.Packages = sortedKeys(packages)
+	data.Statuses = sortedKeys(statuses)
+	data.CategoryNames = sortedKeys(categories)
+	data.Owners = sortedKeys(owners)
+
+	var html strings.Builder
+	if err := tmpl.Execute(&html, data); err != nil {
+		return "", err
+	}
+	return html.String(), nil
+}
+
+// generateDashboardReport generates the dashboard with trends from the analytics' own runs
+func (ta *TestAnalytics) generateDashboardReport() (string, error) {
+	return ta.GenerateDashboard(nil)
+}
+
+// dashboardCategories converts GetMetrics' category_stats into breakdown rows
+func (ta *TestAnalytics) dashboardCategories() []dashboardCategory {
+	stats := ta.GetMetrics()["category_stats"].(map[string]map[string]int)
+	categories := make([]dashboardCategory, 0, len(stats))
+	for _, name := range sortedKeys(stats) {
+		counts := stats[name]
+		category := dashboardCategory{
+			Name:    name,
+			Total:   counts["total"],
+			Passed:  counts["passed"],
+			Failed:  counts["failed"],
+			Skipped: counts["skipped"],
+		}
+		if category.Total > 0 {
+			category.PassedPercent = float64(category.Passed) / float64(category.Total) * 100
+			category.FailedPercent = float64(category.Failed) / float64(category.Total) * 100
+			category.SkippedPercent = float64(category.Skipped) / float64(category.Total) * 100
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// dashboardTrendCharts builds the pass rate and duration charts, one point per run
+func dashboardTrendCharts(runs []runSummary) []dashboardChart {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	passRates := make([]float64, len(runs))
+	durations := make([]float64, len(runs))
+	labels := make([]string, len(runs))
+	maxDuration := 0.0
+	for i, run := range runs {
+		if run.TotalTests > 0 {
+			passRates[i] = float64(run.PassedTests) / float64(run.TotalTests) * 100
+		}
+		durations[i] = run.Duration.Minutes()
+		if durations[i] > maxDuration {
+			maxDuration = durations[i]
+		}
+
+		labels[i] = run.RunID
+		if labels[i] == "" {
+			labels[i] = fmt.Sprintf("run %d", i+1)
+		}
+		if !run.StartTime.IsZero() {
+			labels[i] += " (" + run.StartTime.Format("2006-01-02 15:04") + ")"
+		}
+	}
+	if maxDuration == 0 {
+		maxDuration = 1
+	}
+
+	passRateChart := newDashboardChart("Pass Rate (%)", passRates, labels, 100, func(v float64) string {
+		return fmt.Sprintf("%.1f%%", v)
+	})
+	durationChart := newDashboardChart("Duration (minutes)", durations, labels, maxDuration, func(v float64) string {
+		return fmt.Sprintf("%.1fm", v)
+	})
+	return []dashboardChart{passRateChart, durationChart}
+}
+
+// newDashboardChart scales values into SVG coordinates, with y=0 at the bottom
+func newDashboardChart(title string, values []float64, labels []string, maxValue float64, format func(float64) string) dashboardChart {
+	chart := dashboardChart{
+		Title:  title,
+		Width:  dashboardChartWidth,
+		Height: dashboardChartHeight,
+		Left:   dashboardChartAxis,
+		Right:  dashboardChartWidth - dashboardChartMargin,
+	}
+
+	plotWidth := float64(chart.Right - chart.Left)
+	plotHeight := float64(dashboardChartHeight - 2*dashboardChartMargin)
+	y := func(value float64) float64 {
+		return float64(dashboardChartHeight-dashboardChartMargin) - value/maxValue*plotHeight
+	}
+
+	for i := 0; i <= dashboardChartLines; i++ {
+		value := maxValue * float64(i) / dashboardChartLines
+		chart.Grid = append(chart.Grid, dashboardPoint{Y: y(value), Label: format(value)})
+	}
+
+	points := make([]string, 0, len(values))
+	for i, value := range values {
+		x := float64(chart.Left) + plotWidth/2
+		if len(values) > 1 {
+			x = float64(chart.Left) + plotWidth*float64(i)/float64(len(values)-1)
+		}
+		point := dashboardPoint{X: x, Y: y(value), Label: labels[i] + ": " + format(value)}
+		chart.Points = append(chart.Points, point)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", point.X, point.Y))
+	}
+	chart.Line = strings.Join(points, " ")
+
+	return chart
+}
@@ -0,0 +1,30 @@
+// Filters the test table by the selected package, status, category and owner
+(function () {
+  var filters = document.querySelectorAll('select[data-filter]');
+  var rows = document.querySelectorAll('tr.test');
+  var counter = document.getElementById('visible-count');
+
+  function apply() {
+    var visible = 0;
+    rows.forEach(function (row) {
+      var show = true;
+      filters.forEach(function (filter) {
+        if (filter.value && row.dataset[filter.dataset.filter] !== filter.value) {
+          show = false;
+        }
+      });
+      row.hidden = !show;
+      if (show) {
+        visible++;
+      }
+    });
+    if (counter) {
+      counter.textContent = visible;
+    }
+  }
+
+  filters.forEach(function (filter) {
+    filter.addEventListener('change', apply);
+  });
+  apply();
+})();
@@ -0,0 +1,78 @@
+package reporting_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestGenerateDashboard(t *testing.T) {
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	history := reporting.NewTestAnalytics()
+	for i, passed := range []int{4, 3, 4} {
+		history.AddResult(reporting.TestSuiteResult{
+			SuiteName:   "network",
+			RunID:       []string{"nightly-1", "nightly-2", "nightly-3"}[i],
+			StartTime:   start.Add(time.Duration(i) * 24 * time.Hour),
+			Duration:    time.Duration(10+i) * time.Minute,
+			TotalTests:  4,
+			PassedTests: passed,
+		})
+	}
+
+	analytics := newAnalytics(
+		reporting.TestSuiteResult{SuiteName: "inspection", Results: []reporting.TestResult{
+			{TestName: "TestGWLBConfiguration", Category: "unit", Status: "PASS", Duration: time.Second},
+			{TestName: "TestInspectionProvisioning", Category: "unit", Status: "FAIL",
+				Error: "timeout waiting for <gwlbe>", Output: "inspection_test.go:42: <script>alert(1)</script>"},
+		}},
+		reporting.TestSuiteResult{SuiteName: "compliance", Results: []reporting.TestResult{
+			{TestName: "TestPCIDSS", Category: "compliance", Status: "SKIP"},
+		}},
+	)
+
+	dashboard, err := analytics.GenerateDashboard(history)
+	require.NoError(t, err)
+
+	// Self-contained: no external stylesheets, scripts or fonts
+	assert.NotContains(t, dashboard, "http://")
+	assert.NotContains(t, dashboard, "https://")
+	assert.NotContains(t, dashboard, "<link")
+	assert.NotContains(t, dashboard, "src=")
+	assert.Contains(t, dashboard, ".stacked .pass")
+	assert.Contains(t, dashboard, "addEventListener('change', apply)")
+
+	// Trend charts have one point per stored run
+	assert.Equal(t, 2, strings.Count(dashboard, "<polyline"))
+	assert.Equal(t, 6, strings.Count(dashboard, "<circle"))
+	assert.Contains(t, dashboard, "<title>nightly-2 (2024-03-02 02:00): 75.0%</title>")
+	assert.Contains(t, dashboard, "<title>nightly-3 (2024-03-03 02:00): 12.0m</title>")
+
+	// Category breakdown from category_stats
+	assert.Contains(t, dashboard, `title="1 passed, 1 failed, 0 skipped"`)
+	assert.Contains(t, dashboard, `style="width: 50.0%"`)
+
+	// Filters and filterable rows
+	assert.Contains(t, dashboard, `<select data-filter="package"><option value="">All</option><option>compliance</option><option>inspection</option></select>`)
+	assert.Contains(t, dashboard, `<tr class="test fail" data-package="inspection" data-status="FAIL" data-category="unit">`)
+
+	// Expandable, escaped output
+	assert.Contains(t, dashboard, "<details><summary>timeout waiting for &lt;gwlbe&gt;</summary><pre>inspection_test.go:42: &lt;script&gt;alert(1)&lt;/script&gt;</pre></details>")
+	assert.NotContains(t, dashboard, "<script>alert(1)</script>")
+
+	// Failure clusters
+	assert.Contains(t, dashboard, "<h2>Failure Clusters</h2>\n        <table>")
+}
+
+func TestGenerateDashboardWithoutHistory(t *testing.T) {
+	dashboard, err := reporting.NewTestAnalytics().GenerateReport("dashboard")
+	require.NoError(t, err)
+	assert.Contains(t, dashboard, "No run history recorded yet.")
+	assert.Contains(t, dashboard, "No test results.")
+	assert.Contains(t, dashboard, "No failures.")
+}
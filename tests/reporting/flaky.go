@@ -0,0 +1,384 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// QuarantineFileName is the machine-readable quarantine list inside the report directory
+	QuarantineFileName = "quarantine.json"
+
+	// QuarantineSkipFileName holds the quarantine as `go test -skip`/`-run` patterns
+	// for the Makefile, one "<package> <pattern>" line per package
+	QuarantineSkipFileName = "quarantine.skip"
+
+	// QuarantineAnyPackage is the package of skip lines for entries without a package
+	QuarantineAnyPackage = "*"
+)
+
+// FlakyTest represents the flakiness score of a single test across stored runs
+type FlakyTest struct {
+	TestName             string  `json:"test_name"`
+	Package              string  `json:"package"`
+	Attempts             int     `json:"attempts"`
+	Passes               int     `json:"passes"`
+	Failures             int     `json:"failures"`
+	Flips                int     `json:"flips"`                  // PASS<->FAIL transitions in chronological order
+	FlipRate             float64 `json:"flip_rate"`              // flips per possible transition
+	MixedCommits         int     `json:"mixed_commits"`          // commits on which the test both passed and failed
+	RetryRecoveries      int     `json:"retry_recoveries"`       // runs where a failure passed on retry
+	StableFailureCommits int     `json:"stable_failure_commits"` // commits where every (repeated) attempt failed
+	Score                float64 `json:"score"`                  // 0 (stable) to 1 (always flaky)
+}
+
+// QuarantineEntry represents a quarantined test
+type QuarantineEntry struct {
+	TestName string  `json:"test_name"`
+	Package  string  `json:"package"`
+	Score    float64 `json:"score"`
+	Reason   string  `json:"reason"`
+}
+
+// Quarantine represents the list of known-flaky tests
+type Quarantine struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Threshold   float64           `json:"threshold"`
+	Tests       []QuarantineEntry `json:"tests"`
+}
+
+// flakyAttempt is one observed outcome of a test
+type flakyAttempt struct {
+	runID     string
+	gitSHA    string
+	status    string
+	timestamp time.Time
+}
+
+// GetFlakyTests scores every test that both passed and failed across the loaded
+// results and returns those scoring at or above the threshold, most flaky first.
+//
+// The score weighs three signals: how often the outcome flips between consecutive
+// attempts, how many failing commits also produced a pass, and how many failing
+// runs recovered on retry. It is then discounted by the share of failing commits
+// where repeated attempts failed consistently, since those point at a real bug.
+func (ta *TestAnalytics) GetFlakyTests(threshold float64) []FlakyTest {
+	attempts := make(map[[2]string][]flakyAttempt)
+
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			if result.Status != "PASS" && result.Status != "FAIL" {
+				continue
+			}
+
+			timestamp := result.Timestamp
+			if timestamp.IsZero() {
+				timestamp = suite.StartTime
+			}
+
+			key := [2]string{result.Package, result.TestName}
+			attempts[key] = append(attempts[key], flakyAttempt{
+				runID:     suite.RunID,
+				gitSHA:    suite.GitSHA,
+				status:    result.Status,
+				timestamp: timestamp,
+			})
+		}
+	}
+
+	var flaky []FlakyTest
+	for key, testAttempts := range attempts {
+		score := scoreFlakiness(key[0], key[1], testAttempts)
+		if score.Passes == 0 || score.Failures == 0 || score.Score < threshold {
+			continue
+		}
+		flaky = append(flaky, score)
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Score != flaky[j].Score {
+			return flaky[i].Score > flaky[j].Score
+		}
+		if flaky[i].Package != flaky[j].Package {
+			return flaky[i].Package < flaky[j].Package
+		}
+		return flaky[i].TestName < flaky[j].TestName
+	})
+
+	return flaky
+}
+
+// scoreFlakiness computes the flakiness signals for a single test
+func scoreFlakiness(pkg, testName string, attempts []flakyAttempt) FlakyTest {
+	sort.SliceStable(attempts, func(i, j int) bool {
+		return attempts[i].timestamp.Before(attempts[j].timestamp)
+	})
+
+	score := FlakyTest{
+		TestName: testName,
+		Package:  pkg,
+		Attempts: len(attempts),
+	}
+
+	byRun := make(map[string][]string)
+	byCommit := make(map[string][]string)
+	for i, attempt := range attempts {
+		if attempt.status == "PASS" {
+			score.Passes++
+		} else {
+			score.Failures++
+		}
+		if i > 0 && attempts[i-1].status != attempt.status {
+			score.Flips++
+		}
+
+		// Without run IDs or SHAs every attempt stands on its own
+		run := attempt.runID
+		if run == "" {
+			run = fmt.Sprintf("#%d", i)
+		}
+		commit := attempt.gitSHA
+		if commit == "" {
+			commit = run
+		}
+		byRun[run] = append(byRun[run], attempt.status)
+		byCommit[commit] = append(byCommit[commit], attempt.status)
+	}
+
+	if len(attempts) > 1 {
+		score.FlipRate = float64(score.Flips) / float64(len(attempts)-1)
+	}
+
+	failingRuns := 0
+	for _, statuses := range byRun {
+		failedAt := -1
+		for i, status := range statuses {
+			if status == "FAIL" && failedAt < 0 {
+				failedAt = i
+			}
+			if status == "PASS" && failedAt >= 0 {
+				score.RetryRecoveries++
+				break
+			}
+		}
+		if failedAt >= 0 {
+			failingRuns++
+		}
+	}
+
+	failingCommits := 0
+	for _, statuses := range byCommit {
+		passed, failed := 0, 0
+		for _, status := range statuses {
+			if status == "PASS" {
+				passed++
+			} else {
+				failed++
+			}
+		}
+		if failed == 0 {
+			continue
+		}
+		failingCommits++
+		if passed > 0 {
+			score.MixedCommits++
+		} else if failed > 1 {
+			score.StableFailureCommits++
+		}
+	}
+
+	if failingCommits == 0 {
+		return score
+	}
+
+	mixedRate := float64(score.MixedCommits) / float64(failingCommits)
+	retryRate := float64(score.RetryRecoveries) / float64(failingRuns)
+	stableRate := float64(score.StableFailureCommits) / float64(failingCommits)
+
+	score.Score = (0.4*score.FlipRate + 0.35*mixedRate + 0.25*retryRate) * (1 - stableRate)
+	return score
+}
+
+// NewQuarantine builds a quarantine list from flaky tests
+func NewQuarantine(flaky []FlakyTest, threshold float64) *Quarantine {
+	quarantine := &Quarantine{
+		GeneratedAt: time.Now().UTC(),
+		Threshold:   threshold,
+		Tests:       make([]QuarantineEntry, 0, len(flaky)),
+	}
+
+	for _, test := range flaky {
+		quarantine.Tests = append(quarantine.Tests, QuarantineEntry{
+			TestName: test.TestName,
+			Package:  test.Package,
+			Score:    test.Score,
+			Reason: fmt.Sprintf("flaky score %.2f: %d flips in %d attempts, %d mixed commits, %d retry recoveries",
+				test.Score, test.Flips, test.Attempts, test.MixedCommits, test.RetryRecoveries),
+		})
+	}
+
+	return quarantine
+}
+
+// LoadQuarantine reads a quarantine list from a JSON file
+func LoadQuarantine(filename string) (*Quarantine, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var quarantine Quarantine
+	if err := json.Unmarshal(data, &quarantine); err != nil {
+		return nil, fmt.Errorf("invalid quarantine file %s: %w", filename, err)
+	}
+	return &quarantine, nil
+}
+
+// Save writes the quarantine list to dir as JSON, along with a skip file that the
+// Makefile reads to pass each package its own `go test -skip` pattern
+func (q *Quarantine) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, QuarantineFileName), data, 0644); err != nil {
+		return err
+	}
+
+	skipFile := filepath.Join(dir, QuarantineSkipFileName)
+	lines := q.skipLines()
+	if len(lines) == 0 {
+		if err := os.Remove(skipFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(skipFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// skipLines returns a "<package> <pattern>" line for each package with
+// quarantined tests, with QuarantineAnyPackage for entries without a package
+func (q *Quarantine) skipLines() []string {
+	if q == nil {
+		return nil
+	}
+
+	packages := make(map[string]bool)
+	for _, entry := range q.Tests {
+		pkg := entry.Package
+		if pkg == "" {
+			pkg = QuarantineAnyPackage
+		}
+		packages[pkg] = true
+	}
+
+	var lines []string
+	for pkg := range packages {
+		if pattern := q.packagePattern(pkg); pattern != "" {
+			lines = append(lines, pkg+" "+pattern)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Contains reports whether a test (or the parent of a subtest) is quarantined
+func (q *Quarantine) Contains(pkg, testName string) bool {
+	if q == nil {
+		return false
+	}
+
+	for _, entry := range q.Tests {
+		if entry.Package != "" && entry.Package != pkg {
+			continue
+		}
+		if entry.TestName == testName || strings.HasPrefix(testName, entry.TestName+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipPattern returns an anchored regular expression matching the quarantined
+// top-level tests of a package, including entries without a package. `go test
+// -skip` matches subtests level by level, so a flaky subtest quarantines its
+// parent test.
+func (q *Quarantine) SkipPattern(pkg string) string {
+	pattern := q.packagePattern(pkg)
+	if pkg == QuarantineAnyPackage {
+		return pattern
+	}
+	if shared := q.packagePattern(QuarantineAnyPackage); shared != "" {
+		if pattern == "" {
+			return shared
+		}
+		return pattern + "|" + shared
+	}
+	return pattern
+}
+
+// packagePattern returns the pattern of the entries of one package, where
+// QuarantineAnyPackage stands for the entries without a package
+func (q *Quarantine) packagePattern(pkg string) string {
+	if q == nil {
+		return ""
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range q.Tests {
+		entryPackage := entry.Package
+		if entryPackage == "" {
+			entryPackage = QuarantineAnyPackage
+		}
+		name := strings.SplitN(entry.TestName, "/", 2)[0]
+		if entryPackage != pkg || name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+	return fmt.Sprintf("^(%s)$", strings.Join(names, "|"))
+}
+
+// SetQuarantine sets the quarantine list used to separate known-flaky failures from regressions
+func (ta *TestAnalytics) SetQuarantine(quarantine *Quarantine) {
+	ta.Quarantine = quarantine
+}
+
+// GetQuarantinedFailures returns failed tests that are on the quarantine list
+func (ta *TestAnalytics) GetQuarantinedFailures() []TestResult {
+	var quarantined []TestResult
+	for _, result := range ta.GetFailedTests() {
+		if ta.Quarantine.Contains(result.Package, result.TestName) {
+			quarantined = append(quarantined, result)
+		}
+	}
+	return quarantined
+}
+
+// GetRegressions returns failed tests that are not on the quarantine list
+func (ta *TestAnalytics) GetRegressions() []TestResult {
+	var regressions []TestResult
+	for _, result := range ta.GetFailedTests() {
+		if !ta.Quarantine.Contains(result.Package, result.TestName) {
+			regressions = append(regressions, result)
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,95 @@
+package reporting_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestGetFlakyTests(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// TestGWLBFailure alternates and recovers on retry; TestAZFailureResiliency is
+	// broken on the last commit and fails every attempt; TestStable always passes.
+	analytics := newAnalytics(
+		reporting.TestSuiteResult{SuiteName: "chaos", RunID: "run-1", GitSHA: "sha-a", StartTime: start, Results: []reporting.TestResult{
+			{TestName: "TestGWLBFailure", Status: "FAIL"},
+			{TestName: "TestGWLBFailure", Status: "PASS"},
+			{TestName: "TestAZFailureResiliency", Status: "PASS"},
+			{TestName: "TestStable", Status: "PASS"},
+		}},
+		reporting.TestSuiteResult{SuiteName: "chaos", RunID: "run-2", GitSHA: "sha-a", StartTime: start.Add(time.Hour), Results: []reporting.TestResult{
+			{TestName: "TestGWLBFailure", Status: "PASS"},
+			{TestName: "TestAZFailureResiliency", Status: "PASS"},
+			{TestName: "TestStable", Status: "PASS"},
+		}},
+		reporting.TestSuiteResult{SuiteName: "chaos", RunID: "run-3", GitSHA: "sha-b", StartTime: start.Add(2 * time.Hour), Results: []reporting.TestResult{
+			{TestName: "TestGWLBFailure", Status: "FAIL"},
+			{TestName: "TestGWLBFailure", Status: "PASS"},
+			{TestName: "TestAZFailureResiliency", Status: "FAIL"},
+			{TestName: "TestAZFailureResiliency", Status: "FAIL"},
+			{TestName: "TestStable", Status: "PASS"},
+		}},
+	)
+
+	flaky := analytics.GetFlakyTests(0.3)
+	require.Len(t, flaky, 1)
+	assert.Equal(t, "TestGWLBFailure", flaky[0].TestName)
+	assert.Equal(t, 2, flaky[0].RetryRecoveries)
+	assert.Equal(t, 2, flaky[0].MixedCommits)
+	assert.Greater(t, flaky[0].Score, 0.5)
+
+	all := analytics.GetFlakyTests(0)
+	require.Len(t, all, 2)
+	assert.Equal(t, "TestAZFailureResiliency", all[1].TestName)
+	assert.Equal(t, 1, all[1].StableFailureCommits)
+	assert.Equal(t, 0.0, all[1].Score, "consistent failures on a commit are a regression, not a flake")
+}
+
+func TestQuarantine(t *testing.T) {
+	analytics := newAnalytics(reporting.TestSuiteResult{SuiteName: "chaos", RunID: "run-1", GitSHA: "sha-a", StartTime: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Results: []reporting.TestResult{
+		{TestName: "TestGWLBFailure", Status: "FAIL"},
+		{TestName: "TestGWLBFailure/NodeFailure", Status: "FAIL"},
+		{TestName: "TestTransitGatewayFailure", Status: "FAIL"},
+		{TestName: "TestFirewallInstanceFailure.x", Status: "PASS"},
+	}})
+
+	quarantine := reporting.NewQuarantine([]reporting.FlakyTest{
+		{TestName: "TestGWLBFailure", Package: "chaos", Score: 0.8},
+		{TestName: "TestFirewallInstanceFailure.x", Package: "chaos", Score: 0.5},
+		{TestName: "TestNetworkIdempotency/Reapply", Package: "network", Score: 0.6},
+	}, 0.5)
+	assert.Equal(t, `^(TestFirewallInstanceFailure\.x|TestGWLBFailure)$`, quarantine.SkipPattern("chaos"))
+	assert.Equal(t, `^(TestNetworkIdempotency)$`, quarantine.SkipPattern("network"))
+	assert.Empty(t, quarantine.SkipPattern("inspection"), "a quarantined test only skips its own package")
+	anyPackage := &reporting.Quarantine{Tests: []reporting.QuarantineEntry{{TestName: "TestGWLBFailure"}}}
+	assert.Equal(t, `^(TestGWLBFailure)$`, anyPackage.SkipPattern("inspection"))
+
+	dir := t.TempDir()
+	require.NoError(t, quarantine.Save(dir))
+	skip, err := os.ReadFile(filepath.Join(dir, reporting.QuarantineSkipFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "chaos ^(TestFirewallInstanceFailure\\.x|TestGWLBFailure)$\nnetwork ^(TestNetworkIdempotency)$\n", string(skip))
+
+	loaded, err := reporting.LoadQuarantine(filepath.Join(dir, reporting.QuarantineFileName))
+	require.NoError(t, err)
+	assert.True(t, loaded.Contains("chaos", "TestGWLBFailure/NodeFailure"))
+	assert.False(t, loaded.Contains("network", "TestGWLBFailure"))
+
+	analytics.SetQuarantine(loaded)
+	assert.Len(t, analytics.GetQuarantinedFailures(), 2)
+	regressions := analytics.GetRegressions()
+	require.Len(t, regressions, 1)
+	assert.Equal(t, "TestTransitGatewayFailure", regressions[0].TestName)
+
+	report, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, report, "### Regressions (1)")
+	assert.Contains(t, report, "### Quarantined Failures (known flaky) (2)")
+}
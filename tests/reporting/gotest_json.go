@@ -0,0 +1,375 @@
+package reporting
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoTestEvent represents a single event emitted by `go test -json` (see `go doc test2json`)
+type GoTestEvent struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// packageCategories maps test package names to report categories
+var packageCategories = map[string]string{
+	"network":           "unit",
+	"inspection":        "unit",
+	"firewall-vmseries": "unit",
+	"fixtures":          "unit",
+	"reporting":         "unit",
+	"plan":              "unit",
+	"inventory":         "unit",
+	"contract":          "unit",
+	"routing":           "unit",
+	"routecheck":        "unit",
+	"netsec":            "unit",
+	"inspection-report": "unit",
+	"module-contract":   "unit",
+	"routing-check":     "unit",
+	"integration":       "integration",
+	"performance":       "performance",
+	"compliance":        "compliance",
+	"remediation":       "security",
+	"chaos":             "chaos",
+	"cost":              "cost",
+}
+
+// otherCategory is the report category of packages packageCategories does not list
+const otherCategory = "other"
+
+// categoryForPackage returns the report category for a test package
+func categoryForPackage(pkg string) string {
+	if category, ok := packageCategories[pkg]; ok {
+		return category
+	}
+	return otherCategory
+}
+
+var (
+	goSourceLinePattern = regexp.MustCompile(`^\s*[\w./-]+\.go:\d+: (.*)$`)
+	testifyFieldPattern = regexp.MustCompile(`^\s*([A-Z][A-Za-z ]*):\s*(.*)$`)
+
+	// seedPattern matches the fixture seed tests log, see fixtures.NewTestDataManagerForTest
+	seedPattern = regexp.MustCompile(`\bTEST_SEED=(-?\d+)\b`)
+)
+
+// goTestState accumulates events for a single test until it completes
+type goTestState struct {
+	result TestResult
+	output strings.Builder
+	done   bool
+}
+
+// goTestPackageState accumulates events for a single package
+type goTestPackageState struct {
+	importPath string
+	startTime  time.Time
+	endTime    time.Time
+	elapsed    float64
+	status     string
+	seed       *int64
+	output     strings.Builder
+	// tests holds the latest run of each test; -count=N runs a test N times
+	tests map[string]*goTestState
+	order []*goTestState
+}
+
+// ParseGoTestJSON parses a `go test -json` event stream into one TestSuiteResult per package.
+// Lines that are not JSON events, such as make or shell output interleaved with the
+// stream, are ignored.
+func ParseGoTestJSON(r io.Reader) ([]TestSuiteResult, error) {
+	packages := make(map[string]*goTestPackageState)
+	var packageOrder []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var event GoTestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue
+		}
+		if event.Package == "" {
+			continue
+		}
+
+		pkg, ok := packages[event.Package]
+		if !ok {
+			pkg = &goTestPackageState{
+				importPath: event.Package,
+				tests:      make(map[string]*goTestState),
+			}
+			packages[event.Package] = pkg
+			packageOrder = append(packageOrder, event.Package)
+		}
+		pkg.observe(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go test output: %w", err)
+	}
+
+	suites := make([]TestSuiteResult, 0, len(packageOrder))
+	for _, importPath := range packageOrder {
+		suite, ok := packages[importPath].suite()
+		if ok {
+			suites = append(suites, suite)
+		}
+	}
+
+	return suites, nil
+}
+
+// IngestGoTestJSON parses a `go test -json` event stream and adds the resulting suites
+func (ta *TestAnalytics) IngestGoTestJSON(r io.Reader, environment, region string) error {
+	suites, err := ParseGoTestJSON(r)
+	if err != nil {
+		return err
+	}
+
+	for _, suite := range suites {
+		suite.Environment = environment
+		suite.Region = region
+		ta.AddResult(suite)
+	}
+
+	return nil
+}
+
+// observe applies a single event to the package state
+func (p *goTestPackageState) observe(event GoTestEvent) {
+	if !event.Time.IsZero() {
+		if p.startTime.IsZero() || event.Time.Before(p.startTime) {
+			p.startTime = event.Time
+		}
+		if event.Time.After(p.endTime) {
+			p.endTime = event.Time
+		}
+	}
+
+	if event.Action == "output" && p.seed == nil {
+		if match := seedPattern.FindStringSubmatch(event.Output); match != nil {
+			if seed, err := strconv.ParseInt(match[1], 10, 64); err == nil {
+				p.seed = &seed
+			}
+		}
+	}
+
+	if event.Test == "" {
+		switch event.Action {
+		case "output":
+			p.output.WriteString(event.Output)
+		case "pass", "fail", "skip":
+			p.status = event.Action
+			p.elapsed = event.Elapsed
+		}
+		return
+	}
+
+	test := p.test(event)
+	switch event.Action {
+	case "run":
+		test.result.Timestamp = event.Time
+	case "output":
+		if !isGoTestFramingLine(event.Output) {
+			test.output.WriteString(event.Output)
+		}
+	case "pass", "fail", "skip":
+		test.result.Status = strings.ToUpper(event.Action)
+		test.result.Duration = time.Duration(event.Elapsed * float64(time.Second))
+		test.done = true
+	}
+}
+
+// test returns the state for the event's test, creating it on first sight and
+// again when a test that completed runs once more
+func (p *goTestPackageState) test(event GoTestEvent) *goTestState {
+	if test, ok := p.tests[event.Test]; ok && !(test.done && event.Action == "run") {
+		return test
+	}
+
+	pkg := path.Base(p.importPath)
+	test := &goTestState{
+		result: TestResult{
+			TestName:  event.Test,
+			Package:   pkg,
+			Parent:    parentTestName(event.Test),
+			Timestamp: event.Time,
+			Category:  categoryForPackage(pkg),
+		},
+	}
+	p.tests[event.Test] = test
+	p.order = append(p.order, test)
+	return test
+}
+
+// suite converts the package state into a TestSuiteResult. Packages without
+// tests are dropped unless they failed, so build failures are not lost.
+func (p *goTestPackageState) suite() (TestSuiteResult, bool) {
+	pkg := path.Base(p.importPath)
+	dir := packageDir(p.importPath)
+	if dir == "" {
+		dir = path.Join(testsDir, pkg)
+	}
+	suite := TestSuiteResult{
+		SuiteName: p.importPath,
+		Seed:      p.seed,
+		StartTime: p.startTime,
+		EndTime:   p.endTime,
+		Duration:  p.endTime.Sub(p.startTime),
+		Results:   make([]TestResult, 0, len(p.order)),
+	}
+	if p.elapsed > 0 {
+		suite.Duration = time.Duration(p.elapsed * float64(time.Second))
+	}
+
+	for _, test := range p.order {
+		result := test.result
+		result.Output = test.output.String()
+
+		if !test.done {
+			// The binary exited (panic, timeout) before the test reported a result
+			result.Status = "FAIL"
+			result.Error = errTestIncomplete
+		}
+		if result.Status == "FAIL" && result.Error == "" {
+			result.Error = errorFromOutput(result.Output)
+		}
+		if result.Status == "FAIL" {
+			result.Location = sourceLocation(result.Output, dir)
+		}
+
+		suite.Results = append(suite.Results, result)
+	}
+	suite.Results = collapseSubtests(suite.Results)
+
+	if len(suite.Results) == 0 {
+		if p.status != "fail" {
+			return suite, false
+		}
+		output := p.output.String()
+		suite.Results = append(suite.Results, TestResult{
+			TestName:  pkg,
+			Package:   pkg,
+			Status:    "FAIL",
+			Duration:  suite.Duration,
+			Error:     errorFromOutput(output),
+			Output:    output,
+			Timestamp: p.startTime,
+			Category:  categoryForPackage(pkg),
+		})
+	}
+
+	recountSuite(&suite)
+	return suite, true
+}
+
+// collapseSubtests reports the tests with subtests through their subtests, so a
+// subtest is counted once whether it passed or failed, and not again on its parent.
+// A parent is kept only when it failed and none of its subtests did, which is a
+// failure of its own. The output of a failed parent is prepended to the output of
+// its failed subtests. Repeated runs of a test, as with -count=N, stay separate:
+// each subtest belongs to the latest run of its parent before it.
+func collapseSubtests(results []TestResult) []TestResult {
+	parents := make([]int, len(results))
+	hasSubtests := make([]bool, len(results))
+	failedSubtests := make([]bool, len(results))
+	latest := make(map[string]int)
+	for i, result := range results {
+		parents[i] = -1
+		if parent, ok := latest[result.Parent]; ok && result.Parent != "" {
+			parents[i] = parent
+			hasSubtests[parent] = true
+			if result.Status == "FAIL" {
+				failedSubtests[parent] = true
+			}
+		}
+		latest[result.TestName] = i
+	}
+
+	kept := make([]TestResult, 0, len(results))
+	for i := range results {
+		if parent := parents[i]; parent >= 0 && failedSubtests[parent] && results[i].Status == "FAIL" {
+			results[i].Output = results[parent].Output + results[i].Output
+		}
+		if !hasSubtests[i] || (results[i].Status == "FAIL" && !failedSubtests[i]) {
+			kept = append(kept, results[i])
+		}
+	}
+	return kept
+}
+
+// parentTestName returns the parent of a subtest name, or "" for top-level tests
+func parentTestName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// isGoTestFramingLine reports whether an output line is go test's own progress framing
+func isGoTestFramingLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS:", "--- FAIL:", "--- SKIP:"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorFromOutput extracts the most useful failure message from captured test output.
+// Testify "Error:" blocks win over plain t.Errorf lines; otherwise the last non-empty
+// line is used.
+func errorFromOutput(output string) string {
+	lines := strings.Split(output, "\n")
+
+	for i, line := range lines {
+		match := testifyFieldPattern.FindStringSubmatch(line)
+		if match == nil || match[1] != "Error" {
+			continue
+		}
+
+		parts := []string{strings.TrimSpace(match[2])}
+		for _, next := range lines[i+1:] {
+			if testifyFieldPattern.MatchString(next) || strings.TrimSpace(next) == "" {
+				break
+			}
+			parts = append(parts, strings.TrimSpace(next))
+		}
+		return strings.TrimSpace(strings.Join(parts, " "))
+	}
+
+	var fallback string
+	for _, line := range lines {
+		if seedPattern.MatchString(line) {
+			// The fixture seed and replay command are logged, not failures
+			continue
+		}
+		if match := goSourceLinePattern.FindStringSubmatch(line); match != nil && strings.TrimSpace(match[1]) != "" {
+			return strings.TrimSpace(match[1])
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" && trimmed != "FAIL" {
+			fallback = trimmed
+		}
+	}
+
+	return fallback
+}
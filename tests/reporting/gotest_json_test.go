@@ -0,0 +1,284 @@
+package reporting_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+const chaosGoTestJSON = `Running tests in ./chaos...
+{"Time":"2024-05-01T10:00:00Z","Action":"start","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos"}
+{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency","Output":"=== RUN   TestAZFailureResiliency\n"}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZFailureSimulation"}
+{"Time":"2024-05-01T10:00:01Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZFailureSimulation","Output":"    chaos_test.go:360: AZ failure simulated for vpc-0abc\n"}
+{"Time":"2024-05-01T10:00:03Z","Action":"pass","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZFailureSimulation","Elapsed":2}
+{"Time":"2024-05-01T10:00:03Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery"}
+{"Time":"2024-05-01T10:00:04Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Output":"    chaos_test.go:390: \n"}
+{"Time":"2024-05-01T10:00:04Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Output":"        \tError Trace:\tchaos_test.go:390\n"}
+{"Time":"2024-05-01T10:00:04Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Output":"        \tError:      \tShould be true\n"}
+{"Time":"2024-05-01T10:00:04Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Output":"        \tTest:       \tTestAZFailureResiliency/AZRecovery\n"}
+{"Time":"2024-05-01T10:00:04Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Output":"--- FAIL: TestAZFailureResiliency/AZRecovery (1.50s)\n"}
+{"Time":"2024-05-01T10:00:04Z","Action":"fail","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency/AZRecovery","Elapsed":1.5}
+{"Time":"2024-05-01T10:00:05Z","Action":"fail","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestAZFailureResiliency","Elapsed":5}
+{"Time":"2024-05-01T10:00:05Z","Action":"run","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestGWLBFailure"}
+{"Time":"2024-05-01T10:00:05Z","Action":"output","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestGWLBFailure","Output":"    chaos_test.go:100: requires AWS credentials\n"}
+{"Time":"2024-05-01T10:00:05Z","Action":"skip","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Test":"TestGWLBFailure","Elapsed":0}
+{"Time":"2024-05-01T10:00:06Z","Action":"fail","Package":"github.com/your-org/aws-centralized-inspection/tests/chaos","Elapsed":6.2}
+{"Time":"2024-05-01T10:00:06Z","Action":"skip","Package":"github.com/your-org/aws-centralized-inspection/tests/fixtures","Elapsed":0}
+`
+
+func TestParseGoTestJSON(t *testing.T) {
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(chaosGoTestJSON))
+	require.NoError(t, err)
+	require.Len(t, suites, 1, "packages without tests should be dropped")
+
+	suite := suites[0]
+	assert.Equal(t, "github.com/your-org/aws-centralized-inspection/tests/chaos", suite.SuiteName)
+	assert.Equal(t, 6200*time.Millisecond, suite.Duration)
+	assert.Equal(t, 3, suite.TotalTests)
+	assert.Equal(t, 1, suite.PassedTests)
+	assert.Equal(t, 1, suite.FailedTests)
+	assert.Equal(t, 1, suite.SkippedTests)
+
+	results := make(map[string]reporting.TestResult)
+	for _, result := range suite.Results {
+		results[result.TestName] = result
+	}
+
+	simulation := results["TestAZFailureResiliency/AZFailureSimulation"]
+	assert.Equal(t, "PASS", simulation.Status)
+	assert.Equal(t, 2*time.Second, simulation.Duration)
+	assert.Equal(t, "TestAZFailureResiliency", simulation.Parent)
+	assert.Equal(t, "chaos", simulation.Package)
+	assert.Equal(t, "chaos", simulation.Category)
+	assert.Contains(t, simulation.Output, "AZ failure simulated")
+
+	recovery := results["TestAZFailureResiliency/AZRecovery"]
+	assert.Equal(t, "FAIL", recovery.Status)
+	assert.Equal(t, "Should be true", recovery.Error)
+	assert.Equal(t, "tests/chaos/chaos_test.go:390", recovery.Location)
+	assert.NotContains(t, recovery.Output, "--- FAIL", "framing lines should not be captured")
+
+	assert.NotContains(t, results, "TestAZFailureResiliency", "a parent failed by its subtest is not a failure of its own")
+	assert.Equal(t, "SKIP", results["TestGWLBFailure"].Status)
+}
+
+func TestParseGoTestJSONFailedSubtest(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA"}
+{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA/sub"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestA/sub","Output":"    a_test.go:12: boom\n"}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA/sub","Elapsed":1}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestA/ok"}
+{"Time":"2024-05-01T10:00:01Z","Action":"pass","Package":"example.com/tests/network","Test":"TestA/ok","Elapsed":0}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA","Elapsed":1}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestB"}
+{"Time":"2024-05-01T10:00:01Z","Action":"pass","Package":"example.com/tests/network","Test":"TestB","Elapsed":0}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Elapsed":1}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	assert.Equal(t, 3, suites[0].TotalTests)
+	assert.Equal(t, 1, suites[0].FailedTests)
+
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(suites[0])
+	failed := analytics.GetFailedTests()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "TestA/sub", failed[0].TestName)
+	assert.Equal(t, "boom", failed[0].Error)
+}
+
+func TestParseGoTestJSONParents(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestA","Output":"    a_test.go:8: vpc-0abc created\n"}
+{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA/sub"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestA/sub","Output":"    a_test.go:12: boom\n"}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA/sub","Elapsed":1}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA","Elapsed":1}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestB"}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestB/ok"}
+{"Time":"2024-05-01T10:00:01Z","Action":"pass","Package":"example.com/tests/network","Test":"TestB/ok","Elapsed":0}
+{"Time":"2024-05-01T10:00:01Z","Action":"pass","Package":"example.com/tests/network","Test":"TestB","Elapsed":0}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestC"}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestC/ok"}
+{"Time":"2024-05-01T10:00:01Z","Action":"pass","Package":"example.com/tests/network","Test":"TestC/ok","Elapsed":0}
+{"Time":"2024-05-01T10:00:02Z","Action":"output","Package":"example.com/tests/network","Test":"TestC","Output":"    c_test.go:20: cleanup failed\n"}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Test":"TestC","Elapsed":1}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Elapsed":2}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+
+	// Passing and failing parents are both counted through their subtests, except
+	// TestC, which failed on its own
+	var names []string
+	for _, result := range suites[0].Results {
+		names = append(names, result.TestName+" "+result.Status)
+	}
+	assert.Equal(t, []string{"TestA/sub FAIL", "TestB/ok PASS", "TestC FAIL", "TestC/ok PASS"}, names)
+	assert.Equal(t, 4, suites[0].TotalTests)
+	assert.Equal(t, 2, suites[0].PassedTests)
+	assert.Equal(t, 2, suites[0].FailedTests)
+
+	// The output of the failed parent stays with the failure
+	sub := suites[0].Results[0]
+	assert.Equal(t, "boom", sub.Error)
+	assert.Contains(t, sub.Output, "vpc-0abc created")
+	assert.Equal(t, "cleanup failed", suites[0].Results[2].Error)
+}
+
+func TestParseGoTestJSONRepeatedRuns(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA"}
+{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestA/sub"}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/network","Test":"TestA/sub","Elapsed":0}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/network","Test":"TestA","Elapsed":0}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestA"}
+{"Time":"2024-05-01T10:00:01Z","Action":"run","Package":"example.com/tests/network","Test":"TestA/sub"}
+{"Time":"2024-05-01T10:00:01Z","Action":"output","Package":"example.com/tests/network","Test":"TestA/sub","Output":"    a_test.go:12: boom\n"}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA/sub","Elapsed":1}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Test":"TestA","Elapsed":1}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Elapsed":2}
+`
+
+	// go test -count=2 runs TestA twice; each run is a result of its own
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	require.Len(t, suites[0].Results, 2)
+	assert.Equal(t, "PASS", suites[0].Results[0].Status)
+	assert.Equal(t, "FAIL", suites[0].Results[1].Status)
+	assert.Equal(t, "boom", suites[0].Results[1].Error)
+	assert.Equal(t, 1, suites[0].PassedTests)
+	assert.Equal(t, 1, suites[0].FailedTests)
+}
+
+func TestParseGoTestJSONCategory(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/netsec","Test":"TestEvaluate","Elapsed":0}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/netsec","Elapsed":0}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/scratch","Test":"TestScratch","Elapsed":0}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/scratch","Elapsed":0}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 2)
+	assert.Equal(t, "unit", suites[0].Results[0].Category)
+	assert.Equal(t, "other", suites[1].Results[0].Category, "packages without a category are not counted as unit tests")
+}
+
+func TestParseGoTestJSONIncompleteTest(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestNetworkProvisioning"}
+{"Time":"2024-05-01T10:30:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestNetworkProvisioning","Output":"panic: test timed out after 30m0s\n"}
+{"Time":"2024-05-01T10:30:00Z","Action":"fail","Package":"example.com/tests/network","Elapsed":1800}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	require.Len(t, suites[0].Results, 1)
+
+	result := suites[0].Results[0]
+	assert.Equal(t, "FAIL", result.Status)
+	assert.Equal(t, "test did not complete", result.Error)
+	assert.Equal(t, 1, suites[0].FailedTests)
+}
+
+func TestParseGoTestJSONBuildFailure(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/inspection","Output":"# example.com/tests/inspection\n"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/inspection","Output":"inspection_test.go:12:2: undefined: foo\n"}
+{"Time":"2024-05-01T10:00:00Z","Action":"fail","Package":"example.com/tests/inspection","Elapsed":0}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	require.Len(t, suites[0].Results, 1)
+	assert.Equal(t, "FAIL", suites[0].Results[0].Status)
+	assert.Contains(t, suites[0].Results[0].Error, "undefined: foo")
+}
+
+func TestParseGoTestJSONSeed(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"run","Package":"example.com/tests/network","Test":"TestVPCCreation"}
+{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestVPCCreation","Output":"    test_data.go:120: fixtures: TEST_SEED=-424242 environment=dev region=us-east-1\n"}
+{"Time":"2024-05-01T10:00:01Z","Action":"output","Package":"example.com/tests/network","Test":"TestVPCCreation","Output":"    network_test.go:31: vpc-0abc has no flow logs\n"}
+{"Time":"2024-05-01T10:00:02Z","Action":"output","Package":"example.com/tests/network","Test":"TestVPCCreation","Output":"    test_data.go:128: replay fixtures with: TEST_SEED=-424242 go test -run '^TestVPCCreation$'\n"}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Test":"TestVPCCreation","Elapsed":2}
+{"Time":"2024-05-01T10:00:02Z","Action":"fail","Package":"example.com/tests/network","Elapsed":2}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	require.NotNil(t, suites[0].Seed)
+	assert.Equal(t, int64(-424242), *suites[0].Seed)
+	assert.Equal(t, "vpc-0abc has no flow logs", suites[0].Results[0].Error, "seed log lines are not failures")
+
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(suites[0])
+
+	markdown, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "**Seed**: `-424242`")
+
+	junit, err := analytics.GenerateReport("junit")
+	require.NoError(t, err)
+	parsed, err := reporting.ParseJUnitReport([]byte(junit))
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	require.NotNil(t, parsed[0].Seed)
+	assert.Equal(t, int64(-424242), *parsed[0].Seed)
+}
+
+func TestParseGoTestJSONZeroSeed(t *testing.T) {
+	stream := `{"Time":"2024-05-01T10:00:00Z","Action":"output","Package":"example.com/tests/network","Test":"TestVPCCreation","Output":"    test_data.go:120: fixtures: TEST_SEED=0 environment=dev region=us-east-1\n"}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Test":"TestVPCCreation","Elapsed":1}
+{"Time":"2024-05-01T10:00:01Z","Action":"fail","Package":"example.com/tests/network","Elapsed":1}
+`
+
+	suites, err := reporting.ParseGoTestJSON(strings.NewReader(stream))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	require.NotNil(t, suites[0].Seed, "TEST_SEED=0 is recorded")
+	assert.Equal(t, int64(0), *suites[0].Seed)
+
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(suites[0])
+	junit, err := analytics.GenerateReport("junit")
+	require.NoError(t, err)
+	parsed, err := reporting.ParseJUnitReport([]byte(junit))
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	require.NotNil(t, parsed[0].Seed)
+	assert.Equal(t, int64(0), *parsed[0].Seed)
+
+	suites, err = reporting.ParseGoTestJSON(strings.NewReader(`{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/network","Test":"TestVPCCreation","Elapsed":1}
+{"Time":"2024-05-01T10:00:00Z","Action":"pass","Package":"example.com/tests/network","Elapsed":1}
+`))
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	assert.Nil(t, suites[0].Seed, "no seed is logged")
+}
+
+func TestIngestGoTestJSON(t *testing.T) {
+	analytics := reporting.NewTestAnalytics()
+	require.NoError(t, analytics.IngestGoTestJSON(strings.NewReader(chaosGoTestJSON), "dev", "us-east-1"))
+
+	require.Len(t, analytics.Results, 1)
+	assert.Equal(t, "dev", analytics.Results[0].Environment)
+	assert.Equal(t, "us-east-1", analytics.Results[0].Region)
+	assert.Len(t, analytics.GetFailedTests(), 1)
+
+	report, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, report, "TestAZFailureResiliency/AZRecovery")
+}
@@ -0,0 +1,41 @@
+package reporting_test
+
+import (
+	"time"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// newAnalytics returns analytics with the suites added in order, filled in the way
+// the parsers fill them. Each suite gets its own copy of its results and is counted
+// by their status. Results without a package get the suite name, and in suites with
+// a start time, results without a timestamp get one a second after the one before.
+func newAnalytics(suites ...reporting.TestSuiteResult) *reporting.TestAnalytics {
+	analytics := reporting.NewTestAnalytics()
+	for _, suite := range suites {
+		suite.Results = append([]reporting.TestResult(nil), suite.Results...)
+		suite.TotalTests, suite.PassedTests, suite.FailedTests, suite.SkippedTests = len(suite.Results), 0, 0, 0
+		timestamp := suite.StartTime
+		for i := range suite.Results {
+			result := &suite.Results[i]
+			if result.Package == "" {
+				result.Package = suite.SuiteName
+			}
+			if result.Timestamp.IsZero() && !suite.StartTime.IsZero() {
+				result.Timestamp = timestamp.Add(time.Second)
+			}
+			timestamp = result.Timestamp
+
+			switch result.Status {
+			case "PASS":
+				suite.PassedTests++
+			case "FAIL":
+				suite.FailedTests++
+			case "SKIP":
+				suite.SkippedTests++
+			}
+		}
+		analytics.AddResult(suite)
+	}
+	return analytics
+}
@@ -0,0 +1,256 @@
+package reporting
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// histogramSubBucketBits sets the histogram precision: each power-of-two range is
+// split into 2^7 = 128 linear sub-buckets, bounding the relative error below 1%
+const histogramSubBucketBits = 7
+
+const histogramSubBuckets = 1 << histogramSubBucketBits
+
+// ReportedPercentiles are the percentiles summarised in PerfMetrics and reports
+var ReportedPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+// LatencyHistogram is a streaming, mergeable HDR-style latency histogram. Samples
+// are counted in log-linear buckets, so memory stays bounded however many samples
+// are recorded, and histograms from several shards can be merged without loss.
+type LatencyHistogram struct {
+	Count  uint64         `json:"count"`
+	Sum    time.Duration  `json:"sum"`
+	Min    time.Duration  `json:"min"`
+	Max    time.Duration  `json:"max"`
+	Counts map[int]uint64 `json:"counts"` // bucket index -> samples
+}
+
+// HistogramBin is a [Lower, Upper) range of the latency distribution used for rendering
+type HistogramBin struct {
+	Lower   time.Duration `json:"lower"`
+	Upper   time.Duration `json:"upper"`
+	Count   uint64        `json:"count"`
+	Percent float64       `json:"percent"`
+}
+
+// LatencyPercentile is a labelled percentile of a latency histogram
+type LatencyPercentile struct {
+	Label string        `json:"label"`
+	Value time.Duration `json:"value"`
+}
+
+// NewLatencyHistogram creates an empty latency histogram
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{Counts: make(map[int]uint64)}
+}
+
+// histogramBucket returns the bucket index for a value in nanoseconds
+func histogramBucket(value uint64) int {
+	if value < histogramSubBuckets {
+		return int(value)
+	}
+	shift := bits.Len64(value) - 1 - histogramSubBucketBits
+	sub := int(value>>uint(shift)) - histogramSubBuckets
+	return (shift+1)*histogramSubBuckets + sub
+}
+
+// histogramBucketBounds returns the inclusive value range of a bucket in nanoseconds
+func histogramBucketBounds(index int) (uint64, uint64) {
+	if index < histogramSubBuckets {
+		return uint64(index), uint64(index)
+	}
+	shift := index/histogramSubBuckets - 1
+	sub := index % histogramSubBuckets
+	lower := uint64(sub+histogramSubBuckets) << uint(shift)
+	return lower, lower + (1 << uint(shift)) - 1
+}
+
+// Record adds a latency sample. Negative samples are recorded as zero.
+func (h *LatencyHistogram) Record(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64)
+	}
+
+	if h.Count == 0 || latency < h.Min {
+		h.Min = latency
+	}
+	if latency > h.Max {
+		h.Max = latency
+	}
+	h.Count++
+	h.Sum += latency
+	h.Counts[histogramBucket(uint64(latency))]++
+}
+
+// Merge adds all samples of another histogram, such as one from a parallel shard
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other == nil || other.Count == 0 {
+		return
+	}
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64)
+	}
+
+	if h.Count == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	for index, count := range other.Counts {
+		h.Counts[index] += count
+	}
+}
+
+// Mean returns the average recorded latency
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h == nil || h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Percentile returns the latency at or below which the given percentage (0-100)
+// of samples fall. The result is the upper bound of the matching bucket, clamped
+// to the recorded min and max.
+func (h *LatencyHistogram) Percentile(percentile float64) time.Duration {
+	if h == nil || h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(percentile / 100 * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > h.Count {
+		rank = h.Count
+	}
+
+	var seen uint64
+	for _, index := range h.buckets() {
+		seen += h.Counts[index]
+		if seen >= rank {
+			_, upper := histogramBucketBounds(index)
+			value := time.Duration(upper)
+			if value > h.Max {
+				value = h.Max
+			}
+			if value < h.Min {
+				value = h.Min
+			}
+			return value
+		}
+	}
+	return h.Max
+}
+
+// Percentiles returns the ReportedPercentiles of the histogram
+func (h *LatencyHistogram) Percentiles() []LatencyPercentile {
+	percentiles := make([]LatencyPercentile, 0, len(ReportedPercentiles))
+	for _, percentile := range ReportedPercentiles {
+		percentiles = append(percentiles, LatencyPercentile{
+			Label: PercentileLabel(percentile),
+			Value: h.Percentile(percentile),
+		})
+	}
+	return percentiles
+}
+
+// Distribution groups samples by power-of-two latency ranges for rendering
+func (h *LatencyHistogram) Distribution() []HistogramBin {
+	if h == nil || h.Count == 0 {
+		return nil
+	}
+
+	var bins []HistogramBin
+	for _, index := range h.buckets() {
+		value, _ := histogramBucketBounds(index)
+		lower, upper := uint64(0), uint64(1)
+		if value > 0 {
+			lower = uint64(1) << uint(bits.Len64(value)-1)
+			upper = lower * 2
+		}
+		if len(bins) == 0 || bins[len(bins)-1].Lower != time.Duration(lower) {
+			bins = append(bins, HistogramBin{Lower: time.Duration(lower), Upper: time.Duration(upper)})
+		}
+		bins[len(bins)-1].Count += h.Counts[index]
+	}
+
+	for i := range bins {
+		bins[i].Percent = float64(bins[i].Count) / float64(h.Count) * 100
+	}
+	return bins
+}
+
+// buckets returns the non-empty bucket indexes in ascending order
+func (h *LatencyHistogram) buckets() []int {
+	indexes := make([]int, 0, len(h.Counts))
+	for index, count := range h.Counts {
+		if count > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// CheckThreshold returns an error if the latency at the given percentile exceeds
+// the threshold. A percentile of 0 checks the mean latency instead.
+func (h *LatencyHistogram) CheckThreshold(percentile float64, threshold time.Duration) error {
+	if h == nil || h.Count == 0 {
+		return fmt.Errorf("no latency samples recorded")
+	}
+
+	label, latency := "mean", h.Mean()
+	if percentile > 0 {
+		label, latency = PercentileLabel(percentile), h.Percentile(percentile)
+	}
+	if latency > threshold {
+		return fmt.Errorf("%s latency %v exceeds threshold %v", label, latency, threshold)
+	}
+	return nil
+}
+
+// PercentileLabel formats a percentile the way reports show it, e.g. p99 or p99.9
+func PercentileLabel(percentile float64) string {
+	return fmt.Sprintf("p%g", percentile)
+}
+
+// SetLatency attaches a latency histogram and derives the summary statistics from it
+func (pm *PerfMetrics) SetLatency(latency *LatencyHistogram) {
+	pm.Latency = latency
+	pm.AvgResponseTime = latency.Mean()
+	pm.MinResponseTime = latency.Min
+	pm.MaxResponseTime = latency.Max
+	pm.P50ResponseTime = latency.Percentile(50)
+	pm.P90ResponseTime = latency.Percentile(90)
+	pm.P95ResponseTime = latency.Percentile(95)
+	pm.P99ResponseTime = latency.Percentile(99)
+	pm.P999ResponseTime = latency.Percentile(99.9)
+}
+
+// mergePerformance combines performance metrics from two shards. Latency
+// histograms are merged; other figures come from the first shard that has them.
+func mergePerformance(a, b *PerfMetrics) *PerfMetrics {
+	if a == nil {
+		return b
+	}
+	if b == nil || b.Latency == nil {
+		return a
+	}
+
+	merged := *a
+	latency := NewLatencyHistogram()
+	latency.Merge(a.Latency)
+	latency.Merge(b.Latency)
+	merged.SetLatency(latency)
+	return &merged
+}
@@ -0,0 +1,118 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	histogram := reporting.NewLatencyHistogram()
+	samples := make([]time.Duration, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		// Long-tailed latencies between 1ms and roughly 1s
+		sample := time.Duration(random.ExpFloat64()*float64(5*time.Millisecond)) + time.Millisecond
+		samples = append(samples, sample)
+		histogram.Record(sample)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	for _, percentile := range reporting.ReportedPercentiles {
+		exact := samples[int(percentile/100*float64(len(samples)))-1]
+		assert.InEpsilon(t, float64(exact), float64(histogram.Percentile(percentile)), 0.01,
+			"%s should be within 1%% of the exact value", reporting.PercentileLabel(percentile))
+	}
+
+	assert.Equal(t, uint64(100000), histogram.Count)
+	assert.Equal(t, samples[0], histogram.Min)
+	assert.Equal(t, samples[len(samples)-1], histogram.Max)
+	assert.Equal(t, samples[len(samples)-1], histogram.Percentile(100))
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	combined := reporting.NewLatencyHistogram()
+	shardA := reporting.NewLatencyHistogram()
+	shardB := reporting.NewLatencyHistogram()
+	for i := 1; i <= 1000; i++ {
+		latency := time.Duration(i) * time.Millisecond
+		combined.Record(latency)
+		if i%2 == 0 {
+			shardA.Record(latency)
+		} else {
+			shardB.Record(latency)
+		}
+	}
+
+	// Histograms survive a JSON round trip through a shard report
+	data, err := json.Marshal(shardB)
+	require.NoError(t, err)
+	decoded := &reporting.LatencyHistogram{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+
+	merged := reporting.NewLatencyHistogram()
+	merged.Merge(shardA)
+	merged.Merge(decoded)
+
+	assert.Equal(t, combined, merged)
+	assert.Equal(t, 500500*time.Microsecond, merged.Mean())
+}
+
+func TestLatencyHistogramCheckThreshold(t *testing.T) {
+	histogram := reporting.NewLatencyHistogram()
+	for i := 0; i < 98; i++ {
+		histogram.Record(10 * time.Millisecond)
+	}
+	histogram.Record(200 * time.Millisecond)
+	histogram.Record(200 * time.Millisecond)
+
+	assert.NoError(t, histogram.CheckThreshold(95, 50*time.Millisecond))
+	assert.EqualError(t, histogram.CheckThreshold(99, 50*time.Millisecond), "p99 latency 200ms exceeds threshold 50ms")
+	assert.NoError(t, histogram.CheckThreshold(0, 50*time.Millisecond), "the mean is 13.8ms")
+	assert.Error(t, reporting.NewLatencyHistogram().CheckThreshold(99, time.Second))
+}
+
+func TestLatencyDistributionInReports(t *testing.T) {
+	latencyA := reporting.NewLatencyHistogram()
+	latencyB := reporting.NewLatencyHistogram()
+	for i := 0; i < 90; i++ {
+		latencyA.Record(3 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		latencyB.Record(40 * time.Millisecond)
+	}
+
+	shardA := reporting.NewTestAnalytics()
+	shardA.AddResult(reporting.TestSuiteResult{SuiteName: "performance", Performance: &reporting.PerfMetrics{}})
+	shardA.Results[0].Performance.SetLatency(latencyA)
+	shardB := reporting.NewTestAnalytics()
+	shardB.AddResult(reporting.TestSuiteResult{SuiteName: "performance", Performance: &reporting.PerfMetrics{}})
+	shardB.Results[0].Performance.SetLatency(latencyB)
+
+	merged := reporting.MergeAnalytics(shardA, shardB)
+	performance := merged.Results[0].Performance
+	require.NotNil(t, performance)
+	assert.Equal(t, uint64(100), performance.Latency.Count)
+	assert.InEpsilon(t, float64(3*time.Millisecond), float64(performance.P50ResponseTime), 0.01)
+	assert.Equal(t, 40*time.Millisecond, performance.P99ResponseTime)
+	assert.Equal(t, 40*time.Millisecond, performance.MaxResponseTime)
+
+	bins := performance.Latency.Distribution()
+	require.Len(t, bins, 2)
+	assert.InDelta(t, 90.0, bins[0].Percent, 0.001)
+	assert.LessOrEqual(t, bins[0].Lower, 3*time.Millisecond)
+	assert.Greater(t, bins[0].Upper, 3*time.Millisecond)
+
+	html, err := merged.GenerateReport("html")
+	require.NoError(t, err)
+	assert.Contains(t, html, "Latency Distribution (100 samples)")
+	assert.Contains(t, html, "<th>p99.9</th>")
+	assert.Contains(t, html, `style="width: 90.0%"`)
+}
@@ -0,0 +1,271 @@
+package reporting
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// HistoryFileName is the name of the run history file inside the report directory
+const HistoryFileName = "history.jsonl"
+
+// RunInfo identifies a single test run in the history store
+type RunInfo struct {
+	RunID       string `json:"run_id"`
+	GitSHA      string `json:"git_sha"`
+	Environment string `json:"environment"`
+	Region      string `json:"region"`
+}
+
+// HistoryRecord represents one line of the run history file
+type HistoryRecord struct {
+	RunID       string          `json:"run_id"`
+	GitSHA      string          `json:"git_sha"`
+	Environment string          `json:"environment"`
+	Region      string          `json:"region"`
+	RecordedAt  time.Time       `json:"recorded_at"`
+	Suite       TestSuiteResult `json:"suite"`
+}
+
+// HistoryQuery selects a window of runs from the history store
+type HistoryQuery struct {
+	Environment string    // only runs for this environment, if set
+	Region      string    // only runs for this region, if set
+	Since       time.Time // only runs recorded at or after this time, if set
+	Until       time.Time // only runs recorded before this time, if set
+	LastRuns    int       // only the most recent N runs, if > 0
+}
+
+// HistoryStore is an append-only JSONL store of test suite results
+type HistoryStore struct {
+	path string
+}
+
+// NewHistoryStore creates a history store backed by history.jsonl in the given directory
+func NewHistoryStore(dir string) *HistoryStore {
+	return &HistoryStore{
+		path: filepath.Join(dir, HistoryFileName),
+	}
+}
+
+// NewRunID generates a run ID from the current time
+func NewRunID() string {
+	return time.Now().UTC().Format("20060102T150405.000000000Z")
+}
+
+// RunInfoFromEnv builds run info from CI environment variables. TEST_RUN_ID takes
+// precedence over the GitHub Actions run; a run ID is generated when neither is set.
+func RunInfoFromEnv() RunInfo {
+	info := RunInfo{
+		RunID:       os.Getenv("TEST_RUN_ID"),
+		GitSHA:      firstEnv("GIT_SHA", "GITHUB_SHA"),
+		Environment: firstEnv("TEST_ENVIRONMENT", "ENV"),
+		Region:      firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+	}
+
+	if info.RunID == "" {
+		if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+			// Re-runs of a workflow share the run ID, so keep attempts apart
+			info.RunID = "gha-" + runID
+			if attempt := os.Getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+				info.RunID += "-" + attempt
+			}
+		} else {
+			info.RunID = NewRunID()
+		}
+	}
+
+	return info
+}
+
+// firstEnv returns the value of the first non-empty environment variable
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Path returns the location of the history file
+func (hs *HistoryStore) Path() string {
+	return hs.path
+}
+
+// Append writes test suite results to the history file. Every suite must carry a run ID.
+func (hs *HistoryStore) Append(suites ...TestSuiteResult) error {
+	if len(suites) == 0 {
+		return nil
+	}
+
+	var lines []byte
+	recordedAt := time.Now().UTC()
+	for _, suite := range suites {
+		if suite.RunID == "" {
+			return fmt.Errorf("suite %s has no run ID", suite.SuiteName)
+		}
+
+		data, err := json.Marshal(HistoryRecord{
+			RunID:       suite.RunID,
+			GitSHA:      suite.GitSHA,
+			Environment: suite.Environment,
+			Region:      suite.Region,
+			RecordedAt:  recordedAt,
+			Suite:       suite,
+		})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, data...)
+		lines = append(lines, '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(hs.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(hs.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// A single write keeps records from concurrent writers on separate lines
+	if _, err := file.Write(lines); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Records reads all history records matching the query, oldest first
+func (hs *HistoryStore) Records(query HistoryQuery) ([]HistoryRecord, error) {
+	file, err := os.Open(hs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid history record: %w", hs.path, lineNumber, err)
+		}
+		if query.matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].RecordedAt.Before(records[j].RecordedAt)
+	})
+
+	if query.LastRuns > 0 {
+		records = lastRuns(records, query.LastRuns)
+	}
+
+	return records, nil
+}
+
+// Load reads the test suite results matching the query, oldest first
+func (hs *HistoryStore) Load(query HistoryQuery) ([]TestSuiteResult, error) {
+	records, err := hs.Records(query)
+	if err != nil {
+		return nil, err
+	}
+
+	suites := make([]TestSuiteResult, len(records))
+	for i, record := range records {
+		suites[i] = record.Suite
+	}
+	return suites, nil
+}
+
+// matches reports whether a record falls inside the query's filters
+func (q HistoryQuery) matches(record HistoryRecord) bool {
+	if q.Environment != "" && record.Environment != q.Environment {
+		return false
+	}
+	if q.Region != "" && record.Region != q.Region {
+		return false
+	}
+	if !q.Since.IsZero() && record.RecordedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !record.RecordedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// lastRuns keeps only the records belonging to the most recent n run IDs
+func lastRuns(records []HistoryRecord, n int) []HistoryRecord {
+	keep := make(map[string]bool)
+	for i := len(records) - 1; i >= 0 && len(keep) < n; i-- {
+		keep[records[i].RunID] = true
+	}
+
+	var filtered []HistoryRecord
+	for _, record := range records {
+		if keep[record.RunID] {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// SaveHistory stamps every suite with the run info and appends it to the history store.
+// Suite environment and region are kept when already set.
+func (ta *TestAnalytics) SaveHistory(store *HistoryStore, run RunInfo) error {
+	if run.RunID == "" {
+		return fmt.Errorf("run ID is required")
+	}
+
+	for i := range ta.Results {
+		suite := &ta.Results[i]
+		suite.RunID = run.RunID
+		suite.GitSHA = run.GitSHA
+		if suite.Environment == "" {
+			suite.Environment = run.Environment
+		}
+		if suite.Region == "" {
+			suite.Region = run.Region
+		}
+	}
+
+	return store.Append(ta.Results...)
+}
+
+// LoadHistory adds the stored suites matching the query to the analytics
+func (ta *TestAnalytics) LoadHistory(store *HistoryStore, query HistoryQuery) error {
+	suites, err := store.Load(query)
+	if err != nil {
+		return err
+	}
+
+	for _, suite := range suites {
+		ta.AddResult(suite)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package reporting_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+func TestHistoryStoreRoundTrip(t *testing.T) {
+	store := reporting.NewHistoryStore(t.TempDir())
+	start := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+
+	for i, env := range []string{"prod", "dev", "prod", "prod"} {
+		day := start.AddDate(0, 0, i)
+		network := reporting.TestSuiteResult{SuiteName: "network", Environment: env, Region: "us-east-1", StartTime: day, EndTime: day.Add(time.Minute), Duration: time.Minute, Results: []reporting.TestResult{
+			{TestName: "TestPassA", Status: "PASS"},
+			{TestName: "TestPassB", Status: "PASS"},
+			{TestName: "TestPassC", Status: "PASS"},
+		}}
+		if i%2 == 1 {
+			network.Results = append(network.Results, reporting.TestResult{TestName: "TestFailA", Status: "FAIL"})
+		}
+		inspection := reporting.TestSuiteResult{SuiteName: "inspection", Environment: env, Region: "us-east-1", StartTime: day, EndTime: day.Add(time.Minute), Duration: time.Minute, Results: []reporting.TestResult{
+			{TestName: "TestPassA", Status: "PASS"},
+			{TestName: "TestPassB", Status: "PASS"},
+		}}
+		analytics := newAnalytics(network, inspection)
+		require.NoError(t, analytics.SaveHistory(store, reporting.RunInfo{
+			RunID:  "nightly-" + string(rune('1'+i)),
+			GitSHA: "abc123",
+		}))
+	}
+
+	records, err := store.Records(reporting.HistoryQuery{Environment: "prod", LastRuns: 2})
+	require.NoError(t, err)
+	require.Len(t, records, 4, "two runs with two suites each")
+	assert.Equal(t, "nightly-3", records[0].RunID)
+	assert.Equal(t, "nightly-4", records[3].RunID)
+	assert.Equal(t, "abc123", records[0].GitSHA)
+	assert.Equal(t, "prod", records[0].Environment)
+
+	analytics := reporting.NewTestAnalytics()
+	require.NoError(t, analytics.LoadHistory(store, reporting.HistoryQuery{Environment: "prod"}))
+	require.Len(t, analytics.Results, 6)
+
+	trends := analytics.GetTrendAnalysis()
+	assert.Equal(t, []string{"nightly-1", "nightly-3", "nightly-4"}, trends["run_ids"])
+	assert.Equal(t, []float64{100, 100, float64(5) / float64(6) * 100}, trends["pass_rate_trend"])
+	assert.Equal(t, true, trends["regressing"])
+}
+
+func TestHistoryStoreMissingFile(t *testing.T) {
+	store := reporting.NewHistoryStore(t.TempDir())
+
+	suites, err := store.Load(reporting.HistoryQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, suites)
+}
+
+func TestHistoryStoreRejectsInvalidRecords(t *testing.T) {
+	store := reporting.NewHistoryStore(t.TempDir())
+	suite := newAnalytics(reporting.TestSuiteResult{SuiteName: "network", Environment: "dev", StartTime: time.Now(), Results: []reporting.TestResult{
+		{TestName: "TestPassA", Status: "PASS"},
+	}}).Results[0]
+	assert.Error(t, store.Append(suite), "suites need a run ID")
+
+	require.NoError(t, os.WriteFile(store.Path(), []byte("{not json}\n"), 0644))
+	_, err := store.Load(reporting.HistoryQuery{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package reporting
+
+import (
+	"html/template"
+	"strings"
+)
+
+// htmlReportTemplate renders the HTML report. html/template escapes every value,
+// so test names, errors and output cannot inject markup.
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Test Analytics Report</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; }
+        .summary { background: #f0f0f0; padding: 20px; border-radius: 5px; margin-bottom: 20px; }
+        .test-result { margin: 10px 0; padding: 10px; border-left: 5px solid; }
+        .pass { border-color: #28a745; background: #d4edda; }
+        .fail { border-color: #dc3545; background: #f8d7da; }
+        .skip { border-color: #ffc107; background: #fff3cd; }
+        .metric { display: inline-block; margin: 10px; padding: 10px; background: white; border-radius: 5px; }
+        table { width: 100%; border-collapse: collapse; margin: 20px 0; }
+        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
+        th { background-color: #f2f2f2; }
+        .bar { display: inline-block; height: 12px; background: #007bff; vertical-align: middle; }
+    </style>
+</head>
+<body>
+    <h1>Test Analytics Report</h1>
+    <div class="summary">
+        <h2>Summary</h2>
+        <div class="metric">Total Test Suites: {{.Summary.Suites}}</div>
+        <div class="metric">Total Tests: {{.Summary.Tests}}</div>
+        <div class="metric">Passed: {{.Summary.Passed}}</div>
+        <div class="metric">Failed: {{.Summary.Failed}}</div>
+        <div class="metric">Skipped: {{.Summary.Skipped}}</div>
+        <div class="metric">Pass Rate: {{printf "%.1f" .Summary.PassRate}}%</div>
+    </div>
+{{- if .FailureSections}}
+    <h2>Failures</h2>
+{{- range .FailureSections}}
+    <h3>{{.Title}} ({{len .Results}})</h3>
+{{- if .Results}}
+    <table>
+        <tr><th>Test Name</th><th>Package</th><th>Error</th></tr>
+{{- range .Results}}
+        <tr class="fail"><td>{{.TestName}}</td><td>{{.Package}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+    </table>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Owners}}
+    <h2>Owners</h2>
+    <table>
+        <tr><th>Owner</th><th>Tests</th><th>Passed</th><th>Failed</th><th>Skipped</th></tr>
+{{- range .Owners}}
+        <tr><td>{{.Owner}}</td><td>{{.Tests}}</td><td>{{.Passed}}</td><td>{{.Failed}}</td><td>{{.Skipped}}</td></tr>
+{{- end}}
+    </table>
+{{- range $group := .Owners}}
+{{- with .Failures}}
+    <h3>{{$group.Owner}} ({{len .}} failures)</h3>
+    <table>
+        <tr><th>Test Name</th><th>Package</th><th>Error</th></tr>
+{{- range .}}
+        <tr class="fail"><td>{{.TestName}}</td><td>{{.Package}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+    </table>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Clusters}}
+    <h2>Failure Clusters</h2>
+    <table>
+        <tr><th>Count</th><th>Signature</th><th>Packages</th><th>Tests</th><th>Example</th></tr>
+{{- range .Clusters}}
+        <tr class="fail"><td>{{.Count}}</td><td><code>{{.Signature}}</code></td><td>{{join .Packages ", "}}</td><td>{{range $i, $test := .Tests}}{{if $i}}<br>{{end}}{{$test}}{{end}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+    </table>
+{{- end}}
+{{- with .Coverage}}
+    <h2>Coverage</h2>
+    <p>Total: {{printf "%.1f" .Percentage}}% of {{.Statements}} statements</p>
+    <table>
+        <tr><th>Package</th><th>Coverage</th></tr>
+{{- range .SortedPackages}}
+        <tr><td>{{.Package}}</td><td>{{printf "%.1f" .Percentage}}%</td></tr>
+{{- end}}
+    </table>
+{{- end}}
+    <h2>Detailed Results</h2>
+{{- range .Suites}}
+    <h3>{{.SuiteName}} ({{.Environment}})</h3>
+    <table>
+        <tr><th>Test Name</th><th>Status</th><th>Duration</th><th>Category</th><th>Error</th></tr>
+{{- range .Results}}
+        <tr class="{{lower .Status}}"><td>{{.TestName}}</td><td>{{.Status}}</td><td>{{.Duration}}</td><td>{{.Category}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+    </table>
+{{- with .Performance}}{{with .Latency}}
+    <h4>Latency Distribution ({{.Count}} samples)</h4>
+    <table>
+        <tr>{{range .Percentiles}}<th>{{.Label}}</th>{{end}}</tr>
+        <tr>{{range .Percentiles}}<td>{{.Value}}</td>{{end}}</tr>
+    </table>
+    <table>
+        <tr><th>Latency</th><th>Samples</th><th>Distribution</th></tr>
+{{- range .Distribution}}
+        <tr><td>{{.Lower}} - {{.Upper}}</td><td>{{.Count}}</td><td><span class="bar" style="width: {{printf "%.1f" .Percent}}%"></span> {{printf "%.1f" .Percent}}%</td></tr>
+{{- end}}
+    </table>
+{{- end}}{{end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlFailureSection is a titled list of failed tests in the HTML report
+type htmlFailureSection struct {
+	Title   string
+	Results []TestResult
+}
+
+// htmlReportData is the data rendered by htmlReportTemplate
+type htmlReportData struct {
+	Summary         ReportSummary
+	FailureSections []htmlFailureSection
+	Owners          []OwnerGroup
+	Clusters        []FailureCluster
+	Coverage        *CoverageInfo
+	Suites          []TestSuiteResult
+}
+
+// generateHTMLReport generates an HTML report
+func (ta *TestAnalytics) generateHTMLReport() (string, error) {
+	data := htmlReportData{
+		Summary:  ta.Summary(),
+		Clusters: ta.GetFailureClusters(),
+		Coverage: ta.Coverage(),
+		Suites:   ta.Results,
+	}
+
+	// Failures split into regressions and known-flaky tests
+	if ta.Quarantine != nil {
+		data.FailureSections = []htmlFailureSection{
+			{"Regressions", ta.GetRegressions()},
+			{"Quarantined Failures (known flaky)", ta.GetQuarantinedFailures()},
+		}
+	}
+
+	if ta.hasOwners() {
+		data.Owners = ta.GroupByOwner()
+	}
+
+	var html strings.Builder
+	if err := htmlReportTemplate.Execute(&html, data); err != nil {
+		return "", err
+	}
+	return html.String(), nil
+}
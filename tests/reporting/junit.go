@@ -0,0 +1,168 @@
+package reporting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errTestIncomplete is recorded for tests whose binary exited before they reported a result
+const errTestIncomplete = "test did not complete"
+
+// JUnit XML model following the common JUnit XSD: every testsuite carries
+// properties, system-out and system-err, and failures are split from errors
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	ID         int             `xml:"id,attr"`
+	Package    string          `xml:"package,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Hostname   string          `xml:"hostname,attr"`
+	Properties junitProperties `xml:"properties"`
+	TestCases  []junitTestCase `xml:"testcase"`
+	SystemOut  string          `xml:"system-out"`
+	SystemErr  string          `xml:"system-err"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Skipped   *junitSkipped `xml:"skipped"`
+	Error     *junitFailure `xml:"error"`
+	Failure   *junitFailure `xml:"failure"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// isTestError reports whether a failed test errored (panicked, timed out, failed to
+// build or hit a fatal infrastructure error) rather than failing an assertion
+func isTestError(result TestResult) bool {
+	if result.Status != "FAIL" {
+		return false
+	}
+	if result.Error == errTestIncomplete || result.TestName == result.Package {
+		return true
+	}
+	return strings.Contains(result.Output, "panic:") || strings.Contains(result.Error, "FatalError{")
+}
+
+// junitSeconds formats a duration as JUnit seconds
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// generateJUnitReport generates a JUnit XML report
+func (ta *TestAnalytics) generateJUnitReport() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+
+	report := junitTestSuites{}
+	totalDuration := time.Duration(0)
+
+	for i, suite := range ta.Results {
+		junitSuite := junitTestSuite{
+			Name:      suite.SuiteName,
+			ID:        i,
+			Package:   suite.SuiteName,
+			Tests:     len(suite.Results),
+			Time:      junitSeconds(suite.Duration),
+			Timestamp: suite.StartTime.UTC().Format("2006-01-02T15:04:05"),
+			Hostname:  hostname,
+			Properties: junitProperties{Properties: []junitProperty{
+				{Name: "environment", Value: suite.Environment},
+				{Name: "region", Value: suite.Region},
+			}},
+		}
+		if suite.RunID != "" {
+			junitSuite.Properties.Properties = append(junitSuite.Properties.Properties, junitProperty{Name: "run_id", Value: suite.RunID})
+		}
+		if suite.GitSHA != "" {
+			junitSuite.Properties.Properties = append(junitSuite.Properties.Properties, junitProperty{Name: "git_sha", Value: suite.GitSHA})
+		}
+		if suite.Seed != nil {
+			junitSuite.Properties.Properties = append(junitSuite.Properties.Properties, junitProperty{Name: "seed", Value: strconv.FormatInt(*suite.Seed, 10)})
+		}
+		for _, owner := range suiteOwners(suite) {
+			junitSuite.Properties.Properties = append(junitSuite.Properties.Properties, junitProperty{Name: "owner", Value: owner})
+		}
+
+		for _, result := range suite.Results {
+			testCase := junitTestCase{
+				Name:      result.TestName,
+				Classname: result.Package,
+				Time:      junitSeconds(result.Duration),
+				SystemOut: result.Output,
+			}
+
+			switch {
+			case isTestError(result):
+				testCase.Error = &junitFailure{Message: result.Error, Type: "error", Body: result.Output}
+				junitSuite.Errors++
+			case result.Status == "FAIL":
+				testCase.Failure = &junitFailure{Message: result.Error, Type: "failure", Body: result.Output}
+				junitSuite.Failures++
+			case result.Status == "SKIP":
+				testCase.Skipped = &junitSkipped{Message: result.Error}
+				junitSuite.Skipped++
+			}
+			// Output already travels in the failure body
+			if testCase.Error != nil || testCase.Failure != nil {
+				testCase.SystemOut = ""
+			}
+
+			junitSuite.TestCases = append(junitSuite.TestCases, testCase)
+		}
+
+		report.Tests += junitSuite.Tests
+		report.Failures += junitSuite.Failures
+		report.Errors += junitSuite.Errors
+		report.Skipped += junitSuite.Skipped
+		totalDuration += suite.Duration
+		report.Suites = append(report.Suites, junitSuite)
+	}
+	report.Time = junitSeconds(totalDuration)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
@@ -0,0 +1,102 @@
+package reporting_test
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// hostileRun has results whose names and errors contain markup
+var hostileRun = reporting.TestSuiteResult{
+	SuiteName:   "network",
+	Environment: "dev",
+	Region:      "us-east-1",
+	StartTime:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	Duration:    90 * time.Second,
+	Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Status: "FAIL", Duration: time.Minute,
+			Error:  `Error: Invalid value for "vpc_cidr" <"10.0.0.0/33"> & more`,
+			Output: "apply failed: <script>alert('x')</script>\n"},
+		{TestName: "TestNetworkIdempotency", Status: "FAIL",
+			Error: "test did not complete", Output: "panic: test timed out after 30m0s\n"},
+		{TestName: "TestNetworkResiliency", Status: "SKIP", Error: "requires 3 AZs"},
+		{TestName: "TestNetworkConfigurationValidation", Status: "PASS", Output: "ok\n"},
+	},
+}
+
+func TestGenerateJUnitReport(t *testing.T) {
+	report, err := newAnalytics(hostileRun).GenerateReport("junit")
+	require.NoError(t, err)
+
+	var parsed struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Errors   int `xml:"errors,attr"`
+		Suites   []struct {
+			Name       string `xml:"name,attr"`
+			Failures   int    `xml:"failures,attr"`
+			Errors     int    `xml:"errors,attr"`
+			Skipped    int    `xml:"skipped,attr"`
+			Timestamp  string `xml:"timestamp,attr"`
+			Properties []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"properties>property"`
+			TestCases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Body    string `xml:",chardata"`
+				} `xml:"failure"`
+				Error *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"error"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+			SystemOut *string `xml:"system-out"`
+			SystemErr *string `xml:"system-err"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(report), &parsed), "report must be well-formed XML")
+
+	assert.Equal(t, 4, parsed.Tests)
+	assert.Equal(t, 1, parsed.Failures)
+	assert.Equal(t, 1, parsed.Errors)
+
+	require.Len(t, parsed.Suites, 1)
+	suite := parsed.Suites[0]
+	assert.Equal(t, "2024-05-01T10:00:00", suite.Timestamp)
+	assert.Equal(t, 1, suite.Skipped)
+	assert.NotNil(t, suite.SystemOut)
+	assert.NotNil(t, suite.SystemErr)
+	require.Len(t, suite.Properties, 2)
+	assert.Equal(t, "environment", suite.Properties[0].Name)
+	assert.Equal(t, "dev", suite.Properties[0].Value)
+	assert.Equal(t, "us-east-1", suite.Properties[1].Value)
+
+	require.NotNil(t, suite.TestCases[0].Failure)
+	assert.Equal(t, `Error: Invalid value for "vpc_cidr" <"10.0.0.0/33"> & more`, suite.TestCases[0].Failure.Message)
+	assert.Contains(t, suite.TestCases[0].Failure.Body, "<script>")
+	require.NotNil(t, suite.TestCases[1].Error)
+	assert.Nil(t, suite.TestCases[1].Failure)
+	assert.Equal(t, "ok\n", suite.TestCases[3].SystemOut)
+}
+
+func TestGenerateHTMLReportEscapesContent(t *testing.T) {
+	analytics := newAnalytics(hostileRun)
+	analytics.Results[0].Results[0].TestName = "<img src=x onerror=alert(1)>"
+
+	report, err := analytics.GenerateReport("html")
+	require.NoError(t, err)
+
+	assert.NotContains(t, report, "<img src=x")
+	assert.Contains(t, report, "&lt;img src=x onerror=alert(1)&gt;")
+	assert.Contains(t, report, "&lt;&#34;10.0.0.0/33&#34;&gt; &amp; more")
+	assert.Contains(t, report, "Pass Rate: 25.0%")
+	assert.Contains(t, report, "<h2>Failure Clusters</h2>")
+}
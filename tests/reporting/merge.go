@@ -0,0 +1,326 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// junitTimestampLayouts are the timestamp formats accepted when reading JUnit reports
+var junitTimestampLayouts = []string{
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// LoadReport reads a report previously written by ExportResults in json or junit
+// format. The format is detected from the file contents.
+func LoadReport(filename string) (*TestAnalytics, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		analytics := NewTestAnalytics()
+		if err := json.Unmarshal(trimmed, analytics); err != nil {
+			return nil, fmt.Errorf("invalid JSON report %s: %w", filename, err)
+		}
+		return analytics, nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		suites, err := ParseJUnitReport(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JUnit report %s: %w", filename, err)
+		}
+		analytics := NewTestAnalytics()
+		for _, suite := range suites {
+			analytics.AddResult(suite)
+		}
+		return analytics, nil
+	default:
+		return nil, fmt.Errorf("unrecognized report format: %s", filepath.Base(filename))
+	}
+}
+
+// ParseJUnitReport parses a JUnit XML document with either a <testsuites> or a
+// single <testsuite> root element
+func ParseJUnitReport(data []byte) ([]TestSuiteResult, error) {
+	var root junitTestSuites
+	if err := xml.Unmarshal(data, &root); err != nil {
+		var single junitTestSuite
+		if singleErr := xml.Unmarshal(data, &single); singleErr != nil {
+			return nil, err
+		}
+		root.Suites = []junitTestSuite{single}
+	}
+
+	suites := make([]TestSuiteResult, 0, len(root.Suites))
+	for _, junitSuite := range root.Suites {
+		suite := TestSuiteResult{
+			SuiteName: junitSuite.Name,
+			Duration:  parseJUnitSeconds(junitSuite.Time),
+		}
+		for _, layout := range junitTimestampLayouts {
+			if start, err := time.Parse(layout, junitSuite.Timestamp); err == nil {
+				suite.StartTime = start
+				suite.EndTime = start.Add(suite.Duration)
+				break
+			}
+		}
+		for _, property := range junitSuite.Properties.Properties {
+			switch property.Name {
+			case "environment":
+				suite.Environment = property.Value
+			case "region":
+				suite.Region = property.Value
+			case "run_id":
+				suite.RunID = property.Value
+			case "git_sha":
+				suite.GitSHA = property.Value
+			case "seed":
+				if seed, err := strconv.ParseInt(property.Value, 10, 64); err == nil {
+					suite.Seed = &seed
+				}
+			}
+		}
+
+		for _, testCase := range junitSuite.TestCases {
+			pkg := packageFromClassname(testCase.Classname)
+			result := TestResult{
+				TestName:  testCase.Name,
+				Package:   pkg,
+				Status:    "PASS",
+				Duration:  parseJUnitSeconds(testCase.Time),
+				Output:    testCase.SystemOut,
+				Timestamp: suite.StartTime,
+				Category:  categoryForPackage(pkg),
+				Parent:    parentTestName(testCase.Name),
+			}
+
+			switch {
+			case testCase.Failure != nil:
+				result.Status = "FAIL"
+				result.Error = testCase.Failure.Message
+				result.Output = testCase.Failure.Body
+			case testCase.Error != nil:
+				result.Status = "FAIL"
+				result.Error = testCase.Error.Message
+				result.Output = testCase.Error.Body
+			case testCase.Skipped != nil:
+				result.Status = "SKIP"
+				result.Error = testCase.Skipped.Message
+			}
+
+			suite.Results = append(suite.Results, result)
+		}
+		suite.Results = collapseSubtests(suite.Results)
+
+		recountSuite(&suite)
+		suites = append(suites, suite)
+	}
+
+	return suites, nil
+}
+
+// packageFromClassname reduces a JUnit classname such as an import path to the short package name
+func packageFromClassname(classname string) string {
+	if i := strings.LastIndexAny(classname, "/."); i >= 0 && i < len(classname)-1 {
+		return classname[i+1:]
+	}
+	return classname
+}
+
+// parseJUnitSeconds parses a JUnit time attribute, treating malformed values as zero
+func parseJUnitSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// MergeReports loads report files written by parallel CI shards and merges them
+func MergeReports(filenames ...string) (*TestAnalytics, error) {
+	reports := make([]*TestAnalytics, 0, len(filenames))
+	for _, filename := range filenames {
+		report, err := LoadReport(filename)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return MergeAnalytics(reports...), nil
+}
+
+// MergeAnalytics combines suites with the same name from several reports into one.
+// A test that appears more than once (a retried shard, or `go test -count`) keeps
+// its final attempt and records how many attempts were made. Later reports win ties.
+func MergeAnalytics(reports ...*TestAnalytics) *TestAnalytics {
+	merged := NewTestAnalytics()
+	index := make(map[string]int)
+
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+		if merged.Quarantine == nil {
+			merged.Quarantine = report.Quarantine
+		}
+
+		for _, suite := range report.Results {
+			i, ok := index[suite.SuiteName]
+			if !ok {
+				copied := suite
+				copied.Results = nil
+				copied.Duration = 0
+				copied.Coverage = nil
+				copied.Performance = nil
+				merged.Results = append(merged.Results, copied)
+				i = len(merged.Results) - 1
+				index[suite.SuiteName] = i
+			}
+			mergeSuite(&merged.Results[i], suite)
+		}
+	}
+
+	for i := range merged.Results {
+		recountSuite(&merged.Results[i])
+	}
+
+	return merged
+}
+
+// mergeSuite folds a shard's suite into the merged suite
+func mergeSuite(target *TestSuiteResult, shard TestSuiteResult) {
+	if !shard.StartTime.IsZero() && (target.StartTime.IsZero() || shard.StartTime.Before(target.StartTime)) {
+		target.StartTime = shard.StartTime
+	}
+	if shard.EndTime.After(target.EndTime) {
+		target.EndTime = shard.EndTime
+	}
+	target.Duration += shard.Duration
+	target.Coverage = mergeCoverage(target.Coverage, shard.Coverage)
+	target.Performance = mergePerformance(target.Performance, shard.Performance)
+	if target.Environment == "" {
+		target.Environment = shard.Environment
+	}
+	if target.Region == "" {
+		target.Region = shard.Region
+	}
+
+	positions := make(map[[2]string]int, len(target.Results))
+	for i, result := range target.Results {
+		positions[[2]string{result.Package, result.TestName}] = i
+	}
+
+	for _, result := range shard.Results {
+		attempts := result.Attempts
+		if attempts == 0 {
+			attempts = 1
+		}
+
+		key := [2]string{result.Package, result.TestName}
+		i, ok := positions[key]
+		if !ok {
+			result.Attempts = attempts
+			target.Results = append(target.Results, result)
+			positions[key] = len(target.Results) - 1
+			continue
+		}
+
+		previous := target.Results[i]
+		attempts += previous.Attempts
+		if result.Timestamp.Before(previous.Timestamp) {
+			// An earlier attempt arriving late does not replace the final one
+			previous.Attempts = attempts
+			target.Results[i] = previous
+			continue
+		}
+		result.Attempts = attempts
+		target.Results[i] = result
+	}
+}
+
+// mergeCoverage combines coverage from two shards. Shards run their tests over
+// the same code, so a file is as covered as its most covered shard, and the
+// overall and per-package figures are recomputed from the merged files. Coverage
+// without per-file statement counts keeps the totals of the shard that covered
+// the most statements, and the highest figure of each package.
+func mergeCoverage(a, b *CoverageInfo) *CoverageInfo {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	files := make(map[string]float64)
+	statements := make(map[string]int)
+	for _, source := range []*CoverageInfo{a, b} {
+		for file, percentage := range source.FileCoverage {
+			if current, ok := files[file]; !ok || percentage > current {
+				files[file] = percentage
+			}
+			if source.FileStatements[file] > statements[file] {
+				statements[file] = source.FileStatements[file]
+			}
+		}
+	}
+
+	functions := a.Functions
+	if b.Functions > functions {
+		functions = b.Functions
+	}
+	if len(statements) > 0 {
+		merged := coverageFromFiles(files, statements)
+		merged.Functions = functions
+		return merged
+	}
+
+	best := a
+	if b.Percentage*float64(b.Statements) > a.Percentage*float64(a.Statements) {
+		best = b
+	}
+	merged := &CoverageInfo{
+		Functions:       functions,
+		Statements:      best.Statements,
+		Percentage:      best.Percentage,
+		FileCoverage:    files,
+		PackageCoverage: make(map[string]float64),
+	}
+	for _, source := range []*CoverageInfo{a, b} {
+		for pkg, percentage := range source.PackageCoverage {
+			if percentage > merged.PackageCoverage[pkg] {
+				merged.PackageCoverage[pkg] = percentage
+			}
+		}
+	}
+	return merged
+}
+
+// recountSuite recomputes a suite's totals from its results
+func recountSuite(suite *TestSuiteResult) {
+	suite.TotalTests = len(suite.Results)
+	suite.PassedTests = 0
+	suite.FailedTests = 0
+	suite.SkippedTests = 0
+
+	for _, result := range suite.Results {
+		switch result.Status {
+		case "PASS":
+			suite.PassedTests++
+		case "FAIL":
+			suite.FailedTests++
+		case "SKIP":
+			suite.SkippedTests++
+		}
+	}
+}
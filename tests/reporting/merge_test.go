@@ -0,0 +1,112 @@
+package reporting_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// writeShard renders a single-suite report in the given format and writes it to dir
+func writeShard(t *testing.T, dir, name, format string, suite reporting.TestSuiteResult) string {
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(suite)
+
+	report, err := analytics.GenerateReport(format)
+	require.NoError(t, err)
+
+	filename := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(filename, []byte(report), 0644))
+	return filename
+}
+
+func TestMergeReports(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	first := writeShard(t, dir, "network-1.json", "json", reporting.TestSuiteResult{
+		SuiteName: "network", Environment: "dev", Region: "us-east-1",
+		StartTime: start, EndTime: start.Add(time.Minute), Duration: time.Minute,
+		Coverage: &reporting.CoverageInfo{Percentage: 50, Statements: 100},
+		Results: []reporting.TestResult{
+			{TestName: "TestNetworkProvisioning", Package: "network", Status: "FAIL", Error: "timeout", Timestamp: start, Duration: 40 * time.Second},
+			{TestName: "TestNetworkIdempotency", Package: "network", Status: "PASS", Timestamp: start, Duration: 20 * time.Second},
+		},
+	})
+	retry := writeShard(t, dir, "network-retry.xml", "junit", reporting.TestSuiteResult{
+		SuiteName: "network", Environment: "dev", Region: "us-east-1",
+		StartTime: start.Add(time.Hour), Duration: 30 * time.Second,
+		Results: []reporting.TestResult{
+			{TestName: "TestNetworkProvisioning", Package: "network", Status: "PASS", Duration: 30 * time.Second},
+		},
+	})
+	chaos := writeShard(t, dir, "chaos.xml", "junit", reporting.TestSuiteResult{
+		SuiteName: "chaos", StartTime: start, Duration: 2 * time.Minute,
+		Results: []reporting.TestResult{
+			{TestName: "TestGWLBFailure", Package: "chaos", Status: "SKIP", Error: "needs AWS"},
+			{TestName: "TestAZFailureResiliency/AZRecovery", Package: "chaos", Status: "FAIL", Error: `expected "<ok>"`},
+		},
+	})
+
+	merged, err := reporting.MergeReports(first, retry, chaos)
+	require.NoError(t, err)
+	require.Len(t, merged.Results, 2)
+
+	network := merged.Results[0]
+	assert.Equal(t, "network", network.SuiteName)
+	assert.Equal(t, 2, network.TotalTests)
+	assert.Equal(t, 2, network.PassedTests)
+	assert.Equal(t, 0, network.FailedTests)
+	assert.Equal(t, 90*time.Second, network.Duration)
+	assert.Equal(t, start, network.StartTime)
+	assert.Equal(t, "us-east-1", network.Region)
+	require.NotNil(t, network.Coverage)
+	assert.Equal(t, 50.0, network.Coverage.Percentage)
+
+	provisioning := network.Results[0]
+	assert.Equal(t, "TestNetworkProvisioning", provisioning.TestName)
+	assert.Equal(t, "PASS", provisioning.Status, "the final attempt wins")
+	assert.Equal(t, 2, provisioning.Attempts)
+
+	chaosSuite := merged.Results[1]
+	assert.Equal(t, 1, chaosSuite.SkippedTests)
+	assert.Equal(t, 1, chaosSuite.FailedTests)
+	assert.Equal(t, "TestAZFailureResiliency", chaosSuite.Results[1].Parent)
+	assert.Equal(t, `expected "<ok>"`, chaosSuite.Results[1].Error)
+	assert.Equal(t, "chaos", chaosSuite.Results[1].Category)
+
+	report, err := merged.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, report, "- **Total Tests**: 4")
+}
+
+func TestMergeAnalyticsKeepsFinalAttempt(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	late := reporting.NewTestAnalytics()
+	late.AddResult(reporting.TestSuiteResult{SuiteName: "inspection", Results: []reporting.TestResult{
+		{TestName: "TestGWLBHealthChecks", Package: "inspection", Status: "PASS", Timestamp: start.Add(time.Hour)},
+	}})
+	early := reporting.NewTestAnalytics()
+	early.AddResult(reporting.TestSuiteResult{SuiteName: "inspection", Results: []reporting.TestResult{
+		{TestName: "TestGWLBHealthChecks", Package: "inspection", Status: "FAIL", Timestamp: start},
+	}})
+
+	merged := reporting.MergeAnalytics(late, early)
+	require.Len(t, merged.Results, 1)
+	require.Len(t, merged.Results[0].Results, 1)
+	assert.Equal(t, "PASS", merged.Results[0].Results[0].Status)
+	assert.Equal(t, 2, merged.Results[0].Results[0].Attempts)
+}
+
+func TestLoadReportRejectsUnknownFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.md")
+	require.NoError(t, os.WriteFile(filename, []byte("# Test Analytics Report\n"), 0644))
+
+	_, err := reporting.LoadReport(filename)
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package reporting
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// repoImportPath is the import path of the repository root; the tests module lives
+// in its tests directory
+const repoImportPath = "github.com/your-org/aws-centralized-inspection"
+
+// testsDir is the repository directory of the tests module
+const testsDir = "tests"
+
+var (
+	// errorTracePattern matches the first frame of a testify Error Trace
+	errorTracePattern = regexp.MustCompile(`^\s*Error Trace:\s*(\S+\.go):(\d+)`)
+
+	// sourceLocationPattern matches the location of a t.Error or t.Fatal line
+	sourceLocationPattern = regexp.MustCompile(`^\s*([\w./-]+\.go):(\d+): `)
+)
+
+// packageModuleFiles maps test packages that exercise a single module to its main
+// file, where failures without a source location are reported
+var packageModuleFiles = map[string]string{
+	"network":           "modules/network/main.tf",
+	"inspection":        "modules/inspection/main.tf",
+	"firewall-vmseries": "modules/firewall-vmseries/main.tf",
+	"compliance":        "modules/network/main.tf",
+	"remediation":       "modules/automated-remediation/main.tf",
+	"integration":       "live/main.tf",
+	"routecheck":        "modules/inspection/main.tf",
+}
+
+// ModuleFileForTest returns the repository-relative Terraform file the package of a
+// test exercises, or "" when the package covers several modules or none
+func ModuleFileForTest(result TestResult) string {
+	return packageModuleFiles[result.Package]
+}
+
+// FailureLocation returns the repository-relative file and line of the assertion
+// that failed a test: its Location when the ingested stream recorded one, else the
+// first testify Error Trace frame or t.Error line of its output. ok is false when
+// the output has no location.
+func FailureLocation(result TestResult) (file string, line int, ok bool) {
+	location := result.Location
+	if location == "" {
+		location = sourceLocation(result.Output, path.Join(testsDir, result.Package))
+	}
+
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(location[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return location[:i], line, true
+}
+
+// sourceLocation returns the repository-relative "file:line" of the failing
+// assertion in test output, or "". dir is the repository directory of the test
+// package, for the bare file names go test prints.
+func sourceLocation(output, dir string) string {
+	var file, line string
+	for _, text := range strings.Split(output, "\n") {
+		if match := errorTracePattern.FindStringSubmatch(text); match != nil {
+			file, line = match[1], match[2]
+			break
+		}
+		if file != "" || seedPattern.MatchString(text) {
+			continue
+		}
+		if match := sourceLocationPattern.FindStringSubmatch(text); match != nil {
+			file, line = match[1], match[2]
+		}
+	}
+	if file == "" {
+		return ""
+	}
+
+	switch {
+	case strings.HasPrefix(file, "/"):
+		// testify prints absolute paths; keep the part from the tests module on
+		i := strings.LastIndex(file, "/"+testsDir+"/")
+		if i < 0 {
+			return ""
+		}
+		file = file[i+1:]
+	case strings.Contains(file, "/"):
+		file = path.Join(testsDir, file)
+	default:
+		file = path.Join(dir, file)
+	}
+	return file + ":" + line
+}
+
+// packageDir returns the repository directory of a test package import path, or
+// "" when the package is not part of the repository
+func packageDir(importPath string) string {
+	if !strings.HasPrefix(importPath, repoImportPath+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(importPath, repoImportPath+"/")
+}
@@ -0,0 +1,302 @@
+package reporting
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OpenMetricsContentType is served by MetricsHandler
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	// PushGatewayContentType is the Prometheus text format accepted by the Pushgateway
+	PushGatewayContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// MetricsNamespace prefixes every exported metric name
+	MetricsNamespace = "inspection_test"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the test duration histogram.
+// Terratest suites range from sub-second unit tests to half-hour deployments.
+var DurationBuckets = []float64{0.1, 1, 5, 15, 60, 300, 900, 1800}
+
+// metricLabel is a single name="value" label of a series
+type metricLabel struct {
+	name  string
+	value string
+}
+
+// metricsWriter writes metric families in the OpenMetrics text format
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+// family writes the HELP and TYPE lines of a metric family
+func (mw *metricsWriter) family(name, metricType, help string) {
+	fmt.Fprintf(&mw.buf, "# TYPE %s_%s %s\n", MetricsNamespace, name, metricType)
+	fmt.Fprintf(&mw.buf, "# HELP %s_%s %s\n", MetricsNamespace, name, help)
+}
+
+// sample writes a single sample of a family
+func (mw *metricsWriter) sample(name string, value float64, labels ...metricLabel) {
+	mw.buf.WriteString(MetricsNamespace + "_" + name)
+	if len(labels) > 0 {
+		mw.buf.WriteString("{")
+		for i, label := range labels {
+			if i > 0 {
+				mw.buf.WriteString(",")
+			}
+			fmt.Fprintf(&mw.buf, "%s=\"%s\"", label.name, escapeLabelValue(label.value))
+		}
+		mw.buf.WriteString("}")
+	}
+	mw.buf.WriteString(" " + formatMetricValue(value) + "\n")
+}
+
+// escapeLabelValue escapes backslashes, quotes and newlines in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatMetricValue formats a sample value in its shortest exact form
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteOpenMetrics writes the analytics in the OpenMetrics text format: test
+// counts by package, category and status, pass ratios per package and category,
+// test duration histograms per package, and flaky and quarantined test counts.
+func (ta *TestAnalytics) WriteOpenMetrics(w io.Writer) error {
+	mw := &metricsWriter{}
+	summary := ta.Summary()
+
+	mw.family("suites", "gauge", "Number of test suites in the report.")
+	mw.sample("suites", float64(summary.Suites))
+
+	mw.family("results", "gauge", "Number of tests by package, category and status.")
+	counts := make(map[[3]string]int)
+	durations := make(map[string][]time.Duration)
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			counts[[3]string{result.Package, result.Category, strings.ToLower(result.Status)}]++
+			durations[result.Package] = append(durations[result.Package], result.Duration)
+		}
+	}
+	countKeys := make([][3]string, 0, len(counts))
+	for key := range counts {
+		countKeys = append(countKeys, key)
+	}
+	sort.Slice(countKeys, func(i, j int) bool {
+		for k := range countKeys[i] {
+			if countKeys[i][k] != countKeys[j][k] {
+				return countKeys[i][k] < countKeys[j][k]
+			}
+		}
+		return false
+	})
+	for _, key := range countKeys {
+		mw.sample("results", float64(counts[key]),
+			metricLabel{"package", key[0]}, metricLabel{"category", key[1]}, metricLabel{"status", key[2]})
+	}
+
+	if summary.Suites > 0 {
+		metrics := ta.GetMetrics()
+		for _, group := range []struct {
+			name  string
+			label string
+			stats map[string]map[string]int
+		}{
+			{"package_pass_ratio", "package", metrics["package_stats"].(map[string]map[string]int)},
+			{"category_pass_ratio", "category", metrics["category_stats"].(map[string]map[string]int)},
+		} {
+			mw.family(group.name, "gauge", fmt.Sprintf("Share of tests that passed per %s, from 0 to 1.", group.label))
+			for _, name := range sortedKeys(group.stats) {
+				stats := group.stats[name]
+				if stats["total"] == 0 {
+					continue
+				}
+				mw.sample(group.name, float64(stats["passed"])/float64(stats["total"]), metricLabel{group.label, name})
+			}
+		}
+	}
+
+	mw.family("duration_seconds", "histogram", "Test durations per package.")
+	for _, pkg := range sortedKeys(durations) {
+		cumulative := make([]int, len(DurationBuckets))
+		sum := 0.0
+		for _, duration := range durations[pkg] {
+			seconds := duration.Seconds()
+			sum += seconds
+			for i, bound := range DurationBuckets {
+				if seconds <= bound {
+					cumulative[i]++
+				}
+			}
+		}
+		for i, bound := range DurationBuckets {
+			mw.sample("duration_seconds_bucket", float64(cumulative[i]),
+				metricLabel{"package", pkg}, metricLabel{"le", formatMetricValue(bound)})
+		}
+		mw.sample("duration_seconds_bucket", float64(len(durations[pkg])),
+			metricLabel{"package", pkg}, metricLabel{"le", "+Inf"})
+		mw.sample("duration_seconds_sum", sum, metricLabel{"package", pkg})
+		mw.sample("duration_seconds_count", float64(len(durations[pkg])), metricLabel{"package", pkg})
+	}
+
+	flaky := make(map[string]int)
+	for _, test := range ta.GetFlakyTests(0) {
+		flaky[test.Package]++
+	}
+	mw.family("flaky", "gauge", "Number of tests that both passed and failed across the loaded runs, per package.")
+	for _, pkg := range sortedKeys(flaky) {
+		mw.sample("flaky", float64(flaky[pkg]), metricLabel{"package", pkg})
+	}
+
+	if ta.Quarantine != nil {
+		quarantined := make(map[string]int)
+		for _, entry := range ta.Quarantine.Tests {
+			quarantined[entry.Package]++
+		}
+		mw.family("quarantined", "gauge", "Number of quarantined tests per package.")
+		for _, pkg := range sortedKeys(quarantined) {
+			mw.sample("quarantined", float64(quarantined[pkg]), metricLabel{"package", pkg})
+		}
+	}
+
+	if ta.hasOwners() {
+		mw.family("owner_results", "gauge", "Number of tests by owner and status.")
+		for _, group := range ta.GroupByOwner() {
+			for _, count := range []struct {
+				status string
+				value  int
+			}{{"pass", group.Passed}, {"fail", group.Failed}, {"skip", group.Skipped}} {
+				mw.sample("owner_results", float64(count.value), metricLabel{"owner", group.Owner}, metricLabel{"status", count.status})
+			}
+		}
+	}
+
+	mw.buf.WriteString("# EOF\n")
+	_, err := w.Write(mw.buf.Bytes())
+	return err
+}
+
+// generateOpenMetricsReport generates an OpenMetrics text report
+func (ta *TestAnalytics) generateOpenMetricsReport() (string, error) {
+	var buf bytes.Buffer
+	if err := ta.WriteOpenMetrics(&buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteOpenMetricsFile writes the metrics to a file, for example for the node
+// exporter textfile collector. The file is replaced atomically so a scrape never
+// sees a partial write.
+func (ta *TestAnalytics) WriteOpenMetricsFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := ta.WriteOpenMetrics(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// MetricsHandler serves the analytics in the OpenMetrics text format. The
+// analytics are read on every request, so results added later are included.
+func (ta *TestAnalytics) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := ta.snapshot().WriteOpenMetrics(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", OpenMetricsContentType)
+		w.Write(buf.Bytes())
+	})
+}
+
+// PushGateway pushes analytics to a Prometheus Pushgateway
+type PushGateway struct {
+	URL      string            // base URL, e.g. http://pushgateway:9091
+	Job      string            // job label of the pushed group
+	Grouping map[string]string // additional grouping labels, e.g. environment
+	Client   *http.Client      // defaults to a client with a 30 second timeout
+}
+
+// NewPushGateway creates a Pushgateway client for the given job
+func NewPushGateway(baseURL, job string) *PushGateway {
+	return &PushGateway{
+		URL:      strings.TrimSuffix(baseURL, "/"),
+		Job:      job,
+		Grouping: make(map[string]string),
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// groupURL returns the URL of the pushed metrics group
+func (pg *PushGateway) groupURL() string {
+	var path strings.Builder
+	path.WriteString(pg.URL + "/metrics/job/" + url.PathEscape(pg.Job))
+	for _, name := range sortedKeys(pg.Grouping) {
+		path.WriteString("/" + url.PathEscape(name) + "/" + url.PathEscape(pg.Grouping[name]))
+	}
+	return path.String()
+}
+
+// Push replaces the metrics of the group with the current analytics
+func (pg *PushGateway) Push(ta *TestAnalytics) error {
+	if pg.Job == "" {
+		return fmt.Errorf("pushgateway job is required")
+	}
+
+	var buf bytes.Buffer
+	if err := ta.WriteOpenMetrics(&buf); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, pg.groupURL(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", PushGatewayContentType)
+
+	client := pg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package reporting_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// metricsRun is two network runs, the first with a compliance suite, and a flaky
+// network test
+var metricsRun = []reporting.TestSuiteResult{
+	{SuiteName: "network", RunID: "run-1", Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Category: "unit", Status: "PASS", Duration: 500 * time.Millisecond},
+		{TestName: "TestTGWAttachments", Category: "unit", Status: "FAIL", Duration: 90 * time.Second},
+	}},
+	{SuiteName: "compliance", RunID: "run-1", Results: []reporting.TestResult{
+		{TestName: "TestPCIDSS", Package: `compli"ance`, Category: "compliance", Status: "PASS", Duration: 2 * time.Second},
+	}},
+	{SuiteName: "network", RunID: "run-2", Results: []reporting.TestResult{
+		{TestName: "TestTGWAttachments", Category: "unit", Status: "PASS", Duration: 80 * time.Second},
+	}},
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	analytics := newAnalytics(metricsRun...)
+	analytics.SetQuarantine(&reporting.Quarantine{Tests: []reporting.QuarantineEntry{
+		{TestName: "TestTGWAttachments", Package: "network"},
+	}})
+
+	var out strings.Builder
+	require.NoError(t, analytics.WriteOpenMetrics(&out))
+	metrics := out.String()
+
+	for _, line := range []string{
+		"# TYPE inspection_test_suites gauge",
+		"inspection_test_suites 3",
+		`inspection_test_results{package="network",category="unit",status="pass"} 2`,
+		`inspection_test_results{package="network",category="unit",status="fail"} 1`,
+		`inspection_test_package_pass_ratio{package="network"} 0.6666666666666666`,
+		`inspection_test_category_pass_ratio{category="compliance"} 1`,
+		`inspection_test_package_pass_ratio{package="compli\"ance"} 1`,
+		"# TYPE inspection_test_duration_seconds histogram",
+		`inspection_test_duration_seconds_bucket{package="network",le="1"} 1`,
+		`inspection_test_duration_seconds_bucket{package="network",le="60"} 1`,
+		`inspection_test_duration_seconds_bucket{package="network",le="300"} 3`,
+		`inspection_test_duration_seconds_bucket{package="network",le="+Inf"} 3`,
+		`inspection_test_duration_seconds_sum{package="network"} 170.5`,
+		`inspection_test_duration_seconds_count{package="network"} 3`,
+		`inspection_test_flaky{package="network"} 1`,
+		`inspection_test_quarantined{package="network"} 1`,
+	} {
+		assert.Contains(t, metrics, line+"\n")
+	}
+	assert.True(t, strings.HasSuffix(metrics, "# EOF\n"))
+}
+
+func TestWriteOpenMetricsEmpty(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, reporting.NewTestAnalytics().WriteOpenMetrics(&out))
+	assert.Contains(t, out.String(), "inspection_test_suites 0\n")
+}
+
+func TestWriteOpenMetricsFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "textfile", "inspection_tests.prom")
+	require.NoError(t, newAnalytics(metricsRun...).WriteOpenMetricsFile(filename))
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "inspection_test_suites 3")
+
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are cleaned up")
+}
+
+func TestMetricsHandler(t *testing.T) {
+	analytics := newAnalytics(metricsRun...)
+	handler := analytics.MetricsHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, reporting.OpenMetricsContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "inspection_test_suites 3")
+
+	// Results added after the handler was created are served
+	analytics.AddResult(reporting.TestSuiteResult{SuiteName: "chaos"})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "inspection_test_suites 4")
+}
+
+func TestMetricsHandlerConcurrentAddResult(t *testing.T) {
+	analytics := newAnalytics(metricsRun...)
+	handler := analytics.MetricsHandler()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			analytics.AddResult(reporting.TestSuiteResult{SuiteName: "chaos"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+	}()
+	wg.Wait()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "inspection_test_suites 53")
+}
+
+func TestGetMetricsStatusKeys(t *testing.T) {
+	metrics := newAnalytics(metricsRun...).GetMetrics()
+
+	network := metrics["package_stats"].(map[string]map[string]int)["network"]
+	assert.Equal(t, map[string]int{"total": 3, "passed": 2, "failed": 1, "skipped": 0}, network)
+	unit := metrics["category_stats"].(map[string]map[string]int)["unit"]
+	assert.Equal(t, map[string]int{"total": 3, "passed": 2, "failed": 1, "skipped": 0}, unit)
+}
+
+func TestGetMetricsEmpty(t *testing.T) {
+	metrics := reporting.NewTestAnalytics().GetMetrics()
+
+	assert.Equal(t, 0, metrics["total_tests"])
+	assert.Equal(t, 0.0, metrics["pass_rate"])
+	assert.Equal(t, time.Duration(0), metrics["avg_duration"])
+}
+
+func TestPushGateway(t *testing.T) {
+	var method, path, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, contentType, body = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	gateway := reporting.NewPushGateway(server.URL+"/", "inspection-tests")
+	gateway.Grouping["environment"] = "dev"
+	gateway.Grouping["region"] = "us-east-1"
+	require.NoError(t, gateway.Push(newAnalytics(metricsRun...)))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/inspection-tests/environment/dev/region/us-east-1", path)
+	assert.Equal(t, reporting.PushGatewayContentType, contentType)
+	assert.Contains(t, body, `inspection_test_flaky{package="network"} 1`)
+}
+
+func TestPushGatewayErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "text format parsing error", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := reporting.NewPushGateway(server.URL, "inspection-tests").Push(newAnalytics(metricsRun...))
+	assert.ErrorContains(t, err, "400 Bad Request: text format parsing error")
+
+	err = reporting.NewPushGateway(server.URL, "").Push(newAnalytics(metricsRun...))
+	assert.ErrorContains(t, err, "job is required")
+}
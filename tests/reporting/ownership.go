@@ -0,0 +1,260 @@
+package reporting
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// OwnershipFileName is the default test ownership file in the tests directory
+	OwnershipFileName = "TESTOWNERS"
+
+	// Unowned is the owner assigned to tests no ownership rule matches
+	Unowned = "unowned"
+)
+
+// OwnershipRule routes tests matching a pattern to an owner. "chaos/" matches every
+// test in the chaos package, "cost/TestBudget*" matching tests in the cost package,
+// "TestAZ*" matching tests in any package and "*" every test. Test name globs use
+// path.Match syntax and are matched against the top-level test name as well as the
+// full subtest name.
+type OwnershipRule struct {
+	Pattern string `json:"pattern"`
+	Owner   string `json:"owner"`
+	Line    int    `json:"line"`
+}
+
+// Ownership maps tests to owning teams. As in CODEOWNERS, the last matching rule wins.
+type Ownership struct {
+	Rules []OwnershipRule `json:"rules"`
+}
+
+// OwnerGroup holds the results owned by a single owner
+type OwnerGroup struct {
+	Owner       string       `json:"owner"`
+	Tests       int          `json:"tests"`
+	Passed      int          `json:"passed"`
+	Failed      int          `json:"failed"`
+	Skipped     int          `json:"skipped"`
+	FailedTests []string     `json:"failed_tests,omitempty"` // package/test of each failure
+	Results     []TestResult `json:"-"`
+}
+
+// ParseOwnership parses an ownership file. Each non-empty line that is not a
+// # comment holds a pattern and a single owner, separated by whitespace.
+func ParseOwnership(r io.Reader) (*Ownership, error) {
+	ownership := &Ownership{}
+	scanner := bufio.NewScanner(r)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a pattern and one owner, got %q", lineNumber, strings.TrimSpace(line))
+		}
+
+		pattern := fields[0]
+		if err := validateTestPattern(pattern); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		ownership.Rules = append(ownership.Rules, OwnershipRule{Pattern: pattern, Owner: fields[1], Line: lineNumber})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ownership, nil
+}
+
+// LoadOwnership reads an ownership file
+func LoadOwnership(filename string) (*Ownership, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ownership, err := ParseOwnership(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ownership file %s: %w", filename, err)
+	}
+	return ownership, nil
+}
+
+// validateTestPattern rejects test patterns path.Match cannot evaluate
+func validateTestPattern(pattern string) error {
+	pkg, test := splitTestPattern(pattern)
+	for _, glob := range []string{pkg, test} {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// splitTestPattern splits a test pattern into its package and test name globs,
+// either of which matches everything when it is "*"
+func splitTestPattern(pattern string) (string, string) {
+	pkg, test, found := strings.Cut(pattern, "/")
+	if !found {
+		return "*", pkg
+	}
+	if test == "" {
+		test = "*"
+	}
+	return pkg, test
+}
+
+// matchTestPattern reports whether a test pattern, as described on OwnershipRule,
+// matches a test
+func matchTestPattern(pattern, pkg, testName string) bool {
+	pkgGlob, testGlob := splitTestPattern(pattern)
+	if ok, _ := path.Match(pkgGlob, pkg); !ok {
+		return false
+	}
+	if testGlob == "*" {
+		return true
+	}
+
+	topLevel, _, _ := strings.Cut(testName, "/")
+	if ok, _ := path.Match(testGlob, topLevel); ok {
+		return true
+	}
+	ok, _ := path.Match(testGlob, testName)
+	return ok
+}
+
+// OwnerOf returns the owner of a test, or Unowned if no rule matches
+func (o *Ownership) OwnerOf(pkg, testName string) string {
+	if o == nil {
+		return Unowned
+	}
+	for i := len(o.Rules) - 1; i >= 0; i-- {
+		if matchTestPattern(o.Rules[i].Pattern, pkg, testName) {
+			return o.Rules[i].Owner
+		}
+	}
+	return Unowned
+}
+
+// AssignOwners annotates every result with its owner
+func (ta *TestAnalytics) AssignOwners(ownership *Ownership) {
+	for i := range ta.Results {
+		for j := range ta.Results[i].Results {
+			result := &ta.Results[i].Results[j]
+			result.Owner = ownership.OwnerOf(result.Package, result.TestName)
+		}
+	}
+}
+
+// hasOwners reports whether any result has been assigned an owner
+func (ta *TestAnalytics) hasOwners() bool {
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			if result.Owner != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GroupByOwner groups results by owner, ordered by owner name. Results without
+// an owner are grouped under Unowned.
+func (ta *TestAnalytics) GroupByOwner() []OwnerGroup {
+	groups := make(map[string]*OwnerGroup)
+	for _, suite := range ta.Results {
+		for _, result := range suite.Results {
+			owner := result.Owner
+			if owner == "" {
+				owner = Unowned
+			}
+			group, ok := groups[owner]
+			if !ok {
+				group = &OwnerGroup{Owner: owner}
+				groups[owner] = group
+			}
+
+			group.Tests++
+			switch result.Status {
+			case "PASS":
+				group.Passed++
+			case "FAIL":
+				group.Failed++
+				group.FailedTests = append(group.FailedTests, result.Package+"/"+result.TestName)
+			case "SKIP":
+				group.Skipped++
+			}
+			group.Results = append(group.Results, result)
+		}
+	}
+
+	owners := make([]OwnerGroup, 0, len(groups))
+	for _, owner := range sortedKeys(groups) {
+		owners = append(owners, *groups[owner])
+	}
+	return owners
+}
+
+// Failures returns the group's failed results
+func (g OwnerGroup) Failures() []TestResult {
+	var failures []TestResult
+	for _, result := range g.Results {
+		if result.Status == "FAIL" {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// ForOwner returns a copy of the analytics containing only the owner's results,
+// so any report format can be generated as that team's digest
+func (ta *TestAnalytics) ForOwner(owner string) *TestAnalytics {
+	filtered := NewTestAnalytics()
+	filtered.Quarantine = ta.Quarantine
+
+	for _, suite := range ta.Results {
+		var results []TestResult
+		for _, result := range suite.Results {
+			resultOwner := result.Owner
+			if resultOwner == "" {
+				resultOwner = Unowned
+			}
+			if resultOwner == owner {
+				results = append(results, result)
+			}
+		}
+		if len(results) == 0 {
+			continue
+		}
+
+		suite.Results = results
+		recountSuite(&suite)
+		filtered.AddResult(suite)
+	}
+
+	return filtered
+}
+
+// suiteOwners returns the distinct owners of a suite's results in sorted order
+func suiteOwners(suite TestSuiteResult) []string {
+	owners := make(map[string]bool)
+	for _, result := range suite.Results {
+		if result.Owner != "" {
+			owners[result.Owner] = true
+		}
+	}
+	return sortedKeys(owners)
+}
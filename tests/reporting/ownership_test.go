@@ -0,0 +1,169 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+const testOwnership = `
+# default owner
+*                    @platform
+
+chaos/               @sre
+cost/                @finops
+cost/TestBudget*     @finance   # budgets go to finance
+TestAZ*              @network
+`
+
+// ownedRun has chaos, cost and network results for the owners in testOwnership
+var ownedRun = []reporting.TestSuiteResult{
+	{SuiteName: "chaos", Results: []reporting.TestResult{
+		{TestName: "TestInstanceRecovery", Category: "chaos", Status: "FAIL", Error: "instance not replaced"},
+		{TestName: "TestAZFailure/us-east-1a", Category: "chaos", Status: "FAIL", Error: "traffic dropped"},
+	}},
+	{SuiteName: "cost", Results: []reporting.TestResult{
+		{TestName: "TestBudgetAlerts", Category: "cost", Status: "FAIL", Error: "no alert"},
+		{TestName: "TestRightSizing", Category: "cost", Status: "PASS"},
+		{TestName: "TestNATCost", Category: "cost", Status: "SKIP"},
+	}},
+	{SuiteName: "network", Results: []reporting.TestResult{
+		{TestName: "TestNetworkProvisioning", Category: "unit", Status: "PASS"},
+	}},
+}
+
+func TestOwnershipOwnerOf(t *testing.T) {
+	ownership, err := reporting.ParseOwnership(strings.NewReader(testOwnership))
+	require.NoError(t, err)
+	require.Len(t, ownership.Rules, 5)
+	assert.Equal(t, 7, ownership.Rules[3].Line)
+
+	assert.Equal(t, "@sre", ownership.OwnerOf("chaos", "TestInstanceRecovery"))
+	assert.Equal(t, "@network", ownership.OwnerOf("chaos", "TestAZFailure/us-east-1a"), "later rules win and match subtests")
+	assert.Equal(t, "@finance", ownership.OwnerOf("cost", "TestBudgetAlerts"))
+	assert.Equal(t, "@finops", ownership.OwnerOf("cost", "TestRightSizing"))
+	assert.Equal(t, "@platform", ownership.OwnerOf("network", "TestNetworkProvisioning"))
+
+	empty, err := reporting.ParseOwnership(strings.NewReader("# nothing yet\n"))
+	require.NoError(t, err)
+	assert.Equal(t, reporting.Unowned, empty.OwnerOf("network", "TestNetworkProvisioning"))
+}
+
+func TestParseOwnershipErrors(t *testing.T) {
+	_, err := reporting.ParseOwnership(strings.NewReader("chaos/ @sre @network\n"))
+	assert.ErrorContains(t, err, "line 1: expected a pattern and one owner")
+
+	_, err = reporting.ParseOwnership(strings.NewReader("\ncost/[Budget @finops\n"))
+	assert.ErrorContains(t, err, "line 2: invalid pattern")
+}
+
+func TestLoadOwnershipFile(t *testing.T) {
+	ownership, err := reporting.LoadOwnership(filepath.Join("..", reporting.OwnershipFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "@your-org/sre-team", ownership.OwnerOf("chaos", "TestAZFailure"))
+
+	filename := filepath.Join(t.TempDir(), reporting.OwnershipFileName)
+	require.NoError(t, os.WriteFile(filename, []byte("chaos\n"), 0644))
+	_, err = reporting.LoadOwnership(filename)
+	assert.ErrorContains(t, err, "invalid ownership file")
+}
+
+func TestGroupByOwner(t *testing.T) {
+	ownership, err := reporting.ParseOwnership(strings.NewReader(testOwnership))
+	require.NoError(t, err)
+	analytics := newAnalytics(ownedRun...)
+	analytics.AssignOwners(ownership)
+
+	groups := analytics.GroupByOwner()
+	require.Len(t, groups, 5)
+	assert.Equal(t, []string{"@finance", "@finops", "@network", "@platform", "@sre"},
+		[]string{groups[0].Owner, groups[1].Owner, groups[2].Owner, groups[3].Owner, groups[4].Owner})
+
+	finops := groups[1]
+	assert.Equal(t, 2, finops.Tests)
+	assert.Equal(t, 1, finops.Passed)
+	assert.Equal(t, 1, finops.Skipped)
+	assert.Empty(t, finops.Failures())
+
+	sre := groups[4]
+	assert.Equal(t, []string{"chaos/TestInstanceRecovery"}, sre.FailedTests)
+
+	digest := analytics.ForOwner("@sre")
+	require.Len(t, digest.Results, 1)
+	assert.Equal(t, 1, digest.Results[0].TotalTests)
+	assert.Equal(t, 1, digest.Results[0].FailedTests)
+}
+
+func TestOwnerSectionsInReports(t *testing.T) {
+	ownership, err := reporting.ParseOwnership(strings.NewReader(testOwnership))
+	require.NoError(t, err)
+	analytics := newAnalytics(ownedRun...)
+	analytics.AssignOwners(ownership)
+
+	markdown, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "## Owners")
+	assert.Contains(t, markdown, "| @finops | 2 | 1 | 0 | 1 |")
+	assert.Contains(t, markdown, "### @sre (1 failures)\n\n| Test Name | Package | Error |")
+	assert.NotContains(t, markdown, "### @finops (")
+
+	html, err := analytics.GenerateReport("html")
+	require.NoError(t, err)
+	assert.Contains(t, html, "<h2>Owners</h2>")
+	assert.Contains(t, html, "<h3>@network (1 failures)</h3>")
+
+	jsonReport, err := analytics.GenerateReport("json")
+	require.NoError(t, err)
+	var decoded struct {
+		Owners []reporting.OwnerGroup `json:"owners"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(jsonReport), &decoded))
+	require.Len(t, decoded.Owners, 5)
+	assert.Equal(t, []string{"cost/TestBudgetAlerts"}, decoded.Owners[0].FailedTests)
+
+	junit, err := analytics.GenerateReport("junit")
+	require.NoError(t, err)
+	assert.Contains(t, junit, `<property name="owner" value="@finance"></property>`)
+	assert.Contains(t, junit, `<property name="owner" value="@finops"></property>`)
+
+	github, err := analytics.GenerateReport("github")
+	require.NoError(t, err)
+	assert.Contains(t, github, "::group::@sre (1 failures)\n::error ")
+	assert.Equal(t, 3, strings.Count(github, "::endgroup::"))
+
+	metrics, err := analytics.GenerateReport("openmetrics")
+	require.NoError(t, err)
+	assert.Contains(t, metrics, `inspection_test_owner_results{owner="@finance",status="fail"} 1`)
+
+	dashboard, err := analytics.GenerateReport("dashboard")
+	require.NoError(t, err)
+	assert.Contains(t, dashboard, `<select data-filter="owner">`)
+	assert.Contains(t, dashboard, `data-owner="@sre"`)
+}
+
+func TestOwnerSectionsOmittedWithoutOwnership(t *testing.T) {
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(reporting.TestSuiteResult{
+		SuiteName: "network",
+		Results:   []reporting.TestResult{{TestName: "TestNetworkProvisioning", Package: "network", Status: "FAIL"}},
+	})
+
+	markdown, err := analytics.GenerateReport("markdown")
+	require.NoError(t, err)
+	assert.NotContains(t, markdown, "## Owners")
+
+	github, err := analytics.GenerateReport("github")
+	require.NoError(t, err)
+	assert.NotContains(t, github, "::group::")
+
+	jsonReport, err := analytics.GenerateReport("json")
+	require.NoError(t, err)
+	assert.NotContains(t, jsonReport, `"owners"`)
+}
@@ -0,0 +1,236 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName  = "aws-centralized-inspection-tests"
+)
+
+// sarifCategories are the test categories reported as SARIF results
+var sarifCategories = map[string]bool{
+	"compliance": true,
+	"security":   true,
+}
+
+// SARIF 2.1.0 document model, limited to the fields this report uses
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Properties       sarifProperties `json:"properties"`
+}
+
+type sarifProperties struct {
+	Tags []string `json:"tags"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	RelatedLocations    []sarifLocation   `json:"relatedLocations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifFileLocation returns a SARIF location of a line of a repository file
+func sarifFileLocation(file string, line int) sarifLocation {
+	return sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: file, URIBaseID: "%SRCROOT%"},
+			Region:           sarifRegion{StartLine: line},
+		},
+	}
+}
+
+// generateSARIFReport generates a SARIF 2.1.0 report of compliance and security
+// test failures, located at the failing assertion, with the module file the test
+// package exercises as a related location. Failures without an assertion location
+// are located at the module file.
+func (ta *TestAnalytics) generateSARIFReport() (string, error) {
+	rules := make(map[string]sarifRule)
+	results := make([]sarifResult, 0)
+
+	for _, result := range ta.GetFailedTests() {
+		if !sarifCategories[result.Category] {
+			continue
+		}
+		moduleFile := ModuleFileForTest(result)
+		var locations, related []sarifLocation
+		file, line, ok := FailureLocation(result)
+		switch {
+		case ok:
+			locations = []sarifLocation{sarifFileLocation(file, line)}
+			if moduleFile != "" {
+				related = []sarifLocation{sarifFileLocation(moduleFile, 1)}
+			}
+		case moduleFile != "":
+			locations = []sarifLocation{sarifFileLocation(moduleFile, 1)}
+		default:
+			continue
+		}
+
+		ruleID := fmt.Sprintf("%s/%s", result.Package, result.TestName)
+		if _, ok := rules[ruleID]; !ok {
+			rules[ruleID] = sarifRule{
+				ID:               ruleID,
+				Name:             result.TestName,
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("%s test %s", result.Category, result.TestName)},
+				Properties:       sarifProperties{Tags: []string{result.Category, result.Package}},
+			}
+		}
+
+		message := failureMessage(result)
+		if message == "" {
+			message = fmt.Sprintf("%s failed", result.TestName)
+		}
+
+		var properties map[string]string
+		if result.Owner != "" {
+			properties = map[string]string{"owner": result.Owner}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:           ruleID,
+			Level:            "error",
+			Message:          sarifMessage{Text: message},
+			Locations:        locations,
+			RelatedLocations: related,
+			PartialFingerprints: map[string]string{
+				"testSignature/v1": ruleID + ":" + NormalizeErrorSignature(message),
+			},
+			Properties: properties,
+		})
+	}
+
+	ruleIDs := make([]string, 0, len(rules))
+	for id := range rules {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	driver := sarifDriver{Name: sarifToolName, Rules: make([]sarifRule, 0, len(rules))}
+	for _, id := range ruleIDs {
+		driver.Rules = append(driver.Rules, rules[id])
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchemaURI,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// generateGitHubReport generates GitHub Actions workflow commands that annotate
+// failed tests on their failing assertion, or else on the module file their
+// package exercises. Quarantined failures are emitted
+// as warnings. Once owners are assigned, each owner's failures are folded into a
+// log group of their own.
+func (ta *TestAnalytics) generateGitHubReport() (string, error) {
+	var out strings.Builder
+
+	if !ta.hasOwners() {
+		ta.writeGitHubAnnotations(&out, ta.GetFailedTests())
+		return out.String(), nil
+	}
+
+	for _, group := range ta.GroupByOwner() {
+		failures := group.Failures()
+		if len(failures) == 0 {
+			continue
+		}
+		out.WriteString(fmt.Sprintf("::group::%s (%d failures)\n", escapeGitHubData(group.Owner), len(failures)))
+		ta.writeGitHubAnnotations(&out, failures)
+		out.WriteString("::endgroup::\n")
+	}
+	return out.String(), nil
+}
+
+// writeGitHubAnnotations writes one workflow command per failed test
+func (ta *TestAnalytics) writeGitHubAnnotations(out *strings.Builder, failures []TestResult) {
+	for _, result := range failures {
+		level := "error"
+		if ta.Quarantine.Contains(result.Package, result.TestName) {
+			level = "warning"
+		}
+
+		message := failureMessage(result)
+		if message == "" {
+			message = "test failed"
+		}
+
+		properties := []string{}
+		if file, line, ok := FailureLocation(result); ok {
+			properties = append(properties, "file="+escapeGitHubProperty(file), fmt.Sprintf("line=%d", line))
+		} else if file := ModuleFileForTest(result); file != "" {
+			properties = append(properties, "file="+escapeGitHubProperty(file), "line=1")
+		}
+		properties = append(properties, "title="+escapeGitHubProperty(fmt.Sprintf("%s/%s", result.Package, result.TestName)))
+
+		out.WriteString(fmt.Sprintf("::%s %s::%s\n", level, strings.Join(properties, ","), escapeGitHubData(message)))
+	}
+}
+
+// escapeGitHubData escapes a workflow command message
+func escapeGitHubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeGitHubProperty escapes a workflow command property value
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,133 @@
+package reporting_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// complianceRun has compliance, security and chaos failures
+var complianceRun = reporting.TestSuiteResult{SuiteName: "compliance", Results: []reporting.TestResult{
+	{TestName: "TestPCIDSSCompliance/Requirement1_NetworkSecurity", Category: "compliance",
+		Status: "FAIL", Error: "NACL allows 0.0.0.0/0 on port 22,\nexpected deny",
+		Output: "    compliance_test.go:239: \n        \tError Trace:\t/home/runner/work/repo/repo/tests/compliance/compliance_test.go:239\n        \tError:      \tShould be false\n"},
+	{TestName: "TestSecurityGroupRemediation", Package: "remediation", Category: "security",
+		Status: "FAIL", Error: "remediation lambda not found"},
+	{TestName: "TestGWLBFailure", Package: "chaos", Category: "chaos", Status: "FAIL", Error: "listener missing",
+		Output: "    chaos_test.go:120: listener missing\n"},
+	{TestName: "TestHIPAACompliance", Category: "compliance", Status: "PASS"},
+}}
+
+func TestModuleFileForTest(t *testing.T) {
+	assert.Equal(t, "modules/automated-remediation/main.tf",
+		reporting.ModuleFileForTest(reporting.TestResult{TestName: "TestInstanceQuarantine", Package: "remediation"}))
+	assert.Equal(t, "", reporting.ModuleFileForTest(reporting.TestResult{TestName: "TestGWLBFailure/GWLBNodeFailure", Package: "chaos"}),
+		"chaos tests exercise several modules")
+	assert.Equal(t, "", reporting.ModuleFileForTest(reporting.TestResult{TestName: "TestSomething", Package: "unknown"}))
+}
+
+func TestFailureLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		result reporting.TestResult
+		file   string
+		line   int
+	}{
+		{"recorded location", reporting.TestResult{Location: "tests/cmd/routing-check/main_test.go:42", Output: "    main_test.go:7: x\n"},
+			"tests/cmd/routing-check/main_test.go", 42},
+		{"testify trace", reporting.TestResult{Package: "network", Output: "    fixtures.go:30: TEST_SEED=7\n    network_test.go:50: \n        \tError Trace:\tnetwork_test.go:51\n"},
+			"tests/network/network_test.go", 51},
+		{"absolute testify trace", reporting.TestResult{Package: "network", Output: "\tError Trace:\t/src/repo/tests/network/network_test.go:12\n\t            \t/src/repo/tests/network/helpers_test.go:9\n"},
+			"tests/network/network_test.go", 12},
+		{"t.Fatal line", reporting.TestResult{Package: "chaos", Output: "    chaos_test.go:88: listener missing\n"},
+			"tests/chaos/chaos_test.go", 88},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, line, ok := reporting.FailureLocation(tt.result)
+			require.True(t, ok)
+			assert.Equal(t, tt.file, file)
+			assert.Equal(t, tt.line, line)
+		})
+	}
+
+	_, _, ok := reporting.FailureLocation(reporting.TestResult{Package: "network", Output: "panic: test timed out\n"})
+	assert.False(t, ok)
+}
+
+func TestGenerateSARIFReport(t *testing.T) {
+	report, err := newAnalytics(complianceRun).GenerateReport("sarif")
+	require.NoError(t, err)
+
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID           string          `json:"ruleId"`
+				Locations        []sarifLocation `json:"locations"`
+				RelatedLocations []sarifLocation `json:"relatedLocations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(report), &sarif))
+
+	assert.Equal(t, "2.1.0", sarif.Version)
+	require.Len(t, sarif.Runs, 1)
+	require.Len(t, sarif.Runs[0].Results, 2, "only compliance and security failures are reported")
+	assert.Len(t, sarif.Runs[0].Tool.Driver.Rules, 2)
+
+	// The compliance failure is located at its assertion, the remediation failure
+	// without one at the module of its package
+	compliance, remediation := sarif.Runs[0].Results[0], sarif.Runs[0].Results[1]
+	assert.Equal(t, "compliance/TestPCIDSSCompliance/Requirement1_NetworkSecurity", compliance.RuleID)
+	require.Len(t, compliance.Locations, 1)
+	assert.Equal(t, "tests/compliance/compliance_test.go", compliance.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 239, compliance.Locations[0].PhysicalLocation.Region.StartLine)
+	require.Len(t, compliance.RelatedLocations, 1)
+	assert.Equal(t, "modules/network/main.tf", compliance.RelatedLocations[0].PhysicalLocation.ArtifactLocation.URI)
+
+	assert.Equal(t, "remediation/TestSecurityGroupRemediation", remediation.RuleID)
+	assert.Equal(t, "modules/automated-remediation/main.tf", remediation.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, remediation.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Empty(t, remediation.RelatedLocations)
+}
+
+// sarifLocation is the physical location of a SARIF result
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine int `json:"startLine"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+func TestGenerateGitHubReport(t *testing.T) {
+	analytics := newAnalytics(complianceRun)
+	analytics.SetQuarantine(&reporting.Quarantine{
+		Tests: []reporting.QuarantineEntry{{TestName: "TestGWLBFailure", Package: "chaos"}},
+	})
+
+	report, err := analytics.GenerateReport("github")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "::error file=tests/compliance/compliance_test.go,line=239,title=compliance/TestPCIDSSCompliance/Requirement1_NetworkSecurity::NACL allows 0.0.0.0/0 on port 22,%0Aexpected deny", lines[0])
+	assert.Equal(t, "::error file=modules/automated-remediation/main.tf,line=1,title=remediation/TestSecurityGroupRemediation::remediation lambda not found", lines[1])
+	assert.Equal(t, "::warning file=tests/chaos/chaos_test.go,line=120,title=chaos/TestGWLBFailure::listener missing", lines[2])
+}
@@ -6,9 +6,13 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// DefaultReportDir is the directory reports and run history are written to
+const DefaultReportDir = "test-reports"
+
 // TestResult represents the result of a single test
 type TestResult struct {
 	TestName  string        `json:"test_name"`
@@ -18,12 +22,19 @@ type TestResult struct {
 	Error     string        `json:"error,omitempty"`
 	Output    string        `json:"output,omitempty"`
 	Timestamp time.Time     `json:"timestamp"`
-	Category  string        `json:"category"` // "unit", "integration", "performance", "security", etc.
+	Category  string        `json:"category"`           // "unit", "integration", "performance", "security", etc.
+	Parent    string        `json:"parent,omitempty"`   // parent test name for subtests
+	Attempts  int           `json:"attempts,omitempty"` // attempts merged into this result, including retries
+	Owner     string        `json:"owner,omitempty"`    // team from the ownership file, see AssignOwners
+	Location  string        `json:"location,omitempty"` // repository-relative file:line of the failing assertion
 }
 
 // TestSuiteResult represents the results of a test suite execution
 type TestSuiteResult struct {
 	SuiteName    string        `json:"suite_name"`
+	RunID        string        `json:"run_id,omitempty"`
+	GitSHA       string        `json:"git_sha,omitempty"`
+	Seed         *int64        `json:"seed,omitempty"` // fixture seed the tests ran with; nil when not recorded
 	Environment  string        `json:"environment"`
 	Region       string        `json:"region"`
 	StartTime    time.Time     `json:"start_time"`
@@ -44,6 +55,7 @@ type CoverageInfo struct {
 	Functions       int                `json:"functions"`
 	Statements      int                `json:"statements"`
 	FileCoverage    map[string]float64 `json:"file_coverage"`
+	FileStatements  map[string]int     `json:"file_statements,omitempty"`
 	PackageCoverage map[string]float64 `json:"package_coverage"`
 }
 
@@ -55,11 +67,23 @@ type PerfMetrics struct {
 	Throughput      float64            `json:"throughput"`
 	ErrorRate       float64            `json:"error_rate"`
 	ResourceUsage   map[string]float64 `json:"resource_usage"`
+
+	// Percentiles derived from Latency by SetLatency
+	P50ResponseTime  time.Duration     `json:"p50_response_time,omitempty"`
+	P90ResponseTime  time.Duration     `json:"p90_response_time,omitempty"`
+	P95ResponseTime  time.Duration     `json:"p95_response_time,omitempty"`
+	P99ResponseTime  time.Duration     `json:"p99_response_time,omitempty"`
+	P999ResponseTime time.Duration     `json:"p999_response_time,omitempty"`
+	Latency          *LatencyHistogram `json:"latency,omitempty"`
 }
 
-// TestAnalytics provides analytics and reporting for test results
+// TestAnalytics provides analytics and reporting for test results. AddResult may
+// run concurrently with MetricsHandler; other methods are not safe for concurrent use.
 type TestAnalytics struct {
-	Results []TestSuiteResult `json:"results"`
+	Results    []TestSuiteResult `json:"results"`
+	Quarantine *Quarantine       `json:"quarantine,omitempty"`
+
+	mu sync.RWMutex
 }
 
 // NewTestAnalytics creates a new test analytics instance
@@ -71,9 +95,22 @@ func NewTestAnalytics() *TestAnalytics {
 
 // AddResult adds a test suite result to the analytics
 func (ta *TestAnalytics) AddResult(result TestSuiteResult) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
 	ta.Results = append(ta.Results, result)
 }
 
+// snapshot returns analytics over a copy of the results added so far, which
+// AddResult can't change while it is read
+func (ta *TestAnalytics) snapshot() *TestAnalytics {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+	return &TestAnalytics{
+		Results:    append([]TestSuiteResult(nil), ta.Results...),
+		Quarantine: ta.Quarantine,
+	}
+}
+
 // GenerateReport generates a comprehensive test report
 func (ta *TestAnalytics) GenerateReport(format string) (string, error) {
 	switch format {
@@ -85,93 +122,65 @@ func (ta *TestAnalytics) GenerateReport(format string) (string, error) {
 		return ta.generateMarkdownReport()
 	case "junit":
 		return ta.generateJUnitReport()
+	case "sarif":
+		return ta.generateSARIFReport()
+	case "github":
+		return ta.generateGitHubReport()
+	case "openmetrics":
+		return ta.generateOpenMetricsReport()
+	case "dashboard":
+		return ta.generateDashboardReport()
 	default:
 		return "", fmt.Errorf("unsupported format: %s", format)
 	}
 }
 
+// jsonReport is the JSON report: the analytics plus, once owners are assigned,
+// per-owner totals. LoadReport ignores the owners section.
+type jsonReport struct {
+	*TestAnalytics
+	Owners []OwnerGroup `json:"owners,omitempty"`
+}
+
 // generateJSONReport generates a JSON report
 func (ta *TestAnalytics) generateJSONReport() (string, error) {
-	data, err := json.MarshalIndent(ta, "", "  ")
+	report := jsonReport{TestAnalytics: ta}
+	if ta.hasOwners() {
+		report.Owners = ta.GroupByOwner()
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
 
-// generateHTMLReport generates an HTML report
-func (ta *TestAnalytics) generateHTMLReport() (string, error) {
-	var html strings.Builder
-
-	html.WriteString(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Test Analytics Report</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 20px; }
-        .summary { background: #f0f0f0; padding: 20px; border-radius: 5px; margin-bottom: 20px; }
-        .test-result { margin: 10px 0; padding: 10px; border-left: 5px solid; }
-        .pass { border-color: #28a745; background: #d4edda; }
-        .fail { border-color: #dc3545; background: #f8d7da; }
-        .skip { border-color: #ffc107; background: #fff3cd; }
-        .metric { display: inline-block; margin: 10px; padding: 10px; background: white; border-radius: 5px; }
-        table { width: 100%; border-collapse: collapse; margin: 20px 0; }
-        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
-        th { background-color: #f2f2f2; }
-    </style>
-</head>
-<body>
-    <h1>Test Analytics Report</h1>`)
-
-	// Summary section
-	html.WriteString("<div class='summary'>")
-	html.WriteString("<h2>Summary</h2>")
-	totalSuites := len(ta.Results)
-	totalTests := 0
-	totalPassed := 0
-	totalFailed := 0
-	totalSkipped := 0
-
-	for _, suite := range ta.Results {
-		totalTests += suite.TotalTests
-		totalPassed += suite.PassedTests
-		totalFailed += suite.FailedTests
-		totalSkipped += suite.SkippedTests
-	}
-
-	passRate := float64(totalPassed) / float64(totalTests) * 100
+// ReportSummary holds the totals shown at the top of every report
+type ReportSummary struct {
+	Suites   int     `json:"suites"`
+	Tests    int     `json:"tests"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Skipped  int     `json:"skipped"`
+	PassRate float64 `json:"pass_rate"`
+}
 
-	html.WriteString(fmt.Sprintf("<div class='metric'>Total Test Suites: %d</div>", totalSuites))
-	html.WriteString(fmt.Sprintf("<div class='metric'>Total Tests: %d</div>", totalTests))
-	html.WriteString(fmt.Sprintf("<div class='metric'>Passed: %d</div>", totalPassed))
-	html.WriteString(fmt.Sprintf("<div class='metric'>Failed: %d</div>", totalFailed))
-	html.WriteString(fmt.Sprintf("<div class='metric'>Skipped: %d</div>", totalSkipped))
-	html.WriteString(fmt.Sprintf("<div class='metric'>Pass Rate: %.1f%%</div>", passRate))
-	html.WriteString("</div>")
+// Summary totals the test counts across all suites
+func (ta *TestAnalytics) Summary() ReportSummary {
+	summary := ReportSummary{Suites: len(ta.Results)}
 
-	// Detailed results
-	html.WriteString("<h2>Detailed Results</h2>")
 	for _, suite := range ta.Results {
-		html.WriteString(fmt.Sprintf("<h3>%s (%s)</h3>", suite.SuiteName, suite.Environment))
-		html.WriteString("<table>")
-		html.WriteString("<tr><th>Test Name</th><th>Status</th><th>Duration</th><th>Category</th><th>Error</th></tr>")
-
-		for _, result := range suite.Results {
-			statusClass := strings.ToLower(result.Status)
-			html.WriteString(fmt.Sprintf("<tr class='%s'>", statusClass))
-			html.WriteString(fmt.Sprintf("<td>%s</td>", result.TestName))
-			html.WriteString(fmt.Sprintf("<td>%s</td>", result.Status))
-			html.WriteString(fmt.Sprintf("<td>%v</td>", result.Duration))
-			html.WriteString(fmt.Sprintf("<td>%s</td>", result.Category))
-			html.WriteString(fmt.Sprintf("<td>%s</td>", result.Error))
-			html.WriteString("</tr>")
-		}
-
-		html.WriteString("</table>")
+		summary.Tests += suite.TotalTests
+		summary.Passed += suite.PassedTests
+		summary.Failed += suite.FailedTests
+		summary.Skipped += suite.SkippedTests
 	}
 
-	html.WriteString("</body></html>")
-	return html.String(), nil
+	if summary.Tests > 0 {
+		summary.PassRate = float64(summary.Passed) / float64(summary.Tests) * 100
+	}
+	return summary
 }
 
 // generateMarkdownReport generates a Markdown report
@@ -182,32 +191,97 @@ func (ta *TestAnalytics) generateMarkdownReport() (string, error) {
 
 	// Summary
 	md.WriteString("## Summary\n\n")
-	totalSuites := len(ta.Results)
-	totalTests := 0
-	totalPassed := 0
-	totalFailed := 0
-	totalSkipped := 0
+	summary := ta.Summary()
+
+	md.WriteString(fmt.Sprintf("- **Total Test Suites**: %d\n", summary.Suites))
+	md.WriteString(fmt.Sprintf("- **Total Tests**: %d\n", summary.Tests))
+	md.WriteString(fmt.Sprintf("- **Passed**: %d\n", summary.Passed))
+	md.WriteString(fmt.Sprintf("- **Failed**: %d\n", summary.Failed))
+	md.WriteString(fmt.Sprintf("- **Skipped**: %d\n", summary.Skipped))
+	md.WriteString(fmt.Sprintf("- **Pass Rate**: %.1f%%\n\n", summary.PassRate))
+
+	// Failures split into regressions and known-flaky tests
+	if ta.Quarantine != nil {
+		md.WriteString("## Failures\n\n")
+		for _, section := range []struct {
+			title   string
+			results []TestResult
+		}{
+			{"Regressions", ta.GetRegressions()},
+			{"Quarantined Failures (known flaky)", ta.GetQuarantinedFailures()},
+		} {
+			md.WriteString(fmt.Sprintf("### %s (%d)\n\n", section.title, len(section.results)))
+			if len(section.results) == 0 {
+				continue
+			}
+			md.WriteString("| Test Name | Package | Error |\n")
+			md.WriteString("|-----------|---------|-------|\n")
+			for _, result := range section.results {
+				md.WriteString(fmt.Sprintf("| %s | %s | %s |\n", result.TestName, result.Package, markdownCell(result.Error)))
+			}
+			md.WriteString("\n")
+		}
+	}
 
-	for _, suite := range ta.Results {
-		totalTests += suite.TotalTests
-		totalPassed += suite.PassedTests
-		totalFailed += suite.FailedTests
-		totalSkipped += suite.SkippedTests
+	// Failures routed to their owners
+	if ta.hasOwners() {
+		md.WriteString("## Owners\n\n")
+		md.WriteString("| Owner | Tests | Passed | Failed | Skipped |\n")
+		md.WriteString("|-------|-------|--------|--------|---------|\n")
+		groups := ta.GroupByOwner()
+		for _, group := range groups {
+			md.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %d |\n",
+				group.Owner, group.Tests, group.Passed, group.Failed, group.Skipped))
+		}
+		md.WriteString("\n")
+
+		for _, group := range groups {
+			failures := group.Failures()
+			if len(failures) == 0 {
+				continue
+			}
+			md.WriteString(fmt.Sprintf("### %s (%d failures)\n\n", group.Owner, len(failures)))
+			md.WriteString("| Test Name | Package | Error |\n")
+			md.WriteString("|-----------|---------|-------|\n")
+			for _, result := range failures {
+				md.WriteString(fmt.Sprintf("| %s | %s | %s |\n", result.TestName, result.Package, markdownCell(result.Error)))
+			}
+			md.WriteString("\n")
+		}
 	}
 
-	passRate := float64(totalPassed) / float64(totalTests) * 100
+	// Failures grouped by root cause
+	if clusters := ta.GetFailureClusters(); len(clusters) > 0 {
+		md.WriteString("## Failure Clusters\n\n")
+		md.WriteString("| Count | Signature | Packages | Tests |\n")
+		md.WriteString("|-------|-----------|----------|-------|\n")
+		for _, cluster := range clusters {
+			md.WriteString(fmt.Sprintf("| %d | `%s` | %s | %s |\n",
+				cluster.Count, markdownCell(cluster.Signature), strings.Join(cluster.Packages, ", "),
+				strings.Join(cluster.Tests, ", ")))
+		}
+		md.WriteString("\n")
+	}
 
-	md.WriteString(fmt.Sprintf("- **Total Test Suites**: %d\n", totalSuites))
-	md.WriteString(fmt.Sprintf("- **Total Tests**: %d\n", totalTests))
-	md.WriteString(fmt.Sprintf("- **Passed**: %d\n", totalPassed))
-	md.WriteString(fmt.Sprintf("- **Failed**: %d\n", totalFailed))
-	md.WriteString(fmt.Sprintf("- **Skipped**: %d\n", totalSkipped))
-	md.WriteString(fmt.Sprintf("- **Pass Rate**: %.1f%%\n\n", passRate))
+	// Coverage per package
+	if coverage := ta.Coverage(); coverage != nil {
+		md.WriteString("## Coverage\n\n")
+		md.WriteString(fmt.Sprintf("**Total**: %.1f%% of %d statements\n\n", coverage.Percentage, coverage.Statements))
+		md.WriteString("| Package | Coverage |\n")
+		md.WriteString("|---------|----------|\n")
+		for _, entry := range coverage.SortedPackages() {
+			md.WriteString(fmt.Sprintf("| %s | %.1f%% |\n", entry.Package, entry.Percentage))
+		}
+		md.WriteString("\n")
+	}
 
 	// Detailed results
 	md.WriteString("## Detailed Results\n\n")
 	for _, suite := range ta.Results {
 		md.WriteString(fmt.Sprintf("### %s (%s)\n\n", suite.SuiteName, suite.Environment))
+		if suite.Seed != nil {
+			md.WriteString(fmt.Sprintf("**Seed**: `%d`\n\n", *suite.Seed))
+		}
 		md.WriteString("| Test Name | Status | Duration | Category | Error |\n")
 		md.WriteString("|-----------|--------|----------|----------|-------|\n")
 
@@ -225,43 +299,10 @@ func (ta *TestAnalytics) generateMarkdownReport() (string, error) {
 	return md.String(), nil
 }
 
-// generateJUnitReport generates a JUnit XML report
-func (ta *TestAnalytics) generateJUnitReport() (string, error) {
-	var xml strings.Builder
-
-	xml.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
-<testsuites>`)
-
-	for _, suite := range ta.Results {
-		xml.WriteString(fmt.Sprintf(`
-  <testsuite name="%s" tests="%d" failures="%d" skipped="%d" time="%.3f">`,
-			suite.SuiteName, suite.TotalTests, suite.FailedTests, suite.SkippedTests, suite.Duration.Seconds()))
-
-		for _, result := range suite.Results {
-			xml.WriteString(fmt.Sprintf(`
-    <testcase name="%s" classname="%s" time="%.3f">`,
-				result.TestName, result.Package, result.Duration.Seconds()))
-
-			if result.Status == "FAIL" && result.Error != "" {
-				xml.WriteString(fmt.Sprintf(`
-      <failure message="%s">%s</failure>`, result.Error, result.Output))
-			} else if result.Status == "SKIP" {
-				xml.WriteString(`
-      <skipped/>`)
-			}
-
-			xml.WriteString(`
-    </testcase>`)
-		}
-
-		xml.WriteString(`
-  </testsuite>`)
-	}
-
-	xml.WriteString(`
-</testsuites>`)
-
-	return xml.String(), nil
+// markdownCell flattens a value onto one line so it can't break a Markdown table
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.Join(strings.Fields(value), " ")
 }
 
 // GetMetrics returns aggregated metrics across all test suites
@@ -291,7 +332,7 @@ func (ta *TestAnalytics) GetMetrics() map[string]interface{} {
 				}
 			}
 			categoryStats[result.Category]["total"]++
-			categoryStats[result.Category][strings.ToLower(result.Status)]++
+			categoryStats[result.Category][statusStatsKey(result.Status)]++
 
 			// Package statistics
 			if packageStats[result.Package] == nil {
@@ -300,12 +341,19 @@ func (ta *TestAnalytics) GetMetrics() map[string]interface{} {
 				}
 			}
 			packageStats[result.Package]["total"]++
-			packageStats[result.Package][strings.ToLower(result.Status)]++
+			packageStats[result.Package][statusStatsKey(result.Status)]++
 		}
 	}
 
-	passRate := float64(totalPassed) / float64(totalTests) * 100
-	avgDuration := totalDuration / time.Duration(totalSuites)
+	// An empty run has no pass rate or average duration; both are reported as zero
+	passRate := 0.0
+	if totalTests > 0 {
+		passRate = float64(totalPassed) / float64(totalTests) * 100
+	}
+	avgDuration := time.Duration(0)
+	if totalSuites > 0 {
+		avgDuration = totalDuration / time.Duration(totalSuites)
+	}
 
 	return map[string]interface{}{
 		"total_suites":   totalSuites,
@@ -321,30 +369,42 @@ func (ta *TestAnalytics) GetMetrics() map[string]interface{} {
 	}
 }
 
-// GetTrendAnalysis performs trend analysis on test results
+// statusStatsKey returns the category_stats and package_stats key that counts a
+// test status, e.g. "passed" for PASS
+func statusStatsKey(status string) string {
+	switch status {
+	case "PASS":
+		return "passed"
+	case "FAIL":
+		return "failed"
+	case "SKIP":
+		return "skipped"
+	}
+	return strings.ToLower(status)
+}
+
+// GetTrendAnalysis performs trend analysis on test results. Suites that share a
+// run ID (for example packages from one nightly run loaded from history) are
+// aggregated into a single data point.
 func (ta *TestAnalytics) GetTrendAnalysis() map[string]interface{} {
-	if len(ta.Results) < 2 {
+	runs := ta.runSummaries()
+	if len(runs) < 2 {
 		return map[string]interface{}{
 			"error": "Need at least 2 test suite results for trend analysis",
 		}
 	}
 
-	// Sort results by start time
-	sortedResults := make([]TestSuiteResult, len(ta.Results))
-	copy(sortedResults, ta.Results)
-	sort.Slice(sortedResults, func(i, j int) bool {
-		return sortedResults[i].StartTime.Before(sortedResults[j].StartTime)
-	})
-
 	// Calculate trends
-	passRateTrend := make([]float64, len(sortedResults))
-	durationTrend := make([]time.Duration, len(sortedResults))
-
-	for i, result := range sortedResults {
-		if result.TotalTests > 0 {
-			passRateTrend[i] = float64(result.PassedTests) / float64(result.TotalTests) * 100
+	runIDs := make([]string, len(runs))
+	passRateTrend := make([]float64, len(runs))
+	durationTrend := make([]time.Duration, len(runs))
+
+	for i, run := range runs {
+		runIDs[i] = run.RunID
+		if run.TotalTests > 0 {
+			passRateTrend[i] = float64(run.PassedTests) / float64(run.TotalTests) * 100
 		}
-		durationTrend[i] = result.Duration
+		durationTrend[i] = run.Duration
 	}
 
 	// Calculate improvement/regression
@@ -357,6 +417,7 @@ func (ta *TestAnalytics) GetTrendAnalysis() map[string]interface{} {
 	durationChange := lastDuration - firstDuration
 
 	return map[string]interface{}{
+		"run_ids":          runIDs,
 		"pass_rate_trend":  passRateTrend,
 		"duration_trend":   durationTrend,
 		"pass_rate_change": passRateChange,
@@ -366,6 +427,45 @@ func (ta *TestAnalytics) GetTrendAnalysis() map[string]interface{} {
 	}
 }
 
+// runSummary aggregates the suites of a single run
+type runSummary struct {
+	RunID       string
+	StartTime   time.Time
+	Duration    time.Duration
+	TotalTests  int
+	PassedTests int
+}
+
+// runSummaries groups suites by run ID, ordered by start time. Suites without a
+// run ID are treated as runs of their own.
+func (ta *TestAnalytics) runSummaries() []runSummary {
+	var runs []runSummary
+	index := make(map[string]int)
+
+	for _, suite := range ta.Results {
+		i, ok := index[suite.RunID]
+		if !ok || suite.RunID == "" {
+			runs = append(runs, runSummary{RunID: suite.RunID, StartTime: suite.StartTime})
+			i = len(runs) - 1
+			index[suite.RunID] = i
+		}
+
+		run := &runs[i]
+		if suite.StartTime.Before(run.StartTime) {
+			run.StartTime = suite.StartTime
+		}
+		run.Duration += suite.Duration
+		run.TotalTests += suite.TotalTests
+		run.PassedTests += suite.PassedTests
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartTime.Before(runs[j].StartTime)
+	})
+
+	return runs
+}
+
 // ExportResults exports test results to a file
 func (ta *TestAnalytics) ExportResults(filename, format string) error {
 	report, err := ta.GenerateReport(format)
@@ -374,7 +474,7 @@ func (ta *TestAnalytics) ExportResults(filename, format string) error {
 	}
 
 	// Create directory if it doesn't exist
-	dir := "test-reports"
+	dir := DefaultReportDir
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
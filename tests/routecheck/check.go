@@ -0,0 +1,448 @@
+// Package routecheck checks the deployed routes of the hub-and-spoke topology
+// through the EC2 API: spoke routes to the other spokes must target the GWLB
+// endpoint of the spoke, the private route tables of the inspection VPC must route
+// the spokes back through the transit gateway, and default routes must send
+// internet traffic out where it is inspected. Every route checked is a Result, so
+// runs can be reported with the reporting package.
+package routecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// DefaultPrivateRouteTableName is the Name tag pattern of the private route tables
+// of the inspection VPC
+const DefaultPrivateRouteTableName = "*private*"
+
+// defaultRoute is the destination of internet-bound traffic
+const defaultRoute = "0.0.0.0/0"
+
+// Check is the kind of route a result checks
+type Check string
+
+// Checks
+const (
+	// CheckSpokeRoute is the route of a spoke to another spoke, which must target
+	// a GWLB endpoint of the spoke
+	CheckSpokeRoute Check = "spoke-route"
+	// CheckReturnRoute is the route of the inspection VPC back to a spoke, which
+	// must target the transit gateway
+	CheckReturnRoute Check = "return-route"
+	// CheckEgress is a default route: through a NAT gateway in the inspection VPC,
+	// and through a GWLB endpoint or the transit gateway in the spokes
+	CheckEgress Check = "egress"
+)
+
+// Config selects the VPCs and routes to check
+type Config struct {
+	// InspectionVPCID is the inspection VPC; its private route tables are not
+	// checked when empty
+	InspectionVPCID string
+	// SpokeVPCIDs are the spoke VPCs; their route tables are not checked when empty
+	SpokeVPCIDs []string
+	// SpokeCIDRs are the spoke destinations, by default the CIDR blocks of the
+	// spoke VPCs
+	SpokeCIDRs []string
+	// PrivateRouteTableName is the Name tag pattern of the private route tables of
+	// the inspection VPC, DefaultPrivateRouteTableName when empty
+	PrivateRouteTableName string
+	// TransitGatewayID is the transit gateway return routes must target; any
+	// transit gateway when empty
+	TransitGatewayID string
+}
+
+// Result is the outcome of checking one route
+type Result struct {
+	Check       Check  `json:"check"`
+	VPC         string `json:"vpc"`
+	RouteTable  string `json:"route_table"`
+	Destination string `json:"destination"`
+	// Expected describes the target the route must have
+	Expected string `json:"expected"`
+	// Target is the target of the route, empty when there is none
+	Target  string `json:"target,omitempty"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// Name returns the check, route table and destination of the result
+func (r Result) Name() string {
+	return fmt.Sprintf("%s/%s/%s", r.Check, r.RouteTable, r.Destination)
+}
+
+// String returns the result as status, name and message
+func (r Result) String() string {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	return fmt.Sprintf("%s %s: %s", status, r.Name(), r.Message)
+}
+
+// Failed returns the results that did not pass
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Checker checks the routes of the VPCs of a Config
+type Checker struct {
+	api    EC2API
+	config Config
+}
+
+// NewChecker returns a checker that reads the routes through api
+func NewChecker(api EC2API, config Config) *Checker {
+	if config.PrivateRouteTableName == "" {
+		config.PrivateRouteTableName = DefaultPrivateRouteTableName
+	}
+	return &Checker{api: api, config: config}
+}
+
+// Run checks the routes of the spoke VPCs and the inspection VPC. Spokes are
+// checked on the route tables associated with their subnets, or their main route
+// table when no table is. An error means the routes could not be read; route
+// problems are failed results.
+func (c *Checker) Run(ctx context.Context) ([]Result, error) {
+	if c.config.InspectionVPCID == "" && len(c.config.SpokeVPCIDs) == 0 {
+		return nil, errors.New("no inspection or spoke VPC to check")
+	}
+
+	vpcs, err := c.vpcCIDRs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spokeCIDRs := c.config.SpokeCIDRs
+	if len(spokeCIDRs) == 0 {
+		for _, id := range c.config.SpokeVPCIDs {
+			spokeCIDRs = append(spokeCIDRs, vpcs[id]...)
+		}
+	}
+	if len(spokeCIDRs) == 0 {
+		return nil, errors.New("no spoke CIDRs to check")
+	}
+	for _, cidr := range spokeCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, fmt.Errorf("spoke CIDR %q: %w", cidr, err)
+		}
+	}
+
+	var results []Result
+	for _, vpc := range c.config.SpokeVPCIDs {
+		spoke, err := c.checkSpoke(ctx, vpc, vpcs[vpc], spokeCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, spoke...)
+	}
+	if c.config.InspectionVPCID != "" {
+		inspection, err := c.checkInspection(ctx, spokeCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, inspection...)
+	}
+	return results, nil
+}
+
+// vpcCIDRs returns the CIDR blocks of the spoke VPCs by VPC ID
+func (c *Checker) vpcCIDRs(ctx context.Context) (map[string][]string, error) {
+	cidrs := make(map[string][]string)
+	if len(c.config.SpokeVPCIDs) == 0 {
+		return cidrs, nil
+	}
+	input := &ec2.DescribeVpcsInput{VpcIds: aws.StringSlice(c.config.SpokeVPCIDs)}
+	for {
+		output, err := c.api.DescribeVpcsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("describe VPCs: %w", err)
+		}
+		for _, vpc := range output.Vpcs {
+			id := aws.StringValue(vpc.VpcId)
+			cidrs[id] = append(cidrs[id], aws.StringValue(vpc.CidrBlock))
+			for _, association := range vpc.CidrBlockAssociationSet {
+				cidr := aws.StringValue(association.CidrBlock)
+				state := association.CidrBlockState
+				if cidr != aws.StringValue(vpc.CidrBlock) && state != nil && aws.StringValue(state.State) == ec2.VpcCidrBlockStateCodeAssociated {
+					cidrs[id] = append(cidrs[id], cidr)
+				}
+			}
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	for _, id := range c.config.SpokeVPCIDs {
+		if len(cidrs[id]) == 0 {
+			return nil, fmt.Errorf("spoke VPC %s not found", id)
+		}
+	}
+	return cidrs, nil
+}
+
+// checkSpoke checks that the route tables of a spoke route the other spokes to a
+// GWLB endpoint of the spoke, and that a default route, if any, is inspected
+func (c *Checker) checkSpoke(ctx context.Context, vpc string, own []string, spokeCIDRs []string) ([]Result, error) {
+	tables, err := c.routeTables(ctx, filter("vpc-id", vpc))
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := c.subnets(ctx, vpc)
+	if err != nil {
+		return nil, err
+	}
+	tables = usedRouteTables(tables, subnets)
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("spoke VPC %s has no route tables", vpc)
+	}
+	endpoints, err := c.gwlbEndpoints(ctx, vpc)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := "a GWLB endpoint of " + vpc
+	expected := endpoint
+	if len(endpoints) == 0 {
+		expected += " (none found)"
+	}
+	isEndpoint := func(target string) bool { return endpoints[target] }
+
+	var results []Result
+	for _, table := range tables {
+		for _, cidr := range spokeCIDRs {
+			if overlapsAny(cidr, own) {
+				continue
+			}
+			results = append(results, checkRoute(CheckSpokeRoute, vpc, table, cidr, expected, isEndpoint))
+		}
+		if _, ok := lookup(table, defaultRoute); ok {
+			results = append(results, checkRoute(CheckEgress, vpc, table, defaultRoute, endpoint+" or a transit gateway", func(target string) bool {
+				return endpoints[target] || strings.HasPrefix(target, "tgw-")
+			}))
+		}
+	}
+	return results, nil
+}
+
+// checkInspection checks that the private route tables of the inspection VPC
+// route every spoke to the transit gateway and the internet to a NAT gateway
+func (c *Checker) checkInspection(ctx context.Context, spokeCIDRs []string) ([]Result, error) {
+	vpc := c.config.InspectionVPCID
+	tables, err := c.routeTables(ctx, filter("vpc-id", vpc), filter("tag:Name", c.config.PrivateRouteTableName))
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("inspection VPC %s has no route tables named %s", vpc, c.config.PrivateRouteTableName)
+	}
+
+	expected, isTransitGateway := "a transit gateway", func(target string) bool { return strings.HasPrefix(target, "tgw-") }
+	if id := c.config.TransitGatewayID; id != "" {
+		expected, isTransitGateway = id, func(target string) bool { return target == id }
+	}
+	isNATGateway := func(target string) bool { return strings.HasPrefix(target, "nat-") }
+
+	var results []Result
+	for _, table := range tables {
+		for _, cidr := range spokeCIDRs {
+			results = append(results, checkRoute(CheckReturnRoute, vpc, table, cidr, expected, isTransitGateway))
+		}
+		results = append(results, checkRoute(CheckEgress, vpc, table, defaultRoute, "a NAT gateway", isNATGateway))
+	}
+	return results, nil
+}
+
+// checkRoute returns the result of the route a table takes to destination, which
+// passes when the route is active and its target is accepted by want
+func checkRoute(check Check, vpc string, table *ec2.RouteTable, destination, expected string, want func(target string) bool) Result {
+	result := Result{
+		Check:       check,
+		VPC:         vpc,
+		RouteTable:  aws.StringValue(table.RouteTableId),
+		Destination: destination,
+		Expected:    expected,
+	}
+	route, ok := lookup(table, destination)
+	if !ok {
+		result.Message = fmt.Sprintf("no route to %s, expected %s", destination, expected)
+		return result
+	}
+	result.Target = target(route)
+	via := ""
+	if covering := aws.StringValue(route.DestinationCidrBlock); covering != destination {
+		via = " via " + covering
+	}
+	switch {
+	case aws.StringValue(route.State) == ec2.RouteStateBlackhole:
+		result.Message = fmt.Sprintf("route to %s%s is a blackhole: %s no longer exists", destination, via, result.Target)
+	case !want(result.Target):
+		result.Message = fmt.Sprintf("%s routes%s to %s, expected %s", destination, via, result.Target, expected)
+	default:
+		result.Passed = true
+		result.Message = fmt.Sprintf("%s routes%s to %s", destination, via, result.Target)
+	}
+	return result
+}
+
+// lookup returns the most specific route of a table that covers all of
+// destination, as VPC routing picks for any address in it
+func lookup(table *ec2.RouteTable, destination string) (*ec2.Route, bool) {
+	prefix, err := netip.ParsePrefix(destination)
+	if err != nil {
+		return nil, false
+	}
+	var best *ec2.Route
+	bits := -1
+	for _, route := range table.Routes {
+		candidate, err := netip.ParsePrefix(aws.StringValue(route.DestinationCidrBlock))
+		if err != nil || candidate.Bits() > prefix.Bits() || !candidate.Contains(prefix.Addr()) {
+			continue
+		}
+		if candidate.Bits() > bits {
+			best, bits = route, candidate.Bits()
+		}
+	}
+	return best, best != nil
+}
+
+// target returns the target of a route. Routes to GWLB endpoints have the endpoint
+// as their gateway.
+func target(route *ec2.Route) string {
+	for _, id := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.TransitGatewayId,
+		route.VpcPeeringConnectionId,
+		route.NetworkInterfaceId,
+		route.InstanceId,
+		route.EgressOnlyInternetGatewayId,
+		route.LocalGatewayId,
+		route.CarrierGatewayId,
+		route.CoreNetworkArn,
+	} {
+		if aws.StringValue(id) != "" {
+			return aws.StringValue(id)
+		}
+	}
+	return ""
+}
+
+// overlapsAny reports whether cidr overlaps one of the CIDR blocks
+func overlapsAny(cidr string, blocks []string) bool {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+	for _, block := range blocks {
+		if other, err := netip.ParsePrefix(block); err == nil && other.Overlaps(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedRouteTables returns the tables associated with subnets, and the main table
+// when one of the subnets has no association of its own and so uses it, or when no
+// table has subnets
+func usedRouteTables(tables []*ec2.RouteTable, subnets []string) []*ec2.RouteTable {
+	associated := make(map[string]bool)
+	for _, table := range tables {
+		for _, association := range table.Associations {
+			if subnet := aws.StringValue(association.SubnetId); subnet != "" {
+				associated[subnet] = true
+			}
+		}
+	}
+	useMain := len(associated) == 0
+	for _, subnet := range subnets {
+		if !associated[subnet] {
+			useMain = true
+		}
+	}
+
+	var used []*ec2.RouteTable
+	for _, table := range tables {
+		for _, association := range table.Associations {
+			if aws.StringValue(association.SubnetId) != "" || (useMain && aws.BoolValue(association.Main)) {
+				used = append(used, table)
+				break
+			}
+		}
+	}
+	return used
+}
+
+// routeTables returns the route tables that match the filters, across all pages
+func (c *Checker) routeTables(ctx context.Context, filters ...*ec2.Filter) ([]*ec2.RouteTable, error) {
+	input := &ec2.DescribeRouteTablesInput{Filters: filters}
+	var tables []*ec2.RouteTable
+	for {
+		output, err := c.api.DescribeRouteTablesWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("describe route tables: %w", err)
+		}
+		tables = append(tables, output.RouteTables...)
+		if aws.StringValue(output.NextToken) == "" {
+			return tables, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// subnets returns the IDs of the subnets of a VPC, across all pages
+func (c *Checker) subnets(ctx context.Context, vpc string) ([]string, error) {
+	input := &ec2.DescribeSubnetsInput{Filters: []*ec2.Filter{filter("vpc-id", vpc)}}
+	var subnets []string
+	for {
+		output, err := c.api.DescribeSubnetsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("describe subnets: %w", err)
+		}
+		for _, subnet := range output.Subnets {
+			subnets = append(subnets, aws.StringValue(subnet.SubnetId))
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			return subnets, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// gwlbEndpoints returns the IDs of the GWLB endpoints of a VPC
+func (c *Checker) gwlbEndpoints(ctx context.Context, vpc string) (map[string]bool, error) {
+	input := &ec2.DescribeVpcEndpointsInput{Filters: []*ec2.Filter{
+		filter("vpc-id", vpc),
+		filter("vpc-endpoint-type", ec2.VpcEndpointTypeGatewayLoadBalancer),
+	}}
+	endpoints := make(map[string]bool)
+	for {
+		output, err := c.api.DescribeVpcEndpointsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("describe VPC endpoints: %w", err)
+		}
+		for _, endpoint := range output.VpcEndpoints {
+			endpoints[aws.StringValue(endpoint.VpcEndpointId)] = true
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			return endpoints, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// filter returns an EC2 filter on one value
+func filter(name, value string) *ec2.Filter {
+	return &ec2.Filter{Name: aws.String(name), Values: []*string{aws.String(value)}}
+}
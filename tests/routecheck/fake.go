@@ -0,0 +1,351 @@
+package routecheck
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// FakeEC2 is an in-memory EC2API. It supports the IDs and filters the checker
+// uses, with * and ? wildcards in filter values like EC2.
+type FakeEC2 struct {
+	VPCs        []*ec2.Vpc
+	Subnets     []*ec2.Subnet
+	RouteTables []*ec2.RouteTable
+	Endpoints   []*ec2.VpcEndpoint
+	// PageSize splits describe results into pages of this many items when set
+	PageSize int
+	// Err is returned by every call when set
+	Err error
+}
+
+// NewFakeEC2 returns an empty FakeEC2
+func NewFakeEC2() *FakeEC2 {
+	return &FakeEC2{}
+}
+
+// AddVPC adds a VPC with its primary CIDR block and associated CIDR blocks
+func (f *FakeEC2) AddVPC(id string, cidrs ...string) *ec2.Vpc {
+	vpc := &ec2.Vpc{VpcId: aws.String(id)}
+	for i, cidr := range cidrs {
+		if i == 0 {
+			vpc.CidrBlock = aws.String(cidr)
+		}
+		vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, &ec2.VpcCidrBlockAssociation{
+			CidrBlock:      aws.String(cidr),
+			CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)},
+		})
+	}
+	f.VPCs = append(f.VPCs, vpc)
+	return vpc
+}
+
+// AddSubnet adds a subnet of a VPC. Subnets without a route table association use
+// the main route table of their VPC.
+func (f *FakeEC2) AddSubnet(id, vpc string) *ec2.Subnet {
+	subnet := &ec2.Subnet{SubnetId: aws.String(id), VpcId: aws.String(vpc)}
+	f.Subnets = append(f.Subnets, subnet)
+	return subnet
+}
+
+// AddRouteTable adds a route table named name, with the local routes of its VPC
+// and routes. The table is associated with subnets, which are added to the VPC when
+// they are new, or is the main route table of the VPC when there are none.
+func (f *FakeEC2) AddRouteTable(id, vpc, name string, subnets []string, routes ...*ec2.Route) *ec2.RouteTable {
+	table := &ec2.RouteTable{RouteTableId: aws.String(id), VpcId: aws.String(vpc)}
+	if name != "" {
+		table.Tags = []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(name)}}
+	}
+	for _, v := range f.VPCs {
+		if aws.StringValue(v.VpcId) != vpc {
+			continue
+		}
+		for _, association := range v.CidrBlockAssociationSet {
+			table.Routes = append(table.Routes, Route(aws.StringValue(association.CidrBlock), "local"))
+		}
+	}
+	table.Routes = append(table.Routes, routes...)
+	for _, subnet := range subnets {
+		if !containsID(f.Subnets, aws.String(subnet), func(s *ec2.Subnet) *string { return s.SubnetId }) {
+			f.AddSubnet(subnet, vpc)
+		}
+		table.Associations = append(table.Associations, &ec2.RouteTableAssociation{
+			RouteTableId: aws.String(id),
+			SubnetId:     aws.String(subnet),
+			Main:         aws.Bool(false),
+		})
+	}
+	if len(subnets) == 0 {
+		table.Associations = append(table.Associations, &ec2.RouteTableAssociation{RouteTableId: aws.String(id), Main: aws.Bool(true)})
+	}
+	f.RouteTables = append(f.RouteTables, table)
+	return table
+}
+
+// AddGWLBEndpoint adds a GWLB endpoint in subnets of a VPC
+func (f *FakeEC2) AddGWLBEndpoint(id, vpc string, subnets ...string) *ec2.VpcEndpoint {
+	endpoint := &ec2.VpcEndpoint{
+		VpcEndpointId:   aws.String(id),
+		VpcId:           aws.String(vpc),
+		VpcEndpointType: aws.String(ec2.VpcEndpointTypeGatewayLoadBalancer),
+		ServiceName:     aws.String("com.amazonaws.vpce.us-east-1.vpce-svc-" + strings.TrimPrefix(id, "vpce-")),
+		SubnetIds:       aws.StringSlice(subnets),
+		State:           aws.String("available"),
+	}
+	f.Endpoints = append(f.Endpoints, endpoint)
+	return endpoint
+}
+
+// Route returns an active route to target, set on the field EC2 reports it in by
+// its ID prefix: internet gateways, GWLB endpoints and "local" are gateways.
+func Route(destination, target string) *ec2.Route {
+	route := &ec2.Route{
+		DestinationCidrBlock: aws.String(destination),
+		State:                aws.String(ec2.RouteStateActive),
+		Origin:               aws.String(ec2.RouteOriginCreateRoute),
+	}
+	switch {
+	case strings.HasPrefix(target, "nat-"):
+		route.NatGatewayId = aws.String(target)
+	case strings.HasPrefix(target, "tgw-"):
+		route.TransitGatewayId = aws.String(target)
+	case strings.HasPrefix(target, "pcx-"):
+		route.VpcPeeringConnectionId = aws.String(target)
+	case strings.HasPrefix(target, "eni-"):
+		route.NetworkInterfaceId = aws.String(target)
+	case target == "local":
+		route.GatewayId = aws.String(target)
+		route.Origin = aws.String(ec2.RouteOriginCreateRouteTable)
+	default:
+		route.GatewayId = aws.String(target)
+	}
+	return route
+}
+
+// DescribeVpcsWithContext returns the VPCs with the requested IDs that match the
+// filters
+func (f *FakeEC2) DescribeVpcsWithContext(_ aws.Context, input *ec2.DescribeVpcsInput, _ ...request.Option) (*ec2.DescribeVpcsOutput, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var matched []*ec2.Vpc
+	for _, vpc := range f.VPCs {
+		ok, err := matches(input.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{aws.StringValue(vpc.VpcId)}, true
+			case "cidr":
+				return []string{aws.StringValue(vpc.CidrBlock)}, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && selected(input.VpcIds, vpc.VpcId) {
+			matched = append(matched, vpc)
+		}
+	}
+	for _, id := range input.VpcIds {
+		if !containsID(matched, id, func(vpc *ec2.Vpc) *string { return vpc.VpcId }) {
+			return nil, fmt.Errorf("InvalidVpcID.NotFound: The vpc ID '%s' does not exist", aws.StringValue(id))
+		}
+	}
+	page, next, err := f.page(len(matched), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVpcsOutput{Vpcs: matched[page[0]:page[1]], NextToken: next}, nil
+}
+
+// DescribeRouteTablesWithContext returns the route tables with the requested IDs
+// that match the filters
+func (f *FakeEC2) DescribeRouteTablesWithContext(_ aws.Context, input *ec2.DescribeRouteTablesInput, _ ...request.Option) (*ec2.DescribeRouteTablesOutput, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var matched []*ec2.RouteTable
+	for _, table := range f.RouteTables {
+		ok, err := matches(input.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{aws.StringValue(table.VpcId)}, true
+			case "route-table-id":
+				return []string{aws.StringValue(table.RouteTableId)}, true
+			case "association.subnet-id":
+				var subnets []string
+				for _, association := range table.Associations {
+					subnets = append(subnets, aws.StringValue(association.SubnetId))
+				}
+				return subnets, true
+			}
+			return tagValues(table.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && selected(input.RouteTableIds, table.RouteTableId) {
+			matched = append(matched, table)
+		}
+	}
+	page, next, err := f.page(len(matched), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeRouteTablesOutput{RouteTables: matched[page[0]:page[1]], NextToken: next}, nil
+}
+
+// DescribeVpcEndpointsWithContext returns the VPC endpoints with the requested IDs
+// that match the filters
+func (f *FakeEC2) DescribeVpcEndpointsWithContext(_ aws.Context, input *ec2.DescribeVpcEndpointsInput, _ ...request.Option) (*ec2.DescribeVpcEndpointsOutput, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var matched []*ec2.VpcEndpoint
+	for _, endpoint := range f.Endpoints {
+		ok, err := matches(input.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{aws.StringValue(endpoint.VpcId)}, true
+			case "vpc-endpoint-id":
+				return []string{aws.StringValue(endpoint.VpcEndpointId)}, true
+			case "vpc-endpoint-type":
+				return []string{aws.StringValue(endpoint.VpcEndpointType)}, true
+			case "service-name":
+				return []string{aws.StringValue(endpoint.ServiceName)}, true
+			case "vpc-endpoint-state":
+				return []string{aws.StringValue(endpoint.State)}, true
+			}
+			return tagValues(endpoint.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && selected(input.VpcEndpointIds, endpoint.VpcEndpointId) {
+			matched = append(matched, endpoint)
+		}
+	}
+	page, next, err := f.page(len(matched), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVpcEndpointsOutput{VpcEndpoints: matched[page[0]:page[1]], NextToken: next}, nil
+}
+
+// DescribeSubnetsWithContext returns the subnets with the requested IDs that match
+// the filters
+func (f *FakeEC2) DescribeSubnetsWithContext(_ aws.Context, input *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var matched []*ec2.Subnet
+	for _, subnet := range f.Subnets {
+		ok, err := matches(input.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return []string{aws.StringValue(subnet.VpcId)}, true
+			case "subnet-id":
+				return []string{aws.StringValue(subnet.SubnetId)}, true
+			}
+			return tagValues(subnet.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok && selected(input.SubnetIds, subnet.SubnetId) {
+			matched = append(matched, subnet)
+		}
+	}
+	page, next, err := f.page(len(matched), input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeSubnetsOutput{Subnets: matched[page[0]:page[1]], NextToken: next}, nil
+}
+
+// page returns the bounds of the page of n items that starts at token, and the
+// token of the next page
+func (f *FakeEC2) page(n int, token *string) ([2]int, *string, error) {
+	start := 0
+	if aws.StringValue(token) != "" {
+		var err error
+		if start, err = strconv.Atoi(aws.StringValue(token)); err != nil || start < 0 || start > n {
+			return [2]int{}, nil, fmt.Errorf("InvalidNextToken: The token '%s' is invalid", aws.StringValue(token))
+		}
+	}
+	if f.PageSize <= 0 || start+f.PageSize >= n {
+		return [2]int{start, n}, nil, nil
+	}
+	return [2]int{start, start + f.PageSize}, aws.String(strconv.Itoa(start + f.PageSize)), nil
+}
+
+// matches reports whether an item matches all filters, each on any of its values.
+// values returns the values of the item for a filter name, and false for names
+// the fake does not support.
+func matches(filters []*ec2.Filter, values func(name string) ([]string, bool)) (bool, error) {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		have, ok := values(name)
+		if !ok {
+			return false, fmt.Errorf("InvalidParameterValue: The filter '%s' is invalid", name)
+		}
+		if !matchesAny(aws.StringValueSlice(filter.Values), have) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchesAny reports whether a pattern matches one of the values
+func matchesAny(patterns, values []string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if ok, _ := path.Match(pattern, value); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagValues returns the value of the tag a tag:<key> filter selects
+func tagValues(tags []*ec2.Tag, name string) ([]string, bool) {
+	key, ok := strings.CutPrefix(name, "tag:")
+	if !ok {
+		return nil, false
+	}
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return []string{aws.StringValue(tag.Value)}, true
+		}
+	}
+	return nil, true
+}
+
+// selected reports whether id is one of ids, or ids is empty
+func selected(ids []*string, id *string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, want := range ids {
+		if aws.StringValue(want) == aws.StringValue(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsID reports whether one of items has id
+func containsID[T any](items []T, id *string, idOf func(T) *string) bool {
+	for _, item := range items {
+		if aws.StringValue(idOf(item)) == aws.StringValue(id) {
+			return true
+		}
+	}
+	return false
+}
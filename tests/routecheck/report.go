@@ -0,0 +1,51 @@
+package routecheck
+
+import (
+	"time"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+)
+
+// SuiteName is the suite of the results in reports
+const SuiteName = "routing-check"
+
+// Package is the package of the results in reports
+const Package = "routecheck"
+
+// Category is the report category of the results, which puts failed routes into
+// the SARIF report with the other security findings
+const Category = "security"
+
+// Suite returns the results as a test suite, one test per route checked, for the
+// reporting package. Failed routes are failed tests with the message as error.
+func Suite(results []Result, environment, region string, start, end time.Time) reporting.TestSuiteResult {
+	suite := reporting.TestSuiteResult{
+		SuiteName:   SuiteName,
+		Environment: environment,
+		Region:      region,
+		StartTime:   start,
+		EndTime:     end,
+		Duration:    end.Sub(start),
+		TotalTests:  len(results),
+		Results:     make([]reporting.TestResult, 0, len(results)),
+	}
+	for _, result := range results {
+		test := reporting.TestResult{
+			TestName:  result.Name(),
+			Package:   Package,
+			Status:    "PASS",
+			Output:    result.Message,
+			Timestamp: end,
+			Category:  Category,
+		}
+		if result.Passed {
+			suite.PassedTests++
+		} else {
+			test.Status = "FAIL"
+			test.Error = result.Message
+			suite.FailedTests++
+		}
+		suite.Results = append(suite.Results, test)
+	}
+	return suite
+}
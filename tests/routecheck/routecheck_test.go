@@ -0,0 +1,336 @@
+package routecheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/reporting"
+	"github.com/your-org/aws-centralized-inspection/tests/routecheck"
+)
+
+// deployed returns a fake of the deployed topology: two spokes that route each
+// other to their GWLB endpoint, and an inspection VPC whose private route tables
+// route the spokes to the transit gateway and the internet to a NAT gateway
+func deployed() *routecheck.FakeEC2 {
+	fake := routecheck.NewFakeEC2()
+	fake.AddVPC("vpc-inspection", "10.0.0.0/16")
+	fake.AddVPC("vpc-spoke-0", "10.1.0.0/16")
+	fake.AddVPC("vpc-spoke-1", "10.2.0.0/16")
+
+	fake.AddRouteTable("rtb-public", "vpc-inspection", "inspection-public-rt", []string{"subnet-public-0", "subnet-public-1"},
+		routecheck.Route("0.0.0.0/0", "igw-inspection"))
+	for _, i := range []string{"0", "1"} {
+		fake.AddRouteTable("rtb-private-"+i, "vpc-inspection", "inspection-private-rt-"+i, []string{"subnet-private-" + i},
+			routecheck.Route("0.0.0.0/0", "nat-"+i),
+			routecheck.Route("10.1.0.0/16", "tgw-inspection"),
+			routecheck.Route("10.2.0.0/16", "tgw-inspection"))
+	}
+
+	fake.AddRouteTable("rtb-spoke-0-main", "vpc-spoke-0", "", nil)
+	fake.AddRouteTable("rtb-spoke-0", "vpc-spoke-0", "spoke-rt-0", []string{"subnet-spoke-0-0", "subnet-spoke-0-1"},
+		routecheck.Route("10.2.0.0/16", "vpce-spoke-0"))
+	fake.AddGWLBEndpoint("vpce-spoke-0", "vpc-spoke-0", "subnet-spoke-0-0", "subnet-spoke-0-1")
+	fake.AddRouteTable("rtb-spoke-1-main", "vpc-spoke-1", "", nil)
+	fake.AddRouteTable("rtb-spoke-1", "vpc-spoke-1", "spoke-rt-1", []string{"subnet-spoke-1-0", "subnet-spoke-1-1"},
+		routecheck.Route("10.1.0.0/16", "vpce-spoke-1"))
+	fake.AddGWLBEndpoint("vpce-spoke-1", "vpc-spoke-1", "subnet-spoke-1-0", "subnet-spoke-1-1")
+	return fake
+}
+
+// config checks the inspection VPC and both spokes of deployed
+var config = routecheck.Config{
+	InspectionVPCID:  "vpc-inspection",
+	SpokeVPCIDs:      []string{"vpc-spoke-0", "vpc-spoke-1"},
+	TransitGatewayID: "tgw-inspection",
+}
+
+// table returns a route table of the fake
+func table(t *testing.T, fake *routecheck.FakeEC2, id string) *ec2.RouteTable {
+	for _, table := range fake.RouteTables {
+		if aws.StringValue(table.RouteTableId) == id {
+			return table
+		}
+	}
+	t.Fatalf("no route table %s", id)
+	return nil
+}
+
+// setRoute replaces the route of a table to destination, or removes it when route
+// is nil
+func setRoute(t *testing.T, fake *routecheck.FakeEC2, id, destination string, route *ec2.Route) {
+	rt := table(t, fake, id)
+	routes := rt.Routes[:0]
+	for _, r := range rt.Routes {
+		if aws.StringValue(r.DestinationCidrBlock) != destination {
+			routes = append(routes, r)
+		}
+	}
+	if route != nil {
+		routes = append(routes, route)
+	}
+	rt.Routes = routes
+}
+
+func TestRun(t *testing.T) {
+	results, err := routecheck.NewChecker(deployed(), config).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, routecheck.Failed(results))
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name())
+	}
+	assert.Equal(t, []string{
+		"spoke-route/rtb-spoke-0/10.2.0.0/16",
+		"spoke-route/rtb-spoke-1/10.1.0.0/16",
+		"return-route/rtb-private-0/10.1.0.0/16",
+		"return-route/rtb-private-0/10.2.0.0/16",
+		"egress/rtb-private-0/0.0.0.0/0",
+		"return-route/rtb-private-1/10.1.0.0/16",
+		"return-route/rtb-private-1/10.2.0.0/16",
+		"egress/rtb-private-1/0.0.0.0/0",
+	}, names)
+	assert.Equal(t, "PASS spoke-route/rtb-spoke-0/10.2.0.0/16: 10.2.0.0/16 routes to vpce-spoke-0", results[0].String())
+
+	// Paging returns the same results
+	fake := deployed()
+	fake.PageSize = 1
+	paged, err := routecheck.NewChecker(fake, config).Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, results, paged)
+}
+
+func TestRunFindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(*routecheck.Config)
+		change  func(t *testing.T, fake *routecheck.FakeEC2)
+		check   routecheck.Check
+		table   string
+		message string
+	}{
+		{
+			name: "missing spoke route",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-spoke-0", "10.2.0.0/16", nil)
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-0",
+			message: "no route to 10.2.0.0/16, expected a GWLB endpoint of vpc-spoke-0",
+		},
+		{
+			name: "subnet without an association uses the main route table",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				fake.AddSubnet("subnet-spoke-0-2", "vpc-spoke-0")
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-0-main",
+			message: "no route to 10.2.0.0/16, expected a GWLB endpoint of vpc-spoke-0",
+		},
+		{
+			name: "spoke route to the transit gateway",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-spoke-1", "10.1.0.0/16", routecheck.Route("10.1.0.0/16", "tgw-inspection"))
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-1",
+			message: "10.1.0.0/16 routes to tgw-inspection, expected a GWLB endpoint of vpc-spoke-1",
+		},
+		{
+			name: "spoke route to the endpoint of another spoke",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-spoke-0", "10.2.0.0/16", routecheck.Route("10.2.0.0/16", "vpce-spoke-1"))
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-0",
+			message: "10.2.0.0/16 routes to vpce-spoke-1, expected a GWLB endpoint of vpc-spoke-0",
+		},
+		{
+			name: "spoke without endpoint",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				fake.Endpoints = fake.Endpoints[1:]
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-0",
+			message: "10.2.0.0/16 routes to vpce-spoke-0, expected a GWLB endpoint of vpc-spoke-0 (none found)",
+		},
+		{
+			name: "blackhole spoke route",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				route := routecheck.Route("10.2.0.0/16", "vpce-spoke-0")
+				route.State = aws.String(ec2.RouteStateBlackhole)
+				setRoute(t, fake, "rtb-spoke-0", "10.2.0.0/16", route)
+			},
+			check:   routecheck.CheckSpokeRoute,
+			table:   "rtb-spoke-0",
+			message: "route to 10.2.0.0/16 is a blackhole: vpce-spoke-0 no longer exists",
+		},
+		{
+			name: "spoke egress bypasses inspection",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-spoke-1", "0.0.0.0/0", routecheck.Route("0.0.0.0/0", "igw-spoke-1"))
+			},
+			check:   routecheck.CheckEgress,
+			table:   "rtb-spoke-1",
+			message: "0.0.0.0/0 routes to igw-spoke-1, expected a GWLB endpoint of vpc-spoke-1 or a transit gateway",
+		},
+		{
+			name: "missing return route",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-private-1", "10.2.0.0/16", nil)
+			},
+			check:   routecheck.CheckReturnRoute,
+			table:   "rtb-private-1",
+			message: "10.2.0.0/16 routes via 0.0.0.0/0 to nat-1, expected tgw-inspection",
+		},
+		{
+			name: "return route to another transit gateway",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-private-0", "10.1.0.0/16", routecheck.Route("10.1.0.0/16", "tgw-other"))
+			},
+			check:   routecheck.CheckReturnRoute,
+			table:   "rtb-private-0",
+			message: "10.1.0.0/16 routes to tgw-other, expected tgw-inspection",
+		},
+		{
+			name: "private egress to the internet gateway",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-private-0", "0.0.0.0/0", routecheck.Route("0.0.0.0/0", "igw-inspection"))
+			},
+			check:   routecheck.CheckEgress,
+			table:   "rtb-private-0",
+			message: "0.0.0.0/0 routes to igw-inspection, expected a NAT gateway",
+		},
+		{
+			name: "private table without egress",
+			change: func(t *testing.T, fake *routecheck.FakeEC2) {
+				setRoute(t, fake, "rtb-private-0", "0.0.0.0/0", nil)
+			},
+			check:   routecheck.CheckEgress,
+			table:   "rtb-private-0",
+			message: "no route to 0.0.0.0/0, expected a NAT gateway",
+		},
+		{
+			name: "spoke CIDRs outside the spokes",
+			config: func(config *routecheck.Config) {
+				config.SpokeVPCIDs = nil
+				config.SpokeCIDRs = []string{"10.1.0.0/16", "10.2.0.0/16", "10.3.0.0/16"}
+			},
+			check:   routecheck.CheckReturnRoute,
+			table:   "rtb-private-0",
+			message: "10.3.0.0/16 routes via 0.0.0.0/0 to nat-0, expected tgw-inspection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := deployed()
+			if tt.change != nil {
+				tt.change(t, fake)
+			}
+			c := config
+			if tt.config != nil {
+				tt.config(&c)
+			}
+			results, err := routecheck.NewChecker(fake, c).Run(context.Background())
+			require.NoError(t, err)
+
+			failed := routecheck.Failed(results)
+			require.NotEmpty(t, failed)
+			assert.Equal(t, tt.check, failed[0].Check)
+			assert.Equal(t, tt.table, failed[0].RouteTable)
+			assert.Equal(t, tt.message, failed[0].Message)
+		})
+	}
+}
+
+func TestRunCoveringRoutes(t *testing.T) {
+	fake := deployed()
+	setRoute(t, fake, "rtb-private-0", "10.1.0.0/16", nil)
+	setRoute(t, fake, "rtb-private-0", "10.2.0.0/16", routecheck.Route("10.0.0.0/8", "tgw-inspection"))
+	// A more specific route to part of a spoke does not count for the whole spoke
+	setRoute(t, fake, "rtb-spoke-0", "10.2.0.0/24", routecheck.Route("10.2.0.0/24", "pcx-shortcut"))
+
+	results, err := routecheck.NewChecker(fake, config).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, routecheck.Failed(results))
+	assert.Contains(t, results, routecheck.Result{
+		Check:       routecheck.CheckReturnRoute,
+		VPC:         "vpc-inspection",
+		RouteTable:  "rtb-private-0",
+		Destination: "10.2.0.0/16",
+		Expected:    "tgw-inspection",
+		Target:      "tgw-inspection",
+		Passed:      true,
+		Message:     "10.2.0.0/16 routes via 10.0.0.0/8 to tgw-inspection",
+	})
+}
+
+func TestRunErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := routecheck.NewChecker(deployed(), routecheck.Config{}).Run(ctx)
+	assert.EqualError(t, err, "no inspection or spoke VPC to check")
+
+	_, err = routecheck.NewChecker(deployed(), routecheck.Config{InspectionVPCID: "vpc-inspection"}).Run(ctx)
+	assert.EqualError(t, err, "no spoke CIDRs to check")
+
+	_, err = routecheck.NewChecker(deployed(), routecheck.Config{SpokeVPCIDs: []string{"vpc-missing"}}).Run(ctx)
+	assert.ErrorContains(t, err, "InvalidVpcID.NotFound")
+
+	_, err = routecheck.NewChecker(deployed(), routecheck.Config{
+		InspectionVPCID:       "vpc-inspection",
+		SpokeCIDRs:            []string{"10.1.0.0/16"},
+		PrivateRouteTableName: "*isolated*",
+	}).Run(ctx)
+	assert.EqualError(t, err, "inspection VPC vpc-inspection has no route tables named *isolated*")
+
+	_, err = routecheck.NewChecker(deployed(), routecheck.Config{InspectionVPCID: "vpc-inspection", SpokeCIDRs: []string{"10.1.0.0"}}).Run(ctx)
+	assert.ErrorContains(t, err, `spoke CIDR "10.1.0.0"`)
+
+	fake := deployed()
+	fake.Err = errors.New("UnauthorizedOperation")
+	_, err = routecheck.NewChecker(fake, config).Run(ctx)
+	assert.EqualError(t, err, "describe VPCs: UnauthorizedOperation")
+}
+
+func TestSuite(t *testing.T) {
+	fake := deployed()
+	setRoute(t, fake, "rtb-spoke-0", "10.2.0.0/16", nil)
+	results, err := routecheck.NewChecker(fake, config).Run(context.Background())
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	suite := routecheck.Suite(results, "prod", "us-east-1", start, start.Add(2*time.Second))
+	assert.Equal(t, routecheck.SuiteName, suite.SuiteName)
+	assert.Equal(t, 8, suite.TotalTests)
+	assert.Equal(t, 7, suite.PassedTests)
+	assert.Equal(t, 1, suite.FailedTests)
+	assert.Equal(t, 2*time.Second, suite.Duration)
+
+	// The suite is a JSON report the reporting package loads
+	analytics := reporting.NewTestAnalytics()
+	analytics.AddResult(suite)
+	data, err := json.Marshal(analytics)
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "routing-check.json")
+	require.NoError(t, os.WriteFile(filename, data, 0644))
+	loaded, err := reporting.LoadReport(filename)
+	require.NoError(t, err)
+
+	failed := loaded.GetFailedTests()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "spoke-route/rtb-spoke-0/10.2.0.0/16", failed[0].TestName)
+	assert.Equal(t, "no route to 10.2.0.0/16, expected a GWLB endpoint of vpc-spoke-0", failed[0].Error)
+	assert.Equal(t, "modules/inspection/main.tf", reporting.ModuleFileForTest(failed[0]))
+}
@@ -0,0 +1,179 @@
+package routing
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// EphemeralPort is the source port of the flows built by EastWestFlows and
+// InternetFlows
+const EphemeralPort = 49152
+
+// FindingKind is the kind of routing problem a finding reports
+type FindingKind string
+
+// Finding kinds
+const (
+	// FindingBlackhole is a flow or reply dropped on the way
+	FindingBlackhole FindingKind = "blackhole"
+	// FindingLoop is a flow or reply that routes in a circle
+	FindingLoop FindingKind = "loop"
+	// FindingBypass is a flow or reply that reaches its destination uninspected
+	FindingBypass FindingKind = "bypass"
+	// FindingAsymmetric is a flow whose replies pass other firewalls than the flow,
+	// which stateful firewalls drop
+	FindingAsymmetric FindingKind = "asymmetric"
+)
+
+// Finding is a routing problem of one flow
+type Finding struct {
+	Kind    FindingKind
+	Flow    Flow
+	Message string
+	// Path is the path that shows the problem
+	Path *Path
+}
+
+// String returns the finding as kind, flow and message
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Kind, f.Flow, f.Message)
+}
+
+// Analyze traces every flow and its replies, which must all be inspected. It
+// reports flows and replies that are dropped or bypass inspection, and flows whose
+// replies are not inspected by the same endpoints and firewalls in reverse order.
+func (t *Topology) Analyze(flows []Flow) ([]Finding, error) {
+	var findings []Finding
+	for _, flow := range flows {
+		forward, err := t.Trace(flow)
+		if err != nil {
+			return nil, err
+		}
+		if finding, ok := checkPath(forward, "flow"); !ok {
+			findings = append(findings, finding)
+			continue
+		}
+
+		reply, err := t.TraceReply(forward)
+		if err != nil {
+			return nil, err
+		}
+		if finding, ok := checkPath(reply, "reply"); !ok {
+			finding.Flow = flow
+			findings = append(findings, finding)
+			continue
+		}
+
+		if !symmetric(forward.Inspections(), reply.Inspections()) {
+			findings = append(findings, Finding{
+				Kind: FindingAsymmetric,
+				Flow: flow,
+				Message: fmt.Sprintf("flow is inspected by %s, reply by %s",
+					joinInspections(forward.Inspections()), joinInspections(reply.Inspections())),
+				Path: reply,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkPath returns the finding of a path that is dropped or not inspected
+func checkPath(path *Path, direction string) (Finding, bool) {
+	finding := Finding{Flow: path.Flow, Path: path}
+	switch {
+	case path.Outcome == Blackhole:
+		finding.Kind = FindingBlackhole
+		finding.Message = fmt.Sprintf("%s is dropped: %s", direction, path.Reason)
+	case path.Outcome == Loop:
+		finding.Kind = FindingLoop
+		finding.Message = fmt.Sprintf("%s loops: %s", direction, path.Reason)
+	case !path.Inspected():
+		finding.Kind = FindingBypass
+		finding.Message = fmt.Sprintf("%s is not inspected: %s", direction, path)
+	default:
+		return Finding{}, true
+	}
+	return finding, false
+}
+
+// symmetric reports whether the reply passes the inspections of the flow in reverse
+func symmetric(forward, reply []Inspection) bool {
+	if len(forward) != len(reply) {
+		return false
+	}
+	for i := range forward {
+		if forward[i] != reply[len(reply)-1-i] {
+			return false
+		}
+	}
+	return true
+}
+
+// joinInspections returns the inspections separated by commas
+func joinInspections(inspections []Inspection) string {
+	names := make([]string, len(inspections))
+	for i, inspection := range inspections {
+		names[i] = inspection.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Host returns an address in a subnet for test flows: the first address AWS does
+// not reserve
+func (t *Topology) Host(subnet string) (netip.Addr, error) {
+	s, ok := t.index().subnets[subnet]
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("no subnet %s in the topology", subnet)
+	}
+	prefix, err := netip.ParsePrefix(s.CIDR)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("subnet %s: %w", subnet, err)
+	}
+	addr := prefix.Masked().Addr()
+	for i := 0; i < 4; i++ {
+		addr = addr.Next()
+	}
+	return addr, nil
+}
+
+// EastWestFlows returns a flow from each subnet to every subnet of another VPC
+func (t *Topology) EastWestFlows(protocol string, port int, subnets ...string) ([]Flow, error) {
+	index := t.index()
+	for _, subnet := range subnets {
+		if index.subnets[subnet] == nil {
+			return nil, fmt.Errorf("no subnet %s in the topology", subnet)
+		}
+	}
+	var flows []Flow
+	for _, from := range subnets {
+		for _, to := range subnets {
+			if index.subnets[from].VPC == index.subnets[to].VPC {
+				continue
+			}
+			source, err := t.Host(from)
+			if err != nil {
+				return nil, err
+			}
+			destination, err := t.Host(to)
+			if err != nil {
+				return nil, err
+			}
+			flows = append(flows, Flow{Protocol: protocol, Source: source, SourcePort: EphemeralPort, Destination: destination, DestinationPort: port})
+		}
+	}
+	return flows, nil
+}
+
+// InternetFlows returns a flow from each subnet to an internet address
+func (t *Topology) InternetFlows(protocol string, port int, destination netip.Addr, subnets ...string) ([]Flow, error) {
+	var flows []Flow
+	for _, subnet := range subnets {
+		source, err := t.Host(subnet)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, Flow{Protocol: protocol, Source: source, SourcePort: EphemeralPort, Destination: destination, DestinationPort: port})
+	}
+	return flows, nil
+}
@@ -0,0 +1,253 @@
+package routing
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// FromPlan builds a topology from the planned resources of a plan. Resources whose
+// id is only known after apply are named by their address, and references that are
+// unknown stay empty, so the topology of a plan against deployed infrastructure is
+// complete while a first plan usually fails Validate. Routes added with aws_route to
+// route tables outside the plan make route tables without a VPC, to be merged into
+// the topology of the module that owns them.
+func FromPlan(p *plan.Plan) *Topology {
+	return fromPlan(p, nil)
+}
+
+// fromPlan builds the topology of a plan, evaluating unknown references from source
+// when it is not nil
+func fromPlan(p *plan.Plan, source *Source) *Topology {
+	t := &Topology{}
+
+	for _, r := range p.Resources("aws_vpc") {
+		t.VPCs = append(t.VPCs, &VPC{ID: resourceID(r), CIDR: stringValue(r, "cidr_block")})
+	}
+	subnets := make(map[string]*Subnet)
+	for _, r := range p.Resources("aws_subnet") {
+		subnet := &Subnet{ID: resourceID(r), VPC: source.stringValue(r, "vpc_id"), CIDR: stringValue(r, "cidr_block"), AZ: stringValue(r, "availability_zone")}
+		subnets[subnet.ID] = subnet
+		t.Subnets = append(t.Subnets, subnet)
+	}
+
+	tables := make(map[string]*RouteTable)
+	table := func(id string) *RouteTable {
+		if tables[id] == nil {
+			tables[id] = &RouteTable{ID: id}
+			t.RouteTables = append(t.RouteTables, tables[id])
+		}
+		return tables[id]
+	}
+	for _, r := range p.Resources("aws_route_table") {
+		rt := table(resourceID(r))
+		rt.VPC = source.stringValue(r, "vpc_id")
+		routes, _ := r.Value("route")
+		for i := range listValue(routes) {
+			route := func(key string) string { return source.stringValue(r, fmt.Sprintf("route.%d.%s", i, key)) }
+			rt.Routes = append(rt.Routes, Route{
+				Destination:      route("cidr_block"),
+				GatewayID:        route("gateway_id"),
+				NATGatewayID:     route("nat_gateway_id"),
+				TransitGatewayID: route("transit_gateway_id"),
+				VPCEndpointID:    route("vpc_endpoint_id"),
+			})
+		}
+	}
+	for _, r := range p.Resources("aws_route") {
+		if id := source.stringValue(r, "route_table_id"); id != "" {
+			rt := table(id)
+			rt.Routes = append(rt.Routes, Route{
+				Destination:      stringValue(r, "destination_cidr_block"),
+				GatewayID:        source.stringValue(r, "gateway_id"),
+				NATGatewayID:     source.stringValue(r, "nat_gateway_id"),
+				TransitGatewayID: source.stringValue(r, "transit_gateway_id"),
+				VPCEndpointID:    source.stringValue(r, "vpc_endpoint_id"),
+			})
+		}
+	}
+	for _, r := range p.Resources("aws_main_route_table_association") {
+		if rt, ok := tables[source.stringValue(r, "route_table_id")]; ok {
+			rt.Main = true
+		}
+	}
+	for _, r := range p.Resources("aws_route_table_association") {
+		if subnet, ok := subnets[source.stringValue(r, "subnet_id")]; ok {
+			subnet.RouteTable = source.stringValue(r, "route_table_id")
+		}
+	}
+
+	for _, r := range p.Resources("aws_internet_gateway") {
+		t.InternetGateways = append(t.InternetGateways, &Gateway{ID: resourceID(r), VPC: source.stringValue(r, "vpc_id")})
+	}
+	for _, r := range p.Resources("aws_nat_gateway") {
+		t.NATGateways = append(t.NATGateways, &Gateway{ID: resourceID(r), Subnet: source.stringValue(r, "subnet_id")})
+	}
+
+	t.LoadBalancers = loadBalancersFromPlan(p, subnets, source)
+	services := make(map[string]string)
+	for _, r := range p.Resources("aws_vpc_endpoint_service") {
+		for _, arn := range source.listValue(r, "gateway_load_balancer_arns") {
+			services[source.stringValue(r, "service_name")], _ = arn.(string)
+		}
+	}
+	for _, r := range p.Resources("aws_vpc_endpoint") {
+		if stringValue(r, "vpc_endpoint_type") != "GatewayLoadBalancer" {
+			continue
+		}
+		endpoint := &Endpoint{ID: resourceID(r), LoadBalancer: services[source.stringValue(r, "service_name")]}
+		for _, id := range source.listValue(r, "subnet_ids") {
+			endpoint.Subnet, _ = id.(string)
+		}
+		t.Endpoints = append(t.Endpoints, endpoint)
+	}
+
+	t.TransitGateways = transitGatewaysFromPlan(p, source)
+	return t
+}
+
+// loadBalancersFromPlan returns the gateway load balancers with the targets
+// registered in the target groups their listeners forward to. Targets get the AZ of
+// the attachment, or of the subnet that contains an IP target.
+func loadBalancersFromPlan(p *plan.Plan, subnets map[string]*Subnet, source *Source) []*LoadBalancer {
+	targets := make(map[string][]Target)
+	for _, r := range p.Resources("aws_lb_target_group_attachment") {
+		target := Target{ID: source.stringValue(r, "target_id"), AZ: stringValue(r, "availability_zone")}
+		if addr, err := netip.ParseAddr(target.ID); err == nil && (target.AZ == "" || target.AZ == "all") {
+			target.AZ = ""
+			for _, subnet := range subnets {
+				if prefix, err := netip.ParsePrefix(subnet.CIDR); err == nil && prefix.Contains(addr) {
+					target.AZ = subnet.AZ
+				}
+			}
+		}
+		group := source.stringValue(r, "target_group_arn")
+		targets[group] = append(targets[group], target)
+	}
+	listeners := make(map[string][]string)
+	for _, r := range p.Resources("aws_lb_listener") {
+		balancer := source.stringValue(r, "load_balancer_arn")
+		listeners[balancer] = append(listeners[balancer], source.stringValue(r, "default_action.0.target_group_arn"))
+	}
+
+	var balancers []*LoadBalancer
+	for _, r := range p.Resources("aws_lb") {
+		if stringValue(r, "load_balancer_type") != "gateway" {
+			continue
+		}
+		balancer := &LoadBalancer{ID: standIn(r, "arn")}
+		crossZone, _ := r.Value("enable_cross_zone_load_balancing")
+		balancer.CrossZone = crossZone == true
+		for _, group := range listeners[balancer.ID] {
+			balancer.Targets = append(balancer.Targets, targets[group]...)
+		}
+		balancers = append(balancers, balancer)
+	}
+	return balancers
+}
+
+// transitGatewaysFromPlan returns the transit gateways with their attachments,
+// route tables, associations, propagations and static routes
+func transitGatewaysFromPlan(p *plan.Plan, source *Source) []*TransitGateway {
+	var gateways []*TransitGateway
+	byID := make(map[string]*TransitGateway)
+	gateway := func(id string) *TransitGateway {
+		if byID[id] == nil {
+			byID[id] = &TransitGateway{ID: id}
+			gateways = append(gateways, byID[id])
+		}
+		return byID[id]
+	}
+	for _, r := range p.Resources("aws_ec2_transit_gateway") {
+		tgw := gateway(resourceID(r))
+		if stringValue(r, "default_route_table_association") != "disable" {
+			tgw.DefaultRouteTable = stringValue(r, "association_default_route_table_id")
+		}
+	}
+
+	attachments := make(map[string]*Attachment)
+	for _, r := range p.Resources("aws_ec2_transit_gateway_vpc_attachment") {
+		attachment := &Attachment{
+			ID:            resourceID(r),
+			VPC:           source.stringValue(r, "vpc_id"),
+			ApplianceMode: stringValue(r, "appliance_mode_support") == "enable",
+		}
+		for _, id := range source.listValue(r, "subnet_ids") {
+			if id, ok := id.(string); ok {
+				attachment.Subnets = append(attachment.Subnets, id)
+			}
+		}
+		attachments[attachment.ID] = attachment
+		tgw := gateway(source.stringValue(r, "transit_gateway_id"))
+		tgw.Attachments = append(tgw.Attachments, attachment)
+	}
+
+	tables := make(map[string]*TGWRouteTable)
+	for _, r := range p.Resources("aws_ec2_transit_gateway_route_table") {
+		table := &TGWRouteTable{ID: resourceID(r)}
+		tables[table.ID] = table
+		tgw := gateway(source.stringValue(r, "transit_gateway_id"))
+		tgw.RouteTables = append(tgw.RouteTables, table)
+	}
+	for _, r := range p.Resources("aws_ec2_transit_gateway_route_table_association") {
+		if attachment, ok := attachments[source.stringValue(r, "transit_gateway_attachment_id")]; ok {
+			attachment.RouteTable = source.stringValue(r, "transit_gateway_route_table_id")
+		}
+	}
+	for _, r := range p.Resources("aws_ec2_transit_gateway_route_table_propagation") {
+		if attachment, ok := attachments[source.stringValue(r, "transit_gateway_attachment_id")]; ok {
+			attachment.Propagations = append(attachment.Propagations, source.stringValue(r, "transit_gateway_route_table_id"))
+		}
+	}
+	for _, r := range p.Resources("aws_ec2_transit_gateway_route") {
+		if table, ok := tables[source.stringValue(r, "transit_gateway_route_table_id")]; ok {
+			blackhole, _ := r.Value("blackhole")
+			table.Routes = append(table.Routes, TGWRoute{
+				Destination: stringValue(r, "destination_cidr_block"),
+				Attachment:  source.stringValue(r, "transit_gateway_attachment_id"),
+				Blackhole:   blackhole == true,
+			})
+		}
+	}
+	return gateways
+}
+
+// resourceID returns the id of a planned resource, or its address when the id is
+// only known after apply
+func resourceID(r *plan.Resource) string {
+	if id := stringValue(r, "id"); id != "" {
+		return id
+	}
+	return r.Address
+}
+
+// stringValue returns a planned string value, or "" when it is unknown, null or
+// not a string
+func stringValue(r *plan.Resource, path string) string {
+	value, err := r.Value(path)
+	if err != nil {
+		return ""
+	}
+	s, _ := value.(string)
+	return s
+}
+
+// stringValue returns a planned string value that refers to another resource,
+// evaluated from source when it is unknown and source is not nil
+func (s *Source) stringValue(r *plan.Resource, path string) string {
+	value, _ := s.reference(r, path).(string)
+	return value
+}
+
+// listValue returns a planned list value that refers to other resources, evaluated
+// from source when it is unknown and source is not nil
+func (s *Source) listValue(r *plan.Resource, path string) []interface{} {
+	return listValue(s.reference(r, path))
+}
+
+// listValue returns a list value, or nil when the value is not a list
+func listValue(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
@@ -0,0 +1,518 @@
+package routing_test
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/aws-centralized-inspection/tests/inventory"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+	"github.com/your-org/aws-centralized-inspection/tests/routing"
+)
+
+// testTopology has two spokes in two AZs behind an inspection VPC with one GWLB
+// endpoint and firewall per AZ
+const testTopology = `
+vpcs:
+  - {id: vpc-hub, cidr: 10.0.0.0/16}
+  - {id: vpc-a, cidr: 10.1.0.0/16}
+  - {id: vpc-b, cidr: 10.2.0.0/16}
+subnets:
+  - {id: hub-tgw-1, vpc: vpc-hub, cidr: 10.0.0.0/28, az: az1, route_table: rtb-hub-tgw-1}
+  - {id: hub-tgw-2, vpc: vpc-hub, cidr: 10.0.0.16/28, az: az2, route_table: rtb-hub-tgw-2}
+  - {id: hub-gwlbe-1, vpc: vpc-hub, cidr: 10.0.1.0/28, az: az1, route_table: rtb-hub-gwlbe-1}
+  - {id: hub-gwlbe-2, vpc: vpc-hub, cidr: 10.0.1.16/28, az: az2, route_table: rtb-hub-gwlbe-2}
+  - {id: hub-public-1, vpc: vpc-hub, cidr: 10.0.2.0/24, az: az1, route_table: rtb-hub-public-1}
+  - {id: a-1, vpc: vpc-a, cidr: 10.1.0.0/24, az: az1}
+  - {id: a-2, vpc: vpc-a, cidr: 10.1.1.0/24, az: az2}
+  - {id: b-1, vpc: vpc-b, cidr: 10.2.0.0/24, az: az1}
+  - {id: b-2, vpc: vpc-b, cidr: 10.2.1.0/24, az: az2}
+route_tables:
+  - {id: rtb-hub-tgw-1, vpc: vpc-hub, routes: [{destination: 0.0.0.0/0, vpc_endpoint_id: vpce-1}]}
+  - {id: rtb-hub-tgw-2, vpc: vpc-hub, routes: [{destination: 0.0.0.0/0, vpc_endpoint_id: vpce-2}]}
+  - id: rtb-hub-gwlbe-1
+    vpc: vpc-hub
+    routes:
+      - {destination: 10.0.0.0/8, transit_gateway_id: tgw}
+      - {destination: 0.0.0.0/0, nat_gateway_id: nat-1}
+  - id: rtb-hub-gwlbe-2
+    vpc: vpc-hub
+    routes:
+      - {destination: 10.0.0.0/8, transit_gateway_id: tgw}
+      - {destination: 0.0.0.0/0, nat_gateway_id: nat-1}
+  - id: rtb-hub-public-1
+    vpc: vpc-hub
+    routes:
+      - {destination: 10.0.0.0/8, vpc_endpoint_id: vpce-1}
+      - {destination: 0.0.0.0/0, gateway_id: igw}
+  - {id: rtb-a, vpc: vpc-a, main: true, routes: [{destination: 0.0.0.0/0, transit_gateway_id: tgw}]}
+  - {id: rtb-b, vpc: vpc-b, main: true, routes: [{destination: 0.0.0.0/0, transit_gateway_id: tgw}]}
+internet_gateways:
+  - {id: igw, vpc: vpc-hub}
+nat_gateways:
+  - {id: nat-1, subnet: hub-public-1}
+gwlb_endpoints:
+  - {id: vpce-1, subnet: hub-gwlbe-1, load_balancer: gwlb}
+  - {id: vpce-2, subnet: hub-gwlbe-2, load_balancer: gwlb}
+load_balancers:
+  - id: gwlb
+    targets: [{id: fw-1, az: az1}, {id: fw-2, az: az2}]
+transit_gateways:
+  - id: tgw
+    attachments:
+      - {id: att-hub, vpc: vpc-hub, subnets: [hub-tgw-1, hub-tgw-2], appliance_mode: true, route_table: tgw-rtb-hub}
+      - {id: att-a, vpc: vpc-a, subnets: [a-1, a-2], route_table: tgw-rtb-spoke, propagations: [tgw-rtb-hub]}
+      - {id: att-b, vpc: vpc-b, subnets: [b-1, b-2], route_table: tgw-rtb-spoke, propagations: [tgw-rtb-hub]}
+    route_tables:
+      - {id: tgw-rtb-spoke, routes: [{destination: 0.0.0.0/0, attachment: att-hub}]}
+      - {id: tgw-rtb-hub}
+`
+
+var internetAddr = netip.MustParseAddr("198.51.100.7")
+
+// loadTopology writes a topology to a temporary file and loads it
+func loadTopology(t *testing.T, document string) (*routing.Topology, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(document), 0644))
+	return routing.LoadTopology(path)
+}
+
+// testFlows returns the flows between the spokes in both AZs and to the internet
+func testFlows(t *testing.T, topology *routing.Topology) []routing.Flow {
+	t.Helper()
+	flows, err := topology.EastWestFlows("tcp", 443, "a-1", "a-2", "b-1", "b-2")
+	require.NoError(t, err)
+	internet, err := topology.InternetFlows("tcp", 443, internetAddr, "a-1", "b-2")
+	require.NoError(t, err)
+	return append(flows, internet...)
+}
+
+// findingKinds returns the kinds of the findings
+func findingKinds(findings []routing.Finding) []routing.FindingKind {
+	var kinds []routing.FindingKind
+	for _, finding := range findings {
+		kinds = append(kinds, finding.Kind)
+	}
+	return kinds
+}
+
+func TestLoadTopology(t *testing.T) {
+	topology, err := loadTopology(t, testTopology)
+	require.NoError(t, err)
+	assert.Len(t, topology.Subnets, 9)
+	attachment, ok := topology.Attachment("att-hub")
+	require.True(t, ok)
+	assert.True(t, attachment.ApplianceMode)
+
+	_, err = loadTopology(t, "vpcs: [{id: vpc-a, cidr: 10.1.0.0/16, region: us-east-1}]")
+	assert.ErrorContains(t, err, "field region not found")
+
+	_, err = loadTopology(t, `
+vpcs:
+  - {id: vpc-a, cidr: 10.1.0.0/33}
+  - {id: vpc-a, cidr: 10.2.0.0/16}
+subnets:
+  - {id: a-1, vpc: vpc-missing, cidr: 10.1.0.0/24, az: az1, route_table: rtb-missing}
+`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vpc vpc-a: id already used by a vpc")
+	assert.Contains(t, err.Error(), "vpc vpc-a: cidr: netip.ParsePrefix(\"10.1.0.0/33\")")
+	assert.Contains(t, err.Error(), `subnet a-1: vpc "vpc-missing" is not a vpc`)
+	assert.Contains(t, err.Error(), `subnet a-1: route_table "rtb-missing" is not a route table`)
+
+	_, err = routing.LoadTopology(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestTrace(t *testing.T) {
+	topology, err := loadTopology(t, testTopology)
+	require.NoError(t, err)
+
+	flow := routing.Flow{Protocol: "tcp", Source: netip.MustParseAddr("10.1.0.4"), SourcePort: 49152, Destination: netip.MustParseAddr("10.2.1.4"), DestinationPort: 443}
+	path, err := topology.Trace(flow)
+	require.NoError(t, err)
+	assert.Equal(t, routing.Delivered, path.Outcome, path.String())
+	assert.Equal(t, "a-1", path.Hops[0].ID)
+	assert.Equal(t, "b-2", path.Hops[len(path.Hops)-1].ID)
+	require.Len(t, path.Inspections(), 1)
+
+	reply, err := topology.TraceReply(path)
+	require.NoError(t, err)
+	assert.Equal(t, routing.Delivered, reply.Outcome, reply.String())
+	assert.Equal(t, path.Inspections(), reply.Inspections(), "appliance mode keeps the reply on the same firewall")
+	assert.Equal(t, flow.Reverse(), reply.Flow)
+
+	internet, err := topology.Trace(routing.Flow{Protocol: "tcp", Source: flow.Source, SourcePort: 49152, Destination: internetAddr, DestinationPort: 443})
+	require.NoError(t, err)
+	assert.Equal(t, routing.Internet, internet.Outcome, internet.String())
+	assert.Equal(t, routing.HopInternet, internet.Hops[len(internet.Hops)-1].Kind)
+	reply, err = topology.TraceReply(internet)
+	require.NoError(t, err)
+	assert.Equal(t, routing.Delivered, reply.Outcome, reply.String())
+	assert.Equal(t, routing.HopNATGateway, reply.Hops[1].Kind, "replies come back through the NAT gateway")
+
+	_, err = topology.Trace(routing.Flow{Protocol: "tcp", Source: internetAddr, Destination: flow.Destination})
+	assert.EqualError(t, err, "trace tcp 198.51.100.7:0 -> 10.2.1.4:0: source is in no subnet of the topology")
+}
+
+func TestAnalyze(t *testing.T) {
+	topology, err := loadTopology(t, testTopology)
+	require.NoError(t, err)
+	findings, err := topology.Analyze(testFlows(t, topology))
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	t.Run("appliance mode off", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		attachment, _ := topology.Attachment("att-hub")
+		attachment.ApplianceMode = false
+
+		flows, err := topology.EastWestFlows("tcp", 443, "a-1", "b-2")
+		require.NoError(t, err)
+		findings, err := topology.Analyze(flows)
+		require.NoError(t, err)
+		assert.Equal(t, []routing.FindingKind{routing.FindingAsymmetric, routing.FindingAsymmetric}, findingKinds(findings))
+		assert.Contains(t, findings[0].Message, "flow is inspected by vpce-1/fw-1, reply by vpce-2/fw-2")
+	})
+
+	t.Run("bypass", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		spoke := topology.TransitGateways[0].RouteTables[0]
+		spoke.Routes = append(spoke.Routes, routing.TGWRoute{Destination: "10.2.0.0/16", Attachment: "att-b"})
+
+		flows, err := topology.EastWestFlows("tcp", 443, "a-1", "b-1")
+		require.NoError(t, err)
+		findings, err := topology.Analyze(flows)
+		require.NoError(t, err)
+		require.Equal(t, []routing.FindingKind{routing.FindingBypass, routing.FindingBypass}, findingKinds(findings))
+		assert.Contains(t, findings[0].Message, "flow is not inspected")
+		assert.Contains(t, findings[1].Message, "reply is not inspected", "the reply of b-1 goes straight to vpc-a")
+	})
+
+	t.Run("blackholes", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		spoke := topology.TransitGateways[0].RouteTables[0]
+		spoke.Routes = append(spoke.Routes, routing.TGWRoute{Destination: "10.2.1.0/24", Blackhole: true})
+		attachment, _ := topology.Attachment("att-a")
+		attachment.Subnets = []string{"a-1"}
+
+		flows, err := topology.EastWestFlows("tcp", 443, "a-1", "a-2", "b-1", "b-2")
+		require.NoError(t, err)
+		findings, err := topology.Analyze(flows)
+		require.NoError(t, err)
+		var messages []string
+		for _, finding := range findings {
+			assert.Equal(t, routing.FindingBlackhole, finding.Kind)
+			messages = append(messages, finding.Message)
+		}
+		assert.Contains(t, messages, "flow is dropped: transit gateway route table tgw-rtb-spoke blackholes 10.2.1.0/24")
+		assert.Contains(t, messages, "flow is dropped: attachment att-a has no subnet in az2")
+	})
+
+	t.Run("missing firewall", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		topology.LoadBalancers[0].Targets = topology.LoadBalancers[0].Targets[:1]
+
+		findings, err := topology.Analyze(testFlows(t, topology))
+		require.NoError(t, err)
+		require.NotEmpty(t, findings)
+		assert.Contains(t, findings[0].Message, "load balancer gwlb has no target in az2")
+
+		topology.LoadBalancers[0].CrossZone = true
+		findings, err = topology.Analyze(testFlows(t, topology))
+		require.NoError(t, err)
+		assert.Empty(t, findings, "cross-zone load balancing reaches the firewall in the other AZ")
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		for _, table := range topology.RouteTables {
+			if table.ID == "rtb-hub-gwlbe-1" || table.ID == "rtb-hub-gwlbe-2" {
+				table.Routes[0].TransitGatewayID = ""
+				table.Routes[0].VPCEndpointID = "vpce-1"
+			}
+		}
+		flows, err := topology.EastWestFlows("tcp", 443, "a-1", "b-1")
+		require.NoError(t, err)
+		findings, err := topology.Analyze(flows[:1])
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, routing.FindingLoop, findings[0].Kind)
+		assert.Contains(t, findings[0].Message, "is used again")
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		topology, _ := loadTopology(t, testTopology)
+		topology.RouteTables[0].Routes[0].VPCEndpointID = "vpce-deleted"
+		topology.TransitGateways[0].Attachments[0].Subnets = []string{"hub-tgw-1"}
+
+		flows, err := topology.EastWestFlows("tcp", 443, "a-1", "b-1")
+		require.NoError(t, err)
+		findings, err := topology.Analyze(flows[:1])
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "flow is dropped: route table rtb-hub-tgw-1 sends 0.0.0.0/0 to vpce-deleted, which is not in the topology", findings[0].Message)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	topology, err := loadTopology(t, testTopology)
+	require.NoError(t, err)
+
+	// Routes another module adds to a spoke route table
+	merged := topology.Merge(&routing.Topology{
+		RouteTables: []*routing.RouteTable{{ID: "rtb-a", Routes: []routing.Route{
+			{Destination: "10.2.0.0/16", TransitGatewayID: "tgw"},
+			{Destination: "0.0.0.0/0", GatewayID: "igw-elsewhere"},
+		}}},
+		VPCs: []*routing.VPC{{ID: "vpc-c", CIDR: "10.3.0.0/16"}},
+	})
+	require.NoError(t, merged.Validate())
+	assert.Len(t, merged.VPCs, 4)
+	for _, table := range merged.RouteTables {
+		if table.ID == "rtb-a" {
+			assert.Equal(t, "vpc-a", table.VPC)
+			assert.True(t, table.Main)
+			assert.Equal(t, []routing.Route{
+				{Destination: "10.2.0.0/16", TransitGatewayID: "tgw"},
+				{Destination: "0.0.0.0/0", GatewayID: "igw-elsewhere"},
+			}, table.Routes)
+		}
+	}
+	assert.Len(t, topology.RouteTables[5].Routes, 1, "the merged topologies are not changed")
+}
+
+// deployedPlan is a plan against deployed infrastructure, so IDs are known
+const deployedPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.hub", "mode": "managed", "type": "aws_vpc", "name": "hub",
+     "values": {"id": "vpc-hub", "cidr_block": "10.0.0.0/16"}},
+    {"address": "aws_vpc.spoke", "mode": "managed", "type": "aws_vpc", "name": "spoke",
+     "values": {"id": "vpc-spoke", "cidr_block": "10.1.0.0/16"}},
+    {"address": "aws_subnet.hub", "mode": "managed", "type": "aws_subnet", "name": "hub",
+     "values": {"id": "subnet-hub", "vpc_id": "vpc-hub", "cidr_block": "10.0.0.0/24", "availability_zone": "az1"}},
+    {"address": "aws_subnet.gwlbe", "mode": "managed", "type": "aws_subnet", "name": "gwlbe",
+     "values": {"id": "subnet-gwlbe", "vpc_id": "vpc-hub", "cidr_block": "10.0.1.0/24", "availability_zone": "az1"}},
+    {"address": "aws_subnet.public", "mode": "managed", "type": "aws_subnet", "name": "public",
+     "values": {"id": "subnet-public", "vpc_id": "vpc-hub", "cidr_block": "10.0.2.0/24", "availability_zone": "az1"}},
+    {"address": "aws_subnet.spoke", "mode": "managed", "type": "aws_subnet", "name": "spoke",
+     "values": {"id": "subnet-spoke", "vpc_id": "vpc-spoke", "cidr_block": "10.1.0.0/24", "availability_zone": "az1"}},
+    {"address": "aws_route_table.hub", "mode": "managed", "type": "aws_route_table", "name": "hub",
+     "values": {"id": "rtb-hub", "vpc_id": "vpc-hub", "route": [{"cidr_block": "0.0.0.0/0", "vpc_endpoint_id": "vpce-1", "gateway_id": ""}]}},
+    {"address": "aws_route_table.gwlbe", "mode": "managed", "type": "aws_route_table", "name": "gwlbe",
+     "values": {"id": "rtb-gwlbe", "vpc_id": "vpc-hub", "route": []}},
+    {"address": "aws_route.gwlbe_to_spoke", "mode": "managed", "type": "aws_route", "name": "gwlbe_to_spoke",
+     "values": {"route_table_id": "rtb-gwlbe", "destination_cidr_block": "10.1.0.0/16", "transit_gateway_id": "tgw-1"}},
+    {"address": "aws_route.gwlbe_to_nat", "mode": "managed", "type": "aws_route", "name": "gwlbe_to_nat",
+     "values": {"route_table_id": "rtb-gwlbe", "destination_cidr_block": "0.0.0.0/0", "nat_gateway_id": "nat-1"}},
+    {"address": "aws_route_table.public", "mode": "managed", "type": "aws_route_table", "name": "public",
+     "values": {"id": "rtb-public", "vpc_id": "vpc-hub", "route": [
+       {"cidr_block": "0.0.0.0/0", "gateway_id": "igw-1", "vpc_endpoint_id": ""},
+       {"cidr_block": "10.1.0.0/16", "gateway_id": "", "vpc_endpoint_id": "vpce-1"}
+     ]}},
+    {"address": "aws_route_table_association.public", "mode": "managed", "type": "aws_route_table_association", "name": "public",
+     "values": {"subnet_id": "subnet-public", "route_table_id": "rtb-public"}},
+    {"address": "aws_internet_gateway.this", "mode": "managed", "type": "aws_internet_gateway", "name": "this",
+     "values": {"id": "igw-1", "vpc_id": "vpc-hub"}},
+    {"address": "aws_nat_gateway.this", "mode": "managed", "type": "aws_nat_gateway", "name": "this",
+     "values": {"id": "nat-1", "subnet_id": "subnet-public"}},
+    {"address": "aws_route.spoke_default", "mode": "managed", "type": "aws_route", "name": "spoke_default",
+     "values": {"route_table_id": "rtb-spoke", "destination_cidr_block": "0.0.0.0/0", "transit_gateway_id": "tgw-1"}},
+    {"address": "aws_route_table_association.hub", "mode": "managed", "type": "aws_route_table_association", "name": "hub",
+     "values": {"subnet_id": "subnet-hub", "route_table_id": "rtb-hub"}},
+    {"address": "aws_route_table_association.gwlbe", "mode": "managed", "type": "aws_route_table_association", "name": "gwlbe",
+     "values": {"subnet_id": "subnet-gwlbe", "route_table_id": "rtb-gwlbe"}},
+    {"address": "aws_main_route_table_association.spoke", "mode": "managed", "type": "aws_main_route_table_association", "name": "spoke",
+     "values": {"vpc_id": "vpc-spoke", "route_table_id": "rtb-spoke"}},
+    {"address": "aws_lb.gwlb", "mode": "managed", "type": "aws_lb", "name": "gwlb",
+     "values": {"arn": "arn:gwlb", "load_balancer_type": "gateway", "enable_cross_zone_load_balancing": false}},
+    {"address": "aws_lb_listener.gwlb", "mode": "managed", "type": "aws_lb_listener", "name": "gwlb",
+     "values": {"load_balancer_arn": "arn:gwlb", "default_action": [{"target_group_arn": "arn:tg"}]}},
+    {"address": "aws_lb_target_group_attachment.fw", "mode": "managed", "type": "aws_lb_target_group_attachment", "name": "fw",
+     "values": {"target_group_arn": "arn:tg", "target_id": "10.0.0.10"}},
+    {"address": "aws_vpc_endpoint_service.gwlb", "mode": "managed", "type": "aws_vpc_endpoint_service", "name": "gwlb",
+     "values": {"service_name": "com.amazonaws.vpce.gwlb", "gateway_load_balancer_arns": ["arn:gwlb"]}},
+    {"address": "aws_vpc_endpoint.gwlb", "mode": "managed", "type": "aws_vpc_endpoint", "name": "gwlb",
+     "values": {"id": "vpce-1", "vpc_endpoint_type": "GatewayLoadBalancer", "service_name": "com.amazonaws.vpce.gwlb", "subnet_ids": ["subnet-gwlbe"]}},
+    {"address": "aws_ec2_transit_gateway.this", "mode": "managed", "type": "aws_ec2_transit_gateway", "name": "this",
+     "values": {"id": "tgw-1", "default_route_table_association": "disable"}},
+    {"address": "aws_ec2_transit_gateway_vpc_attachment.hub", "mode": "managed", "type": "aws_ec2_transit_gateway_vpc_attachment", "name": "hub",
+     "values": {"id": "tgw-attach-hub", "transit_gateway_id": "tgw-1", "vpc_id": "vpc-hub", "subnet_ids": ["subnet-hub"], "appliance_mode_support": "enable"}},
+    {"address": "aws_ec2_transit_gateway_vpc_attachment.spoke", "mode": "managed", "type": "aws_ec2_transit_gateway_vpc_attachment", "name": "spoke",
+     "values": {"id": "tgw-attach-spoke", "transit_gateway_id": "tgw-1", "vpc_id": "vpc-spoke", "subnet_ids": ["subnet-spoke"], "appliance_mode_support": "disable"}},
+    {"address": "aws_ec2_transit_gateway_route_table.hub", "mode": "managed", "type": "aws_ec2_transit_gateway_route_table", "name": "hub",
+     "values": {"id": "tgw-rtb-hub", "transit_gateway_id": "tgw-1"}},
+    {"address": "aws_ec2_transit_gateway_route_table.spoke", "mode": "managed", "type": "aws_ec2_transit_gateway_route_table", "name": "spoke",
+     "values": {"id": "tgw-rtb-spoke", "transit_gateway_id": "tgw-1"}},
+    {"address": "aws_ec2_transit_gateway_route_table_association.hub", "mode": "managed", "type": "aws_ec2_transit_gateway_route_table_association", "name": "hub",
+     "values": {"transit_gateway_attachment_id": "tgw-attach-hub", "transit_gateway_route_table_id": "tgw-rtb-hub"}},
+    {"address": "aws_ec2_transit_gateway_route_table_association.spoke", "mode": "managed", "type": "aws_ec2_transit_gateway_route_table_association", "name": "spoke",
+     "values": {"transit_gateway_attachment_id": "tgw-attach-spoke", "transit_gateway_route_table_id": "tgw-rtb-spoke"}},
+    {"address": "aws_ec2_transit_gateway_route_table_propagation.spoke", "mode": "managed", "type": "aws_ec2_transit_gateway_route_table_propagation", "name": "spoke",
+     "values": {"transit_gateway_attachment_id": "tgw-attach-spoke", "transit_gateway_route_table_id": "tgw-rtb-hub"}},
+    {"address": "aws_ec2_transit_gateway_route.spoke_default", "mode": "managed", "type": "aws_ec2_transit_gateway_route", "name": "spoke_default",
+     "values": {"transit_gateway_route_table_id": "tgw-rtb-spoke", "destination_cidr_block": "0.0.0.0/0", "transit_gateway_attachment_id": "tgw-attach-hub", "blackhole": false}}
+  ]}}
+}`
+
+func TestFromPlan(t *testing.T) {
+	p, err := plan.Parse([]byte(deployedPlan))
+	require.NoError(t, err)
+	topology := routing.FromPlan(p)
+
+	// The spoke route table comes from the routes and the main association only, so
+	// it needs the VPC from a fixture or another module's plan
+	require.Error(t, topology.Validate())
+	topology = topology.Merge(&routing.Topology{
+		RouteTables: []*routing.RouteTable{{ID: "rtb-spoke", VPC: "vpc-spoke", Main: true}},
+	})
+	require.NoError(t, topology.Validate())
+
+	attachment, ok := topology.Attachment("tgw-attach-hub")
+	require.True(t, ok)
+	assert.True(t, attachment.ApplianceMode)
+	assert.Equal(t, "tgw-rtb-hub", attachment.RouteTable)
+	require.Len(t, topology.LoadBalancers, 1)
+	assert.Equal(t, []routing.Target{{ID: "10.0.0.10", AZ: "az1"}}, topology.LoadBalancers[0].Targets)
+	require.Len(t, topology.Endpoints, 1)
+	assert.Equal(t, routing.Endpoint{ID: "vpce-1", Subnet: "subnet-gwlbe", LoadBalancer: "arn:gwlb"}, *topology.Endpoints[0])
+
+	flows, err := topology.InternetFlows("tcp", 443, internetAddr, "subnet-spoke")
+	require.NoError(t, err)
+	findings, err := topology.Analyze(flows)
+	require.NoError(t, err)
+	assert.Empty(t, findings, "internet traffic of the spoke is inspected both ways")
+
+	// Traffic to the hub VPC itself takes its local route past the endpoint
+	flows, err = topology.InternetFlows("tcp", 443, netip.MustParseAddr("10.0.0.100"), "subnet-spoke")
+	require.NoError(t, err)
+	findings, err = topology.Analyze(flows)
+	require.NoError(t, err)
+	assert.Equal(t, []routing.FindingKind{routing.FindingBypass}, findingKinds(findings))
+}
+
+// firstPlanModule is the source of the module firstPlan was made from
+const firstPlanModule = `
+variable "subnets" { type = list(string) }
+variable "azs" {
+  type    = list(string)
+  default = ["az1", "az2"]
+}
+
+resource "aws_vpc" "this" { cidr_block = "10.9.0.0/16" }
+
+resource "aws_subnet" "this" {
+  count             = length(var.subnets)
+  vpc_id            = aws_vpc.this.id
+  cidr_block        = var.subnets[count.index]
+  availability_zone = var.azs[count.index]
+}
+
+resource "aws_route_table" "this" {
+  vpc_id = aws_vpc.this.id
+  route {
+    cidr_block      = "0.0.0.0/0"
+    vpc_endpoint_id = aws_vpc_endpoint.gwlb.id
+  }
+}
+
+resource "aws_route_table_association" "this" {
+  count          = length(var.subnets)
+  subnet_id      = aws_subnet.this[count.index].id
+  route_table_id = aws_route_table.this.id
+}
+
+resource "aws_lb" "gwlb" { load_balancer_type = "gateway" }
+
+resource "aws_vpc_endpoint_service" "gwlb" {
+  gateway_load_balancer_arns = [aws_lb.gwlb.arn]
+}
+
+resource "aws_vpc_endpoint" "gwlb" {
+  vpc_endpoint_type = "GatewayLoadBalancer"
+  service_name      = aws_vpc_endpoint_service.gwlb.service_name
+  subnet_ids        = [aws_subnet.this[1].id]
+}
+
+output "subnet_ids" { value = aws_subnet.this[*].id }
+output "vpc_id" { value = aws_vpc.this.id }
+`
+
+// firstPlan is a plan of firstPlanModule before anything is applied, so every ID
+// and reference is unknown
+const firstPlan = `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.this", "mode": "managed", "type": "aws_vpc", "name": "this", "values": {"cidr_block": "10.9.0.0/16"}},
+    {"address": "aws_subnet.this[0]", "mode": "managed", "type": "aws_subnet", "name": "this", "index": 0,
+     "values": {"cidr_block": "10.9.0.0/24", "availability_zone": "az1"}},
+    {"address": "aws_subnet.this[1]", "mode": "managed", "type": "aws_subnet", "name": "this", "index": 1,
+     "values": {"cidr_block": "10.9.1.0/24", "availability_zone": "az2"}},
+    {"address": "aws_route_table.this", "mode": "managed", "type": "aws_route_table", "name": "this",
+     "values": {"route": [{"cidr_block": "0.0.0.0/0", "gateway_id": ""}]}},
+    {"address": "aws_route_table_association.this[0]", "mode": "managed", "type": "aws_route_table_association", "name": "this", "index": 0, "values": {}},
+    {"address": "aws_route_table_association.this[1]", "mode": "managed", "type": "aws_route_table_association", "name": "this", "index": 1, "values": {}},
+    {"address": "aws_lb.gwlb", "mode": "managed", "type": "aws_lb", "name": "gwlb", "values": {"load_balancer_type": "gateway"}},
+    {"address": "aws_vpc_endpoint_service.gwlb", "mode": "managed", "type": "aws_vpc_endpoint_service", "name": "gwlb", "values": {}},
+    {"address": "aws_vpc_endpoint.gwlb", "mode": "managed", "type": "aws_vpc_endpoint", "name": "gwlb",
+     "values": {"vpc_endpoint_type": "GatewayLoadBalancer"}}
+  ]}},
+  "resource_changes": [
+    {"address": "aws_vpc.this", "change": {"actions": ["create"], "after_unknown": {"id": true}}},
+    {"address": "aws_subnet.this[0]", "change": {"actions": ["create"], "after_unknown": {"id": true, "vpc_id": true}}},
+    {"address": "aws_subnet.this[1]", "change": {"actions": ["create"], "after_unknown": {"id": true, "vpc_id": true}}},
+    {"address": "aws_route_table.this", "change": {"actions": ["create"], "after_unknown": {"id": true, "vpc_id": true, "route": [{"vpc_endpoint_id": true}]}}},
+    {"address": "aws_route_table_association.this[0]", "change": {"actions": ["create"], "after_unknown": {"id": true, "subnet_id": true, "route_table_id": true}}},
+    {"address": "aws_route_table_association.this[1]", "change": {"actions": ["create"], "after_unknown": {"id": true, "subnet_id": true, "route_table_id": true}}},
+    {"address": "aws_lb.gwlb", "change": {"actions": ["create"], "after_unknown": {"id": true, "arn": true}}},
+    {"address": "aws_vpc_endpoint_service.gwlb", "change": {"actions": ["create"], "after_unknown": {"id": true, "service_name": true, "gateway_load_balancer_arns": true}}},
+    {"address": "aws_vpc_endpoint.gwlb", "change": {"actions": ["create"], "after_unknown": {"id": true, "service_name": true, "subnet_ids": true}}}
+  ]
+}`
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(firstPlanModule), 0644))
+	module, err := inventory.LoadModule(dir)
+	require.NoError(t, err)
+	p, err := plan.Parse([]byte(firstPlan))
+	require.NoError(t, err)
+
+	require.Error(t, routing.FromPlan(p).Validate(), "a first plan leaves the references unknown")
+
+	_, err = routing.NewSource(p, module, nil)
+	assert.EqualError(t, err, "variable subnets is required")
+	source, err := routing.NewSource(p, module, map[string]interface{}{"subnets": []string{"10.9.0.0/24", "10.9.1.0/24"}})
+	require.NoError(t, err)
+	topology := source.Topology()
+	require.NoError(t, topology.Validate())
+
+	require.Len(t, topology.Subnets, 2)
+	assert.Equal(t, routing.Subnet{ID: "aws_subnet.this[1]", VPC: "aws_vpc.this", CIDR: "10.9.1.0/24", AZ: "az2", RouteTable: "aws_route_table.this"}, *topology.Subnets[1])
+	require.Len(t, topology.RouteTables, 1)
+	assert.Equal(t, []routing.Route{{Destination: "0.0.0.0/0", VPCEndpointID: "aws_vpc_endpoint.gwlb"}}, topology.RouteTables[0].Routes)
+	require.Len(t, topology.Endpoints, 1)
+	assert.Equal(t, routing.Endpoint{ID: "aws_vpc_endpoint.gwlb", Subnet: "aws_subnet.this[1]", LoadBalancer: "aws_lb.gwlb"}, *topology.Endpoints[0],
+		"the service name stands in for the computed one on both sides")
+
+	ids, err := source.OutputIDs("subnet_ids")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_subnet.this[0]", "aws_subnet.this[1]"}, ids)
+	ids, err = source.OutputIDs("vpc_id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_vpc.this"}, ids)
+	_, err = source.OutputIDs("missing")
+	assert.Error(t, err)
+
+	topology.Rename(map[string]string{"aws_vpc.this": "vpc-1", "aws_subnet.this[1]": "subnet-1"})
+	assert.Equal(t, "vpc-1", topology.VPCs[0].ID)
+	assert.Equal(t, "vpc-1", topology.Subnets[0].VPC)
+	assert.Equal(t, "subnet-1", topology.Endpoints[0].Subnet)
+	require.NoError(t, topology.Validate())
+}
@@ -0,0 +1,300 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/your-org/aws-centralized-inspection/tests/inventory"
+	"github.com/your-org/aws-centralized-inspection/tests/plan"
+)
+
+// Source is a plan together with the source of the module it was made from. A first
+// plan leaves the references between new resources unknown, such as the vpc_id of a
+// subnet; Source evaluates them from the module source, so the topology of a first
+// plan is connected. References to resources of the plan evaluate to the IDs
+// FromPlan gives them: the planned ID or ARN, or else the resource address. Other
+// attributes only known after apply stand in as the address and attribute name,
+// such as aws_vpc_endpoint_service.gwlb.service_name.
+type Source struct {
+	plan   *plan.Plan
+	module *inventory.Module
+	ctx    *hcl.EvalContext
+}
+
+// NewSource returns the source of a plan of the root module. vars are the variables
+// the plan was made with; variables missing from vars take their default.
+func NewSource(p *plan.Plan, module *inventory.Module, vars map[string]interface{}) (*Source, error) {
+	variables := make(map[string]cty.Value)
+	for name, variable := range module.Variables {
+		value, ok := vars[name]
+		switch {
+		case ok:
+			converted, err := ctyValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: %w", name, err)
+			}
+			variables[name] = converted
+		case variable.Default != nil:
+			converted, diags := variable.Default.Value(nil)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("variable %s: %s", name, diags.Error())
+			}
+			variables[name] = converted
+		default:
+			return nil, fmt.Errorf("variable %s is required", name)
+		}
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(variables)},
+		Functions: functions,
+	}
+	for resourceType, names := range resourceValues(p, module) {
+		ctx.Variables[resourceType] = cty.ObjectVal(names)
+	}
+	return &Source{plan: p, module: module, ctx: ctx}, nil
+}
+
+// Topology builds the topology of the plan like FromPlan, evaluating the references
+// the plan leaves unknown
+func (s *Source) Topology() *Topology {
+	return fromPlan(s.plan, s)
+}
+
+// OutputIDs returns an output of the module that is an ID or a list of IDs, such as
+// spoke_vpc_ids, with the IDs of the topology
+func (s *Source) OutputIDs(name string) ([]string, error) {
+	output, ok := s.module.Outputs[name]
+	if !ok {
+		return nil, fmt.Errorf("module %s has no output %s", s.module.Name, name)
+	}
+	value, diags := output.Value.Value(s.ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("output %s: %s", name, diags.Error())
+	}
+	switch ids := goValue(value).(type) {
+	case string:
+		return []string{ids}, nil
+	case []interface{}:
+		result := make([]string, len(ids))
+		for i, id := range ids {
+			if result[i], ok = id.(string); !ok {
+				return nil, fmt.Errorf("output %s: element %d is not a string", name, i)
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("output %s is not an ID or a list of IDs", name)
+}
+
+// reference returns a planned value that refers to another resource, such as a
+// vpc_id. Unknown values are evaluated from the source of the resource, or stand in
+// for a computed attribute. Without a source unknown values are nil.
+func (s *Source) reference(r *plan.Resource, path string) interface{} {
+	value, err := r.Value(path)
+	if s == nil || !errors.Is(err, plan.ErrUnknown) {
+		return value
+	}
+	resource, ok := s.resource(r)
+	if !ok {
+		return nil
+	}
+
+	// Path steps name nested blocks, each followed by an optional index, then an
+	// attribute and the indexes into its value
+	steps := strings.Split(path, ".")
+	body := resource.Body
+	for len(steps) > 1 {
+		blocks := inventory.Blocks(body, steps[0])
+		if len(blocks) == 0 {
+			break
+		}
+		i, err := strconv.Atoi(steps[1])
+		if err != nil {
+			i, steps = 0, steps[1:]
+		} else {
+			steps = steps[2:]
+		}
+		if i >= len(blocks) || len(steps) == 0 {
+			return nil
+		}
+		body = blocks[i]
+	}
+	attribute, ok := body.Attributes[steps[0]]
+	if !ok {
+		if body == resource.Body && len(steps) == 1 {
+			return standIn(r, path)
+		}
+		return nil
+	}
+
+	ctx := s.ctx.NewChild()
+	switch index := r.Index.(type) {
+	case int:
+		ctx.Variables = map[string]cty.Value{"count": cty.ObjectVal(map[string]cty.Value{"index": cty.NumberIntVal(int64(index))})}
+	case string:
+		ctx.Variables = map[string]cty.Value{"each": cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal(index), "value": cty.DynamicVal})}
+	}
+	result, diags := attribute.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil
+	}
+	for _, step := range steps[1:] {
+		i, err := strconv.Atoi(step)
+		if err != nil || !result.CanIterateElements() || result.IsNull() || !result.IsKnown() || i >= result.LengthInt() {
+			return nil
+		}
+		result = result.Index(cty.NumberIntVal(int64(i)))
+	}
+	return goValue(result)
+}
+
+// resource returns the source of a planned resource of the root module
+func (s *Source) resource(r *plan.Resource) (*inventory.Resource, bool) {
+	if r.Module != "" {
+		return nil, false
+	}
+	for _, resource := range s.module.ResourcesOfType(r.Type) {
+		if resource.Name == r.Name {
+			return resource, true
+		}
+	}
+	return nil, false
+}
+
+// functions are the Terraform functions the network modules use to link resources
+var functions = map[string]function.Function{
+	"concat":  stdlib.ConcatFunc,
+	"element": stdlib.ElementFunc,
+	"flatten": stdlib.FlattenFunc,
+	"floor":   stdlib.FloorFunc,
+	"length":  stdlib.LengthFunc,
+	"lookup":  stdlib.LookupFunc,
+	"max":     stdlib.MaxFunc,
+	"merge":   stdlib.MergeFunc,
+	"min":     stdlib.MinFunc,
+	"range":   stdlib.RangeFunc,
+	"slice":   stdlib.SliceFunc,
+}
+
+// resourceValues returns the planned resources of the types in the module source by
+// type and name, as the objects references to them evaluate to. Counted resources are
+// tuples and resources with for_each objects keyed by their keys.
+func resourceValues(p *plan.Plan, module *inventory.Module) map[string]map[string]cty.Value {
+	byName := make(map[string]map[string][]*plan.Resource)
+	for _, source := range module.Resources {
+		if byName[source.Type] != nil {
+			continue
+		}
+		byName[source.Type] = make(map[string][]*plan.Resource)
+		for _, r := range p.Resources(source.Type) {
+			if r.Module == "" {
+				byName[r.Type][r.Name] = append(byName[r.Type][r.Name], r)
+			}
+		}
+	}
+
+	values := make(map[string]map[string]cty.Value)
+	for resourceType, names := range byName {
+		values[resourceType] = make(map[string]cty.Value)
+		for name, resources := range names {
+			switch resources[0].Index.(type) {
+			case int:
+				elements := make([]cty.Value, len(resources))
+				for i := range elements {
+					elements[i] = cty.DynamicVal
+				}
+				for _, r := range resources {
+					if i, ok := r.Index.(int); ok && i < len(elements) {
+						elements[i] = resourceValue(r)
+					}
+				}
+				values[resourceType][name] = cty.TupleVal(elements)
+			case string:
+				instances := make(map[string]cty.Value)
+				for _, r := range resources {
+					key, _ := r.Index.(string)
+					instances[key] = resourceValue(r)
+				}
+				values[resourceType][name] = cty.ObjectVal(instances)
+			default:
+				values[resourceType][name] = resourceValue(resources[0])
+			}
+		}
+	}
+	return values
+}
+
+// resourceValue returns the string attributes of a planned resource, with stand-ins
+// for those only known after apply
+func resourceValue(r *plan.Resource) cty.Value {
+	attributes := make(map[string]cty.Value)
+	for name, value := range r.Values {
+		if s, ok := value.(string); ok {
+			attributes[name] = cty.StringVal(s)
+		}
+	}
+	for _, name := range r.UnknownAttributes() {
+		attributes[name] = cty.StringVal(standIn(r, name))
+	}
+	attributes["id"] = cty.StringVal(resourceID(r))
+	return cty.ObjectVal(attributes)
+}
+
+// standIn returns the value that stands in for an attribute of a resource that is
+// only known after apply: the address for the id and ARN, which name the resource,
+// or the address and the attribute name
+func standIn(r *plan.Resource, attribute string) string {
+	switch attribute {
+	case "id":
+		return resourceID(r)
+	case "arn":
+		if arn := stringValue(r, "arn"); arn != "" {
+			return arn
+		}
+		return r.Address
+	}
+	return r.Address + "." + attribute
+}
+
+// ctyValue converts a variable value to cty through its JSON form
+func ctyValue(value interface{}) (cty.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	valueType, err := ctyjson.ImpliedType(data)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(data, valueType)
+}
+
+// goValue converts a string or a list of strings to the form of planned values, or
+// returns nil for other values and values that are not wholly known
+func goValue(value cty.Value) interface{} {
+	if value.IsNull() || !value.IsWhollyKnown() {
+		return nil
+	}
+	switch {
+	case value.Type() == cty.String:
+		return value.AsString()
+	case value.CanIterateElements() && !value.Type().IsMapType() && !value.Type().IsObjectType():
+		var list []interface{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			list = append(list, goValue(element))
+		}
+		return list
+	}
+	return nil
+}
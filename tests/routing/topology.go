@@ -0,0 +1,437 @@
+// Package routing models the network paths of the hub-and-spoke topology: VPC and
+// transit gateway route tables, GWLB endpoints and the firewalls behind them, NAT and
+// internet gateways. It traces flows hop by hop without AWS, so tests can prove that
+// traffic between spokes and to the internet is inspected on both directions of a
+// flow and that no route ends in a blackhole.
+package routing
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Topology is the routing configuration of a set of VPCs. IDs are AWS IDs, or
+// resource addresses for resources whose IDs are only known after apply.
+type Topology struct {
+	VPCs             []*VPC            `yaml:"vpcs"`
+	Subnets          []*Subnet         `yaml:"subnets"`
+	RouteTables      []*RouteTable     `yaml:"route_tables"`
+	InternetGateways []*Gateway        `yaml:"internet_gateways,omitempty"`
+	NATGateways      []*Gateway        `yaml:"nat_gateways,omitempty"`
+	Endpoints        []*Endpoint       `yaml:"gwlb_endpoints,omitempty"`
+	LoadBalancers    []*LoadBalancer   `yaml:"load_balancers,omitempty"`
+	TransitGateways  []*TransitGateway `yaml:"transit_gateways,omitempty"`
+}
+
+// VPC is a VPC and its primary CIDR
+type VPC struct {
+	ID   string `yaml:"id"`
+	CIDR string `yaml:"cidr"`
+}
+
+// Subnet is a subnet and the route table associated with it. Subnets without one
+// use the main route table of their VPC.
+type Subnet struct {
+	ID         string `yaml:"id"`
+	VPC        string `yaml:"vpc"`
+	CIDR       string `yaml:"cidr"`
+	AZ         string `yaml:"az"`
+	RouteTable string `yaml:"route_table,omitempty"`
+}
+
+// RouteTable is a VPC route table. The local route of the VPC is implied.
+type RouteTable struct {
+	ID     string  `yaml:"id"`
+	VPC    string  `yaml:"vpc"`
+	Main   bool    `yaml:"main,omitempty"`
+	Routes []Route `yaml:"routes,omitempty"`
+}
+
+// Route is a route of a VPC route table. Exactly one target is set, named like the
+// Terraform aws_route arguments.
+type Route struct {
+	Destination      string `yaml:"destination"`
+	GatewayID        string `yaml:"gateway_id,omitempty"`
+	NATGatewayID     string `yaml:"nat_gateway_id,omitempty"`
+	TransitGatewayID string `yaml:"transit_gateway_id,omitempty"`
+	VPCEndpointID    string `yaml:"vpc_endpoint_id,omitempty"`
+}
+
+// Target returns the ID the route sends traffic to
+func (r Route) Target() string {
+	for _, target := range []string{r.GatewayID, r.NATGatewayID, r.TransitGatewayID, r.VPCEndpointID} {
+		if target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// Gateway is an internet gateway attached to a VPC or a NAT gateway in a subnet
+type Gateway struct {
+	ID     string `yaml:"id"`
+	VPC    string `yaml:"vpc,omitempty"`
+	Subnet string `yaml:"subnet,omitempty"`
+	// RouteTable is the edge route table of an internet gateway, which routes the
+	// traffic coming in from the internet. Without one it goes to the local route.
+	RouteTable string `yaml:"route_table,omitempty"`
+}
+
+// Endpoint is a Gateway Load Balancer endpoint. Traffic sent to it is inspected by
+// a firewall behind the load balancer and comes back out of the endpoint, where the
+// route table of the endpoint's subnet forwards it.
+type Endpoint struct {
+	ID           string `yaml:"id"`
+	Subnet       string `yaml:"subnet"`
+	LoadBalancer string `yaml:"load_balancer"`
+}
+
+// LoadBalancer is a Gateway Load Balancer and its firewall targets
+type LoadBalancer struct {
+	ID string `yaml:"id"`
+	// CrossZone sends traffic to targets in every AZ instead of the endpoint's AZ
+	CrossZone bool     `yaml:"cross_zone,omitempty"`
+	Targets   []Target `yaml:"targets"`
+}
+
+// Target is a firewall registered with a load balancer
+type Target struct {
+	ID string `yaml:"id"`
+	AZ string `yaml:"az"`
+}
+
+// TransitGateway is a transit gateway with its attachments and route tables
+type TransitGateway struct {
+	ID string `yaml:"id"`
+	// DefaultRouteTable is associated with and propagated to by attachments that
+	// name no route table of their own
+	DefaultRouteTable string           `yaml:"default_route_table,omitempty"`
+	Attachments       []*Attachment    `yaml:"attachments"`
+	RouteTables       []*TGWRouteTable `yaml:"route_tables"`
+}
+
+// Attachment is a VPC attachment of a transit gateway
+type Attachment struct {
+	ID      string   `yaml:"id"`
+	VPC     string   `yaml:"vpc"`
+	Subnets []string `yaml:"subnets"`
+	// ApplianceMode keeps both directions of a flow in the same AZ of the VPC
+	ApplianceMode bool `yaml:"appliance_mode,omitempty"`
+	// RouteTable is the transit gateway route table the attachment is associated with
+	RouteTable string `yaml:"route_table,omitempty"`
+	// Propagations are the route tables the VPC CIDR is propagated to
+	Propagations []string `yaml:"propagations,omitempty"`
+}
+
+// TGWRouteTable is a transit gateway route table with its static routes
+type TGWRouteTable struct {
+	ID     string     `yaml:"id"`
+	Routes []TGWRoute `yaml:"routes,omitempty"`
+}
+
+// TGWRoute is a static transit gateway route to an attachment, or a blackhole
+type TGWRoute struct {
+	Destination string `yaml:"destination"`
+	Attachment  string `yaml:"attachment,omitempty"`
+	Blackhole   bool   `yaml:"blackhole,omitempty"`
+}
+
+// LoadTopology loads a topology from a YAML or JSON file
+func LoadTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	topology := &Topology{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(topology); err != nil {
+		return nil, fmt.Errorf("topology %s: %w", path, err)
+	}
+	if err := topology.Validate(); err != nil {
+		return nil, fmt.Errorf("topology %s: %w", path, err)
+	}
+	return topology, nil
+}
+
+// Merge returns a topology with the resources of both topologies. Resources with
+// the same ID are taken from other, so a plan can override a fixture, except that
+// the routes of route tables with the same ID are combined: a module that adds
+// aws_route resources to the route tables of another module contributes a route
+// table without a VPC that only holds those routes.
+func (t *Topology) Merge(other *Topology) *Topology {
+	merged := &Topology{
+		VPCs:             mergeByID(t.VPCs, other.VPCs, func(v *VPC) string { return v.ID }),
+		Subnets:          mergeByID(t.Subnets, other.Subnets, func(s *Subnet) string { return s.ID }),
+		RouteTables:      mergeRouteTables(t.RouteTables, other.RouteTables),
+		InternetGateways: mergeByID(t.InternetGateways, other.InternetGateways, func(g *Gateway) string { return g.ID }),
+		NATGateways:      mergeByID(t.NATGateways, other.NATGateways, func(g *Gateway) string { return g.ID }),
+		Endpoints:        mergeByID(t.Endpoints, other.Endpoints, func(e *Endpoint) string { return e.ID }),
+		LoadBalancers:    mergeByID(t.LoadBalancers, other.LoadBalancers, func(l *LoadBalancer) string { return l.ID }),
+		TransitGateways:  mergeByID(t.TransitGateways, other.TransitGateways, func(g *TransitGateway) string { return g.ID }),
+	}
+	return merged
+}
+
+// Rename replaces IDs wherever they are used, such as the placeholder network IDs
+// a module was planned with by the IDs of the network topology
+func (t *Topology) Rename(ids map[string]string) {
+	rename := func(id *string) {
+		if renamed, ok := ids[*id]; ok {
+			*id = renamed
+		}
+	}
+	for _, vpc := range t.VPCs {
+		rename(&vpc.ID)
+	}
+	for _, subnet := range t.Subnets {
+		for _, id := range []*string{&subnet.ID, &subnet.VPC, &subnet.RouteTable} {
+			rename(id)
+		}
+	}
+	for _, table := range t.RouteTables {
+		rename(&table.ID)
+		rename(&table.VPC)
+		for i := range table.Routes {
+			route := &table.Routes[i]
+			for _, id := range []*string{&route.GatewayID, &route.NATGatewayID, &route.TransitGatewayID, &route.VPCEndpointID} {
+				rename(id)
+			}
+		}
+	}
+	for _, gateway := range append(append([]*Gateway{}, t.InternetGateways...), t.NATGateways...) {
+		for _, id := range []*string{&gateway.ID, &gateway.VPC, &gateway.Subnet, &gateway.RouteTable} {
+			rename(id)
+		}
+	}
+	for _, endpoint := range t.Endpoints {
+		for _, id := range []*string{&endpoint.ID, &endpoint.Subnet, &endpoint.LoadBalancer} {
+			rename(id)
+		}
+	}
+	for _, balancer := range t.LoadBalancers {
+		rename(&balancer.ID)
+		for i := range balancer.Targets {
+			rename(&balancer.Targets[i].ID)
+		}
+	}
+	for _, tgw := range t.TransitGateways {
+		rename(&tgw.ID)
+		rename(&tgw.DefaultRouteTable)
+		for _, attachment := range tgw.Attachments {
+			rename(&attachment.ID)
+			rename(&attachment.VPC)
+			rename(&attachment.RouteTable)
+			for i := range attachment.Subnets {
+				rename(&attachment.Subnets[i])
+			}
+			for i := range attachment.Propagations {
+				rename(&attachment.Propagations[i])
+			}
+		}
+		for _, table := range tgw.RouteTables {
+			rename(&table.ID)
+			for i := range table.Routes {
+				rename(&table.Routes[i].Attachment)
+			}
+		}
+	}
+}
+
+// Attachment returns the transit gateway attachment with an ID
+func (t *Topology) Attachment(id string) (*Attachment, bool) {
+	for _, tgw := range t.TransitGateways {
+		for _, attachment := range tgw.Attachments {
+			if attachment.ID == id {
+				return attachment, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// mergeByID appends other to base, replacing elements of base with the same ID
+func mergeByID[T any](base, other []T, id func(T) string) []T {
+	replaced := make(map[string]bool, len(other))
+	for _, element := range other {
+		replaced[id(element)] = true
+	}
+	var merged []T
+	for _, element := range base {
+		if !replaced[id(element)] {
+			merged = append(merged, element)
+		}
+	}
+	return append(merged, other...)
+}
+
+// mergeRouteTables merges route tables by ID, combining their routes. Routes of
+// other replace routes of base to the same destination.
+func mergeRouteTables(base, other []*RouteTable) []*RouteTable {
+	byID := make(map[string]*RouteTable, len(base))
+	for _, table := range base {
+		byID[table.ID] = table
+	}
+	var added []*RouteTable
+	replaced := make(map[string]*RouteTable)
+	for _, table := range other {
+		previous, ok := byID[table.ID]
+		if !ok {
+			added = append(added, table)
+			continue
+		}
+		combined := *table
+		if combined.VPC == "" {
+			combined.VPC, combined.Main = previous.VPC, previous.Main
+		}
+		destinations := make(map[string]bool, len(table.Routes))
+		for _, route := range table.Routes {
+			destinations[route.Destination] = true
+		}
+		combined.Routes = nil
+		for _, route := range previous.Routes {
+			if !destinations[route.Destination] {
+				combined.Routes = append(combined.Routes, route)
+			}
+		}
+		combined.Routes = append(combined.Routes, table.Routes...)
+		replaced[table.ID] = &combined
+	}
+
+	merged := make([]*RouteTable, 0, len(base)+len(added))
+	for _, table := range base {
+		if combined, ok := replaced[table.ID]; ok {
+			table = combined
+		}
+		merged = append(merged, table)
+	}
+	return append(merged, added...)
+}
+
+// Validate checks that CIDRs parse, IDs are unique and every reference names a
+// resource of the topology. Route targets are not checked: a route to a missing
+// target is a blackhole the trace reports.
+func (t *Topology) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	ids := make(map[string]string)
+	define := func(kind, id string) {
+		if id == "" {
+			fail("%s without an id", kind)
+			return
+		}
+		if previous, ok := ids[id]; ok {
+			fail("%s %s: id already used by a %s", kind, id, previous)
+			return
+		}
+		ids[id] = kind
+	}
+	refer := func(kind, id, field, target, targetKind string) {
+		if ids[target] != targetKind {
+			fail("%s %s: %s %q is not a %s", kind, id, field, target, targetKind)
+		}
+	}
+	prefix := func(kind, id, field, cidr string) {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			fail("%s %s: %s: %v", kind, id, field, err)
+		}
+	}
+
+	for _, vpc := range t.VPCs {
+		define("vpc", vpc.ID)
+		prefix("vpc", vpc.ID, "cidr", vpc.CIDR)
+	}
+	for _, table := range t.RouteTables {
+		define("route table", table.ID)
+	}
+	for _, subnet := range t.Subnets {
+		define("subnet", subnet.ID)
+	}
+	for _, gateway := range t.InternetGateways {
+		define("internet gateway", gateway.ID)
+	}
+	for _, gateway := range t.NATGateways {
+		define("nat gateway", gateway.ID)
+	}
+	for _, balancer := range t.LoadBalancers {
+		define("load balancer", balancer.ID)
+	}
+	for _, endpoint := range t.Endpoints {
+		define("endpoint", endpoint.ID)
+	}
+	for _, tgw := range t.TransitGateways {
+		define("transit gateway", tgw.ID)
+		for _, table := range tgw.RouteTables {
+			define("transit gateway route table", table.ID)
+		}
+		for _, attachment := range tgw.Attachments {
+			define("attachment", attachment.ID)
+		}
+	}
+
+	for _, subnet := range t.Subnets {
+		refer("subnet", subnet.ID, "vpc", subnet.VPC, "vpc")
+		prefix("subnet", subnet.ID, "cidr", subnet.CIDR)
+		if subnet.RouteTable != "" {
+			refer("subnet", subnet.ID, "route_table", subnet.RouteTable, "route table")
+		}
+	}
+	for _, table := range t.RouteTables {
+		refer("route table", table.ID, "vpc", table.VPC, "vpc")
+		for _, route := range table.Routes {
+			prefix("route table", table.ID, "destination", route.Destination)
+		}
+	}
+	for _, gateway := range t.InternetGateways {
+		refer("internet gateway", gateway.ID, "vpc", gateway.VPC, "vpc")
+		if gateway.RouteTable != "" {
+			refer("internet gateway", gateway.ID, "route_table", gateway.RouteTable, "route table")
+		}
+	}
+	for _, gateway := range t.NATGateways {
+		refer("nat gateway", gateway.ID, "subnet", gateway.Subnet, "subnet")
+	}
+	for _, endpoint := range t.Endpoints {
+		refer("endpoint", endpoint.ID, "subnet", endpoint.Subnet, "subnet")
+		refer("endpoint", endpoint.ID, "load_balancer", endpoint.LoadBalancer, "load balancer")
+	}
+	for _, tgw := range t.TransitGateways {
+		if tgw.DefaultRouteTable != "" {
+			refer("transit gateway", tgw.ID, "default_route_table", tgw.DefaultRouteTable, "transit gateway route table")
+		}
+		for _, attachment := range tgw.Attachments {
+			refer("attachment", attachment.ID, "vpc", attachment.VPC, "vpc")
+			for _, subnet := range attachment.Subnets {
+				refer("attachment", attachment.ID, "subnets", subnet, "subnet")
+			}
+			for _, table := range append([]string{attachment.RouteTable}, attachment.Propagations...) {
+				if table != "" {
+					refer("attachment", attachment.ID, "route table", table, "transit gateway route table")
+				}
+			}
+		}
+		for _, table := range tgw.RouteTables {
+			for _, route := range table.Routes {
+				prefix("transit gateway route table", table.ID, "destination", route.Destination)
+				if !route.Blackhole {
+					refer("transit gateway route table", table.ID, "attachment", route.Attachment, "attachment")
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid topology:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,572 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Flow is the 5-tuple of a connection, seen from the side that opens it
+type Flow struct {
+	Protocol        string
+	Source          netip.Addr
+	SourcePort      int
+	Destination     netip.Addr
+	DestinationPort int
+}
+
+// Reverse returns the flow of the replies
+func (f Flow) Reverse() Flow {
+	return Flow{Protocol: f.Protocol, Source: f.Destination, SourcePort: f.DestinationPort, Destination: f.Source, DestinationPort: f.SourcePort}
+}
+
+// String returns the flow as protocol source:port -> destination:port
+func (f Flow) String() string {
+	return fmt.Sprintf("%s %s -> %s", f.Protocol, netip.AddrPortFrom(f.Source, uint16(f.SourcePort)), netip.AddrPortFrom(f.Destination, uint16(f.DestinationPort)))
+}
+
+// hash returns the same value for both directions of a flow, like the flow hash
+// transit gateways and load balancers use to pick an AZ or a target
+func (f Flow) hash() uint32 {
+	a := netip.AddrPortFrom(f.Source, uint16(f.SourcePort)).String()
+	b := netip.AddrPortFrom(f.Destination, uint16(f.DestinationPort)).String()
+	if a > b {
+		a, b = b, a
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%s", strings.ToLower(f.Protocol), a, b)
+	return h.Sum32()
+}
+
+// HopKind is the kind of resource a hop passes
+type HopKind string
+
+// Hop kinds
+const (
+	HopSubnet          HopKind = "subnet"
+	HopEndpoint        HopKind = "gwlb-endpoint"
+	HopLoadBalancer    HopKind = "gwlb"
+	HopFirewall        HopKind = "firewall"
+	HopTransitGateway  HopKind = "transit-gateway"
+	HopAttachment      HopKind = "tgw-attachment"
+	HopNATGateway      HopKind = "nat-gateway"
+	HopInternetGateway HopKind = "internet-gateway"
+	HopInternet        HopKind = "internet"
+)
+
+// Hop is one resource on the path of a flow
+type Hop struct {
+	Kind HopKind
+	ID   string
+	AZ   string
+	// RouteTable is the VPC or transit gateway route table that sent the flow here
+	RouteTable string
+}
+
+// String returns the hop as kind id, with the AZ when it is known
+func (h Hop) String() string {
+	if h.AZ == "" {
+		return fmt.Sprintf("%s %s", h.Kind, h.ID)
+	}
+	return fmt.Sprintf("%s %s (%s)", h.Kind, h.ID, h.AZ)
+}
+
+// Outcome is how a traced flow ends
+type Outcome string
+
+// Outcomes of a trace
+const (
+	// Delivered flows reach a subnet containing the destination
+	Delivered Outcome = "delivered"
+	// Internet flows leave through an internet gateway
+	Internet Outcome = "internet"
+	// Blackhole flows are dropped for lack of a route, target or attachment
+	Blackhole Outcome = "blackhole"
+	// Loop flows come back to a route table they already passed
+	Loop Outcome = "loop"
+)
+
+// Path is the route a flow takes through the topology
+type Path struct {
+	Flow    Flow
+	Hops    []Hop
+	Outcome Outcome
+	// Reason explains why a blackholed or looping flow was dropped
+	Reason string
+}
+
+// Inspection is a firewall that inspects a flow and the endpoint the flow used
+type Inspection struct {
+	Endpoint string
+	Firewall string
+}
+
+// String returns the inspection as endpoint/firewall
+func (i Inspection) String() string {
+	return i.Endpoint + "/" + i.Firewall
+}
+
+// Inspections returns the firewalls the flow passes, in order
+func (p *Path) Inspections() []Inspection {
+	var inspections []Inspection
+	endpoint := ""
+	for _, hop := range p.Hops {
+		switch hop.Kind {
+		case HopEndpoint:
+			endpoint = hop.ID
+		case HopFirewall:
+			inspections = append(inspections, Inspection{Endpoint: endpoint, Firewall: hop.ID})
+		}
+	}
+	return inspections
+}
+
+// Inspected reports whether a firewall inspects the flow
+func (p *Path) Inspected() bool {
+	return len(p.Inspections()) > 0
+}
+
+// Reached reports whether the flow was delivered or left for the internet
+func (p *Path) Reached() bool {
+	return p.Outcome == Delivered || p.Outcome == Internet
+}
+
+// String returns the hops joined by arrows and the outcome
+func (p *Path) String() string {
+	hops := make([]string, len(p.Hops))
+	for i, hop := range p.Hops {
+		hops[i] = hop.String()
+	}
+	result := strings.Join(hops, " -> ") + ": " + string(p.Outcome)
+	if p.Reason != "" {
+		result += " (" + p.Reason + ")"
+	}
+	return result
+}
+
+// add appends a hop
+func (p *Path) add(kind HopKind, id, az, routeTable string) {
+	p.Hops = append(p.Hops, Hop{Kind: kind, ID: id, AZ: az, RouteTable: routeTable})
+}
+
+// drop ends the path
+func (p *Path) drop(outcome Outcome, format string, args ...interface{}) *Path {
+	p.Outcome = outcome
+	p.Reason = fmt.Sprintf(format, args...)
+	return p
+}
+
+// Trace follows a flow from the subnet of its source to its destination
+func (t *Topology) Trace(flow Flow) (*Path, error) {
+	n := t.index()
+	source := n.subnetContaining(flow.Source)
+	if source == nil {
+		return nil, fmt.Errorf("trace %s: source is in no subnet of the topology", flow)
+	}
+	path := &Path{Flow: flow}
+	path.add(HopSubnet, source.ID, source.AZ, "")
+	return n.route(path, n.routeTableOf(source), source.VPC, source.AZ, "subnet "+source.ID), nil
+}
+
+// TraceReply follows the replies of a traced flow. Replies to flows that left for
+// the internet come back through the NAT gateway the flow used, or through the
+// internet gateway and its edge route table.
+func (t *Topology) TraceReply(forward *Path) (*Path, error) {
+	if !forward.Reached() {
+		return nil, fmt.Errorf("trace reply of %s: the flow was not delivered", forward.Flow)
+	}
+	if forward.Outcome == Delivered {
+		return t.Trace(forward.Flow.Reverse())
+	}
+
+	n := t.index()
+	path := &Path{Flow: forward.Flow.Reverse()}
+	path.add(HopInternet, forward.Flow.Destination.String(), "", "")
+	var gateway *Gateway
+	for _, hop := range forward.Hops {
+		switch hop.Kind {
+		case HopNATGateway:
+			subnet := n.subnets[n.natGateways[hop.ID].Subnet]
+			path.add(HopNATGateway, hop.ID, subnet.AZ, "")
+			path.add(HopSubnet, subnet.ID, subnet.AZ, "")
+			return n.route(path, n.routeTableOf(subnet), subnet.VPC, subnet.AZ, "subnet "+subnet.ID), nil
+		case HopInternetGateway:
+			gateway = n.internetGateways[hop.ID]
+		}
+	}
+	if gateway != nil {
+		path.add(HopInternetGateway, gateway.ID, "", "")
+		if gateway.RouteTable == "" {
+			return n.deliver(path, gateway.VPC, ""), nil
+		}
+		return n.route(path, n.routeTables[gateway.RouteTable], gateway.VPC, "", "internet gateway "+gateway.ID), nil
+	}
+	return nil, fmt.Errorf("trace reply of %s: the flow left through no gateway", forward.Flow)
+}
+
+// network indexes a topology by ID
+type network struct {
+	topology         *Topology
+	vpcs             map[string]*VPC
+	subnets          map[string]*Subnet
+	routeTables      map[string]*RouteTable
+	mainRouteTables  map[string]*RouteTable
+	internetGateways map[string]*Gateway
+	natGateways      map[string]*Gateway
+	endpoints        map[string]*Endpoint
+	loadBalancers    map[string]*LoadBalancer
+	transitGateways  map[string]*TransitGateway
+	tgwRouteTables   map[string]*TGWRouteTable
+}
+
+// index builds the lookup maps of the topology
+func (t *Topology) index() *network {
+	n := &network{
+		topology:         t,
+		vpcs:             make(map[string]*VPC),
+		subnets:          make(map[string]*Subnet),
+		routeTables:      make(map[string]*RouteTable),
+		mainRouteTables:  make(map[string]*RouteTable),
+		internetGateways: make(map[string]*Gateway),
+		natGateways:      make(map[string]*Gateway),
+		endpoints:        make(map[string]*Endpoint),
+		loadBalancers:    make(map[string]*LoadBalancer),
+		transitGateways:  make(map[string]*TransitGateway),
+		tgwRouteTables:   make(map[string]*TGWRouteTable),
+	}
+	for _, vpc := range t.VPCs {
+		n.vpcs[vpc.ID] = vpc
+	}
+	for _, subnet := range t.Subnets {
+		n.subnets[subnet.ID] = subnet
+	}
+	for _, table := range t.RouteTables {
+		n.routeTables[table.ID] = table
+		if table.Main {
+			n.mainRouteTables[table.VPC] = table
+		}
+	}
+	for _, gateway := range t.InternetGateways {
+		n.internetGateways[gateway.ID] = gateway
+	}
+	for _, gateway := range t.NATGateways {
+		n.natGateways[gateway.ID] = gateway
+	}
+	for _, endpoint := range t.Endpoints {
+		n.endpoints[endpoint.ID] = endpoint
+	}
+	for _, balancer := range t.LoadBalancers {
+		n.loadBalancers[balancer.ID] = balancer
+	}
+	for _, tgw := range t.TransitGateways {
+		n.transitGateways[tgw.ID] = tgw
+		for _, table := range tgw.RouteTables {
+			n.tgwRouteTables[table.ID] = table
+		}
+	}
+	return n
+}
+
+// subnetContaining returns the subnet whose CIDR contains addr, if any
+func (n *network) subnetContaining(addr netip.Addr) *Subnet {
+	return n.subnetIn("", addr)
+}
+
+// subnetIn returns the subnet of a VPC, or of any VPC when vpc is "", that contains
+// addr
+func (n *network) subnetIn(vpc string, addr netip.Addr) *Subnet {
+	var best *Subnet
+	bestBits := -1
+	for _, subnet := range n.topology.Subnets {
+		prefix, err := netip.ParsePrefix(subnet.CIDR)
+		if err != nil || !prefix.Contains(addr) || (vpc != "" && subnet.VPC != vpc) {
+			continue
+		}
+		if prefix.Bits() > bestBits {
+			best, bestBits = subnet, prefix.Bits()
+		}
+	}
+	return best
+}
+
+// routeTableOf returns the route table associated with a subnet, or the main route
+// table of its VPC
+func (n *network) routeTableOf(subnet *Subnet) *RouteTable {
+	if table, ok := n.routeTables[subnet.RouteTable]; ok {
+		return table
+	}
+	return n.mainRouteTables[subnet.VPC]
+}
+
+// route follows the flow from a VPC route table until it is delivered or dropped.
+// from names where the flow entered the VPC, for messages.
+func (n *network) route(path *Path, table *RouteTable, vpc, az, from string) *Path {
+	destination := path.Flow.Destination
+	visited := make(map[string]bool)
+	for {
+		if table == nil {
+			return path.drop(Blackhole, "%s has no route table", from)
+		}
+		if visited[table.ID] {
+			return path.drop(Loop, "route table %s is used again", table.ID)
+		}
+		visited[table.ID] = true
+
+		route, local, ok := n.lookup(table, destination)
+		if !ok {
+			return path.drop(Blackhole, "route table %s has no route to %s", table.ID, destination)
+		}
+		if local {
+			return n.deliver(path, vpc, table.ID)
+		}
+
+		target := route.Target()
+		var subnet *Subnet
+		switch {
+		case n.endpoints[target] != nil:
+			endpoint := n.endpoints[target]
+			if subnet = n.subnets[endpoint.Subnet]; subnet == nil || subnet.VPC != vpc {
+				return path.drop(Blackhole, "route table %s sends %s to endpoint %s outside %s", table.ID, route.Destination, target, vpc)
+			}
+			path.add(HopEndpoint, endpoint.ID, subnet.AZ, table.ID)
+			if reason := n.inspect(path, endpoint, subnet.AZ); reason != "" {
+				return path.drop(Blackhole, "%s", reason)
+			}
+		case n.natGateways[target] != nil:
+			gateway := n.natGateways[target]
+			if subnet = n.subnets[gateway.Subnet]; subnet == nil || subnet.VPC != vpc {
+				return path.drop(Blackhole, "route table %s sends %s to NAT gateway %s outside %s", table.ID, route.Destination, target, vpc)
+			}
+			path.add(HopNATGateway, gateway.ID, subnet.AZ, table.ID)
+		case n.internetGateways[target] != nil:
+			gateway := n.internetGateways[target]
+			if gateway.VPC != vpc {
+				return path.drop(Blackhole, "route table %s sends %s to internet gateway %s of another VPC", table.ID, route.Destination, target)
+			}
+			path.add(HopInternetGateway, gateway.ID, "", table.ID)
+			if private := n.vpcContaining(destination); private != nil {
+				return path.drop(Blackhole, "internet gateway %s cannot reach %s in %s", gateway.ID, destination, private.ID)
+			}
+			path.add(HopInternet, destination.String(), "", "")
+			path.Outcome = Internet
+			return path
+		case n.transitGateways[target] != nil:
+			if subnet = n.transit(path, n.transitGateways[target], vpc, az, table.ID, visited); subnet == nil {
+				return path
+			}
+		default:
+			return path.drop(Blackhole, "route table %s sends %s to %s, which is not in the topology", table.ID, route.Destination, target)
+		}
+
+		path.add(HopSubnet, subnet.ID, subnet.AZ, "")
+		vpc, az, from = subnet.VPC, subnet.AZ, "subnet "+subnet.ID
+		table = n.routeTableOf(subnet)
+	}
+}
+
+// deliver ends the path in the subnet of vpc that contains the destination
+func (n *network) deliver(path *Path, vpc, table string) *Path {
+	subnet := n.subnetIn(vpc, path.Flow.Destination)
+	if subnet == nil {
+		return path.drop(Blackhole, "no subnet of %s contains %s", vpc, path.Flow.Destination)
+	}
+	if last := path.Hops[len(path.Hops)-1]; last.Kind != HopSubnet || last.ID != subnet.ID {
+		path.add(HopSubnet, subnet.ID, subnet.AZ, table)
+	}
+	path.Outcome = Delivered
+	return path
+}
+
+// vpcContaining returns the VPC whose CIDR contains addr, if any
+func (n *network) vpcContaining(addr netip.Addr) *VPC {
+	for _, vpc := range n.topology.VPCs {
+		if prefix, err := netip.ParsePrefix(vpc.CIDR); err == nil && prefix.Contains(addr) {
+			return vpc
+		}
+	}
+	return nil
+}
+
+// lookup returns the longest prefix match of a route table, including the implied
+// local route of its VPC
+func (n *network) lookup(table *RouteTable, destination netip.Addr) (Route, bool, bool) {
+	var best Route
+	bestBits, local := -1, false
+	if vpc, ok := n.vpcs[table.VPC]; ok {
+		if prefix, err := netip.ParsePrefix(vpc.CIDR); err == nil && prefix.Contains(destination) {
+			bestBits, local = prefix.Bits(), true
+		}
+	}
+	for _, route := range table.Routes {
+		prefix, err := netip.ParsePrefix(route.Destination)
+		if err != nil || !prefix.Contains(destination) || prefix.Bits() <= bestBits {
+			continue
+		}
+		best, bestBits, local = route, prefix.Bits(), false
+	}
+	return best, local, bestBits >= 0
+}
+
+// inspect adds the load balancer and firewall hops of an endpoint, returning why the
+// flow is dropped when no firewall can take it
+func (n *network) inspect(path *Path, endpoint *Endpoint, az string) string {
+	balancer, ok := n.loadBalancers[endpoint.LoadBalancer]
+	if !ok {
+		return fmt.Sprintf("endpoint %s has no load balancer", endpoint.ID)
+	}
+	var targets []Target
+	for _, target := range balancer.Targets {
+		if balancer.CrossZone || target.AZ == az {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Sprintf("load balancer %s has no target in %s", balancer.ID, az)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	target := targets[path.Flow.hash()%uint32(len(targets))]
+
+	path.add(HopLoadBalancer, balancer.ID, az, "")
+	path.add(HopFirewall, target.ID, target.AZ, "")
+	path.add(HopEndpoint, endpoint.ID, az, "")
+	return ""
+}
+
+// transit routes the flow through a transit gateway, returning the subnet of the
+// attachment it leaves through, or nil when the flow is dropped
+func (n *network) transit(path *Path, tgw *TransitGateway, vpc, az, from string, visited map[string]bool) *Subnet {
+	var source *Attachment
+	for _, attachment := range tgw.Attachments {
+		if attachment.VPC == vpc {
+			source = attachment
+		}
+	}
+	if source == nil {
+		path.drop(Blackhole, "route table %s sends traffic to %s, which has no attachment for %s", from, tgw.ID, vpc)
+		return nil
+	}
+	if az != "" && !n.hasSubnetIn(source, az) {
+		path.drop(Blackhole, "attachment %s has no subnet in %s", source.ID, az)
+		return nil
+	}
+
+	tableID := source.RouteTable
+	if tableID == "" {
+		tableID = tgw.DefaultRouteTable
+	}
+	table, ok := n.tgwRouteTables[tableID]
+	if !ok {
+		path.drop(Blackhole, "attachment %s is associated with no route table", source.ID)
+		return nil
+	}
+	path.add(HopTransitGateway, tgw.ID, az, table.ID)
+	if visited[table.ID] {
+		path.drop(Loop, "transit gateway route table %s is used again", table.ID)
+		return nil
+	}
+	visited[table.ID] = true
+
+	route, ok := n.lookupTGW(tgw, table, path.Flow.Destination)
+	switch {
+	case !ok:
+		path.drop(Blackhole, "transit gateway route table %s has no route to %s", table.ID, path.Flow.Destination)
+		return nil
+	case route.Blackhole:
+		path.drop(Blackhole, "transit gateway route table %s blackholes %s", table.ID, route.Destination)
+		return nil
+	}
+
+	var destination *Attachment
+	for _, attachment := range tgw.Attachments {
+		if attachment.ID == route.Attachment {
+			destination = attachment
+		}
+	}
+	if destination == nil {
+		path.drop(Blackhole, "transit gateway route table %s sends %s to %s, which is not an attachment of %s", table.ID, route.Destination, route.Attachment, tgw.ID)
+		return nil
+	}
+	subnet := n.attachmentSubnet(destination, az, path.Flow)
+	if subnet == nil {
+		path.drop(Blackhole, "attachment %s has no subnets", destination.ID)
+		return nil
+	}
+	path.add(HopAttachment, destination.ID, subnet.AZ, table.ID)
+	return subnet
+}
+
+// hasSubnetIn reports whether an attachment has a subnet in az
+func (n *network) hasSubnetIn(attachment *Attachment, az string) bool {
+	for _, id := range attachment.Subnets {
+		if subnet, ok := n.subnets[id]; ok && subnet.AZ == az {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTGW returns the longest prefix match of the static and propagated routes of
+// a transit gateway route table. Static routes win over propagated routes with the
+// same prefix.
+func (n *network) lookupTGW(tgw *TransitGateway, table *TGWRouteTable, destination netip.Addr) (TGWRoute, bool) {
+	routes := append([]TGWRoute(nil), table.Routes...)
+	for _, attachment := range tgw.Attachments {
+		propagations := attachment.Propagations
+		if len(propagations) == 0 && attachment.RouteTable == "" && tgw.DefaultRouteTable != "" {
+			propagations = []string{tgw.DefaultRouteTable}
+		}
+		vpc, ok := n.vpcs[attachment.VPC]
+		if !ok {
+			continue
+		}
+		for _, propagation := range propagations {
+			if propagation == table.ID {
+				routes = append(routes, TGWRoute{Destination: vpc.CIDR, Attachment: attachment.ID})
+			}
+		}
+	}
+
+	var best TGWRoute
+	bestBits := -1
+	for _, route := range routes {
+		prefix, err := netip.ParsePrefix(route.Destination)
+		if err != nil || !prefix.Contains(destination) || prefix.Bits() <= bestBits {
+			continue
+		}
+		best, bestBits = route, prefix.Bits()
+	}
+	return best, bestBits >= 0
+}
+
+// attachmentSubnet picks the subnet a transit gateway sends a flow to. Appliance
+// mode picks one by flow hash, so both directions of a flow use the same AZ;
+// otherwise the flow stays in the AZ it came from when the attachment has a subnet
+// there.
+func (n *network) attachmentSubnet(attachment *Attachment, az string, flow Flow) *Subnet {
+	var subnets []*Subnet
+	for _, id := range attachment.Subnets {
+		if subnet, ok := n.subnets[id]; ok {
+			subnets = append(subnets, subnet)
+		}
+	}
+	if len(subnets) == 0 {
+		return nil
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		if subnets[i].AZ != subnets[j].AZ {
+			return subnets[i].AZ < subnets[j].AZ
+		}
+		return subnets[i].ID < subnets[j].ID
+	})
+	if attachment.ApplianceMode {
+		return subnets[flow.hash()%uint32(len(subnets))]
+	}
+	for _, subnet := range subnets {
+		if subnet.AZ == az {
+			return subnet
+		}
+	}
+	return subnets[0]
+}